@@ -4,39 +4,113 @@ TLS Certificate Monitor - Main Application Entry Point
 """
 
 import asyncio
+import json
 import logging
 import signal
 import sys
 from pathlib import Path
-from typing import Optional
+from typing import Any, Callable, Coroutine, Dict, List, Optional, Tuple
 
 import click
 import uvicorn
+from cryptography.hazmat.primitives import serialization
 from fastapi import FastAPI
 
 from tls_cert_monitor import __version__
+from tls_cert_monitor.alerts import AlertEngine
 from tls_cert_monitor.api import create_app
+from tls_cert_monitor.audit import setup_audit_log
 from tls_cert_monitor.cache import CacheManager
-from tls_cert_monitor.config import Config, load_config
+from tls_cert_monitor.changes import ChangeTracker
+from tls_cert_monitor.check import (
+    CHECK_TIMEOUT_SECONDS,
+    certificate_findings,
+    check_target,
+    format_check,
+    worst_finding,
+)
+from tls_cert_monitor.config import (
+    Config,
+    config_json_schema,
+    generate_example_config,
+    load_config,
+)
+from tls_cert_monitor.cyclonedx import build_bom
+from tls_cert_monitor.digest import DigestReporter
+from tls_cert_monitor.grafana import build_dashboard
+from tls_cert_monitor.health import HealthChecker, run_blocking
+from tls_cert_monitor.history import CertificateHistory
 from tls_cert_monitor.hot_reload import HotReloadManager
 from tls_cert_monitor.logger import setup_logging
-from tls_cert_monitor.metrics import MetricsCollector
+from tls_cert_monitor.metrics import (
+    SEVERITY_LEVELS,
+    MetricsCollector,
+    count_certificates_by_severity,
+)
+from tls_cert_monitor.notifiers import create_notifiers
+from tls_cert_monitor.output import (
+    OUTPUT_FORMATS,
+    format_csv,
+    format_json,
+    format_results,
+    format_table,
+)
+from tls_cert_monitor.prometheus_rules import build_rules, format_rules
+from tls_cert_monitor.remote_config import check_remote_config
+from tls_cert_monitor.scan_state import ScanStateStore
 from tls_cert_monitor.scanner import CertificateScanner
+from tls_cert_monitor.silences import SilenceManager
+from tls_cert_monitor.snmp import SnmpAgent
+from tls_cert_monitor.systemd import SystemdNotifier
+from tls_cert_monitor.tracing import setup_tracing, shutdown_tracing
+from tls_cert_monitor.zabbix import ZabbixSender
+
+# Exit codes of --once by worst certificate severity, and of check by worst finding
+ONCE_EXIT_CODES = {"ok": 0, "warning": 1, "critical": 2, "expired": 2}
+CHECK_EXIT_CODES = {"ok": 0, "warning": 1, "critical": 2}
+# Exit code of --once and check when the scan or check cannot run (invalid configuration,
+# unreachable server, ...), as UNKNOWN of monitoring plugins
+FAILED_EXIT_CODE = 3
 
 
 class TLSCertMonitor:
     """Main application class for TLS Certificate Monitor."""
 
-    def __init__(self, config_path: Optional[str] = None, dry_run: bool = False):
+    def __init__(
+        self,
+        config_path: Optional[str] = None,
+        dry_run: bool = False,
+        once: bool = False,
+        output: str = "text",
+        config_overrides: Optional[Dict[str, Any]] = None,
+        remote_config: Optional[str] = None,
+        profile: Optional[str] = None,
+    ):
         self.config: Optional[Config] = None
         self.scanner: Optional[CertificateScanner] = None
         self.metrics: Optional[MetricsCollector] = None
         self.cache: Optional[CacheManager] = None
+        self.silences: Optional[SilenceManager] = None
         self.hot_reload: Optional[HotReloadManager] = None
+        self.digest: Optional[DigestReporter] = None
+        self.alerts: Optional[AlertEngine] = None
+        self.systemd: Optional[SystemdNotifier] = None
+        self.zabbix: Optional[ZabbixSender] = None
+        self.snmp: Optional[SnmpAgent] = None
+        self.history: Optional[CertificateHistory] = None
+        self.changes: Optional[ChangeTracker] = None
+        self.scan_state: Optional[ScanStateStore] = None
+        self.health_checker = HealthChecker()
         self.app: Optional[FastAPI] = None
         self.config_path = config_path
+        self.config_overrides = config_overrides or {}
+        self.remote_config = remote_config
+        self.profile = profile
         self.dry_run = dry_run
+        self.once = once
+        self.output = output
         self._shutdown_event = asyncio.Event()
+        self._reload_task: Optional[asyncio.Task] = None
         # Initialize logger early to avoid AttributeError
         self.logger = logging.getLogger(__name__)
 
@@ -98,6 +172,15 @@ class TLSCertMonitor:
                     except (OSError, PermissionError):
                         continue
 
+    def _load_config(self) -> Config:
+        """Load configuration from all sources given on the command line."""
+        return load_config(
+            self.config_path,
+            overrides=self.config_overrides,
+            remote_config=self.remote_config,
+            profile=self.profile,
+        )
+
     async def initialize(self) -> None:
         """Initialize all application components."""
         try:
@@ -105,10 +188,15 @@ class TLSCertMonitor:
             self._ensure_temp_directory()
 
             # Load configuration
-            self.config = load_config(self.config_path)
+            self.config = self._load_config()
 
             # Setup logging
             setup_logging(self.config)
+            setup_audit_log(self.config.audit_log, self.config.syslog)
+            try:
+                setup_tracing(self.config.tracing)
+            except RuntimeError as e:
+                self.logger.error(f"Tracing disabled: {e}")
             self.logger.info("Initializing TLS Certificate Monitor")
 
             # Initialize cache
@@ -118,21 +206,98 @@ class TLSCertMonitor:
             # Initialize metrics collector
             self.metrics = MetricsCollector()
 
+            # Initialize silences / maintenance windows
+            self.silences = SilenceManager(self.config)
+
             # Initialize certificate scanner
             self.scanner = CertificateScanner(
-                config=self.config, cache=self.cache, metrics=self.metrics
+                config=self.config,
+                cache=self.cache,
+                metrics=self.metrics,
+                silences=self.silences,
             )
 
-            # Initialize hot reload manager
-            if self.config.hot_reload:
-                self.hot_reload = HotReloadManager(
-                    config=self.config, scanner=self.scanner, config_path=self.config_path
+            notifiers = create_notifiers(self.config.notifiers)
+
+            # Initialize built-in alert rules
+            if self.config.alerts.enabled and not self.dry_run:
+                self.alerts = AlertEngine(
+                    config=self.config, scanner=self.scanner, notifiers=notifiers
+                )
+
+            # Initialize scheduled digest reports (not needed for a one-off dry-run scan)
+            if self.config.digest.enabled and not self.dry_run:
+                self.digest = DigestReporter(
+                    config=self.config, scanner=self.scanner, notifiers=notifiers
                 )
-                await self.hot_reload.start()
+                await self.digest.start()
+
+            # Push certificate expiry items to Zabbix after every scan
+            if self.config.zabbix.enabled and not self.dry_run:
+                self.zabbix = ZabbixSender(config=self.config, scanner=self.scanner)
+
+            # Record every observed certificate for audits
+            if self.config.history.enabled and not self.dry_run:
+                self.history = CertificateHistory(config=self.config, scanner=self.scanner)
+
+            # Diff every scan against the previous one for /api/v1/changes and /api/v1/events
+            if self.config.changes.enabled and not self.dry_run:
+                self.changes = ChangeTracker(config=self.config, scanner=self.scanner)
+
+            # Serve the last scan before the restart, flagged stale, until the first scan
+            if self.config.scan_state_path() and not self.dry_run:
+                self.scan_state = ScanStateStore(config=self.config, scanner=self.scanner)
+                restored = self.scan_state.load()
+                if restored:
+                    restored = self.scanner.restore_scan_results(restored)
+                    self.logger.info(
+                        f"Restored the scan state of {len(restored['directories'])} directories "
+                        f"from {self.scan_state.path}"
+                    )
+                    # The first scan then reports what changed while the monitor was down
+                    if self.changes:
+                        self.changes.record(restored)
+
+            # Answer SNMP managers polling the summary of the last scan
+            if self.config.snmp.enabled and not self.dry_run:
+                self.snmp = SnmpAgent(config=self.config, scanner=self.scanner)
+                await self.snmp.start()
+
+            # Initialize hot reload manager (also handles SIGHUP reloads when
+            # file watching is disabled; start() only watches if hot_reload is set)
+            self.hot_reload = HotReloadManager(
+                config=self.config,
+                scanner=self.scanner,
+                config_path=self.config_path,
+                config_overrides=self.config_overrides,
+                remote_config=self.remote_config,
+                profile=self.profile,
+            )
+            await self.hot_reload.start()
+            self.health_checker.register("hot_reload", self.hot_reload.get_health_status)
+            if self.remote_config:
+                remote_config, scanner = self.remote_config, self.scanner
+
+                async def remote_config_health() -> Dict[str, Any]:
+                    # Give up along with the check, so a slow store doesn't keep a health thread
+                    return await run_blocking(
+                        check_remote_config,
+                        remote_config,
+                        scanner.config.health_check_timeout_seconds,
+                    )
+
+                self.health_checker.register("remote_config", remote_config_health)
 
             # Create FastAPI app
             self.app = create_app(
-                scanner=self.scanner, metrics=self.metrics, cache=self.cache, config=self.config
+                scanner=self.scanner,
+                metrics=self.metrics,
+                cache=self.cache,
+                config=self.config,
+                hot_reload=self.hot_reload,
+                health_checker=self.health_checker,
+                history=self.history,
+                changes=self.changes,
             )
 
             # Start initial scan
@@ -144,23 +309,280 @@ class TLSCertMonitor:
             self.logger.error(f"Failed to initialize application: {e}")
             raise
 
-    async def run(self) -> None:
-        """Run the application server or perform dry-run scan."""
+    async def dry_run_scan(self) -> int:
+        """
+        Validate the configuration and simulate a scan without starting the server.
+
+        Directories are walked read-only: the cache is never loaded or persisted and no
+        notifications are sent.
+
+        Returns:
+            Exit code: 0 if every directory could be scanned, 1 otherwise
+        """
+        self._ensure_temp_directory()
+        self.config = self._load_config()
+        # Keep stdout for machine-readable output
+        setup_logging(self.config, stream=sys.stderr if self.output != "text" else None)
+        self.logger.info("Running in dry-run mode - simulating a scan without starting the server")
+        if self.config.tls_cert and self.config.tls_key:
+            check_tls_key(self.config.tls_key, self.config.tls_key_password)
+
+        # The cache is never initialized, so nothing is read from or written to disk
+        self.cache = CacheManager(self.config)
+        self.metrics = MetricsCollector()
+        self.silences = SilenceManager(self.config)
+        self.scanner = CertificateScanner(
+            config=self.config, cache=self.cache, metrics=self.metrics, silences=self.silences
+        )
+
+        try:
+            results = await self.scanner.simulate_scan()
+        finally:
+            await self.scanner.stop()
+
+        if self.output == "text":
+            print(format_dry_run_summary(results))
+        else:
+            print(format_results(results, self.output))
+        self.logger.info("Dry-run scan completed")
+
+        failed = any("error" in result for result in results["directories"].values())
+        return 1 if failed else 0
+
+    async def once_scan(self) -> int:
+        """
+        Perform a single scan without starting the server, for cron jobs and CI gates.
+
+        The cache is used as by the server (a file cache speeds up repeated runs); no
+        notifications are sent, but items are pushed to Zabbix if enabled (a zabbix_sender cron
+        job) and certificates recorded in the history if enabled. Logs go to stderr, the summary
+        to stdout.
+
+        Returns:
+            Exit code by worst certificate severity: 0 ok, 1 warning, 2 critical or expired
+        """
+        self._ensure_temp_directory()
+        self.config = self._load_config()
+        setup_logging(self.config, stream=sys.stderr)
+
+        self.cache = CacheManager(self.config)
+        await self.cache.initialize()
+        self.metrics = MetricsCollector()
+        self.silences = SilenceManager(self.config)
+        self.scanner = CertificateScanner(
+            config=self.config, cache=self.cache, metrics=self.metrics, silences=self.silences
+        )
+        if self.config.zabbix.enabled:
+            self.zabbix = ZabbixSender(config=self.config, scanner=self.scanner)
+        if self.config.history.enabled:
+            self.history = CertificateHistory(config=self.config, scanner=self.scanner)
+
+        try:
+            results = await self.scanner.scan_once()
+        finally:
+            await self.scanner.stop()
+            await self.cache.close()
+            if self.history:
+                self.history.close()
+
+        if self.output == "text":
+            print(format_once_summary(results))
+        else:
+            print(format_results(results, self.output))
+        # Silenced certificates are acknowledged: they do not fail the run
+        counts = count_certificates_by_severity(results["directories"], include_silenced=False)
+        return max(
+            (code for severity, code in ONCE_EXIT_CODES.items() if counts[severity]), default=0
+        )
+
+    async def validate(self) -> int:
+        """
+        Validate the configuration from all sources, including the server's TLS key.
+
+        Returns:
+            Exit code: 0 if the configuration is valid, 1 otherwise
+        """
+        try:
+            self.config = self._load_config()
+            if self.config.tls_cert and self.config.tls_key:
+                check_tls_key(self.config.tls_key, self.config.tls_key_password)
+        except ValueError as e:
+            print(f"Configuration is invalid: {e}")
+            return 1
+
+        directories = len(self.config.certificate_directories)
+        print(
+            f"Configuration is valid (certificate directories: {directories}, "
+            f"scan interval: {self.config.scan_interval})"
+        )
+        return 0
+
+    async def generate_dashboard(self, file: str) -> int:
+        """
+        Write the Grafana dashboard for the configuration's thresholds.
+
+        Args:
+            file: Dashboard file ("-": stdout)
+
+        Returns:
+            Exit code: 0 if written, 1 if the configuration is invalid
+        """
+        try:
+            self.config = self._load_config()
+        except ValueError as e:
+            print(f"Configuration is invalid: {e}", file=sys.stderr)
+            return 1
+
+        dashboard = json.dumps(build_dashboard(self.config), indent=2) + "\n"
+        _write_generated(file, dashboard, "Grafana dashboard")
+        return 0
+
+    async def generate_rules(self, file: str, expiry_days: Optional[List[int]] = None) -> int:
+        """
+        Write the Prometheus alerting rules for the configuration's thresholds.
+
+        Args:
+            file: Rule file ("-": stdout)
+            expiry_days: Days before expiry to alert at (default: the expiry thresholds)
+
+        Returns:
+            Exit code: 0 if written, 1 if the configuration is invalid
+        """
+        try:
+            self.config = self._load_config()
+        except ValueError as e:
+            print(f"Configuration is invalid: {e}", file=sys.stderr)
+            return 1
+
+        rules = format_rules(build_rules(self.config, expiry_days))
+        _write_generated(file, rules, "Prometheus alerting rules")
+        return 0
+
+    async def check(
+        self, target: str, timeout: float = CHECK_TIMEOUT_SECONDS, output: str = "text"
+    ) -> int:
+        """
+        Check one certificate file or TLS server (host:port) and print its details and findings.
+
+        Args:
+            target: File path or host:port
+            timeout: Seconds to connect to a TLS server
+            output: Output format: text, json (certificate data and findings), table, csv or
+                cyclonedx
+
+        Returns:
+            Exit code by worst finding: 0 none, 1 warning, 2 critical, 3 if the check failed
+        """
+        self.config = self._load_config()
+        self.metrics = MetricsCollector()
+        self.scanner = CertificateScanner(
+            config=self.config,
+            cache=CacheManager(self.config),
+            metrics=self.metrics,
+            silences=SilenceManager(self.config),
+        )
+        try:
+            cert = check_target(self.scanner, target, timeout)
+        except (OSError, RuntimeError, ValueError) as e:
+            print(f"Check failed: {e}", file=sys.stderr)
+            return FAILED_EXIT_CODE
+        finally:
+            await self.scanner.stop()
+
+        findings = certificate_findings(cert)
+        if output == "json":
+            findings_data = [{"severity": s, "finding": d} for s, d in findings]
+            print(format_json({**cert, "findings": findings_data}))
+        elif output == "table":
+            print(format_table([cert]))
+        elif output == "csv":
+            print(format_csv([cert]))
+        elif output == "cyclonedx":
+            print(format_json(build_bom([cert])))
+        else:
+            print(format_check(cert, findings))
+        # Silenced certificates are acknowledged: shown with their findings, exit code 0
+        return 0 if cert.get("silenced") else CHECK_EXIT_CODES[worst_finding(findings)]
+
+    async def transfer_cache(
+        self, export_path: Optional[str] = None, import_path: Optional[str] = None
+    ) -> int:
+        """
+        Export the persistent cache to a snapshot file and/or import one into it.
+
+        Args:
+            export_path: Snapshot file to write ("-" for stdout)
+            import_path: Snapshot file to import ("-" for stdin)
+
+        Returns:
+            Exit code: 0 on success, 1 if the cache is not persistent or the import failed
+        """
+        self.config = self._load_config()
+        # Keep stdout for the snapshot
+        setup_logging(self.config, stream=sys.stderr if export_path == "-" else None)
+        self.cache = CacheManager(self.config)
+        if not self.cache.persistent:
+            print("Cache snapshots need a file cache: set cache_type to file or both")
+            return 1
+
+        await self.cache.initialize()
+        try:
+            if import_path:
+                if import_path == "-":
+                    snapshot_text = sys.stdin.read()
+                else:
+                    snapshot_text = Path(import_path).read_text(encoding="utf-8")
+                try:
+                    result = await self.cache.import_snapshot(json.loads(snapshot_text))
+                except ValueError as e:
+                    print(f"Failed to import cache snapshot: {e}")
+                    return 1
+                print(
+                    f"Imported {result['imported']} cache entries "
+                    f"({result['skipped']} skipped) into {self.cache.cache_file}",
+                    file=sys.stderr if export_path == "-" else sys.stdout,
+                )
+
+            if export_path:
+                snapshot_text = json.dumps(await self.cache.export_snapshot(), indent=2)
+                if export_path == "-":
+                    print(snapshot_text)
+                else:
+                    Path(export_path).write_text(snapshot_text + "\n", encoding="utf-8")
+                    print(f"Cache snapshot written to {export_path}")
+        finally:
+            await self.cache.close()
+        return 0
+
+    async def run(self) -> int:
+        """
+        Run the application server or perform dry-run scan.
+
+        Returns:
+            Process exit code
+        """
+        # Handle dry-run mode
+        if self.dry_run:
+            return await self.dry_run_scan()
+        if self.once:
+            return await self.once_scan()
+
         if not self.app:
             await self.initialize()
 
         # At this point, config is guaranteed to be set by initialize()
         assert self.config is not None, "Config should be initialized"
 
-        # Handle dry-run mode
-        if self.dry_run:
-            self.logger.info("Running in dry-run mode - scanning certificates only")
-            # Perform one scan and exit
-            if self.scanner:
-                await self.scanner.scan_once()
-            self.logger.info("Dry-run scan completed")
-            await self.shutdown()
-            return
+        # Optionally serve only once metrics hold the results of the first scan
+        if self.config.initial_scan_wait_seconds and self.scanner:
+            self.logger.info(
+                f"Waiting up to {self.config.initial_scan_wait} for the initial scan before serving"
+            )
+            if not await self.scanner.wait_for_first_scan(self.config.initial_scan_wait_seconds):
+                self.logger.warning(
+                    f"Initial scan not completed within {self.config.initial_scan_wait} - "
+                    "serving anyway"
+                )
 
         config_dict = {
             "app": self.app,
@@ -172,10 +594,12 @@ class TLSCertMonitor:
 
         # Add TLS configuration if provided
         if self.config.tls_cert and self.config.tls_key:
+            check_tls_key(self.config.tls_key, self.config.tls_key_password)
             config_dict.update(
                 {
                     "ssl_keyfile": self.config.tls_key,
                     "ssl_certfile": self.config.tls_cert,
+                    "ssl_keyfile_password": self.config.tls_key_password,
                 }
             )
             self.logger.info(
@@ -190,8 +614,17 @@ class TLSCertMonitor:
         for sig in [signal.SIGTERM, signal.SIGINT]:
             signal.signal(sig, self._signal_handler)
 
+        # SIGHUP forces a config reload and re-scan (not available on Windows)
+        if hasattr(signal, "SIGHUP"):
+            asyncio.get_running_loop().add_signal_handler(signal.SIGHUP, self._reload_handler)
+
         server = uvicorn.Server(uvicorn.Config(**config_dict))  # type: ignore[arg-type]
 
+        # Under systemd, notify readiness after the first scan and ping the watchdog
+        if self.scanner:
+            self.systemd = SystemdNotifier(self.scanner, lambda: server.started)
+            await self.systemd.start()
+
         # Run server with graceful shutdown
         try:
             await server.serve()
@@ -200,49 +633,459 @@ class TLSCertMonitor:
         finally:
             await self.shutdown()
 
+        return 0
+
     def _signal_handler(self, signum: int, frame: Optional[object]) -> None:
         """Handle shutdown signals."""
         if hasattr(self, "logger"):
             self.logger.info(f"Received signal {signum}, initiating graceful shutdown")
         self._shutdown_event.set()
 
+    def _reload_handler(self) -> None:
+        """Handle SIGHUP by reloading configuration and re-scanning."""
+        self.logger.info("Received SIGHUP, reloading configuration")
+        if self.hot_reload:
+            self._reload_task = asyncio.create_task(self.hot_reload.reload_and_rescan())
+
     async def shutdown(self) -> None:
         """Gracefully shutdown all components."""
         if hasattr(self, "logger"):
             self.logger.info("Starting graceful shutdown")
 
+        # Tell systemd the monitor is stopping
+        if self.systemd:
+            await self.systemd.stop()
+
         # Stop hot reload manager
         if self.hot_reload:
             await self.hot_reload.stop()
 
+        # Stop digest reports
+        if self.digest:
+            await self.digest.stop()
+
+        # End event streams, so open connections do not hold up the server shutdown
+        if self.changes:
+            self.changes.close()
+
+        # Stop the SNMP agent
+        if self.snmp:
+            await self.snmp.stop()
+
         # Stop scanner
         if self.scanner:
             await self.scanner.stop()
 
-        # Close cache
+        # Close the certificate history (after the scanner: a scan may be recording)
+        if self.history:
+            self.history.close()
+
+        # Close cache (hot reload may have replaced the scanner's cache)
+        if self.scanner:
+            self.cache = self.scanner.cache
         if self.cache:
             await self.cache.close()
 
+        # Export the spans still pending
+        shutdown_tracing()
+
         if hasattr(self, "logger"):
             self.logger.info("Graceful shutdown completed")
 
 
-@click.command()
-@click.option(
-    "--config",
-    "-f",
-    type=click.Path(exists=True, path_type=Path),
-    help="Path to configuration file",
+def check_tls_key(key_path: str, password: Optional[str]) -> None:
+    """
+    Check that the server's TLS key can be loaded with the configured passphrase.
+
+    Args:
+        key_path: PEM private key file
+        password: Passphrase for an encrypted key
+
+    Raises:
+        ValueError: If the key cannot be read or decrypted
+    """
+    try:
+        key_data = Path(key_path).read_bytes()
+    except OSError as e:
+        raise ValueError(f"Cannot read TLS key {key_path}: {e}") from e
+
+    try:
+        serialization.load_pem_private_key(
+            key_data, password=password.encode("utf-8") if password else None
+        )
+    except TypeError as e:
+        # Raised for an encrypted key without a password (or a password for a plain key)
+        if password:
+            raise ValueError(f"TLS key {key_path} is not encrypted; remove tls_key_password") from e
+        raise ValueError(
+            f"TLS key {key_path} is encrypted; set tls_key_password or tls_key_password_file"
+        ) from e
+    except ValueError as e:
+        raise ValueError(f"Cannot load TLS key {key_path} (wrong passphrase?): {e}") from e
+
+
+def format_dry_run_summary(results: Dict[str, Any]) -> str:
+    """
+    Format simulated scan results for the terminal.
+
+    Args:
+        results: Results from CertificateScanner.simulate_scan()
+
+    Returns:
+        Human-readable summary
+    """
+    lines = []
+    totals = {"matched": 0, "excluded": 0, "parsed": 0, "errors": 0}
+
+    for directory, result in results["directories"].items():
+        lines.append(directory)
+        if "error" in result:
+            lines.append(f"  ERROR     {result['error']}")
+            totals["errors"] += 1
+            continue
+        if "skipped" in result:
+            lines.append(f"  SKIPPED   {result['skipped']}")
+            continue
+
+        lines.append(
+            f"  files matched: {len(result['matched'])}, excluded: {len(result['excluded'])}, "
+            f"parsed: {len(result['certificates'])}, errors: {len(result['errors'])}"
+        )
+        for item in result["excluded"]:
+            lines.append(f"  excluded  {item['path']} ({item['reason']})")
+        for cert in result["certificates"]:
+            lines.append(
+                f"  parsed    {cert['path']}: {cert.get('common_name', 'unknown')}, "
+                f"expires {cert.get('not_after', 'unknown')} [{cert.get('severity', 'ok')}]"
+            )
+        for item in result["errors"]:
+            lines.append(f"  error     {item['path']}: {item['error']}")
+
+        totals["matched"] += len(result["matched"])
+        totals["excluded"] += len(result["excluded"])
+        totals["parsed"] += len(result["certificates"])
+        totals["errors"] += len(result["errors"])
+
+    lines.append(
+        f"Summary: {len(results['directories'])} directories, {totals['matched']} files matched, "
+        f"{totals['excluded']} excluded, {totals['parsed']} parsed, {totals['errors']} error(s)"
+    )
+    return "\n".join(lines)
+
+
+def format_once_summary(results: Dict[str, Any]) -> str:
+    """
+    Format the results of a --once scan for the terminal.
+
+    Certificates that are not ok are listed worst first, then directories that failed to scan
+    (files that failed to parse are counted; they are logged).
+
+    Args:
+        results: Results from CertificateScanner.scan_once()
+
+    Returns:
+        Human-readable summary
+    """
+    lines = []
+    certificates = [
+        cert
+        for result in results["directories"].values()
+        for cert in result.get("certificates", [])
+        if cert.get("severity", "ok") != "ok"
+    ]
+    certificates.sort(key=lambda cert: -SEVERITY_LEVELS.index(cert["severity"]))
+    for cert in certificates:
+        silenced = " (silenced)" if cert.get("silenced") else ""
+        lines.append(
+            f"{cert['severity'].upper():<9} {cert['path']}: {cert.get('common_name', 'unknown')}, "
+            f"expires {cert.get('not_after', 'unknown')}{silenced}"
+        )
+    for directory, result in results["directories"].items():
+        if "error" in result:
+            lines.append(f"ERROR     {directory}: {result['error']}")
+
+    counts = count_certificates_by_severity(results["directories"])
+    summary = results["summary"]
+    lines.append(
+        f"Summary: {sum(counts.values())} certificates ({counts['expired']} expired, "
+        f"{counts['critical']} critical, {counts['warning']} warning, {counts['ok']} ok), "
+        f"{summary['total_files']} files, {summary['total_errors']} error(s) "
+        f"in {summary['total_duration']:.2f}s"
+    )
+    return "\n".join(lines)
+
+
+def _cli_overrides(**options: Any) -> Dict[str, Any]:
+    """
+    Build configuration overrides from command line flags.
+
+    Flags that were not given (None or empty multi-value options) are dropped so
+    the environment and configuration file values still apply.
+    """
+    overrides: Dict[str, Any] = {}
+    expiry_thresholds = {}
+
+    for key, value in options.items():
+        if value is None or value == ():
+            continue
+        if key in ("expiry_warning", "expiry_critical"):
+            expiry_thresholds[key.replace("expiry_", "")] = value
+        elif isinstance(value, tuple):
+            overrides[key] = list(value)
+        else:
+            overrides[key] = value
+
+    if expiry_thresholds:
+        overrides["expiry_thresholds"] = expiry_thresholds
+
+    return overrides
+
+
+# Flags of the configuration sources; the other CONFIG_OPTIONS override settings
+SOURCE_OPTIONS = ("config", "remote_config", "profile")
+
+# Flags shared by the commands loading the configuration
+CONFIG_OPTIONS = [
+    click.option(
+        "--config",
+        "-f",
+        type=click.Path(exists=True, path_type=Path),
+        help="Path to configuration file or conf.d-style directory of *.yaml files",
+    ),
+    click.option(
+        "--remote-config",
+        envvar="TLS_MONITOR_REMOTE_CONFIG",
+        help="consul:// or etcd:// URL of a YAML configuration key, merged over the file",
+    ),
+    click.option(
+        "--profile",
+        help="Configuration profile from the profiles: section (default: TLS_MONITOR_PROFILE)",
+    ),
+    click.option("--port", type=int, help="Server port"),
+    click.option("--bind-address", help="Server bind address"),
+    click.option("--tls-cert", help="TLS certificate for the metrics endpoint"),
+    click.option("--tls-key", help="TLS private key for the metrics endpoint"),
+    click.option(
+        "--cert-dir",
+        "certificate_directories",
+        multiple=True,
+        help="Certificate directory to monitor (repeatable)",
+    ),
+    click.option(
+        "--exclude-dir",
+        "exclude_directories",
+        multiple=True,
+        help="Directory to exclude from scanning (repeatable)",
+    ),
+    click.option(
+        "--exclude-pattern",
+        "exclude_file_patterns",
+        multiple=True,
+        help="Regex of file names to exclude (repeatable)",
+    ),
+    click.option(
+        "--exclude-file",
+        "exclude_files",
+        multiple=True,
+        help="Glob of files to exclude, e.g. '*-backup.pem' (repeatable)",
+    ),
+    click.option(
+        "--p12-password",
+        "p12_passwords",
+        multiple=True,
+        help="Password to try for P12/PFX files (repeatable)",
+    ),
+    click.option("--scan-interval", help="Scan interval (e.g. 5m, 1h)"),
+    click.option("--workers", type=int, help="Number of scan workers"),
+    click.option(
+        "--log-level",
+        type=click.Choice(["DEBUG", "INFO", "WARNING", "ERROR", "CRITICAL"], case_sensitive=False),
+        help="Log level",
+    ),
+    click.option("--log-file", help="Log file path"),
+    click.option("--hot-reload/--no-hot-reload", default=None, help="Enable or disable hot reload"),
+    click.option(
+        "--watch-files/--no-watch-files",
+        default=None,
+        help="Enable or disable watching certificate directories for changes",
+    ),
+    click.option(
+        "--cache/--no-cache", "cache_enabled", default=None, help="Enable or disable the cache"
+    ),
+    click.option(
+        "--cache-type", type=click.Choice(["memory", "file", "both"]), help="Cache backend"
+    ),
+    click.option(
+        "--cache-backend", type=click.Choice(["json", "sqlite"]), help="File cache storage backend"
+    ),
+    click.option(
+        "--cache-compression",
+        type=click.Choice(["none", "gzip"]),
+        help="Compression of persisted cache entries",
+    ),
+    click.option("--cache-dir", help="Cache directory"),
+    click.option("--cache-ttl", help="Cache entry TTL (e.g. 1h)"),
+    click.option("--cache-max-size", type=int, help="Maximum cache size in bytes"),
+    click.option(
+        "--cache-max-entries", type=int, help="Maximum number of cache entries (0: no limit)"
+    ),
+    click.option("--cache-max-entry-size", type=int, help="Largest cached value in bytes"),
+    click.option(
+        "--cache-save-interval", help="Interval of file cache saves (e.g. 5m, 0s: never)"
+    ),
+    click.option(
+        "--ip-whitelist/--no-ip-whitelist",
+        "enable_ip_whitelist",
+        default=None,
+        help="Enable or disable the IP whitelist",
+    ),
+    click.option(
+        "--allowed-ip",
+        "allowed_ips",
+        multiple=True,
+        help="IP address or CIDR allowed to access the API (repeatable)",
+    ),
+    click.option("--expiry-warning", help="Warning expiry threshold (e.g. 30d)"),
+    click.option("--expiry-critical", help="Critical expiry threshold (e.g. 7d)"),
+]
+
+
+# Output format of the scan and check commands
+OUTPUT_OPTION = click.option(
+    "--output",
+    "-o",
+    type=click.Choice(OUTPUT_FORMATS),
+    default="text",
+    show_default=True,
+    help="Output format: text summary, or json, table, csv or cyclonedx for scripts and tickets",
 )
+
+
+def config_options(command: Callable[..., Any]) -> Callable[..., Any]:
+    """Add the configuration source and setting flags (CONFIG_OPTIONS) to a command."""
+    for option in reversed(CONFIG_OPTIONS):
+        command = option(command)
+    return command
+
+
+def _merge_options(ctx: click.Context, options: Dict[str, Any]) -> Dict[str, Any]:
+    """
+    Merge the flags of a command over those given before it.
+
+    Both tls-cert-monitor --config FILE serve and tls-cert-monitor serve --config FILE work.
+    """
+    merged = dict(ctx.obj or {})
+    merged.update({key: value for key, value in options.items() if value not in (None, ())})
+    return merged
+
+
+def _create_monitor(options: Dict[str, Any], **kwargs: Any) -> TLSCertMonitor:
+    """Create the application from configuration flags (see CONFIG_OPTIONS)."""
+    settings = {key: value for key, value in options.items() if key not in SOURCE_OPTIONS}
+    if settings.get("log_level"):
+        settings["log_level"] = settings["log_level"].upper()
+    config = options.get("config")
+    return TLSCertMonitor(
+        str(config) if config else None,
+        config_overrides=_cli_overrides(**settings),
+        remote_config=options.get("remote_config"),
+        profile=options.get("profile"),
+        **kwargs,
+    )
+
+
+def _run(command: Coroutine[Any, Any, int], failed_exit_code: int = 1) -> None:
+    """Run a command, exiting with its exit code (failed_exit_code if it raises)."""
+    try:
+        # Simple execution - Nuitka-winsvc handles service mode automatically
+        exit_code = asyncio.run(command)
+    except KeyboardInterrupt:
+        print("\nShutdown requested by user", file=sys.stderr)
+        sys.exit(0)
+    except Exception as e:
+        # On stderr: stdout may carry a scan report (--output json, csv or cyclonedx)
+        print(f"Application failed: {e}", file=sys.stderr)
+        sys.exit(failed_exit_code)
+    if exit_code:
+        sys.exit(exit_code)
+
+
+def _print_version() -> None:
+    """Print the version."""
+    print(f"TLS Certificate Monitor v{__version__}")
+
+
+def _write_generated(file: str, content: str, name: str) -> None:
+    """Write generated content (configuration, dashboard, rules) to a file ("-": stdout)."""
+    if file == "-":
+        print(content, end="")
+    else:
+        Path(file).write_text(content, encoding="utf-8")
+        print(f"{name} written to {file}")
+
+
+def _write_example_config(file: str) -> None:
+    """Write the commented example configuration to a file ("-": stdout)."""
+    _write_generated(file, generate_example_config(), "Example configuration")
+
+
+@click.group(invoke_without_command=True)
+@config_options
 @click.option("--version", "-v", is_flag=True, help="Show version information")
-@click.option("--dry-run", is_flag=True, help="Enable dry-run mode (scan only, don't start server)")
+@click.option(
+    "--dry-run",
+    is_flag=True,
+    help="Validate the config and simulate a read-only scan, printing a summary (no server)",
+)
+@click.option(
+    "--once",
+    is_flag=True,
+    help="Scan once, print a summary and exit: 0 ok, 1 warning, 2 critical or expired (no server)",
+)
+@click.option("--print-schema", is_flag=True, help="Print the configuration JSON Schema and exit")
+@click.option(
+    "--generate-config",
+    is_flag=False,
+    flag_value="-",
+    default=None,
+    metavar="[FILE]",
+    help="Write a commented example configuration with all defaults to FILE (or stdout) and exit",
+)
+@click.option(
+    "--generate-dashboard",
+    is_flag=False,
+    flag_value="-",
+    default=None,
+    metavar="[FILE]",
+    help="Write a Grafana dashboard of the metrics to FILE (or stdout) and exit",
+)
+@click.option(
+    "--export-cache",
+    metavar="FILE",
+    help="Export the file cache as a portable JSON snapshot to FILE ('-': stdout) and exit",
+)
+@click.option(
+    "--import-cache",
+    metavar="FILE",
+    help="Import a cache snapshot from FILE ('-': stdin) into the file cache and exit",
+)
+@click.pass_context
 def main(
-    config: Optional[Path],
+    ctx: click.Context,
     version: bool,
     dry_run: bool,
+    once: bool,
+    print_schema: bool,
+    generate_config: Optional[str],
+    generate_dashboard: Optional[str],
+    export_cache: Optional[str],
+    import_cache: Optional[str],
+    **options: Any,
 ) -> None:
     """TLS Certificate Monitor - Monitor SSL/TLS certificates for expiration and security issues.
 
+    Without a command, runs the server (serve). Flags given before a command apply to it.
+
     \b
     Windows Service Management (run as Administrator):
       .\\tls-cert-monitor.exe install    Install as Windows service
@@ -250,22 +1093,155 @@ def main(
 
     Note: Service management commands are handled by Nuitka-winsvc and
     will override the normal application behavior."""
+    ctx.obj = options
+    if ctx.invoked_subcommand is not None:
+        return
 
-    # Handle special flags first (before any potential import issues)
+    # Flags of the commands, kept for compatibility
     if version:
-        print(f"TLS Certificate Monitor v{__version__}")
+        _print_version()
+        return
+
+    if print_schema:
+        print(json.dumps(config_json_schema(), indent=2))
         return
 
+    if generate_config:
+        _write_example_config(generate_config)
+        return
+
+    monitor = _create_monitor(options, dry_run=dry_run, once=once)
+    if generate_dashboard:
+        _run(monitor.generate_dashboard(generate_dashboard))
+    elif export_cache or import_cache:
+        _run(monitor.transfer_cache(export_cache, import_cache))
+    else:
+        _run(monitor.run(), FAILED_EXIT_CODE if once else 1)
+
+
+@main.command()
+@config_options
+@click.pass_context
+def serve(ctx: click.Context, **options: Any) -> None:
+    """Scan periodically and serve metrics and the API (the default command)."""
+    _run(_create_monitor(_merge_options(ctx, options)).run())
+
+
+@main.command()
+@config_options
+@click.option(
+    "--dry-run",
+    is_flag=True,
+    help="Simulate a read-only scan instead, listing matched, excluded and parsed files",
+)
+@OUTPUT_OPTION
+@click.pass_context
+def scan(ctx: click.Context, dry_run: bool, output: str, **options: Any) -> None:
+    """Scan once, print a summary and exit.
+
+    The json output is the scan results as returned by the API's /scan endpoint; table and csv
+    list one certificate per row; cyclonedx is a CycloneDX BOM of the certificates.
+
+    \b
+    Exit codes: 0 ok, 1 warning, 2 critical or expired, 3 scan failed.
+    With --dry-run: 0 if every directory could be scanned, 1 otherwise."""
+    monitor = _create_monitor(
+        _merge_options(ctx, options), dry_run=dry_run, once=not dry_run, output=output
+    )
+    _run(monitor.run(), 1 if dry_run else FAILED_EXIT_CODE)
+
+
+@main.command()
+@click.argument("target")
+@config_options
+@click.option(
+    "--timeout",
+    type=float,
+    default=CHECK_TIMEOUT_SECONDS,
+    show_default=True,
+    help="Seconds to connect to a TLS server",
+)
+@OUTPUT_OPTION
+@click.option("--json", "as_json", is_flag=True, help="Same as --output json")
+@click.pass_context
+def check(
+    ctx: click.Context, target: str, timeout: float, output: str, as_json: bool, **options: Any
+) -> None:
+    """Check a certificate file or TLS server (host:port) and print its details and findings.
+
+    The thresholds, P12 passwords and silences come from the configuration.
+
+    \b
+    Exit codes: 0 no findings, 1 warning, 2 critical (e.g. expired), 3 check failed."""
+    # Only errors, on stderr: stdout is the report
+    logging.basicConfig(level=logging.ERROR, stream=sys.stderr)
+    monitor = _create_monitor(_merge_options(ctx, options))
     try:
-        # Simple execution - Nuitka-winsvc handles service mode automatically
-        monitor = TLSCertMonitor(str(config) if config else None, dry_run=dry_run)
-        asyncio.run(monitor.run())
-    except KeyboardInterrupt:
-        print("\nShutdown requested by user")
-        sys.exit(0)
+        exit_code = asyncio.run(monitor.check(target, timeout, "json" if as_json else output))
     except Exception as e:
-        print(f"Application failed: {e}")
-        sys.exit(1)
+        print(f"Check failed: {e}", file=sys.stderr)
+        exit_code = FAILED_EXIT_CODE
+    sys.exit(exit_code)
+
+
+@main.command()
+@config_options
+@click.pass_context
+def validate(ctx: click.Context, **options: Any) -> None:
+    """Validate the configuration and exit: 0 valid, 1 invalid.
+
+    Use scan --dry-run to also check which files the directories match."""
+    _run(_create_monitor(_merge_options(ctx, options)).validate())
+
+
+@main.command("version")
+def version_command() -> None:
+    """Show version information."""
+    _print_version()
+
+
+@main.command("generate-config")
+@click.argument("file", default="-")
+@click.option("--schema", is_flag=True, help="Print the configuration JSON Schema instead")
+def generate_config_command(file: str, schema: bool) -> None:
+    """Write a commented example configuration with all defaults to FILE (default: stdout)."""
+    if schema:
+        print(json.dumps(config_json_schema(), indent=2))
+        return
+    _write_example_config(file)
+
+
+@main.command("generate-dashboard")
+@click.argument("file", default="-")
+@config_options
+@click.pass_context
+def generate_dashboard_command(ctx: click.Context, file: str, **options: Any) -> None:
+    """Write a Grafana dashboard of the metrics to FILE (default: stdout).
+
+    Import it in Grafana or add it to a dashboard provisioning directory. The expiry thresholds
+    and scan interval of the configuration color its panels."""
+    _run(_create_monitor(_merge_options(ctx, options)).generate_dashboard(file))
+
+
+@main.command("generate-rules")
+@click.argument("file", default="-")
+@config_options
+@click.option(
+    "--expiry-days",
+    type=click.IntRange(min=1),
+    multiple=True,
+    help="Days before expiry to alert at, repeatable (default: the expiry thresholds)",
+)
+@click.pass_context
+def generate_rules_command(
+    ctx: click.Context, file: str, expiry_days: Tuple[int, ...], **options: Any
+) -> None:
+    """Write Prometheus alerting rules on the metrics to FILE (default: stdout).
+
+    Expiry windows, expired certificates, weak keys, deprecated signature algorithms and stalled
+    scans, with the thresholds and scan interval of the configuration."""
+    monitor = _create_monitor(_merge_options(ctx, options))
+    _run(monitor.generate_rules(file, list(expiry_days) or None))
 
 
 if __name__ == "__main__":
@@ -49,6 +49,11 @@ setup(
     install_requires=read_requirements("requirements.txt"),
     extras_require={
         "dev": read_requirements("requirements-dev.txt"),
+        "aws": ["boto3>=1.28.0,<2.0.0"],
+        "tracing": [
+            "opentelemetry-sdk>=1.20.0,<2.0.0",
+            "opentelemetry-exporter-otlp>=1.20.0,<2.0.0",
+        ],
     },
     entry_points={
         "console_scripts": [
@@ -0,0 +1,590 @@
+"""
+Tests for built-in alert rules.
+"""
+
+import time
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from tls_cert_monitor.alerts import ALERT_HOLD_SCANS, AlertEngine, AlertRouter
+from tls_cert_monitor.config import AlertsConfig, Config, NotifierConfig
+from tls_cert_monitor.notifiers import AlertmanagerNotifier, Notification
+
+
+def _cert(path, serial="1", **extra):
+    """Build certificate data as annotated by the scanner."""
+    return {
+        "common_name": "test.example.com",
+        "path": path,
+        "serial": serial,
+        "issuer": "Test CA",
+        "not_after": "2027-01-15T00:00:00+00:00",
+        "key_algorithm": "RSAPublicKey",
+        "key_size": 2048,
+        "signature_algorithm": "sha256WithRSAEncryption",
+        "is_weak_key": False,
+        "is_deprecated_algorithm": False,
+        "silenced": False,
+        **extra,
+    }
+
+
+def _scan(*certs):
+    return {"directories": {"/certs": {"certificates": list(certs), "parse_errors": 0}}}
+
+
+@pytest.fixture
+def config():
+    return Config(
+        certificate_directories=[],
+        notifiers=[{"name": "ops", "type": "webhook", "url": "http://localhost/hook"}],
+        alerts={"enabled": True, "notifiers": ["ops"]},
+    )
+
+
+@pytest.fixture
+def notifier():
+    notifier = MagicMock()
+    notifier.name = "ops"
+    notifier.send = AsyncMock()
+    return notifier
+
+
+@pytest.fixture
+def engine(config, notifier):
+    return AlertEngine(config=config, scanner=MagicMock(), notifiers={"ops": notifier})
+
+
+class TestAlertsConfig:
+    """Test alert configuration."""
+
+    def test_defaults(self):
+        """Test alerts are disabled by default."""
+        config = Config()
+        assert config.alerts.enabled is False
+        assert config.alerts.weak_crypto is True
+        assert config.alerts.expiry is True
+
+    def test_unknown_notifier_reference(self):
+        """Test alerts referencing an undefined notifier are rejected."""
+        with pytest.raises(ValueError):
+            Config(alerts={"enabled": True, "notifiers": ["missing"]})
+
+
+class TestWeakCryptoAlerts:
+    """Test weak key and deprecated algorithm alerts."""
+
+    def test_registers_scan_listener(self, config, notifier):
+        """Test engine evaluates after every scan."""
+        scanner = MagicMock()
+        engine = AlertEngine(config=config, scanner=scanner, notifiers={"ops": notifier})
+        scanner.add_scan_listener.assert_called_once_with(engine.evaluate)
+
+    @pytest.mark.asyncio
+    async def test_alerts_once_per_certificate(self, engine, notifier):
+        """Test an alert fires on first detection only."""
+        scan = _scan(
+            _cert("/certs/weak.pem", is_weak_key=True, key_size=1024),
+            _cert("/certs/ok.pem"),
+        )
+
+        first = await engine.evaluate(scan)
+        second = await engine.evaluate(scan)
+
+        assert len(first) == 1
+        assert second == []
+        notifier.send.assert_called_once()
+        notification = notifier.send.call_args[0][0]
+        assert notification.labels["rule"] == "weak_key"
+        assert notification.labels["path"] == "/certs/weak.pem"
+        assert "1024 bits" in notification.title
+
+    @pytest.mark.asyncio
+    async def test_weak_key_and_deprecated_algorithm(self, engine):
+        """Test both problems on one certificate raise separate alerts."""
+        scan = _scan(
+            _cert(
+                "/certs/legacy.pem",
+                is_weak_key=True,
+                is_deprecated_algorithm=True,
+                signature_algorithm="sha1WithRSAEncryption",
+            )
+        )
+
+        notifications = await engine.evaluate(scan)
+
+        assert {n.labels["rule"] for n in notifications} == {"weak_key", "deprecated_algorithm"}
+
+    @pytest.mark.asyncio
+    async def test_new_certificate_at_same_path(self, engine):
+        """Test a replacement certificate with the same problem alerts again."""
+        await engine.evaluate(_scan(_cert("/certs/weak.pem", "1", is_weak_key=True)))
+
+        replaced = _scan(_cert("/certs/weak.pem", "2", is_weak_key=True))
+        notifications = await engine.evaluate(replaced)
+
+        weak_key_alerts = [n for n in notifications if n.labels["rule"] == "weak_key"]
+        assert len(weak_key_alerts) == 1
+
+    @pytest.mark.asyncio
+    async def test_rearms_after_removal(self, engine):
+        """Test an alert fires again if the certificate disappears and comes back."""
+        scan = _scan(_cert("/certs/weak.pem", is_weak_key=True))
+
+        await engine.evaluate(scan)
+        await engine.evaluate(_scan())
+
+        assert len(await engine.evaluate(scan)) == 1
+
+    @pytest.mark.asyncio
+    async def test_silenced_certificates(self, engine):
+        """Test silenced certificates alert only after the silence ends."""
+        silenced = _scan(_cert("/certs/weak.pem", is_weak_key=True, silenced=True))
+        unsilenced = _scan(_cert("/certs/weak.pem", is_weak_key=True))
+
+        assert await engine.evaluate(silenced) == []
+        assert len(await engine.evaluate(unsilenced)) == 1
+
+    @pytest.mark.asyncio
+    async def test_rule_disabled(self, config, notifier):
+        """Test the rule can be disabled."""
+        config.alerts.weak_crypto = False
+        engine = AlertEngine(config=config, scanner=MagicMock(), notifiers={"ops": notifier})
+
+        assert await engine.evaluate(_scan(_cert("/certs/weak.pem", is_weak_key=True))) == []
+
+
+class TestExpiryAlerts:
+    """Test warning and critical expiry alerts and their resolution."""
+
+    @pytest.mark.asyncio
+    async def test_fires_once_and_resolves_after_renewal(self, engine):
+        """Test critical certificates alert once and resolve when renewed."""
+        critical = _cert("/certs/a.pem", "1", fingerprint_sha256="aa", severity="critical")
+        renewed = _cert("/certs/a.pem", "2", fingerprint_sha256="bb", severity="ok")
+
+        first = await engine.evaluate(_scan(critical))
+        assert [(n.labels["rule"], n.status) for n in first] == [("expiry", "firing")]
+        assert first[0].severity == "critical"
+        assert first[0].labels["fingerprint"] == "aa"
+
+        assert await engine.evaluate(_scan(critical)) == []
+
+        notifications = await engine.evaluate(_scan(renewed))
+        resolved = [n for n in notifications if n.labels["rule"] == "expiry"]
+        assert [n.status for n in resolved] == ["resolved"]
+        assert resolved[0].labels == first[0].labels
+        assert "serial 2" in resolved[0].body
+
+    @pytest.mark.asyncio
+    async def test_expired_and_removed(self, engine):
+        """Test expired certificates fire and resolve when the file is removed."""
+        expired = _cert("/certs/old.pem", fingerprint_sha256="cc", severity="expired")
+
+        notifications = await engine.evaluate(_scan(expired))
+        assert "has expired" in notifications[0].title
+
+        notifications = await engine.evaluate(_scan())
+        assert notifications[0].status == "resolved"
+        assert "no longer present" in notifications[0].body
+
+    @pytest.mark.asyncio
+    async def test_warning_fires(self, engine):
+        """Test certificates in the warning window raise a warning expiry alert once."""
+        warning = _cert(
+            "/certs/a.pem",
+            fingerprint_sha256="aa",
+            severity="warning",
+            expiration_timestamp=time.time() + 20.5 * 86400,
+        )
+
+        notifications = await engine.evaluate(_scan(warning))
+
+        assert [(n.labels["rule"], n.severity, n.status) for n in notifications] == [
+            ("expiry", "warning", "firing")
+        ]
+        assert "expires in 20 days" in notifications[0].title
+        assert "warning threshold 30d" in notifications[0].body
+        assert await engine.evaluate(_scan(warning)) == []
+        resolved = await engine.evaluate(_scan({**warning, "severity": "ok"}))
+        assert [(n.severity, n.status) for n in resolved] == [("warning", "resolved")]
+
+    @pytest.mark.asyncio
+    async def test_escalation(self, engine):
+        """Test warning to critical raises the same alert as critical, resolved as critical."""
+        warning = _cert("/certs/a.pem", fingerprint_sha256="aa", severity="warning")
+        critical = {**warning, "severity": "critical"}
+        expired = {**warning, "severity": "expired"}
+
+        first = await engine.evaluate(_scan(warning))
+        escalated = await engine.evaluate(_scan(critical))
+        assert await engine.evaluate(_scan(expired)) == []
+        resolved = await engine.evaluate(_scan())
+
+        assert [(n.severity, n.status) for n in first + escalated + resolved] == [
+            ("warning", "firing"),
+            ("critical", "firing"),
+            ("critical", "resolved"),
+        ]
+        assert first[0].labels == escalated[0].labels == resolved[0].labels
+        assert escalated[0].starts_at == first[0].starts_at
+        assert "Previously alerted as warning" in escalated[0].body
+
+    @pytest.mark.asyncio
+    async def test_no_escalation_while_silenced(self, engine):
+        """Test a silenced certificate keeps its alert open without changing tiers."""
+        warning = _cert("/certs/a.pem", fingerprint_sha256="aa", severity="warning")
+
+        assert len(await engine.evaluate(_scan(warning))) == 1
+        critical = {**warning, "severity": "critical", "silenced": True}
+        assert await engine.evaluate(_scan(critical)) == []
+        notifications = await engine.evaluate(_scan({**critical, "silenced": False}))
+        assert [n.severity for n in notifications] == ["critical"]
+
+    @pytest.mark.asyncio
+    async def test_silenced(self, engine):
+        """Test silenced certificates don't fire and firing ones stay open while silenced."""
+        critical = _cert("/certs/a.pem", fingerprint_sha256="aa", severity="critical")
+
+        assert await engine.evaluate(_scan({**critical, "silenced": True})) == []
+        assert len(await engine.evaluate(_scan(critical))) == 1
+        assert await engine.evaluate(_scan({**critical, "silenced": True})) == []
+
+    @pytest.mark.asyncio
+    async def test_alertmanager_refresh(self, notifier):
+        """Test firing alerts are pushed to Alertmanager on every evaluation until resolved."""
+        config = Config(
+            certificate_directories=[],
+            scan_interval="5m",
+            notifiers=[
+                {"name": "ops", "type": "webhook", "url": "http://localhost/hook"},
+                {"name": "am", "type": "alertmanager", "url": "http://localhost:9093"},
+            ],
+            alerts={"enabled": True, "notifiers": ["ops", "am"]},
+        )
+        alertmanager = AlertmanagerNotifier(
+            NotifierConfig(name="am", type="alertmanager", url="http://localhost:9093")
+        )
+        alertmanager.send = AsyncMock()
+        engine = AlertEngine(
+            config=config, scanner=MagicMock(), notifiers={"ops": notifier, "am": alertmanager}
+        )
+        critical = _cert("/certs/a.pem", "1", fingerprint_sha256="aa", severity="critical")
+
+        for _ in range(3):
+            await engine.evaluate(_scan(critical))
+        await engine.evaluate(_scan(_cert("/certs/a.pem", "2", fingerprint_sha256="bb")))
+
+        def expiry_alerts(target):
+            calls = target.send.call_args_list
+            return [call.args[0] for call in calls if call.args[0].labels["rule"] == "expiry"]
+
+        assert [notification.status for notification in expiry_alerts(notifier)] == [
+            "firing",
+            "resolved",
+        ]
+        sent = expiry_alerts(alertmanager)
+        assert [notification.status for notification in sent] == [
+            "firing",
+            "firing",
+            "firing",
+            "resolved",
+        ]
+        assert sent[0] is sent[2]
+        assert sent[3].starts_at == sent[0].starts_at
+        assert sent[0].ends_at - sent[0].starts_at >= ALERT_HOLD_SCANS * 300 - 1
+
+        # Alertmanager identifies alerts by their severity too: escalating resolves the warning
+        alertmanager.send.reset_mock()
+        warning = _cert("/certs/b.pem", fingerprint_sha256="cc", severity="warning")
+        await engine.evaluate(_scan(warning))
+        await engine.evaluate(_scan({**warning, "severity": "critical"}))
+        assert [(n.severity, n.status) for n in expiry_alerts(alertmanager)] == [
+            ("warning", "firing"),
+            ("critical", "firing"),
+            ("warning", "resolved"),
+        ]
+
+
+class TestContentChangeAlerts:
+    """Test certificate content change alerts."""
+
+    @pytest.mark.asyncio
+    async def test_first_scan_is_baseline(self, engine):
+        """Test no change alerts on the first scan."""
+        assert await engine.evaluate(_scan(_cert("/certs/a.pem", fingerprint_sha256="aa"))) == []
+
+    @pytest.mark.asyncio
+    async def test_renewal(self, engine, notifier):
+        """Test a later-expiring certificate with the same name is reported as renewed."""
+        await engine.evaluate(
+            _scan(_cert("/certs/a.pem", "1", fingerprint_sha256="aa", expiration_timestamp=100))
+        )
+
+        renewed = _cert(
+            "/certs/a.pem",
+            "2",
+            fingerprint_sha256="bb",
+            expiration_timestamp=200,
+            not_after="2028-01-15T00:00:00+00:00",
+        )
+        notifications = await engine.evaluate(_scan(renewed))
+
+        assert len(notifications) == 1
+        notification = notifications[0]
+        assert notification.labels["rule"] == "content_change"
+        assert notification.labels["change"] == "renewed"
+        assert notification.severity == "info"
+        assert notification.data["old"]["serial"] == "1"
+        assert notification.data["new"]["serial"] == "2"
+        assert notification.data["new"]["not_after"] == "2028-01-15T00:00:00+00:00"
+        assert "serial 1" in notification.body and "serial 2" in notification.body
+
+    @pytest.mark.asyncio
+    async def test_unexpected_replacement(self, engine):
+        """Test a different certificate in the same file is reported as replaced."""
+        await engine.evaluate(
+            _scan(_cert("/certs/a.pem", "1", fingerprint_sha256="aa", expiration_timestamp=200))
+        )
+
+        replaced = _cert(
+            "/certs/a.pem",
+            "9",
+            common_name="other.example.com",
+            fingerprint_sha256="cc",
+            expiration_timestamp=300,
+        )
+        notifications = await engine.evaluate(_scan(replaced))
+
+        assert notifications[0].labels["change"] == "replaced"
+        assert notifications[0].severity == "warning"
+
+    @pytest.mark.asyncio
+    async def test_unchanged_and_new_files(self, engine):
+        """Test unchanged files and newly added files don't alert."""
+        await engine.evaluate(_scan(_cert("/certs/a.pem", fingerprint_sha256="aa")))
+
+        notifications = await engine.evaluate(
+            _scan(
+                _cert("/certs/a.pem", fingerprint_sha256="aa"),
+                _cert("/certs/b.pem", fingerprint_sha256="bb"),
+            )
+        )
+
+        assert notifications == []
+
+    @pytest.mark.asyncio
+    async def test_serial_fallback_without_fingerprint(self, engine):
+        """Test change detection falls back to the serial when fingerprints are missing."""
+        await engine.evaluate(_scan(_cert("/certs/a.pem", "1")))
+
+        assert len(await engine.evaluate(_scan(_cert("/certs/a.pem", "2")))) == 1
+
+
+class TestParseErrorAlerts:
+    """Test parse error count and ratio alerts."""
+
+    @staticmethod
+    def _scan_with_errors(errors, files):
+        return {"directories": {"/certs": {"files_processed": files, "parse_errors": errors}}}
+
+    @pytest.mark.asyncio
+    async def test_disabled_by_default(self, engine):
+        """Test no alert without a configured threshold."""
+        assert await engine.evaluate(self._scan_with_errors(50, 50)) == []
+
+    @pytest.mark.asyncio
+    async def test_count_threshold(self, config, notifier):
+        """Test alert fires once when the error count is reached and re-arms after recovery."""
+        config.alerts.parse_error_count = 5
+        engine = AlertEngine(config=config, scanner=MagicMock(), notifiers={"ops": notifier})
+
+        assert await engine.evaluate(self._scan_with_errors(4, 100)) == []
+
+        notifications = await engine.evaluate(self._scan_with_errors(5, 100))
+        assert len(notifications) == 1
+        assert notifications[0].labels == {"rule": "parse_errors", "directory": "/certs"}
+        assert notifications[0].data["parse_errors"] == 5
+
+        assert await engine.evaluate(self._scan_with_errors(6, 100)) == []
+        assert await engine.evaluate(self._scan_with_errors(0, 100)) == []
+        assert len(await engine.evaluate(self._scan_with_errors(9, 100))) == 1
+
+    @pytest.mark.asyncio
+    async def test_ratio_threshold(self, config, notifier):
+        """Test alert on the share of files that failed to parse."""
+        config.alerts.parse_error_ratio = 0.5
+        engine = AlertEngine(config=config, scanner=MagicMock(), notifiers={"ops": notifier})
+
+        assert await engine.evaluate(self._scan_with_errors(4, 10)) == []
+        notifications = await engine.evaluate(self._scan_with_errors(5, 10))
+        assert len(notifications) == 1
+        assert "50%" in notifications[0].body
+
+    def test_threshold_validation(self):
+        """Test invalid thresholds are rejected."""
+        with pytest.raises(ValueError):
+            Config(alerts={"parse_error_ratio": 1.5})
+        with pytest.raises(ValueError):
+            Config(alerts={"parse_error_count": 0})
+
+
+class TestAlertRouting:
+    """Test alert routing to notifiers."""
+
+    @pytest.fixture
+    def notifiers(self):
+        return {name: MagicMock(name=name) for name in ("default", "team-a", "team-b", "sec")}
+
+    @staticmethod
+    def _alert(severity="warning", **labels):
+        return Notification(title="alert", body="", severity=severity, labels=labels)
+
+    def test_default_route(self, notifiers):
+        """Test alerts matching no route go to the default notifiers."""
+        router = AlertRouter(AlertsConfig(notifiers=["default"]), notifiers)
+
+        assert router.route(self._alert(path="/srv/other/cert.pem")) == [notifiers["default"]]
+
+    def test_route_by_directory(self, notifiers, tmp_path):
+        """Test certificate and directory alerts route by directory."""
+        team_a = tmp_path / "team-a"
+        router = AlertRouter(
+            AlertsConfig(
+                notifiers=["default"],
+                routes=[{"directories": [str(team_a)], "notifiers": ["team-a"]}],
+            ),
+            notifiers,
+        )
+
+        cert_alert = self._alert(path=str(team_a / "sub" / "cert.pem"))
+        directory_alert = self._alert(rule="parse_errors", directory=str(team_a))
+        sibling_alert = self._alert(path=str(tmp_path / "team-ab" / "cert.pem"))
+
+        assert router.route(cert_alert) == [notifiers["team-a"]]
+        assert router.route(directory_alert) == [notifiers["team-a"]]
+        assert router.route(sibling_alert) == [notifiers["default"]]
+
+    def test_route_by_labels(self, notifiers):
+        """Test label and severity matchers."""
+        router = AlertRouter(
+            AlertsConfig(
+                notifiers=["default"],
+                routes=[
+                    {"matchers": {"common_name": "*.team-b.com"}, "notifiers": ["team-b"]},
+                    {"matchers": {"rule": "weak_key", "severity": "warning"}, "notifiers": ["sec"]},
+                ],
+            ),
+            notifiers,
+        )
+
+        assert router.route(self._alert(common_name="API.team-b.com")) == [notifiers["team-b"]]
+        assert router.route(self._alert(rule="weak_key")) == [notifiers["sec"]]
+        assert router.route(self._alert(severity="info", rule="weak_key")) == [
+            notifiers["default"]
+        ]
+
+    def test_first_match_and_continue(self, notifiers):
+        """Test the first match wins unless the route sets continue."""
+        routes = [
+            {"matchers": {"rule": "weak_key"}, "notifiers": ["sec"], "continue": True},
+            {"matchers": {"common_name": "*.team-a.com"}, "notifiers": ["team-a"]},
+            {"matchers": {"common_name": "*.com"}, "notifiers": ["team-b"]},
+        ]
+        router = AlertRouter(AlertsConfig(notifiers=["default"], routes=routes), notifiers)
+
+        alert = self._alert(rule="weak_key", common_name="www.team-a.com")
+        assert router.route(alert) == [notifiers["sec"], notifiers["team-a"]]
+
+    def test_unknown_route_notifier(self):
+        """Test routes referencing undefined notifiers are rejected."""
+        with pytest.raises(ValueError):
+            Config(alerts={"routes": [{"matchers": {"rule": "*"}, "notifiers": ["missing"]}]})
+
+    @pytest.mark.asyncio
+    async def test_engine_uses_routes(self, notifier):
+        """Test the engine dispatches alerts through the router."""
+        team = MagicMock()
+        team.name = "team"
+        team.send = AsyncMock()
+        config = Config(
+            certificate_directories=[],
+            notifiers=[
+                {"name": "ops", "type": "webhook", "url": "http://localhost/ops"},
+                {"name": "team", "type": "webhook", "url": "http://localhost/team"},
+            ],
+            alerts={
+                "enabled": True,
+                "notifiers": ["ops"],
+                "routes": [{"directories": ["/certs/team"], "notifiers": ["team"]}],
+            },
+        )
+        engine = AlertEngine(
+            config=config, scanner=MagicMock(), notifiers={"ops": notifier, "team": team}
+        )
+
+        await engine.evaluate(_scan(_cert("/certs/team/weak.pem", is_weak_key=True)))
+
+        team.send.assert_called_once()
+        notifier.send.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_route_expiry_by_severity(self, notifier):
+        """Test warning and critical expiry alerts of a certificate go to their own notifiers."""
+        pager = MagicMock()
+        pager.name = "pager"
+        pager.send = AsyncMock()
+        config = Config(
+            certificate_directories=[],
+            notifiers=[
+                {"name": "ops", "type": "webhook", "url": "http://localhost/ops"},
+                {"name": "pager", "type": "webhook", "url": "http://localhost/pager"},
+            ],
+            alerts={
+                "enabled": True,
+                "notifiers": ["ops"],
+                "routes": [
+                    {"matchers": {"rule": "expiry", "severity": "critical"}, "notifiers": ["pager"]}
+                ],
+            },
+        )
+        engine = AlertEngine(
+            config=config, scanner=MagicMock(), notifiers={"ops": notifier, "pager": pager}
+        )
+        warning = _cert("/certs/a.pem", fingerprint_sha256="aa", severity="warning")
+
+        await engine.evaluate(_scan(warning))
+        await engine.evaluate(_scan({**warning, "severity": "critical"}))
+
+        assert [call.args[0].severity for call in notifier.send.call_args_list] == ["warning"]
+        assert [call.args[0].severity for call in pager.send.call_args_list] == ["critical"]
+
+    @pytest.mark.asyncio
+    async def test_directory_labels_used_for_routing(self, notifier):
+        """Test labels from per-directory settings can be matched by routes."""
+        team = MagicMock()
+        team.name = "team"
+        team.send = AsyncMock()
+        config = Config(
+            certificate_directories=[],
+            notifiers=[
+                {"name": "ops", "type": "webhook", "url": "http://localhost/ops"},
+                {"name": "team", "type": "webhook", "url": "http://localhost/team"},
+            ],
+            alerts={
+                "enabled": True,
+                "notifiers": ["ops"],
+                "routes": [{"matchers": {"team": "payments"}, "notifiers": ["team"]}],
+            },
+        )
+        engine = AlertEngine(
+            config=config, scanner=MagicMock(), notifiers={"ops": notifier, "team": team}
+        )
+
+        cert = _cert("/srv/pay/weak.pem", is_weak_key=True, labels={"team": "payments"})
+        await engine.evaluate(_scan(cert))
+
+        team.send.assert_called_once()
+        assert team.send.call_args.args[0].labels["team"] == "payments"
+        notifier.send.assert_not_called()
@@ -0,0 +1,170 @@
+"""
+Tests for the audit log.
+"""
+
+import json
+import logging
+from unittest.mock import AsyncMock
+
+import pytest
+from fastapi.testclient import TestClient
+from pydantic import ValidationError
+
+from tls_cert_monitor.api import create_app
+from tls_cert_monitor.audit import AUDIT_LOGGER_NAME, audit, setup_audit_log
+from tls_cert_monitor.cache import CacheManager
+from tls_cert_monitor.config import AuditLogConfig, Config, SyslogConfig
+from tls_cert_monitor.hot_reload import HotReloadManager
+from tls_cert_monitor.metrics import MetricsCollector
+from tls_cert_monitor.scanner import CertificateScanner
+
+
+@pytest.fixture
+def audit_file(tmp_path):
+    """Audit log file, with the audit log reset afterwards."""
+    path = tmp_path / "audit" / "audit.log"
+    yield path
+    setup_audit_log(AuditLogConfig(), SyslogConfig())
+
+
+def _read_events(path):
+    for handler in logging.getLogger(AUDIT_LOGGER_NAME).handlers:
+        handler.flush()
+    return [json.loads(line) for line in path.read_text().splitlines()]
+
+
+class TestAuditLog:
+    """Test the audit log."""
+
+    def test_config(self):
+        """Test an enabled audit log needs a destination."""
+        with pytest.raises(ValidationError):
+            AuditLogConfig(enabled=True)
+        with pytest.raises(ValidationError):
+            AuditLogConfig(syslog=True, syslog_facility="audit-ish")
+        assert AuditLogConfig(enabled=True, syslog=True).syslog_facility == "authpriv"
+
+    def test_disabled(self, audit_file):
+        """Test nothing is recorded while the audit log is disabled."""
+        setup_audit_log(AuditLogConfig(file=str(audit_file)), SyslogConfig())
+
+        audit("cache.clear", "10.0.0.5")
+
+        assert not audit_file.exists()
+
+    def test_separate_from_application_log(self, audit_file, caplog):
+        """Test audit records go to their own file only, as JSON lines."""
+        setup_audit_log(AuditLogConfig(enabled=True, file=str(audit_file)), SyslogConfig())
+
+        with caplog.at_level(logging.INFO):
+            audit("loglevel.set", "10.0.0.5", level="DEBUG")
+
+        events = _read_events(audit_file)
+        assert len(events) == 1
+        assert events[0].pop("timestamp")
+        assert events[0] == {
+            "action": "loglevel.set",
+            "outcome": "success",
+            "remote_addr": "10.0.0.5",
+            "principal": "anonymous",
+            "details": {"level": "DEBUG"},
+        }
+        assert not any(record.name == AUDIT_LOGGER_NAME for record in caplog.records)
+
+    @pytest.mark.asyncio
+    async def test_api_operations(self, tmp_path, audit_file, caplog):
+        """Test mutating API operations are recorded with the client and principal."""
+        config = Config(
+            certificate_directories=[str(tmp_path)],
+            cache_dir="",
+            enable_ip_whitelist=False,
+            audit_log={
+                "enabled": True,
+                "file": str(audit_file),
+                "principal_header": "X-Forwarded-User",
+            },
+        )
+        setup_audit_log(config.audit_log, config.syslog)
+        caplog.set_level(logging.INFO)
+        cache = CacheManager(config)
+        await cache.initialize()
+        metrics = MetricsCollector()
+        scanner = CertificateScanner(config=config, cache=cache, metrics=metrics)
+        client = TestClient(
+            create_app(scanner=scanner, metrics=metrics, cache=cache, config=config)
+        )
+        headers = {"X-Forwarded-User": "alice", "X-Forwarded-For": "203.0.113.7"}
+
+        response = client.post(
+            "/silences", json={"matchers": {"path": "/etc/ssl/test/*"}}, headers=headers
+        )
+        silence_id = response.json()["id"]
+        client.delete(f"/silences/{silence_id}")
+        request_id = client.post("/cache/clear", headers=headers).headers["x-request-id"]
+        client.get("/cache/stats")
+        assert client.post("/config/reload").status_code == 409
+
+        events = _read_events(audit_file)
+        assert [event["action"] for event in events] == [
+            "silence.create",
+            "silence.delete",
+            "cache.clear",
+        ]
+        assert events[0]["principal"] == "alice"
+        assert events[0]["remote_addr"] == "testclient"
+        assert events[0]["details"]["forwarded_for"] == "203.0.113.7"
+        assert events[0]["details"]["silence"]["matchers"] == {"path": "/etc/ssl/test/*"}
+        assert events[1]["principal"] == "anonymous"
+        assert events[1]["details"] == {"silence_id": silence_id}
+        assert events[2]["request_id"] == request_id
+        await cache.close()
+
+    @pytest.mark.asyncio
+    async def test_config_reload(self, tmp_path, audit_file, caplog):
+        """Test reloads are recorded, a failed one as a failure answered with its error."""
+        settings = (
+            f"certificate_directories: ['{tmp_path}']\ncache_dir: ''\nenable_ip_whitelist: false\n"
+            f"audit_log:\n  enabled: true\n  file: '{audit_file}'\n"
+        )
+        config_file = tmp_path / "config.yaml"
+        config_file.write_text(settings + "workers: 2\n")
+        config = Config(
+            certificate_directories=[str(tmp_path)],
+            cache_dir="",
+            enable_ip_whitelist=False,
+            audit_log={"enabled": True, "file": str(audit_file)},
+        )
+        setup_audit_log(config.audit_log, config.syslog)
+        caplog.set_level(logging.INFO)
+        metrics = MetricsCollector()
+        scanner = CertificateScanner(config=config, cache=CacheManager(config), metrics=metrics)
+        scanner.scan_once = AsyncMock()
+        hot_reload = HotReloadManager(config, scanner, config_path=str(config_file))
+        client = TestClient(
+            create_app(
+                scanner=scanner,
+                metrics=metrics,
+                cache=scanner.cache,
+                config=config,
+                hot_reload=hot_reload,
+            )
+        )
+
+        reloaded = client.post("/config/reload")
+        config_file.write_text(settings + "worker: 4\n")
+        failed = client.post("/config/reload")
+
+        assert reloaded.status_code == 200
+        assert failed.status_code == 400
+        assert "Configuration reload failed" in failed.json()["detail"]
+        assert "worker" in failed.json()["detail"]
+        # The failed reload keeps the configuration, and does not re-scan with it
+        assert scanner.config.workers == 2
+        scanner.scan_once.assert_called_once()
+        events = _read_events(audit_file)
+        assert [(event["action"], event["outcome"]) for event in events] == [
+            ("config.reload", "success"),
+            ("config.reload", "failure"),
+        ]
+        assert events[0]["details"]["settings"] == ["workers"]
+        assert "worker" in events[1]["details"]["error"]
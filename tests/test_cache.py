@@ -3,11 +3,20 @@ Tests for cache management.
 """
 
 import asyncio
+import json
 import tempfile
+from pathlib import Path
 
 import pytest
 
-from tls_cert_monitor.cache import CacheEntry, CacheManager
+from tls_cert_monitor import cache as cache_module
+from tls_cert_monitor.cache import (
+    CacheEntry,
+    CacheManager,
+    container_memory_limit,
+    entry_size,
+    estimate_size,
+)
 from tls_cert_monitor.config import Config
 
 
@@ -61,6 +70,31 @@ class TestCacheEntry:
         assert entry.last_access > 0
 
 
+class TestContainerMemoryLimit:
+    """Test detection of the container memory limit."""
+
+    def test_container_memory_limit(self, tmp_path, monkeypatch):
+        """Test cgroup v2 and v1 limits are read and "no limit" values ignored."""
+        v2, v1 = tmp_path / "memory.max", tmp_path / "memory.limit_in_bytes"
+        monkeypatch.setattr(cache_module, "CGROUP_MEMORY_LIMIT_FILES", (str(v2), str(v1)))
+        assert container_memory_limit() is None
+
+        v1.write_text("9223372036854771712\n")
+        assert container_memory_limit() is None
+        v1.write_text("536870912\n")
+        assert container_memory_limit() == 536870912
+
+        v2.write_text("max\n")
+        assert container_memory_limit() is None
+        v2.write_text("268435456\n")
+        assert container_memory_limit() == 268435456
+
+        config = Config(cache_memory_limit="auto")
+        assert CacheManager(config).memory_limit == int(268435456 * 0.9)
+        with pytest.raises(ValueError):
+            Config(cache_memory_limit="lots")
+
+
 @pytest.mark.asyncio
 class TestCacheManager:
     """Test cache manager functionality."""
@@ -117,6 +151,27 @@ class TestCacheManager:
 
             await cache.close()
 
+    async def test_cache_per_entry_ttl(self):
+        """Test entries keep their own TTL, given in seconds or as a duration."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            cache = CacheManager(Config(cache_dir=temp_dir, cache_ttl="1h"))
+            await cache.initialize()
+
+            await cache.set("default", "value")
+            await cache.set("short", "value", ttl="10m")
+            await cache.set("long", "value", ttl=86400)
+
+            assert cache._memory_cache["default"].ttl == 3600
+            assert cache._memory_cache["short"].ttl == 600
+            assert cache._memory_cache["long"].ttl == 86400
+
+            with pytest.raises(ValueError):
+                await cache.set("negative", "value", ttl=-1)
+            with pytest.raises(ValueError):
+                await cache.set("invalid", "value", ttl="soon")
+
+            await cache.close()
+
     async def test_cache_delete(self):
         """Test cache deletion."""
         with tempfile.TemporaryDirectory() as temp_dir:
@@ -236,15 +291,41 @@ class TestCacheManager:
             # Key should be reasonable length (full SHA256 hash)
             assert len(key1) == 64  # Full SHA256 hash for security
 
+    async def test_key_sources(self):
+        """Test keys namespaced by source never collide and are counted per source."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            config = Config(cache_dir=temp_dir)
+            cache = CacheManager(config)
+            await cache.initialize()
+
+            file_key = cache.make_key("cert", "example.com", source="file")
+            endpoint_key = cache.make_key("cert", "example.com", source="endpoint")
+            assert file_key != endpoint_key
+            assert file_key == f"file:{cache.make_key('cert', 'example.com')}"
+            with pytest.raises(ValueError):
+                cache.make_key("cert", source="a:b")
+
+            await cache.set(file_key, "file value")
+            await cache.set(endpoint_key, "endpoint value")
+            await cache.set("plain", "value")
+
+            sources = (await cache.get_stats())["sources"]
+            assert sources["file"]["entries"] == 1
+            assert sources["endpoint"]["entries"] == 1
+            assert sources["other"]["entries"] == 1
+            assert sum(s["size_bytes"] for s in sources.values()) == cache._current_size
+
+            await cache.close()
+
     async def test_cache_size_limits(self):
         """Test cache size limit enforcement."""
         with tempfile.TemporaryDirectory() as temp_dir:
-            config = Config(cache_dir=temp_dir, cache_max_size=1000)  # 1KB limit
+            config = Config(cache_dir=temp_dir, cache_max_size=3000)  # 3KB limit
             cache = CacheManager(config)
             await cache.initialize()
 
             # Add large entries that exceed the limit
-            large_data = "x" * 400  # 400 bytes each
+            large_data = "x" * 400  # ~1KB each, including key and entry overhead
 
             await cache.set("key1", large_data)
             await cache.set("key2", large_data)
@@ -255,6 +336,338 @@ class TestCacheManager:
 
             await cache.close()
 
+    async def test_size_accounting(self):
+        """Test entry sizes reflect the memory used by nested values."""
+        cert_data = {
+            "path": "/etc/ssl/certs/a.pem",
+            "sans": [f"host{i}.example.com" for i in range(50)],
+        }
+        assert estimate_size(cert_data) > len(str(cert_data))
+        assert estimate_size({"values": list(range(1000))}) > estimate_size({"values": [1]})
+
+        with tempfile.TemporaryDirectory() as temp_dir:
+            cache = CacheManager(Config(cache_dir=temp_dir))
+            await cache.initialize()
+            await cache.set("key1", cert_data)
+
+            stats = await cache.get_stats()
+            assert stats["current_size_bytes"] == entry_size("key1", cert_data)
+            await cache.close()
+
+    async def test_lru_eviction(self):
+        """Test eviction removes the least recently used entry, not the oldest one."""
+        large_data = "x" * 400
+        with tempfile.TemporaryDirectory() as temp_dir:
+            # Room for two entries
+            config = Config(cache_dir=temp_dir, cache_max_size=2 * entry_size("key1", large_data))
+            cache = CacheManager(config)
+            await cache.initialize()
+
+            await cache.set("key1", large_data)
+            await cache.set("key2", large_data)
+            assert await cache.get("key1") == large_data  # key1 is now most recently used
+            await cache.set("key3", large_data)  # Should evict key2
+
+            assert await cache.get("key1") == large_data
+            assert await cache.get("key2") is None
+            assert await cache.get("key3") == large_data
+
+            await cache.close()
+
+    async def test_lru_order_persisted(self):
+        """Test LRU order survives a save and reload."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            max_size = 2 * entry_size("key1", "x" * 400)
+            config = Config(cache_type="file", cache_dir=temp_dir, cache_max_size=max_size)
+            cache = CacheManager(config)
+            await cache.initialize()
+            await cache.set("key1", "x" * 400)
+            await cache.set("key2", "x" * 400)
+            await cache.get("key1")
+            await cache.close()
+
+            reloaded = CacheManager(config)
+            await reloaded.initialize()
+            await reloaded.wait_until_loaded()
+            await reloaded.set("key3", "x" * 400)
+
+            assert await reloaded.get("key1") is not None
+            assert await reloaded.get("key2") is None
+            await reloaded.close()
+
+    async def test_background_warmup(self):
+        """Test the persistent cache loads in the background behind newer entries."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            config = Config(cache_type="file", cache_dir=temp_dir)
+            cache = CacheManager(config)
+            await cache.initialize()
+            for key in ("key1", "key2", "key3"):
+                await cache.set(key, f"old-{key}")
+            await cache.close()
+
+            reloaded = CacheManager(config)
+            await reloaded.initialize()
+            assert reloaded.loading
+            assert (await reloaded.get_health_status())["cache_loading"]
+
+            # Changes made while loading take precedence over loaded entries
+            await reloaded.set("key1", "new-key1")
+            await reloaded.delete("key2")
+            await reloaded.wait_until_loaded()
+
+            assert not reloaded.loading
+            assert await reloaded.get("key1") == "new-key1"
+            assert await reloaded.get("key2") is None
+            assert await reloaded.get("key3") == "old-key3"
+            # Newer entries stay the most recently used
+            assert list(reloaded._memory_cache)[-1] == "key3"
+            await reloaded.close()
+
+    async def test_clear_during_warmup(self):
+        """Test clearing the cache while loading drops the loaded entries."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            config = Config(cache_type="file", cache_dir=temp_dir)
+            cache = CacheManager(config)
+            await cache.initialize()
+            await cache.set("key1", "value1")
+            await cache.close()
+
+            reloaded = CacheManager(config)
+            await reloaded.initialize()
+            await reloaded.clear()
+            await reloaded.close()
+
+            final = CacheManager(config)
+            await final.initialize()
+            await final.wait_until_loaded()
+            assert await final.get("key1") is None
+            await final.close()
+
+    async def test_save_skipped_when_unchanged(self):
+        """Test saves are skipped when nothing changed since the last save."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            config = Config(cache_type="file", cache_dir=temp_dir, cache_save_interval="0s")
+            cache = CacheManager(config)
+            await cache.initialize()
+            await cache.wait_until_loaded()
+
+            await cache.set("key1", "value1")
+            assert await cache.save_to_disk()
+            assert not await cache.save_to_disk()
+
+            await cache.get("key1")
+            assert await cache.save_to_disk()
+            await cache.close()
+
+            reloaded = CacheManager(config)
+            await reloaded.initialize()
+            await reloaded.wait_until_loaded()
+            # Freshly loaded entries are already on disk
+            assert not await reloaded.save_to_disk()
+            await reloaded.close()
+
+    async def test_periodic_save(self):
+        """Test the cache is saved every cache_save_interval."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            config = Config(cache_type="file", cache_dir=temp_dir, cache_save_interval="1s")
+            cache = CacheManager(config)
+            await cache.initialize()
+            await cache.set("key1", "value1")
+            assert not cache.cache_file.exists()
+
+            await asyncio.sleep(1.5)
+            assert cache.cache_file.exists()
+            await cache.close()
+            assert cache._maintenance_task is None
+
+    async def test_corrupted_cache_quarantined(self):
+        """Test a cache file failing its checksum is quarantined and reported."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            config = Config(cache_type="file", cache_dir=temp_dir)
+            cache = CacheManager(config)
+            await cache.initialize()
+            await cache.set("key1", "value1")
+            await cache.close()
+            cache.cache_file.write_text(cache.cache_file.read_text().replace("value1", "value2"))
+
+            reloaded = CacheManager(config)
+            await reloaded.initialize()
+            await reloaded.wait_until_loaded()
+
+            assert await reloaded.get("key1") is None
+            health = await reloaded.get_health_status()
+            assert len(health["cache_quarantined_files"]) == 1
+            assert all(Path(path).exists() for path in health["cache_quarantined_files"])
+            await reloaded.close()
+
+    async def test_save_failures_reported(self, monkeypatch):
+        """Test failed saves and loads are reported in health until a save succeeds."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            config = Config(cache_type="file", cache_dir=temp_dir)
+            cache = CacheManager(config)
+            await cache.initialize()
+            await cache.set("key1", "value1")
+            await cache.close()
+            cache.cache_file.write_text("{not json")
+
+            reloaded = CacheManager(config)
+            await reloaded.initialize()
+            await reloaded.wait_until_loaded()
+            assert (await reloaded.get_health_status())["cache_last_load_error"]
+
+            def fail(*args):
+                raise OSError("No space left on device")
+
+            monkeypatch.setattr(reloaded.backend, "save", fail)
+            for value in ("a", "b"):
+                await reloaded.set("key1", value)
+                assert not await reloaded.save_to_disk()
+            health = await reloaded.get_health_status()
+            assert health["cache_save_failures"] == 2
+            assert health["cache_last_save_error"] == "No space left on device"
+
+            monkeypatch.undo()
+            assert await reloaded.save_to_disk()
+            health = await reloaded.get_health_status()
+            assert health["cache_save_failures"] == 0
+            assert health["cache_last_save_error"] is None
+            assert health["cache_last_load_error"] is None
+            await reloaded.close()
+
+    async def test_cache_max_entries(self):
+        """Test the entry count limit evicts LRU entries, counted apart from size evictions."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            config = Config(cache_dir=temp_dir, cache_max_entries=2)
+            cache = CacheManager(config)
+            await cache.initialize()
+
+            await cache.set("key1", "value1")
+            await cache.set("key2", "value2")
+            await cache.get("key1")
+            await cache.set("key3", "value3")
+            # Replacing an entry does not count against the limit
+            await cache.set("key3", "value3b")
+
+            assert await cache.get("key1") == "value1"
+            assert await cache.get("key2") is None
+            assert await cache.get("key3") == "value3b"
+            stats = await cache.get_stats()
+            assert stats["entries_total"] == 2
+            assert stats["evictions_count"] == 1
+            assert stats["evictions_size"] == 0
+
+            cache.max_entries = 0
+            cache.max_size = entry_size("key4", "value4")
+            await cache.set("key4", "value4")
+            stats = await cache.get_stats()
+            assert stats["entries_total"] == 1
+            assert stats["evictions_size"] == 2
+
+            await cache.close()
+
+    async def test_oversized_values_not_cached(self):
+        """Test values over cache_max_entry_size bypass the cache without evictions."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            cache = CacheManager(Config(cache_dir=temp_dir, cache_max_entry_size=2000))
+            await cache.initialize()
+
+            await cache.set("small", "x" * 100)
+            await cache.set("large", "initial")
+            await cache.set("large", "x" * 5000)
+
+            assert await cache.get("small") == "x" * 100
+            # The out of date value is not served either
+            assert await cache.get("large") is None
+            stats = await cache.get_stats()
+            assert stats["oversized_skips"] == 1
+            assert stats["evictions_size"] == 0
+            await cache.close()
+
+    async def test_snapshot_export_import(self):
+        """Test a snapshot of one cache pre-seeds another, keeping newer local entries."""
+        with tempfile.TemporaryDirectory() as source_dir, tempfile.TemporaryDirectory() as dest_dir:
+            source = CacheManager(Config(cache_type="file", cache_dir=source_dir))
+            await source.initialize()
+            await source.set("key1", {"common_name": "a.example.com"})
+            await source.set("key2", "old")
+            await source.set("expired", "value", ttl=0)
+            await asyncio.sleep(0.01)
+            snapshot = json.loads(json.dumps(await source.export_snapshot()))
+            await source.close()
+
+            assert snapshot["format"] == "tls-cert-monitor-cache"
+            assert set(snapshot["entries"]) == {"key1", "key2"}
+
+            dest = CacheManager(Config(cache_type="file", cache_dir=dest_dir))
+            await dest.initialize()
+            await dest.set("key2", "newer")
+            assert await dest.import_snapshot(snapshot) == {"imported": 1, "skipped": 1}
+            assert await dest.get("key1") == {"common_name": "a.example.com"}
+            assert await dest.get("key2") == "newer"
+            # Imported entries keep their age
+            assert dest._memory_cache["key1"].timestamp == snapshot["entries"]["key1"]["timestamp"]
+
+            with pytest.raises(ValueError, match="Not a TLS Certificate Monitor cache snapshot"):
+                await dest.import_snapshot({"entries": {}})
+            with pytest.raises(ValueError, match="version"):
+                await dest.import_snapshot({**snapshot, "version": 99})
+            await dest.close()
+
+    async def test_memory_pressure(self, monkeypatch):
+        """Test the cache shrinks while process memory is over cache_memory_limit."""
+        rss = {"value": 0}
+
+        class FakeProcess:
+            def memory_info(self):
+                return type("MemoryInfo", (), {"rss": rss["value"]})()
+
+        monkeypatch.setattr(cache_module.psutil, "Process", FakeProcess)
+        monkeypatch.setattr(cache_module, "MEMORY_CHECK_INTERVAL", 0)
+        with tempfile.TemporaryDirectory() as temp_dir:
+            cache = CacheManager(Config(cache_dir=temp_dir, cache_memory_limit=1_000_000))
+            await cache.initialize()
+
+            rss["value"] = 500_000
+            for i in range(4):
+                await cache.set(f"key{i}", "x" * 400)
+            assert (await cache.get_stats())["entries_total"] == 4
+
+            # Over the limit by about two entries: the cache is capped two entries below its
+            # size, leaving room for one old entry next to the new one
+            rss["value"] = 1_000_000 + 2 * entry_size("key0", "x" * 400) - 1
+            await cache.set("key4", "x" * 400)
+            stats = await cache.get_stats()
+            assert stats["evictions_memory"] == 3
+            assert await cache.get("key0") is None
+            assert await cache.get("key4") is not None
+            assert (await cache.get_health_status())["cache_memory_pressure"]
+
+            rss["value"] = 500_000
+            await cache.set("key5", "x" * 400)
+            assert not (await cache.get_health_status())["cache_memory_pressure"]
+            await cache.close()
+
+    async def test_concurrent_access(self):
+        """Test concurrent workers keep entries and size accounting consistent."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            cache = CacheManager(Config(cache_dir=temp_dir))
+            await cache.initialize()
+
+            async def worker(worker_id):
+                for i in range(20):
+                    key = f"key{i}"
+                    await cache.set(key, {"worker": worker_id, "index": i})
+                    await cache.get(key)
+
+            await asyncio.gather(*(worker(n) for n in range(8)))
+
+            stats = await cache.get_stats()
+            assert stats["entries_total"] == 20
+            assert stats["current_size_bytes"] == sum(
+                entry.size for entry in cache._memory_cache.values()
+            )
+            await cache.close()
+
     async def test_health_status(self):
         """Test cache health status."""
         with tempfile.TemporaryDirectory() as temp_dir:
@@ -278,3 +691,35 @@ class TestCacheManager:
             assert health["cache_total_accesses"] == 1
 
             await cache.close()
+
+    async def test_cache_disabled(self):
+        """Test that a disabled cache stores nothing and never touches the disk."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            cache_dir = Path(temp_dir) / "cache"
+            config = Config(cache_enabled=False, cache_type="both", cache_dir=str(cache_dir))
+            cache = CacheManager(config)
+            await cache.initialize()
+
+            await cache.set("test_key", "test_value")
+            assert await cache.get("test_key") is None
+
+            health = await cache.get_health_status()
+            assert health["cache_enabled"] is False
+            assert health["cache_file_path"] is None
+
+            await cache.close()
+            assert not cache_dir.exists()
+
+    async def test_empty_cache_dir_is_memory_only(self, tmp_path, monkeypatch):
+        """Test that an empty cache_dir keeps a file cache in memory only."""
+        monkeypatch.chdir(tmp_path)
+        config = Config(cache_type="file", cache_dir="")
+        cache = CacheManager(config)
+        await cache.initialize()
+
+        await cache.set("test_key", "test_value")
+        assert await cache.get("test_key") == "test_value"
+        assert cache.persistent is False
+
+        await cache.close()
+        assert list(tmp_path.iterdir()) == []
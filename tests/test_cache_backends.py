@@ -0,0 +1,262 @@
+"""
+Tests for persistent cache backends.
+"""
+
+import gzip
+import json
+import sqlite3
+import threading
+import time
+
+import pytest
+
+from tls_cert_monitor.cache import CacheManager
+from tls_cert_monitor.cache_backends import (
+    CacheChanges,
+    CacheCorruptedError,
+    CacheFileLock,
+    CacheLockTimeout,
+    JsonFileBackend,
+    SQLiteBackend,
+    create_cache_backend,
+)
+from tls_cert_monitor.config import Config
+
+
+def _entry(value, timestamp=1000.0):
+    return {
+        "value": value,
+        "timestamp": timestamp,
+        "ttl": 3600,
+        "size": 100,
+        "access_count": 0,
+        "last_access": timestamp,
+    }
+
+
+class TestCacheBackends:
+    """Test JSON file and SQLite cache backends."""
+
+    def test_create_cache_backend(self, tmp_path):
+        """Test backends are created by name."""
+        assert isinstance(create_cache_backend("json", tmp_path), JsonFileBackend)
+        assert create_cache_backend("json", tmp_path).path == tmp_path / "cache.json"
+        assert create_cache_backend("sqlite", tmp_path).path == tmp_path / "cache.db"
+
+    @pytest.mark.parametrize("backend_class", [JsonFileBackend, SQLiteBackend])
+    def test_round_trip(self, tmp_path, backend_class):
+        """Test entries and stats survive a save and load."""
+        backend = backend_class(tmp_path)
+        assert backend.load() == ({}, {})
+
+        entries = {"a": _entry({"common_name": "a.example.com"}), "b": _entry(["x", 1])}
+        stats = {"access_count": 3, "hit_count": 1}
+        backend.save(entries, stats, CacheChanges(upserted={"a", "b"}))
+        backend.close()
+
+        assert backend_class(tmp_path).load() == (entries, stats)
+
+    @pytest.mark.parametrize("backend_class", [JsonFileBackend, SQLiteBackend])
+    def test_compressed_round_trip(self, tmp_path, backend_class):
+        """Test compressed entries survive a save and load."""
+        backend = backend_class(tmp_path, "gzip")
+        entries = {"a": _entry({"common_name": "a.example.com"})}
+        backend.save(entries, {"hit_count": 1}, CacheChanges(upserted={"a"}))
+        backend.close()
+
+        assert backend_class(tmp_path, "gzip").load() == (entries, {"hit_count": 1})
+
+    def test_compressed_json_file(self, tmp_path):
+        """Test the compressed JSON backend writes a gzip file."""
+        backend = create_cache_backend("json", tmp_path, "gzip")
+        assert backend.path == tmp_path / "cache.json.gz"
+
+        backend.save({"a": _entry("a")}, {}, CacheChanges(upserted={"a"}))
+        data = json.loads(gzip.decompress(backend.path.read_bytes()))
+        assert data["entries"]["a"]["value"] == "a"
+
+    @pytest.mark.parametrize("compression", ["none", "gzip"])
+    def test_json_checksum(self, tmp_path, compression):
+        """Test the JSON file is verified against its embedded checksum."""
+        backend = JsonFileBackend(tmp_path, compression)
+        backend.save({"a": _entry("a")}, {}, CacheChanges(upserted={"a"}))
+        with backend._open(backend.path, "r") as f:
+            text = f.read()
+        assert json.loads(text)["checksum"]
+
+        # Files without a checksum (older versions) load unverified
+        with backend._open(backend.path, "w") as f:
+            f.write(json.dumps({"entries": {"a": _entry("a")}, "stats": {}}))
+        assert list(backend.load()[0]) == ["a"]
+
+        with backend._open(backend.path, "w") as f:
+            f.write(text.replace('"a"', '"b"'))
+        with pytest.raises(CacheCorruptedError, match="Checksum mismatch"):
+            backend.load()
+
+        backend.path.write_bytes(backend.path.read_bytes()[:-10])
+        with pytest.raises(CacheCorruptedError):
+            backend.load()
+
+        quarantined = backend.quarantine()
+        assert len(quarantined) == 1
+        assert ".corrupt-" in quarantined[0].name
+        assert backend.load() == ({}, {})
+
+    def test_json_interrupted_save(self, tmp_path, monkeypatch):
+        """Test a save failing before its rename leaves the previous file valid."""
+        backend = JsonFileBackend(tmp_path)
+        backend.save({"a": _entry("a")}, {}, CacheChanges(upserted={"a"}))
+
+        def fail(temp_file, target_file):
+            raise OSError("killed")
+
+        monkeypatch.setattr(backend, "_atomic_replace", fail)
+        with pytest.raises(OSError, match="killed"):
+            backend.save({"b": _entry("b")}, {}, CacheChanges(upserted={"b"}))
+
+        assert list(backend.load()[0]) == ["a"]
+        assert sorted(path.name for path in tmp_path.iterdir()) == ["cache.json"]
+
+    def test_json_legacy_manifest(self, tmp_path):
+        """Test a stale manifest of an earlier version is ignored and removed on save."""
+        backend = JsonFileBackend(tmp_path)
+        backend.save({"a": _entry("a")}, {}, CacheChanges(upserted={"a"}))
+        backend.legacy_manifest_path.write_text(f"{'0' * 64}  cache.json\n")
+
+        assert list(backend.load()[0]) == ["a"]
+        backend.save({"b": _entry("b")}, {}, CacheChanges(upserted={"b"}))
+        assert not backend.legacy_manifest_path.exists()
+
+    def test_file_lock(self, tmp_path):
+        """Test the cache lock excludes other holders until released."""
+        holder = CacheFileLock(tmp_path / "cache.lock")
+        waiter = CacheFileLock(tmp_path / "cache.lock", timeout=0.1)
+
+        with holder:
+            with pytest.raises(CacheLockTimeout):
+                waiter.acquire()
+            assert waiter.contended
+
+        with waiter:
+            assert not waiter.contended
+
+    @pytest.mark.asyncio
+    async def test_manager_waits_for_lock(self, tmp_path):
+        """Test saves wait for another process's lock and report the shared cache_dir."""
+        cache = CacheManager(Config(cache_type="file", cache_dir=str(tmp_path)))
+        await cache.initialize()
+        await cache.wait_until_loaded()
+        await cache.set("key1", "value1")
+
+        other = CacheFileLock(tmp_path / "cache.lock")
+        other.acquire()
+        threading.Timer(0.2, other.release).start()
+        assert await cache.save_to_disk()
+
+        assert (await cache.get_health_status())["cache_dir_shared"]
+        await cache.close()
+
+    def test_sqlite_mixed_compression(self, tmp_path):
+        """Test SQLite reads rows written before compression was toggled."""
+        SQLiteBackend(tmp_path).save({"a": _entry("a")}, {}, CacheChanges(upserted={"a"}))
+
+        backend = SQLiteBackend(tmp_path, "gzip")
+        entries, _ = backend.load()
+        entries["b"] = _entry("b")
+        backend.save(entries, {}, CacheChanges(upserted={"b"}))
+        backend.close()
+
+        entries, _ = SQLiteBackend(tmp_path).load()
+        assert {key: entry["value"] for key, entry in entries.items()} == {"a": "a", "b": "b"}
+
+    def test_sqlite_incremental_save(self, tmp_path):
+        """Test SQLite saves apply only the recorded changes."""
+        backend = SQLiteBackend(tmp_path)
+        entries = {"a": _entry("a"), "b": _entry("b"), "c": _entry("c")}
+        backend.save(entries, {}, CacheChanges(upserted={"a", "b", "c"}))
+
+        # Unchanged entries are not rewritten, even if the in-memory copy differs
+        entries["a"] = _entry("a2")
+        entries["b"]["last_access"] = 2000.0
+        del entries["c"]
+        backend.save(entries, {}, CacheChanges(touched={"b"}, removed={"c"}))
+
+        loaded, _ = backend.load()
+        assert sorted(loaded) == ["a", "b"]
+        assert loaded["a"]["value"] == "a"
+        assert loaded["b"]["last_access"] == 2000.0
+
+        backend.save(entries, {}, CacheChanges(cleared=True, upserted={"a"}))
+        loaded, _ = backend.load()
+        assert list(loaded) == ["a"]
+        assert loaded["a"]["value"] == "a2"
+        backend.close()
+
+    @pytest.mark.asyncio
+    async def test_manager_with_sqlite(self, tmp_path):
+        """Test the cache manager persists through the SQLite backend."""
+        config = Config(cache_type="file", cache_backend="sqlite", cache_dir=str(tmp_path))
+        cache = CacheManager(config)
+        await cache.initialize()
+        await cache.set("key1", {"common_name": "a.example.com"})
+        await cache.set("key2", "value2")
+        await cache.delete("key2")
+        await cache.close()
+
+        reloaded = CacheManager(config)
+        await reloaded.initialize()
+        await reloaded.wait_until_loaded()
+        assert await reloaded.get("key1") == {"common_name": "a.example.com"}
+        assert await reloaded.get("key2") is None
+        health = await reloaded.get_health_status()
+        assert health["cache_file_path"] == str(tmp_path / "cache.db")
+        await reloaded.close()
+
+    @pytest.mark.asyncio
+    @pytest.mark.parametrize("threshold, compacted", [(25, True), (0, False)])
+    async def test_compaction_on_load(self, tmp_path, threshold, compacted):
+        """Test a load that prunes most entries rewrites the database without them."""
+        backend = SQLiteBackend(tmp_path)
+        now = time.time()
+        entries = {f"old{i}": _entry("x" * 2000) for i in range(200)}
+        entries.update({f"new{i}": _entry("fresh", timestamp=now) for i in range(10)})
+        backend.save(entries, {}, CacheChanges(upserted=set(entries)))
+        backend.close()
+        size_before = (tmp_path / "cache.db").stat().st_size
+
+        config = Config(
+            cache_type="file",
+            cache_backend="sqlite",
+            cache_dir=str(tmp_path),
+            cache_compact_threshold=threshold,
+            cache_save_interval="0s",
+        )
+        cache = CacheManager(config)
+        await cache.initialize()
+        await cache.wait_until_loaded()
+        assert (cache._compaction_task is not None) is compacted
+        if compacted:
+            await cache._compaction_task
+            connection = sqlite3.connect(str(tmp_path / "cache.db"))
+            assert connection.execute("SELECT COUNT(*) FROM entries").fetchone() == (10,)
+            connection.close()
+            assert (tmp_path / "cache.db").stat().st_size < size_before / 4
+        await cache.close()
+
+    @pytest.mark.asyncio
+    async def test_corrupted_sqlite_cache_discarded(self, tmp_path):
+        """Test a corrupted database is quarantined and the cache starts empty."""
+        (tmp_path / "cache.db").write_bytes(b"not a database" * 100)
+        config = Config(cache_type="file", cache_backend="sqlite", cache_dir=str(tmp_path))
+        cache = CacheManager(config)
+        await cache.initialize()
+
+        await cache.set("key1", "value1")
+        await cache.close()
+
+        quarantined = [str(path) for path in tmp_path.glob("cache.db.corrupt-*")]
+        assert quarantined == cache.quarantined_files
+        connection = sqlite3.connect(str(tmp_path / "cache.db"))
+        assert connection.execute("SELECT key FROM entries").fetchall() == [("key1",)]
+        connection.close()
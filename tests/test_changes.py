@@ -0,0 +1,168 @@
+"""
+Tests for the scan-to-scan changes.
+"""
+
+import asyncio
+import json
+from unittest.mock import MagicMock
+
+import pytest
+from fastapi.testclient import TestClient
+
+from tls_cert_monitor.api import create_app
+from tls_cert_monitor.cache import CacheManager
+from tls_cert_monitor.changes import ChangeTracker
+from tls_cert_monitor.config import ChangesConfig, Config
+from tls_cert_monitor.metrics import MetricsCollector
+from tls_cert_monitor.scanner import CertificateScanner
+
+
+def _cert(fingerprint, path, expiration=2000, severity="ok", **fields):
+    """Certificate data as found by a scan."""
+    return {
+        "fingerprint_sha256": fingerprint,
+        "path": path,
+        "common_name": "api.example.com",
+        "serial": fingerprint.upper(),
+        "expiration_timestamp": expiration,
+        "severity": severity,
+        **fields,
+    }
+
+
+def _scan(*certs):
+    """Scan results of certificates in /etc/ssl."""
+    return {"directories": {"/etc/ssl": {"certificates": list(certs)}}}
+
+
+class TestChanges:
+    """Test diffing scans and serving the changes."""
+
+    def test_baseline(self):
+        """Test the first scan only records the baseline, and unchanged scans record nothing."""
+        tracker = ChangeTracker(Config())
+
+        assert tracker.record(_scan(_cert("aa", "/etc/ssl/a.pem"))) is None
+        assert tracker.record(_scan(_cert("aa", "/etc/ssl/a.pem"))) is None
+        assert tracker.get_changes() == {"changes": [], "last_id": 0, "truncated": False}
+
+    def test_diff(self):
+        """Test added, removed, renewed and replaced certificates are told apart."""
+        tracker = ChangeTracker(Config())
+        tracker.record(
+            _scan(
+                _cert("aa", "/etc/ssl/renewed.pem"),
+                _cert("bb", "/etc/ssl/replaced.pem"),
+                _cert("cc", "/etc/ssl/removed.pem"),
+            )
+        )
+
+        changeset = tracker.record(
+            _scan(
+                _cert("dd", "/etc/ssl/renewed.pem", expiration=3000),
+                _cert("ee", "/etc/ssl/replaced.pem", common_name="www.example.com"),
+                _cert("ff", "/etc/ssl/added.pem"),
+            ),
+            now=0,
+        )
+
+        assert changeset["id"] == 1
+        assert changeset["timestamp"].startswith("1970-01-01")
+        assert [cert["path"] for cert in changeset["added"]] == ["/etc/ssl/added.pem"]
+        assert [cert["path"] for cert in changeset["removed"]] == ["/etc/ssl/removed.pem"]
+        [renewed] = changeset["renewed"]
+        assert renewed["old"]["fingerprint_sha256"] == "aa"
+        assert renewed["new"]["fingerprint_sha256"] == "dd"
+        assert renewed["directory"] == "/etc/ssl"
+        assert [change["path"] for change in changeset["replaced"]] == ["/etc/ssl/replaced.pem"]
+        assert changeset["problematic"] == []
+
+    def test_problematic(self):
+        """Test certificates are reported when they get worse, not when they improve."""
+        tracker = ChangeTracker(Config())
+        tracker.record(
+            _scan(_cert("aa", "/etc/ssl/a.pem"), _cert("bb", "/etc/ssl/b.pem", severity="critical"))
+        )
+
+        changeset = tracker.record(
+            _scan(
+                _cert("aa", "/etc/ssl/a.pem", severity="warning", is_weak_key=True),
+                _cert("bb", "/etc/ssl/b.pem", severity="warning"),
+                _cert("cc", "/etc/ssl/c.pem", severity="expired"),
+            )
+        )
+
+        problems = {cert["path"]: cert["problems"] for cert in changeset["problematic"]}
+        assert problems == {
+            "/etc/ssl/a.pem": ["warning", "weak_key"],
+            "/etc/ssl/c.pem": ["expired"],
+        }
+
+    def test_get_changes(self):
+        """Test polling after a changeset id, and truncation once changesets were dropped."""
+        tracker = ChangeTracker(Config(changes=ChangesConfig(max_changesets=2)))
+        tracker.record(_scan())
+        for index in range(3):
+            tracker.record(_scan(_cert(f"a{index}", f"/etc/ssl/{index}.pem")))
+
+        assert [changeset["id"] for changeset in tracker.get_changes(2)["changes"]] == [3]
+        assert tracker.get_changes(2)["truncated"] is False
+        assert tracker.get_changes()["truncated"] is True
+        assert tracker.get_changes(limit=1)["changes"][0]["id"] == 2
+        # An id from before a restart
+        assert tracker.get_changes(10) == {"changes": [], "last_id": 3, "truncated": True}
+
+    @pytest.mark.asyncio
+    async def test_events(self):
+        """Test the event stream replays missed changesets, then streams new ones."""
+        scanner = MagicMock()
+        tracker = ChangeTracker(Config(), scanner)
+        listener = scanner.add_scan_listener.call_args[0][0]
+        await listener(_scan())
+        await listener(_scan(_cert("aa", "/etc/ssl/a.pem")))
+
+        stream = tracker.events(since=0)
+        replayed = await stream.__anext__()
+        pending = asyncio.ensure_future(stream.__anext__())
+        await listener(_scan(_cert("aa", "/etc/ssl/a.pem"), _cert("bb", "/etc/ssl/b.pem")))
+        streamed = await asyncio.wait_for(pending, 5)
+        tracker.close()
+        with pytest.raises(StopAsyncIteration):
+            await asyncio.wait_for(stream.__anext__(), 5)
+
+        assert replayed.startswith("id: 1\nevent: changes\ndata: ")
+        data = json.loads(streamed.split("data: ", 1)[1])
+        assert data["id"] == 2
+        assert [cert["path"] for cert in data["added"]] == ["/etc/ssl/b.pem"]
+
+    @pytest.mark.asyncio
+    async def test_api(self, tmp_path):
+        """Test the changes endpoint, and the conflict when changes are disabled."""
+        config = Config(
+            certificate_directories=[str(tmp_path)],
+            cache_dir=str(tmp_path),
+            enable_ip_whitelist=False,
+        )
+        cache = CacheManager(config)
+        await cache.initialize()
+        metrics = MetricsCollector()
+        scanner = CertificateScanner(config=config, cache=cache, metrics=metrics)
+        tracker = ChangeTracker(config)
+        tracker.record(_scan())
+        tracker.record(_scan(_cert("aa", "/etc/ssl/a.pem")))
+
+        client = TestClient(
+            create_app(
+                scanner=scanner, metrics=metrics, cache=cache, config=config, changes=tracker
+            )
+        )
+        listed = client.get("/api/v1/changes")
+        after = client.get("/api/v1/changes", params={"since": 1})
+        disabled = TestClient(
+            create_app(scanner=scanner, metrics=metrics, cache=cache, config=config)
+        ).get("/api/v1/changes")
+        await cache.close()
+
+        assert listed.json()["changes"][0]["added"][0]["path"] == "/etc/ssl/a.pem"
+        assert after.json() == {"changes": [], "last_id": 1, "truncated": False}
+        assert disabled.status_code == 409
@@ -0,0 +1,146 @@
+"""
+Tests for ad-hoc certificate checks.
+"""
+
+import socket
+import ssl
+import threading
+from datetime import datetime, timedelta, timezone
+from unittest.mock import MagicMock
+
+import pytest
+from cryptography import x509
+from cryptography.hazmat.primitives import hashes, serialization
+from cryptography.hazmat.primitives.asymmetric import rsa
+from cryptography.x509.oid import NameOID
+
+from tls_cert_monitor.cache import CacheManager
+from tls_cert_monitor.check import (
+    certificate_findings,
+    check_target,
+    format_check,
+    parse_endpoint,
+    worst_finding,
+)
+from tls_cert_monitor.config import Config
+from tls_cert_monitor.metrics import MetricsCollector
+from tls_cert_monitor.scanner import CertificateScanner
+
+
+def _write_certificate(directory, name="site", days=365, key_size=2048):
+    """Write a self-signed certificate and its key, returning their paths."""
+    key = rsa.generate_private_key(public_exponent=65537, key_size=key_size)
+    subject = x509.Name([x509.NameAttribute(NameOID.COMMON_NAME, f"{name}.example.com")])
+    now = datetime.now(timezone.utc)
+    cert = (
+        x509.CertificateBuilder()
+        .subject_name(subject)
+        .issuer_name(subject)
+        .public_key(key.public_key())
+        .serial_number(x509.random_serial_number())
+        .not_valid_before(now - timedelta(days=1))
+        .not_valid_after(now + timedelta(days=days))
+        .sign(key, hashes.SHA256())
+    )
+    cert_path = directory / f"{name}.pem"
+    key_path = directory / f"{name}.key"
+    cert_path.write_bytes(cert.public_bytes(serialization.Encoding.PEM))
+    key_path.write_bytes(
+        key.private_bytes(
+            serialization.Encoding.PEM,
+            serialization.PrivateFormat.PKCS8,
+            serialization.NoEncryption(),
+        )
+    )
+    return cert_path, key_path
+
+
+@pytest.fixture
+def scanner():
+    """Scanner analyzing checked certificates."""
+    config = Config(cache_dir="")
+    scanner = CertificateScanner(
+        config=config, cache=CacheManager(config), metrics=MagicMock(spec=MetricsCollector)
+    )
+    yield scanner
+    scanner._executor.shutdown(wait=True)
+
+
+class TestCheck:
+    """Test checks of a certificate file or TLS server."""
+
+    def test_parse_endpoint(self):
+        """Test host:port targets are recognized, IPv6 addresses in brackets."""
+        assert parse_endpoint("example.com:443") == ("example.com", 443)
+        assert parse_endpoint("[2001:db8::1]:8443") == ("2001:db8::1", 8443)
+        assert parse_endpoint("example.com") is None
+        assert parse_endpoint("example.com:70000") is None
+        assert parse_endpoint("C:\\certs\\site.pem") is None
+
+    def test_file(self, tmp_path, scanner):
+        """Test a file is analyzed as a scan does, with expiry findings."""
+        cert_path, _ = _write_certificate(tmp_path, days=3)
+
+        cert = check_target(scanner, str(cert_path))
+        findings = certificate_findings(cert)
+
+        assert cert["common_name"] == "site.example.com"
+        assert cert["severity"] == "critical"
+        assert worst_finding(findings) == "critical"
+        assert findings[0][1].startswith("Expires in 2 days")
+        report = format_check(cert, findings)
+        assert "Common name:         site.example.com" in report
+        assert "CRITICAL  Expires in 2 days" in report
+
+    def test_no_findings(self, tmp_path, scanner):
+        """Test a healthy certificate has no findings."""
+        cert_path, _ = _write_certificate(tmp_path)
+
+        findings = certificate_findings(check_target(scanner, str(cert_path)))
+
+        assert findings == []
+        assert worst_finding(findings) == "ok"
+
+    def test_invalid_targets(self, tmp_path, scanner):
+        """Test unparsable files and unknown targets fail the check."""
+        junk = tmp_path / "junk.pem"
+        junk.write_text("not a certificate")
+
+        with pytest.raises(RuntimeError):
+            check_target(scanner, str(junk))
+        with pytest.raises(ValueError):
+            check_target(scanner, str(tmp_path / "missing.pem"))
+
+    def test_tls_server(self, tmp_path, scanner):
+        """Test the certificate of a TLS server is analyzed, reporting failed verification."""
+        cert_path, key_path = _write_certificate(tmp_path, name="localhost")
+        context = ssl.SSLContext(ssl.PROTOCOL_TLS_SERVER)
+        context.load_cert_chain(cert_path, key_path)
+        server = socket.create_server(("127.0.0.1", 0))
+        port = server.getsockname()[1]
+
+        def serve():
+            # The verified handshake fails, the unverified one completes
+            for _ in range(2):
+                conn, _ = server.accept()
+                try:
+                    with context.wrap_socket(conn, server_side=True):
+                        pass
+                except (OSError, ssl.SSLError):
+                    pass
+
+        thread = threading.Thread(target=serve, daemon=True)
+        thread.start()
+        try:
+            cert = check_target(scanner, f"127.0.0.1:{port}", timeout=5)
+        finally:
+            thread.join(5)
+            server.close()
+
+        assert cert["path"] == f"127.0.0.1:{port}"
+        assert cert["common_name"] == "localhost.example.com"
+        assert cert["verify_error"]
+        assert any(
+            description.startswith("Verification failed")
+            for _, description in certificate_findings(cert)
+        )
@@ -0,0 +1,84 @@
+"""
+Tests for the expired certificate clean-up suggestions.
+"""
+
+from fastapi.testclient import TestClient
+
+from tls_cert_monitor.api import create_app
+from tls_cert_monitor.cache import CacheManager
+from tls_cert_monitor.cleanup import build_cleanup
+from tls_cert_monitor.config import Config
+from tls_cert_monitor.metrics import MetricsCollector
+from tls_cert_monitor.scanner import CertificateScanner
+
+NOW = 1_800_000_000
+DAY = 86400
+
+
+def _cert(path, fingerprint, days, common_name="www.example.com"):
+    """Certificate data as found by a scan, expiring in days (negative: expired)."""
+    return {
+        "path": path,
+        "common_name": common_name,
+        "fingerprint_sha256": fingerprint,
+        "expiration_timestamp": NOW + days * DAY,
+        "severity": "expired" if days < 0 else "ok",
+    }
+
+
+def _results():
+    """Scan results with an old certificate left next to its renewal, and a copy of it."""
+    return {
+        "directories": {
+            "/etc/ssl": {
+                "certificates": [
+                    _cert("/etc/ssl/www.pem", "new", 60),
+                    _cert("/etc/ssl/www-2024.pem", "old", -90),
+                    _cert("/etc/ssl/recent.pem", "recent", -5, "recent.example.com"),
+                ]
+            },
+            "/srv/backup": {"certificates": [_cert("/srv/backup/www.pem", "old", -90)]},
+        }
+    }
+
+
+class TestCleanup:
+    """Test listing expired certificates to clean up."""
+
+    def test_suggestions(self):
+        """Test certificates expired long enough are listed with duplicates and renewals."""
+        report = build_cleanup(_results(), expired_days=30, now=NOW)
+
+        assert report["expired_days"] == 30
+        assert [cert["path"] for cert in report["certificates"]] == [
+            "/etc/ssl/www-2024.pem",
+            "/srv/backup/www.pem",
+        ]
+        first = report["certificates"][0]
+        assert first["directory"] == "/etc/ssl"
+        assert first["days_expired"] == 90
+        assert first["duplicates"] == ["/srv/backup/www.pem"]
+        assert [renewal["path"] for renewal in first["renewed_by"]] == ["/etc/ssl/www.pem"]
+
+        recent = build_cleanup(_results(), expired_days=0, now=NOW)["certificates"][-1]
+        assert recent["path"] == "/etc/ssl/recent.pem"
+        assert recent["duplicates"] == []
+        assert recent["renewed_by"] == []
+
+    def test_api(self, tmp_path):
+        """Test the endpoint serves the last scan, 503 before the first one."""
+        config = Config(certificate_directories=[str(tmp_path)], enable_ip_whitelist=False)
+        metrics = MetricsCollector()
+        scanner = CertificateScanner(config=config, cache=CacheManager(config), metrics=metrics)
+        client = TestClient(
+            create_app(scanner=scanner, metrics=metrics, cache=scanner.cache, config=config)
+        )
+
+        before = client.get("/api/v1/cleanup")
+        scanner.last_scan_results = _results()
+        after = client.get("/api/v1/cleanup", params={"expired_days": 1000})
+        invalid = client.get("/api/v1/cleanup", params={"expired_days": -1})
+
+        assert before.status_code == 503
+        assert after.json()["certificates"] == []
+        assert invalid.status_code == 400
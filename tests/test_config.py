@@ -9,7 +9,15 @@ from pathlib import Path
 import pytest
 import yaml
 
-from tls_cert_monitor.config import Config, create_example_config, load_config
+from tls_cert_monitor.config import (
+    EXAMPLE_CONFIG_SECTIONS,
+    Config,
+    config_json_schema,
+    create_example_config,
+    diff_configs,
+    generate_example_config,
+    load_config,
+)
 
 
 class TestConfig:
@@ -30,6 +38,18 @@ class TestConfig:
         assert "127.0.0.1" in config.allowed_ips
         assert "::1" in config.allowed_ips
 
+    def test_windows_default_paths(self, monkeypatch):
+        """Test default directories use %ProgramData% on Windows."""
+        monkeypatch.setattr("platform.system", lambda: "Windows")
+        monkeypatch.setenv("PROGRAMDATA", "/programdata")
+
+        config = Config()
+
+        assert config.certificate_directories == [
+            str(Path("/programdata") / "tls-cert-monitor" / "certs")
+        ]
+        assert config.cache_dir == str(Path("/programdata") / "tls-cert-monitor" / "cache")
+
     def test_duration_parsing(self):
         """Test duration parsing."""
         config = Config(scan_interval="10m", cache_ttl="2h")
@@ -37,6 +57,16 @@ class TestConfig:
         assert config.scan_interval_seconds == 600  # 10 minutes
         assert config.cache_ttl_seconds == 7200  # 2 hours
 
+    def test_cache_ttls(self):
+        """Test per-kind cache TTLs override cache_ttl."""
+        config = Config(cache_ttl="2h", cache_ttls={"cert": "1d"})
+
+        assert config.cache_ttl_seconds_for("cert") == 86400
+        assert config.cache_ttl_seconds_for("ocsp") == 7200
+
+        with pytest.raises(ValueError, match="cache_ttls.cert"):
+            Config(cache_ttls={"cert": "forever"})
+
     def test_invalid_duration(self):
         """Test invalid duration format."""
         with pytest.raises(ValueError):
@@ -55,6 +85,53 @@ class TestConfig:
         with pytest.raises(ValueError):
             Config(port=70000)
 
+    def test_expiry_thresholds_defaults(self):
+        """Test default expiry severity thresholds."""
+        config = Config()
+
+        assert config.expiry_thresholds.warning == "30d"
+        assert config.expiry_thresholds.critical == "7d"
+        assert config.expiry_thresholds.warning_seconds == 30 * 86400
+        assert config.expiry_thresholds.critical_seconds == 7 * 86400
+
+    def test_expiry_thresholds_validation(self):
+        """Test critical threshold must not exceed warning threshold."""
+        with pytest.raises(ValueError):
+            Config(expiry_thresholds={"warning": "7d", "critical": "30d"})
+
+        with pytest.raises(ValueError):
+            Config(expiry_thresholds={"warning": "soon"})
+
+    def test_diff_configs(self):
+        """Test reload diffs compare real values but report redacted ones."""
+        old = Config(
+            certificate_directories=["/etc/ssl/certs"],
+            p12_passwords=["a", "b"],
+            expiry_thresholds={"warning": "30d", "critical": "7d"},
+        )
+        new = Config(
+            certificate_directories=["/etc/ssl/certs", "/srv/certs"],
+            p12_passwords=["a", "c"],
+            expiry_thresholds={"warning": "45d", "critical": "7d"},
+        )
+
+        changes = {change["setting"]: change for change in diff_configs(old, new)}
+
+        assert sorted(changes) == [
+            "certificate_directories",
+            "expiry_thresholds.warning",
+            "p12_passwords",
+        ]
+        assert changes["expiry_thresholds.warning"] == {
+            "setting": "expiry_thresholds.warning",
+            "old": "30d",
+            "new": "45d",
+        }
+        # Rotated secret is reported without revealing it
+        assert changes["p12_passwords"]["new"] == ["***REDACTED*** (2 passwords)"]
+        assert changes["certificate_directories"]["new"] == ["***/certs", "***/certs"]
+        assert diff_configs(old, old) == []
+
 
 class TestLoadConfig:
     """Test configuration loading."""
@@ -92,6 +169,215 @@ class TestLoadConfig:
         config = load_config()
         assert config.port == 3200  # Default value
 
+    def test_load_from_directory(self):
+        """Test merging a conf.d-style directory in lexical order."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            config_dir = Path(temp_dir)
+            (config_dir / "00-base.yaml").write_text(
+                yaml.dump(
+                    {
+                        "port": 8080,
+                        "certificate_directories": ["/base/certs"],
+                        "expiry_thresholds": {"warning": "45d", "critical": "10d"},
+                    }
+                )
+            )
+            (config_dir / "10-team-a.yaml").write_text(
+                yaml.dump(
+                    {
+                        "port": 9090,
+                        "certificate_directories": ["/team-a/certs"],
+                        "expiry_thresholds": {"critical": "14d"},
+                    }
+                )
+            )
+            (config_dir / "20-team-b.yml").write_text(
+                yaml.dump({"certificate_directories": ["/team-b/certs"]})
+            )
+            (config_dir / ".hidden.yaml").write_text(yaml.dump({"port": 1}))
+            (config_dir / "README.txt").write_text("not configuration")
+
+            config = load_config(temp_dir)
+
+        assert config.port == 9090
+        assert config.certificate_directories == [
+            "/base/certs",
+            "/team-a/certs",
+            "/team-b/certs",
+        ]
+        assert config.expiry_thresholds.warning == "45d"
+        assert config.expiry_thresholds.critical == "14d"
+
+    def test_load_from_directory_rejects_non_mapping(self):
+        """Test that directory files must contain a mapping."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            (Path(temp_dir) / "00-list.yaml").write_text(yaml.dump(["/not/a/mapping"]))
+
+            with pytest.raises(ValueError):
+                load_config(temp_dir)
+
+    def test_unknown_keys_rejected(self):
+        """Test that unknown keys fail validation with a suggestion."""
+        with pytest.raises(ValueError, match="did you mean 'scan_interval'"):
+            Config(scan_intervall="5m")
+        with pytest.raises(ValueError):
+            Config(expiry_thresholds={"warn": "14d"})
+
+    def test_json_schema(self):
+        """Test the exported JSON Schema."""
+        schema = config_json_schema()
+
+        assert schema["additionalProperties"] is False
+        assert "scan_interval" in schema["properties"]
+        assert "directory_settings" not in schema["properties"]
+        directory_items = schema["properties"]["certificate_directories"]["items"]
+        assert {"$ref": "#/$defs/DirectoryConfig"} in directory_items["anyOf"]
+
+    def test_secret_file_references(self):
+        """Test secrets read from *_file references."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            passwords_file = Path(temp_dir) / "p12-passwords"
+            passwords_file.write_text("first\n\nchangeit\n")
+            smtp_file = Path(temp_dir) / "smtp-password"
+            smtp_file.write_text("s3cret\n")
+
+            config = Config(
+                p12_passwords_file=str(passwords_file),
+                notifiers=[
+                    {
+                        "name": "mail",
+                        "type": "email",
+                        "smtp_host": "smtp.example.com",
+                        "from_address": "monitor@example.com",
+                        "to_addresses": ["ops@example.com"],
+                        "smtp_password_file": str(smtp_file),
+                    }
+                ],
+            )
+
+            assert config.p12_passwords == ["first", "", "changeit"]
+            assert config.notifiers[0].smtp_password == "s3cret"
+
+            with pytest.raises(ValueError, match="not both"):
+                Config(p12_passwords=["inline"], p12_passwords_file=str(passwords_file))
+
+        with pytest.raises(ValueError, match="Cannot read p12_passwords_file"):
+            Config(p12_passwords_file="/nonexistent/p12-passwords")
+
+        # Only secret fields accept file references
+        with pytest.raises(ValueError):
+            Config(port_file="/nonexistent/port")
+
+    def test_tls_key_password_file(self):
+        """Test the TLS key passphrase can be read from a file or the environment."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            password_file = Path(temp_dir) / "tls-key-password"
+            password_file.write_text("key-passphrase\n")
+
+            config_path = Path(temp_dir) / "config.yaml"
+            config_path.write_text("port: 3200\n")
+
+            config = Config(tls_key_password_file=str(password_file))
+            assert config.tls_key_password == "key-passphrase"
+
+            os.environ["TLS_MONITOR_TLS_KEY_PASSWORD_FILE"] = str(password_file)
+            try:
+                config = load_config(str(config_path))
+            finally:
+                del os.environ["TLS_MONITOR_TLS_KEY_PASSWORD_FILE"]
+
+        assert config.tls_key_password == "key-passphrase"
+
+    def test_environment_secret_file_overrides_inline(self):
+        """Test that a *_file environment override replaces the inline value."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            passwords_file = Path(temp_dir) / "p12-passwords"
+            passwords_file.write_text("from-secret\n")
+            config_path = Path(temp_dir) / "config.yaml"
+            config_path.write_text(yaml.dump({"p12_passwords": ["inline"]}))
+
+            os.environ["TLS_MONITOR_P12_PASSWORDS_FILE"] = str(passwords_file)
+            try:
+                config = load_config(str(config_path))
+            finally:
+                del os.environ["TLS_MONITOR_P12_PASSWORDS_FILE"]
+
+        assert config.p12_passwords == ["from-secret"]
+
+    def test_profiles(self):
+        """Test profile overrides selected by argument or TLS_MONITOR_PROFILE."""
+        with tempfile.NamedTemporaryFile(mode="w", suffix=".yaml", delete=False) as f:
+            yaml.dump(
+                {
+                    "port": 3200,
+                    "log_level": "INFO",
+                    "expiry_thresholds": {"warning": "30d", "critical": "7d"},
+                    "profiles": {
+                        "dev": {"log_level": "DEBUG", "scan_interval": "1m"},
+                        "prod": {"port": 443, "expiry_thresholds": {"warning": "45d"}},
+                    },
+                },
+                f,
+            )
+            config_path = f.name
+
+        try:
+            config = load_config(config_path)
+            assert config.log_level == "INFO"  # No profile selected
+
+            config = load_config(config_path, profile="dev")
+            assert config.log_level == "DEBUG"
+            assert config.scan_interval == "1m"
+            assert config.port == 3200
+
+            os.environ["TLS_MONITOR_PROFILE"] = "prod"
+            try:
+                config = load_config(config_path)
+            finally:
+                del os.environ["TLS_MONITOR_PROFILE"]
+            assert config.port == 443
+            assert config.expiry_thresholds.warning == "45d"
+            assert config.expiry_thresholds.critical == "7d"
+
+            with pytest.raises(ValueError, match="Unknown profile 'staging'"):
+                load_config(config_path, profile="staging")
+        finally:
+            os.unlink(config_path)
+
+    def test_directory_objects(self):
+        """Test certificate directories given as objects with per-directory settings."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            config = Config(
+                certificate_directories=[
+                    "/etc/ssl/certs",
+                    {
+                        "path": temp_dir,
+                        "interval": "1m",
+                        "excludes": ["^old-"],
+                        "labels": {"team": "web"},
+                        "workers": 8,
+                    },
+                ],
+                scan_interval="10m",
+            )
+            resolved = str(Path(temp_dir).resolve())
+
+            assert config.certificate_directories == [
+                str(Path("/etc/ssl/certs").resolve()),
+                resolved,
+            ]
+            assert config.get_directory_config(resolved).labels == {"team": "web"}
+            assert config.get_directory_config(resolved).workers == 8
+            assert config.directory_scan_interval_seconds(resolved) == 60
+            assert config.directory_scan_interval_seconds("/etc/ssl/certs") == 600
+
+    def test_directory_objects_invalid(self):
+        """Test validation of per-directory settings."""
+        with pytest.raises(ValueError):
+            Config(certificate_directories=[{"path": "/tmp", "interval": "soon"}])
+        with pytest.raises(ValueError):
+            Config(certificate_directories=[{"path": "/tmp", "excludes": ["[unclosed"]}])
+
     def test_environment_variable_override(self):
         """Test environment variable overrides."""
         os.environ["TLS_MONITOR_PORT"] = "9090"
@@ -118,6 +404,55 @@ class TestLoadConfig:
             del os.environ["TLS_MONITOR_CERT_DIRECTORIES"]
             del os.environ["TLS_MONITOR_P12_PASSWORDS"]
 
+    def test_environment_expiry_thresholds(self):
+        """Test environment variables for expiry thresholds."""
+        os.environ["TLS_MONITOR_EXPIRY_WARNING"] = "45d"
+        os.environ["TLS_MONITOR_EXPIRY_CRITICAL"] = "14d"
+
+        try:
+            config = load_config()
+            assert config.expiry_thresholds.warning == "45d"
+            assert config.expiry_thresholds.critical == "14d"
+        finally:
+            del os.environ["TLS_MONITOR_EXPIRY_WARNING"]
+            del os.environ["TLS_MONITOR_EXPIRY_CRITICAL"]
+
+
+    def test_override_precedence(self):
+        """Test that flag overrides win over environment variables and the file."""
+        with tempfile.NamedTemporaryFile(mode="w", suffix=".yaml", delete=False) as f:
+            yaml.dump(
+                {
+                    "port": 8080,
+                    "workers": 2,
+                    "certificate_directories": ["/file/certs"],
+                    "expiry_thresholds": {"warning": "60d", "critical": "10d"},
+                },
+                f,
+            )
+            config_path = f.name
+
+        os.environ["TLS_MONITOR_PORT"] = "9090"
+        os.environ["TLS_MONITOR_WORKERS"] = "6"
+
+        try:
+            config = load_config(
+                config_path,
+                overrides={
+                    "port": 7070,
+                    "certificate_directories": ["/flag/certs"],
+                    "expiry_thresholds": {"critical": "3d"},
+                },
+            )
+            assert config.port == 7070  # flag > env > file
+            assert config.workers == 6  # env > file
+            assert config.certificate_directories == ["/flag/certs"]
+            assert config.expiry_thresholds.warning == "60d"
+            assert config.expiry_thresholds.critical == "3d"
+        finally:
+            del os.environ["TLS_MONITOR_PORT"]
+            del os.environ["TLS_MONITOR_WORKERS"]
+            os.unlink(config_path)
 
 class TestCreateExampleConfig:
     """Test example configuration creation."""
@@ -145,3 +480,12 @@ class TestCreateExampleConfig:
             assert config.port == 3200
             assert config.enable_ip_whitelist is True
             assert "127.0.0.1" in config.allowed_ips
+
+    def test_generate_example_config(self):
+        """Test the generated example covers every setting and loads to the defaults."""
+        documented = {key for _, settings in EXAMPLE_CONFIG_SECTIONS for key, _ in settings}
+        assert documented == set(Config.model_fields) - {"directory_settings"}
+
+        example = generate_example_config()
+        assert "# Passwords tried for P12/PFX files" in example
+        assert Config(**yaml.safe_load(example)) == Config()
@@ -0,0 +1,124 @@
+"""
+Tests for the CycloneDX inventory export.
+"""
+
+import json
+
+from fastapi.testclient import TestClient
+
+from tls_cert_monitor.api import create_app
+from tls_cert_monitor.cache import CacheManager
+from tls_cert_monitor.config import Config
+from tls_cert_monitor.cyclonedx import BOM_MEDIA_TYPE, build_bom
+from tls_cert_monitor.metrics import MetricsCollector
+from tls_cert_monitor.output import format_results
+from tls_cert_monitor.scanner import CertificateScanner
+
+FINGERPRINT = "ab" * 32
+
+
+def _cert(path, **fields):
+    """Certificate data as found by a scan."""
+    return {
+        "path": path,
+        "common_name": "api.example.com",
+        "subject": "CN=api.example.com",
+        "issuer": "CN=Example CA",
+        "serial": "1234",
+        "fingerprint_sha256": FINGERPRINT,
+        "not_before": "2026-01-01T00:00:00+00:00",
+        "not_after": "2027-01-01T00:00:00+00:00",
+        "severity": "ok",
+        "days_until_expiry": 79,
+        "key_algorithm": "RSA",
+        "key_size": 2048,
+        "signature_algorithm": "sha256WithRSAEncryption",
+        "san_list": ["api.example.com"],
+        **fields,
+    }
+
+
+def _properties(component):
+    """Get the values of the properties of a component by name."""
+    properties = {}
+    for prop in component["properties"]:
+        properties.setdefault(prop["name"], []).append(prop["value"])
+    return properties
+
+
+class TestCycloneDX:
+    """Test exporting the certificate inventory as CycloneDX."""
+
+    def test_component(self):
+        """Test a certificate is a cryptographic asset with its properties and evaluation."""
+        cert = _cert("/etc/ssl/api.crt", is_weak_key=True, labels={"team": "payments"})
+        bom = build_bom([cert], timestamp=0)
+
+        assert bom["bomFormat"] == "CycloneDX"
+        assert bom["specVersion"] == "1.6"
+        assert bom["serialNumber"].startswith("urn:uuid:")
+        assert bom["metadata"]["timestamp"] == "1970-01-01T00:00:00Z"
+        [component] = bom["components"]
+        assert component["type"] == "cryptographic-asset"
+        assert component["bom-ref"] == f"certificate:{FINGERPRINT}"
+        assert component["hashes"] == [{"alg": "SHA-256", "content": FINGERPRINT}]
+        crypto = component["cryptoProperties"]
+        assert crypto["assetType"] == "certificate"
+        assert crypto["certificateProperties"] == {
+            "subjectName": "CN=api.example.com",
+            "issuerName": "CN=Example CA",
+            "notValidBefore": "2026-01-01T00:00:00+00:00",
+            "notValidAfter": "2027-01-01T00:00:00+00:00",
+            "certificateFormat": "X.509",
+            "certificateExtension": "crt",
+        }
+        properties = _properties(component)
+        assert properties["tls-cert-monitor:severity"] == ["ok"]
+        assert properties["tls-cert-monitor:key_size"] == ["2048"]
+        assert properties["tls-cert-monitor:san"] == ["api.example.com"]
+        assert properties["tls-cert-monitor:label:team"] == ["payments"]
+        assert properties["tls-cert-monitor:finding"][0].startswith("warning: ")
+
+    def test_occurrences(self):
+        """Test a certificate in several files is one component with an occurrence per file."""
+        bom = build_bom(
+            [
+                _cert("/etc/ssl/copy.pem"),
+                _cert("/etc/ssl/api.pem"),
+                _cert("/etc/ssl/www.pem", fingerprint_sha256="cd" * 32),
+            ]
+        )
+
+        first, second = bom["components"]
+        assert first["evidence"]["occurrences"] == [
+            {"location": "/etc/ssl/api.pem"},
+            {"location": "/etc/ssl/copy.pem"},
+        ]
+        assert second["bom-ref"] == f"certificate:{'cd' * 32}"
+
+    def test_output_format(self):
+        """Test scan -o cyclonedx prints the BOM of the scan results."""
+        results = {"directories": {"/etc/ssl": {"certificates": [_cert("/etc/ssl/api.pem")]}}}
+
+        bom = json.loads(format_results(results, "cyclonedx"))
+
+        assert bom["components"][0]["name"] == "api.example.com"
+
+    def test_api(self, tmp_path):
+        """Test the endpoint serves the last scan, and 503 before the first one."""
+        config = Config(certificate_directories=[str(tmp_path)], enable_ip_whitelist=False)
+        metrics = MetricsCollector()
+        scanner = CertificateScanner(config=config, cache=CacheManager(config), metrics=metrics)
+        client = TestClient(
+            create_app(scanner=scanner, metrics=metrics, cache=scanner.cache, config=config)
+        )
+
+        before = client.get("/api/v1/inventory/cyclonedx")
+        scanner.last_scan_results = {
+            "directories": {"/etc/ssl": {"certificates": [_cert("/etc/ssl/api.pem")]}}
+        }
+        after = client.get("/api/v1/inventory/cyclonedx")
+
+        assert before.status_code == 503
+        assert after.headers["content-type"] == BOM_MEDIA_TYPE
+        assert after.json()["components"][0]["bom-ref"] == f"certificate:{FINGERPRINT}"
@@ -0,0 +1,232 @@
+"""
+Tests for scheduled digest reports.
+"""
+
+from datetime import datetime
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from tls_cert_monitor.config import Config
+from tls_cert_monitor.digest import DigestReporter
+from tls_cert_monitor.schedule import CronSchedule
+
+NOW = 1_800_000_000.0
+DAY = 86400
+
+
+def _cert(path, serial, days, severity="ok", **extra):
+    """Build certificate data as annotated by the scanner."""
+    return {
+        "common_name": f"{serial}.example.com",
+        "path": path,
+        "serial": serial,
+        "issuer": "Test CA",
+        "not_after": "2027-01-15T00:00:00+00:00",
+        "expiration_timestamp": NOW + days * DAY,
+        "severity": severity,
+        "is_weak_key": False,
+        "is_deprecated_algorithm": False,
+        **extra,
+    }
+
+
+def _scan(*certs, parse_errors=0):
+    return {
+        "timestamp": NOW,
+        "directories": {
+            "/certs": {
+                "certificates": list(certs),
+                "parse_errors": parse_errors,
+            }
+        },
+    }
+
+
+class TestCronSchedule:
+    """Test cron schedule parsing and evaluation."""
+
+    def test_daily_alias(self):
+        """Test @daily fires at midnight."""
+        schedule = CronSchedule("@daily")
+        assert schedule.next_after(datetime(2026, 3, 4, 10, 30)) == datetime(2026, 3, 5, 0, 0)
+
+    def test_specific_time(self):
+        """Test a fixed minute and hour."""
+        schedule = CronSchedule("30 8 * * *")
+        assert schedule.next_after(datetime(2026, 3, 4, 8, 29)) == datetime(2026, 3, 4, 8, 30)
+        assert schedule.next_after(datetime(2026, 3, 4, 8, 30)) == datetime(2026, 3, 5, 8, 30)
+
+    def test_weekly_on_monday(self):
+        """Test day-of-week restriction (2026-03-04 is a Wednesday)."""
+        schedule = CronSchedule("0 9 * * 1")
+        assert schedule.next_after(datetime(2026, 3, 4, 12, 0)) == datetime(2026, 3, 9, 9, 0)
+
+    def test_sunday_as_seven(self):
+        """Test 7 is accepted as Sunday."""
+        schedule = CronSchedule("0 0 * * 7")
+        assert schedule.next_after(datetime(2026, 3, 4)) == datetime(2026, 3, 8, 0, 0)
+
+    def test_steps_ranges_and_lists(self):
+        """Test step, range and list syntax."""
+        schedule = CronSchedule("*/15 9-17 * * 1-5")
+        assert schedule.minutes == {0, 15, 30, 45}
+        assert schedule.hours == set(range(9, 18))
+        assert CronSchedule("0,30 * * * *").minutes == {0, 30}
+
+    def test_day_of_month_or_weekday(self):
+        """Test day-of-month and day-of-week match either when both are set."""
+        schedule = CronSchedule("0 0 1 * 1")
+        # Monday 2026-03-09 comes before the 1st of April
+        assert schedule.next_after(datetime(2026, 3, 4)) == datetime(2026, 3, 9, 0, 0)
+
+    @pytest.mark.parametrize(
+        "expression", ["", "* * * *", "60 * * * *", "* * * 13 *", "*/0 * * * *", "a * * * *"]
+    )
+    def test_invalid_expressions(self, expression):
+        """Test invalid cron expressions are rejected."""
+        with pytest.raises(ValueError):
+            CronSchedule(expression)
+
+    def test_config_validation(self):
+        """Test digest schedule is validated with the configuration."""
+        with pytest.raises(ValueError):
+            Config(digest={"enabled": True, "schedule": "every day"})
+
+
+class TestDigestConfig:
+    """Test digest and notifier configuration."""
+
+    def test_unknown_notifier_reference(self):
+        """Test digest referencing an undefined notifier is rejected."""
+        with pytest.raises(ValueError):
+            Config(digest={"enabled": True, "notifiers": ["ops"]})
+
+    def test_notifier_required_fields(self):
+        """Test notifier type-specific fields are required."""
+        with pytest.raises(ValueError):
+            Config(notifiers=[{"name": "ops", "type": "webhook"}])
+        with pytest.raises(ValueError):
+            Config(notifiers=[{"name": "ops", "type": "email", "smtp_host": "localhost"}])
+        with pytest.raises(ValueError):
+            Config(notifiers=[{"name": "ops", "type": "pager", "url": "http://x"}])
+
+    def test_duplicate_notifier_names(self):
+        """Test notifier names must be unique."""
+        with pytest.raises(ValueError):
+            Config(
+                notifiers=[
+                    {"name": "ops", "type": "webhook", "url": "http://a"},
+                    {"name": "ops", "type": "webhook", "url": "http://b"},
+                ]
+            )
+
+
+class TestDigestReporter:
+    """Test digest building and delivery."""
+
+    @pytest.fixture
+    def config(self):
+        return Config(
+            certificate_directories=[],
+            notifiers=[{"name": "ops", "type": "webhook", "url": "http://localhost/hook"}],
+            digest={"enabled": True, "schedule": "0 8 * * *", "notifiers": ["ops"]},
+        )
+
+    @pytest.fixture
+    def notifier(self):
+        notifier = MagicMock()
+        notifier.name = "ops"
+        notifier.send = AsyncMock()
+        return notifier
+
+    @pytest.fixture
+    def scanner(self):
+        scanner = MagicMock()
+        scanner.last_scan_results = None
+        return scanner
+
+    @pytest.fixture
+    def reporter(self, config, scanner, notifier):
+        return DigestReporter(config=config, scanner=scanner, notifiers={"ops": notifier})
+
+    def test_registers_scan_listener(self, reporter, scanner):
+        """Test reporter listens for scan results."""
+        scanner.add_scan_listener.assert_called_once()
+
+    @pytest.mark.asyncio
+    async def test_build_digest(self, reporter, scanner):
+        """Test expiring, expired and problem sections."""
+        scanner.last_scan_results = _scan(
+            _cert("/certs/ok.pem", "1", 90),
+            _cert("/certs/soon.pem", "2", 20, "warning"),
+            _cert("/certs/urgent.pem", "3", 3, "critical"),
+            _cert("/certs/old.pem", "4", -2, "expired"),
+            _cert("/certs/weak.pem", "5", 90, is_weak_key=True),
+            _cert("/certs/muted.pem", "6", 1, "critical", silenced=True),
+            parse_errors=2,
+        )
+        await reporter._on_scan_complete(scanner.last_scan_results)
+
+        digest = reporter.build_digest(now=NOW)
+
+        assert digest["total_certificates"] == 6
+        assert digest["silenced_certificates"] == 1
+        assert [c["path"] for c in digest["expiring"]] == ["/certs/urgent.pem", "/certs/soon.pem"]
+        assert digest["expiring"][0]["days_until_expiry"] == 3
+        assert [c["path"] for c in digest["expired"]] == ["/certs/old.pem"]
+        problems = {(p["path"], p["problem"]) for p in digest["problems"]}
+        assert ("/certs/weak.pem", "weak key") in problems
+        assert ("/certs", "2 parse error(s)") in problems
+        assert digest["new"] == [] and digest["removed"] == []
+
+    @pytest.mark.asyncio
+    async def test_new_and_removed_since_last_digest(self, reporter, scanner, notifier):
+        """Test certificates added or removed since the previous digest."""
+        first = _scan(_cert("/certs/a.pem", "1", 90), _cert("/certs/b.pem", "2", 90))
+        scanner.last_scan_results = first
+        await reporter._on_scan_complete(first)
+
+        # b.pem renewed (new serial), c.pem added, a.pem removed
+        scanner.last_scan_results = _scan(
+            _cert("/certs/b.pem", "20", 365), _cert("/certs/c.pem", "3", 365)
+        )
+        digest = reporter.build_digest(now=NOW)
+        assert {c["serial"] for c in digest["new"]} == {"20", "3"}
+        assert {c["serial"] for c in digest["removed"]} == {"1", "2"}
+
+        # The next digest only reports changes after this one
+        await reporter.send_digest()
+        digest = reporter.build_digest(now=NOW)
+        assert digest["new"] == [] and digest["removed"] == []
+
+    @pytest.mark.asyncio
+    async def test_send_digest(self, reporter, scanner, notifier):
+        """Test digest is rendered and delivered to configured notifiers."""
+        scanner.last_scan_results = _scan(_cert("/certs/soon.pem", "2", 20, "warning"))
+
+        delivered = await reporter.send_digest()
+
+        assert delivered == 1
+        notification = notifier.send.call_args[0][0]
+        assert "1 expiring" in notification.title
+        assert notification.severity == "warning"
+        assert "/certs/soon.pem" in notification.body
+        assert notification.data["expiring"][0]["serial"] == "2"
+        assert reporter.last_sent is not None
+
+    @pytest.mark.asyncio
+    async def test_send_digest_dry_run(self, config, scanner, notifier):
+        """Test dry run mode logs instead of sending."""
+        config.dry_run = True
+        reporter = DigestReporter(config=config, scanner=scanner, notifiers={"ops": notifier})
+
+        assert await reporter.send_digest() == 0
+        notifier.send.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_send_failure_is_logged(self, reporter, notifier):
+        """Test a failing notifier doesn't raise."""
+        notifier.send.side_effect = ConnectionError("refused")
+
+        assert await reporter.send_digest() == 0
@@ -196,12 +196,12 @@ def docker_environment(tmp_path_factory) -> Generator[dict, None, None]:
 port: {CONTAINER_PORT}
 bind_address: "0.0.0.0"
 log_level: "INFO"
-scan_interval_seconds: 60
+scan_interval: "1m"
 workers: 2
 certificate_directories:
   - "/certs"
 cache_dir: "/tmp/cache"
-cache_ttl_seconds: 300
+cache_ttl: "5m"
 hot_reload: true
 dry_run: false
 enable_ip_whitelist: false
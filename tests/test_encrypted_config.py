@@ -0,0 +1,118 @@
+"""
+Tests for SOPS/age encrypted configuration files.
+"""
+
+import os
+import stat
+
+import pytest
+
+from tls_cert_monitor.config import load_config
+from tls_cert_monitor.encrypted_config import (
+    ConfigDecryptionError,
+    is_age_encrypted,
+    is_sops_document,
+    read_config_file,
+)
+
+SOPS_DOCUMENT = """\
+port: ENC[AES256_GCM,data:4Vr1,iv:a,tag:b,type:int]
+sops:
+    age:
+        - recipient: age1example
+    mac: ENC[AES256_GCM,data:x,iv:y,tag:z,type:str]
+    version: 3.8.1
+"""
+
+
+@pytest.fixture
+def fake_tools(tmp_path, monkeypatch):
+    """Put fake sops and age binaries on PATH that log their arguments and print YAML."""
+    bin_dir = tmp_path / "bin"
+    bin_dir.mkdir()
+    log = tmp_path / "calls.log"
+    for tool in ("sops", "age"):
+        script = bin_dir / tool
+        script.write_text(
+            "#!/bin/sh\n"
+            f'echo "{tool} $* key=$SOPS_AGE_KEY_FILE" >> "{log}"\n'
+            'if [ -n "$FAIL" ]; then echo "no key could decrypt" >&2; exit 1; fi\n'
+            "printf 'port: 9443\\nworkers: 6\\n'\n"
+        )
+        script.chmod(script.stat().st_mode | stat.S_IEXEC)
+    monkeypatch.setenv("PATH", f"{bin_dir}{os.pathsep}{os.environ.get('PATH', '')}")
+    return log
+
+
+class TestEncryptedConfig:
+    """Test detection and decryption of encrypted configuration files."""
+
+    def test_detection(self):
+        """Test SOPS documents and age files are detected."""
+        assert is_age_encrypted(b"age-encryption.org/v1\n-> X25519 abc\n")
+        assert is_age_encrypted(b"-----BEGIN AGE ENCRYPTED FILE-----\nYWdl\n")
+        assert not is_age_encrypted(b"port: 3200\n")
+
+        assert is_sops_document({"port": "ENC[...]", "sops": {"mac": "ENC[...]"}})
+        assert not is_sops_document({"port": 3200})
+        assert not is_sops_document({"sops": "not metadata"})
+
+    def test_plain_file(self, tmp_path, fake_tools):
+        """Test plain files are read without running any tool."""
+        config_file = tmp_path / "config.yaml"
+        config_file.write_text("port: 3200\n")
+
+        assert read_config_file(config_file) == {"port": 3200}
+        assert not fake_tools.exists()
+
+    def test_sops_file(self, tmp_path, fake_tools, monkeypatch):
+        """Test SOPS files are decrypted with sops, passing the age key file."""
+        monkeypatch.setenv("TLS_MONITOR_AGE_KEY_FILE", "/keys/age.txt")
+        monkeypatch.delenv("SOPS_AGE_KEY_FILE", raising=False)
+        config_file = tmp_path / "config.yaml"
+        config_file.write_text(SOPS_DOCUMENT)
+
+        config = load_config(str(config_file))
+
+        assert config.port == 9443
+        assert config.workers == 6
+        call = fake_tools.read_text()
+        assert call.startswith("sops --decrypt")
+        assert "key=/keys/age.txt" in call
+
+    def test_age_file(self, tmp_path, fake_tools, monkeypatch):
+        """Test age files are decrypted with the configured identity."""
+        monkeypatch.setenv("TLS_MONITOR_AGE_KEY_FILE", "/keys/age.txt")
+        config_file = tmp_path / "config.yaml.age"
+        config_file.write_bytes(b"age-encryption.org/v1\n-> X25519 abc\n")
+
+        assert read_config_file(config_file) == {"port": 9443, "workers": 6}
+        assert "--identity /keys/age.txt" in fake_tools.read_text()
+
+    def test_age_file_without_identity(self, tmp_path, fake_tools, monkeypatch):
+        """Test a clear error when no age identity is configured."""
+        monkeypatch.delenv("TLS_MONITOR_AGE_KEY_FILE", raising=False)
+        monkeypatch.delenv("SOPS_AGE_KEY_FILE", raising=False)
+        config_file = tmp_path / "config.yaml.age"
+        config_file.write_bytes(b"age-encryption.org/v1\n")
+
+        with pytest.raises(ConfigDecryptionError, match="TLS_MONITOR_AGE_KEY_FILE"):
+            read_config_file(config_file)
+
+    def test_decryption_failure(self, tmp_path, fake_tools, monkeypatch):
+        """Test tool errors are reported."""
+        monkeypatch.setenv("FAIL", "1")
+        config_file = tmp_path / "config.yaml"
+        config_file.write_text(SOPS_DOCUMENT)
+
+        with pytest.raises(ConfigDecryptionError, match="no key could decrypt"):
+            read_config_file(config_file)
+
+    def test_missing_tool(self, tmp_path, monkeypatch):
+        """Test a clear error when the decryption tool is not installed."""
+        monkeypatch.setenv("PATH", str(tmp_path))
+        config_file = tmp_path / "config.yaml"
+        config_file.write_text(SOPS_DOCUMENT)
+
+        with pytest.raises(ConfigDecryptionError, match="'sops' is not installed"):
+            read_config_file(config_file)
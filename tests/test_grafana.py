@@ -0,0 +1,61 @@
+"""
+Tests for the Grafana dashboard.
+"""
+
+import json
+import re
+
+from tls_cert_monitor.config import Config, ExpiryThresholds
+from tls_cert_monitor.grafana import DASHBOARD_UID, GRID_WIDTH, build_dashboard
+from tls_cert_monitor.metrics import MetricsCollector
+
+
+def _panels(dashboard):
+    """Get the panels that are not rows."""
+    return [panel for panel in dashboard["panels"] if panel["type"] != "row"]
+
+
+class TestGrafana:
+    """Test building the Grafana dashboard."""
+
+    def test_metric_names(self):
+        """Test every query uses metrics the exporter exposes."""
+        collector = MetricsCollector()
+        exposed = set()
+        for attribute in vars(collector).values():
+            name = getattr(attribute, "_name", None)
+            if name:
+                exposed.update({name, f"{name}_sum", f"{name}_count"})
+
+        for panel in _panels(build_dashboard(Config())):
+            for target in panel["targets"]:
+                used = set(re.findall(r"\b((?:ssl|app)_[a-z_]+)\b", target["expr"]))
+                assert used, panel["title"]
+                assert used <= exposed, (panel["title"], used - exposed)
+
+    def test_thresholds(self):
+        """Test days left are colored with the configured thresholds."""
+        config = Config(
+            expiry_thresholds=ExpiryThresholds(warning="45d", critical="10d"), scan_interval="10m"
+        )
+        panels = {panel["title"]: panel for panel in _panels(build_dashboard(config))}
+
+        steps = panels["Certificates by days left"]["fieldConfig"]["defaults"]["thresholds"]
+        assert [step["value"] for step in steps["steps"]] == [None, 0, 10, 45]
+        last_scan = panels["Last scan"]["fieldConfig"]["defaults"]["thresholds"]["steps"]
+        assert last_scan[-1]["value"] == 1200
+
+    def test_layout(self):
+        """Test the dashboard is JSON with unique panel ids laid out on the grid."""
+        dashboard = json.loads(json.dumps(build_dashboard(Config())))
+
+        assert dashboard["uid"] == DASHBOARD_UID
+        ids = [panel["id"] for panel in dashboard["panels"]]
+        assert len(ids) == len(set(ids))
+        for panel in dashboard["panels"]:
+            grid = panel["gridPos"]
+            assert grid["x"] + grid["w"] <= GRID_WIDTH, panel["title"]
+        variables = [variable["name"] for variable in dashboard["templating"]["list"]]
+        assert variables == ["datasource", "instance"]
+        for panel in _panels(dashboard):
+            assert 'instance=~"$instance"' in panel["targets"][0]["expr"], panel["title"]
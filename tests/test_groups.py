@@ -0,0 +1,119 @@
+"""
+Tests for directory groups and their labels.
+"""
+
+from unittest.mock import AsyncMock, patch
+
+import pytest
+from fastapi.testclient import TestClient
+
+from tls_cert_monitor.api import create_app
+from tls_cert_monitor.cache import CacheManager
+from tls_cert_monitor.config import Config
+from tls_cert_monitor.metrics import MetricsCollector
+from tls_cert_monitor.scanner import CertificateScanner
+
+GROUPS = {
+    "payments": {
+        "description": "Checkout and billing",
+        "labels": {"team": "payments", "service": "checkout"},
+    }
+}
+
+
+def _config(tmp_path):
+    """Configuration with a payments directory and an ungrouped one."""
+    (tmp_path / "payments").mkdir(exist_ok=True)
+    (tmp_path / "other").mkdir(exist_ok=True)
+    return Config(
+        certificate_directories=[
+            {
+                "path": str(tmp_path / "payments"),
+                "group": "payments",
+                "labels": {"service": "billing"},
+            },
+            str(tmp_path / "other"),
+        ],
+        groups=GROUPS,
+        enable_ip_whitelist=False,
+    )
+
+
+class TestGroups:
+    """Test grouping directories under labeled groups."""
+
+    def test_labels(self, tmp_path):
+        """Test directories get the group label and labels, overridden by their own."""
+        tmp_path = tmp_path.resolve()
+        config = _config(tmp_path)
+
+        assert config.directory_labels(str(tmp_path / "payments")) == {
+            "group": "payments",
+            "team": "payments",
+            "service": "billing",
+        }
+        assert config.directory_labels(str(tmp_path / "other")) == {}
+        assert config.directory_label_names() == ["group", "service", "team"]
+
+    def test_unknown_group(self, tmp_path):
+        """Test a directory in a group that is not defined is rejected."""
+        with pytest.raises(ValueError, match="Unknown group 'billing'.*groups: payments"):
+            Config(
+                certificate_directories=[{"path": str(tmp_path), "group": "billing"}],
+                groups=GROUPS,
+            )
+
+    def test_metrics(self):
+        """Test ssl_cert_labels exports the labels as label_ labels, following the config."""
+        metrics = MetricsCollector()
+        metrics.set_certificate_label_names(["group", "team-name"])
+        metrics.update_certificate_metrics(
+            {
+                "common_name": "pay.example.com",
+                "path": "/srv/payments/pay.pem",
+                "labels": {"group": "payments", "team-name": "payments"},
+            }
+        )
+        metrics.update_certificate_metrics({"common_name": "www.example.com", "path": "/w.pem"})
+
+        output = metrics.get_metrics()
+
+        assert (
+            'ssl_cert_labels{common_name="pay.example.com",path="/srv/payments/pay.pem",'
+            'label_group="payments",label_team_name="payments"} 1'
+        ) in output
+        assert "www.example.com" not in output.split("# TYPE ssl_cert_labels")[1]
+
+        metrics.set_certificate_label_names(["team"])
+        assert "label_group" not in metrics.get_metrics()
+
+    @pytest.mark.asyncio
+    async def test_scan_and_api(self, tmp_path):
+        """Test scans label certificates, and /api/v1/groups summarizes the groups."""
+        tmp_path = tmp_path.resolve()
+        config = _config(tmp_path)
+        (tmp_path / "payments" / "pay.pem").write_text("certificate")
+        metrics = MetricsCollector()
+        scanner = CertificateScanner(config=config, cache=CacheManager(config), metrics=metrics)
+        parsed = {
+            "common_name": "pay.example.com",
+            "path": str(tmp_path / "payments" / "pay.pem"),
+            "expiration_timestamp": 0,
+        }
+
+        with patch.object(scanner, "_process_certificate_file", AsyncMock(return_value=parsed)):
+            results = await scanner.scan_once()
+        await scanner.stop()
+        groups = TestClient(
+            create_app(scanner=scanner, metrics=metrics, cache=scanner.cache, config=config)
+        ).get("/api/v1/groups")
+
+        result = results["directories"][str(tmp_path / "payments")]
+        assert result["labels"]["team"] == "payments"
+        assert result["certificates"][0]["labels"]["group"] == "payments"
+        assert 'label_team="payments"' in metrics.get_metrics()
+        [group] = groups.json()["groups"]
+        assert group["name"] == "payments"
+        assert group["labels"] == GROUPS["payments"]["labels"]
+        assert group["directories"] == [str(tmp_path / "payments")]
+        assert group["certificates_by_severity"]["expired"] == 1
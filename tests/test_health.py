@@ -0,0 +1,476 @@
+"""
+Tests for health evaluation.
+"""
+
+import asyncio
+import io
+import logging
+import re
+import shutil
+import time
+from collections import namedtuple
+
+import pytest
+from fastapi.testclient import TestClient
+from pydantic import ValidationError
+
+from tls_cert_monitor import health
+from tls_cert_monitor.api import create_app
+from tls_cert_monitor.cache import CacheManager
+from tls_cert_monitor.config import Config, HealthThresholds
+from tls_cert_monitor.health import (
+    DEGRADED,
+    HEALTHY,
+    UNHEALTHY,
+    HealthChecker,
+    HealthCheckFailure,
+    check_statuses,
+    disk_usage,
+    disk_usage_key,
+    evaluate_health,
+    run_blocking,
+    run_checks,
+    worst_status,
+)
+from tls_cert_monitor.logger import setup_logging
+from tls_cert_monitor.metrics import MetricsCollector
+from tls_cert_monitor.scanner import CertificateScanner
+
+
+def _health(**overrides):
+    data = {
+        "cache_enabled": True,
+        "cache_hit_rate": 0.9,
+        "scans_completed": 5,
+        "parse_error_ratio": 0.0,
+        "diskspace__certs": {"directory": "/certs", "percent_used": 40.0},
+    }
+    data.update(overrides)
+    return data
+
+
+class TestHealth:
+    """Test /healthz status evaluation against thresholds."""
+
+    def test_worst_status(self):
+        """Test statuses are ordered healthy < degraded < unhealthy."""
+        assert worst_status([]) == HEALTHY
+        assert worst_status([DEGRADED, HEALTHY]) == DEGRADED
+        assert worst_status([DEGRADED, UNHEALTHY, HEALTHY]) == UNHEALTHY
+
+    def test_healthy(self):
+        """Test healthy data has no reasons."""
+        assert evaluate_health(_health(), HealthThresholds()) == (HEALTHY, [])
+
+    @pytest.mark.parametrize(
+        "percent_used, expected", [(79.9, HEALTHY), (80.0, DEGRADED), (95.0, UNHEALTHY)]
+    )
+    def test_disk_usage_thresholds(self, percent_used, expected):
+        """Test disk usage degrades at 80% and is unhealthy at 90% by default."""
+        data = _health(diskspace__certs={"directory": "/certs", "percent_used": percent_used})
+
+        status, reasons = evaluate_health(data, HealthThresholds())
+
+        assert status == expected
+        assert all("/certs" in reason for reason in reasons)
+
+    def test_cache_hit_rate(self):
+        """Test a low hit rate degrades, but not before the second scan."""
+        thresholds = HealthThresholds()
+
+        assert evaluate_health(_health(cache_hit_rate=0.2), thresholds)[0] == DEGRADED
+        assert evaluate_health(_health(cache_hit_rate=0.0, scans_completed=1), thresholds) == (
+            HEALTHY,
+            [],
+        )
+        assert evaluate_health(_health(cache_hit_rate=0.0, cache_enabled=False), thresholds) == (
+            HEALTHY,
+            [],
+        )
+
+    def test_cache_persistence_failures(self):
+        """Test repeated save failures and a failed load degrade with the last error."""
+        thresholds = HealthThresholds()
+
+        assert evaluate_health(_health(cache_save_failures=1), thresholds)[0] == HEALTHY
+        status, reasons = evaluate_health(
+            _health(cache_save_failures=2, cache_last_save_error="disk full"), thresholds
+        )
+        assert status == DEGRADED
+        assert reasons == ["2 cache saves failed in a row: disk full"]
+
+        status, reasons = evaluate_health(_health(cache_last_load_error="bad checksum"), thresholds)
+        assert status == DEGRADED
+        assert reasons == ["Persistent cache failed to load: bad checksum"]
+
+    def test_parse_error_ratio(self):
+        """Test a high parse error ratio degrades."""
+        status, reasons = evaluate_health(_health(parse_error_ratio=0.25), HealthThresholds())
+
+        assert status == DEGRADED
+        assert reasons == ["25% of the files of the last scan failed to parse (> 10%)"]
+        assert evaluate_health(_health(parse_error_ratio=None), HealthThresholds())[0] == HEALTHY
+
+    def test_disk_usage(self, tmp_path):
+        """Test disk usage of a directory's filesystem."""
+        usage = disk_usage(str(tmp_path))
+
+        expected = shutil.disk_usage(tmp_path)
+        assert usage["directory"] == str(tmp_path)
+        assert usage["total_bytes"] == expected.total
+        assert 0 <= usage["percent_used"] <= 100
+
+        with pytest.raises(OSError):
+            disk_usage(str(tmp_path / "missing"))
+
+    def test_disk_usage_without_size(self, monkeypatch):
+        """Test filesystems reporting no size do not fail the health check."""
+        usage_type = namedtuple("usage", "total used free")
+        monkeypatch.setattr(health.shutil, "disk_usage", lambda path: usage_type(0, 0, 0))
+
+        assert disk_usage("/proc")["percent_used"] == 0.0
+
+    def test_disk_usage_key(self):
+        """Test POSIX and Windows paths map to plain keys."""
+        assert disk_usage_key("/etc/ssl/certs") == "diskspace__etc_ssl_certs"
+        assert disk_usage_key("C:\\ProgramData\\certs") == "diskspace_C__ProgramData_certs"
+        assert disk_usage_key("\\\\server\\share") == "diskspace___server_share"
+
+    def test_expiring_certificates(self):
+        """Test expired certificates are unhealthy and expiring ones degraded by default."""
+        data = _health(
+            expiry_thresholds={"warning": "30d", "critical": "7d"},
+            expiring_certificates={"expired": 0, "critical": 2, "warning": 1},
+        )
+
+        status, reasons = evaluate_health(data, HealthThresholds())
+
+        assert status == DEGRADED
+        assert reasons == [
+            "2 certificate(s) expire within 7d (critical)",
+            "1 certificate(s) expire within 30d (warning)",
+        ]
+
+        data["expiring_certificates"]["expired"] = 1
+        assert evaluate_health(data, HealthThresholds())[0] == UNHEALTHY
+
+        ignored = HealthThresholds(
+            certificates_warning="healthy",
+            certificates_critical="healthy",
+            certificates_expired="degraded",
+        )
+        assert evaluate_health(data, ignored) == (DEGRADED, ["1 certificate(s) expired"])
+
+        with pytest.raises(ValidationError):
+            HealthThresholds(certificates_expired="critical")
+
+    def test_stalled_scan(self):
+        """Test a scan running far longer than recent scans degrades."""
+        scan = {
+            "started_at": 0,
+            "running_seconds": 1200.0,
+            "files_processed": 10,
+            "directory": "/mnt/nfs",
+            "oldest_file": "/mnt/nfs/site.pem",
+        }
+        thresholds = HealthThresholds(scan_stall_min="1m")
+
+        status, _ = evaluate_health(
+            _health(current_scan=scan, average_scan_duration=300), thresholds
+        )
+        assert status == HEALTHY
+        status, reasons = evaluate_health(
+            _health(current_scan=scan, average_scan_duration=30), thresholds
+        )
+        assert status == DEGRADED
+        assert reasons == ["Scan running for 1200s (> 150s), stalled on /mnt/nfs/site.pem"]
+
+        # Without history, scans are stalled after scan_stall_min (default 15m)
+        assert evaluate_health(_health(current_scan=scan), HealthThresholds())[0] == DEGRADED
+
+    def test_check_statuses(self):
+        """Test each check is judged on its own problems."""
+        data = _health(
+            cache_hit_rate=0.2,
+            diskspace__certs={"directory": "/certs", "percent_used": 95.0},
+            health_check_errors={"metrics": {"status": DEGRADED, "error": "timed out"}},
+        )
+
+        statuses = check_statuses(
+            ["scanner", "cache", "metrics", "system", "disk /certs"], data, HealthThresholds()
+        )
+
+        assert statuses == {
+            "scanner": HEALTHY,
+            "cache": DEGRADED,
+            "metrics": DEGRADED,
+            "system": HEALTHY,
+            "disk /certs": UNHEALTHY,
+        }
+
+    def test_configured_thresholds(self):
+        """Test thresholds are read from the configuration."""
+        config = Config(
+            health_thresholds={
+                "disk_usage_degraded": 30,
+                "disk_usage_unhealthy": 50,
+                "cache_hit_rate_degraded": 0.95,
+                "parse_error_ratio_degraded": 0.5,
+            }
+        )
+
+        status, reasons = evaluate_health(_health(parse_error_ratio=0.25), config.health_thresholds)
+
+        assert status == DEGRADED
+        assert len(reasons) == 2  # disk usage and hit rate
+
+        with pytest.raises(ValidationError):
+            HealthThresholds(disk_usage_degraded=95, disk_usage_unhealthy=90)
+
+    @pytest.mark.asyncio
+    async def test_run_checks_concurrently_with_timeouts(self):
+        """Test a hung or failing check does not hold up or fail the others."""
+
+        async def quick():
+            return {"quick": True}
+
+        async def hung():
+            await asyncio.sleep(10)
+            return {"hung": True}
+
+        async def failing():
+            raise OSError("Stale file handle")
+
+        start = time.monotonic()
+        data = await run_checks(
+            {
+                "quick": quick,
+                "hung": hung,
+                "failing": failing,
+                # Blocking calls run off the event loop, so they time out too
+                "blocking": lambda: run_blocking(time.sleep, 1),
+            },
+            timeout=0.2,
+        )
+
+        assert time.monotonic() - start < 1
+        assert data["quick"] is True
+        assert "hung" not in data
+        assert data["health_check_errors"] == {
+            "hung": {"status": DEGRADED, "error": "timed out after 0.2s"},
+            "blocking": {"status": DEGRADED, "error": "timed out after 0.2s"},
+            "failing": {"status": DEGRADED, "error": "Stale file handle"},
+        }
+        status, reasons = evaluate_health(data, HealthThresholds())
+        assert status == DEGRADED
+        assert "Health check hung failed: timed out after 0.2s" in reasons
+
+    def test_register_checks(self):
+        """Test names of registered checks are unique and not built-in."""
+        checker = HealthChecker()
+
+        async def check():
+            return {}
+
+        checker.register("alerts", check)
+        for name in ("alerts", "cache", "disk /etc/ssl"):
+            with pytest.raises(ValueError):
+                checker.register(name, check)
+        assert list(checker.checks) == ["alerts"]
+
+        checker.unregister("alerts")
+        checker.unregister("alerts")
+        assert checker.checks == {}
+
+        with pytest.raises(ValueError):
+            HealthCheckFailure("down", status=HEALTHY)
+
+    @pytest.mark.asyncio
+    async def test_readyz(self, tmp_path):
+        """Test /readyz fails until the first scan completed."""
+        config = Config(
+            certificate_directories=[str(tmp_path)], cache_dir="", enable_ip_whitelist=False
+        )
+        cache = CacheManager(config)
+        await cache.initialize()
+        metrics = MetricsCollector()
+        scanner = CertificateScanner(config=config, cache=cache, metrics=metrics)
+        client = TestClient(
+            create_app(scanner=scanner, metrics=metrics, cache=cache, config=config)
+        )
+
+        response = client.get("/readyz")
+        assert response.status_code == 503
+        assert response.json()["ready"] is False
+
+        await scanner.scan_once()
+        response = client.get("/readyz")
+        assert response.status_code == 200
+        assert response.json()["ready"] is True
+        await scanner.stop()
+        await cache.close()
+
+    @pytest.mark.asyncio
+    async def test_loglevel(self, tmp_path):
+        """Test the log level can be read and changed at runtime."""
+        config = Config(
+            certificate_directories=[str(tmp_path)], cache_dir="", enable_ip_whitelist=False
+        )
+        cache = CacheManager(config)
+        await cache.initialize()
+        metrics = MetricsCollector()
+        scanner = CertificateScanner(config=config, cache=cache, metrics=metrics)
+        client = TestClient(
+            create_app(scanner=scanner, metrics=metrics, cache=cache, config=config)
+        )
+        root_level = logging.getLogger().level
+        logging.getLogger().setLevel(logging.INFO)
+
+        try:
+            assert client.get("/loglevel").json()["level"] == "INFO"
+
+            response = client.put("/loglevel", json={"level": "debug"})
+            assert response.status_code == 200
+            assert response.json()["previous_level"] == "INFO"
+            assert logging.getLogger().level == logging.DEBUG
+            assert client.get("/loglevel").json() == {
+                "level": "DEBUG",
+                "configured_level": "INFO",
+            }
+
+            assert client.put("/loglevel", json={"level": "verbose"}).status_code == 400
+            assert logging.getLogger().level == logging.DEBUG
+        finally:
+            logging.getLogger().setLevel(root_level)
+            await cache.close()
+
+    @pytest.mark.asyncio
+    async def test_request_ids(self, tmp_path, caplog):
+        """Test requests get an ID, in responses, error responses and their log lines."""
+        config = Config(
+            certificate_directories=[str(tmp_path)], cache_dir="", enable_ip_whitelist=False
+        )
+        cache = CacheManager(config)
+        await cache.initialize()
+        metrics = MetricsCollector()
+        scanner = CertificateScanner(config=config, cache=cache, metrics=metrics)
+        client = TestClient(
+            create_app(scanner=scanner, metrics=metrics, cache=cache, config=config)
+        )
+        stream = io.StringIO()
+        root_handlers = list(logging.getLogger().handlers)
+        root_level = logging.getLogger().level
+
+        try:
+            setup_logging(Config(log_level="DEBUG", log_journald=False), stream)
+            caplog.set_level(logging.DEBUG)
+
+            response = client.get("/loglevel", headers={"X-Request-ID": "lb-4711"})
+            assert response.headers["x-request-id"] == "lb-4711"
+            assert "[lb-4711] GET /loglevel 200" in stream.getvalue()
+
+            # Generated when missing or unsafe to log
+            generated = client.get("/loglevel").headers["x-request-id"]
+            assert re.fullmatch(r"[0-9a-f]{32}", generated)
+            response = client.get("/loglevel", headers={"X-Request-ID": "a\nFAKE log line"})
+            assert response.headers["x-request-id"] not in ("a\nFAKE log line", generated)
+
+            response = client.delete("/silences/unknown", headers={"X-Request-ID": "lb-4712"})
+            assert response.status_code == 404
+            assert response.json()["request_id"] == "lb-4712"
+            assert response.headers["x-request-id"] == "lb-4712"
+        finally:
+            logging.getLogger().handlers[:] = root_handlers
+            logging.getLogger().setLevel(root_level)
+            await cache.close()
+
+    @pytest.mark.asyncio
+    async def test_registered_checks_in_healthz(self, tmp_path):
+        """Test /healthz reports registered checks under their name and their failures."""
+        config = Config(
+            certificate_directories=[str(tmp_path)], cache_dir="", enable_ip_whitelist=False
+        )
+        cache = CacheManager(config)
+        await cache.initialize()
+        metrics = MetricsCollector()
+        scanner = CertificateScanner(config=config, cache=cache, metrics=metrics)
+        checker = HealthChecker()
+
+        async def network_scanner():
+            return {"endpoints": 3}
+
+        async def cloud_source():
+            raise HealthCheckFailure(
+                "credentials expired", status=UNHEALTHY, data={"accounts": 2}
+            )
+
+        checker.register("network_scanner", network_scanner)
+        checker.register("cloud_source", cloud_source)
+        app = create_app(
+            scanner=scanner, metrics=metrics, cache=cache, config=config, health_checker=checker
+        )
+
+        response = TestClient(app).get("/healthz?verbose=true")
+
+        assert response.status_code == 503
+        data = response.json()
+        assert data["status"] == UNHEALTHY
+        assert data["network_scanner"] == {"endpoints": 3}
+        assert data["cloud_source"] == {"accounts": 2}
+        assert data["health_check_errors"] == {
+            "cloud_source": {"status": UNHEALTHY, "error": "credentials expired"}
+        }
+        assert "Health check cloud_source failed: credentials expired" in data["health_reasons"]
+
+        terse = TestClient(app).get("/healthz")
+        assert terse.status_code == 503
+        assert terse.json() == {
+            "status": UNHEALTHY,
+            "health_reasons": data["health_reasons"],
+            "failing_checks": {"cloud_source": UNHEALTHY},
+            "version": data["version"],
+        }
+
+        metrics_output = TestClient(app).get("/metrics").text
+        assert 'ssl_cert_monitor_health_check{name="cloud_source"} 2' in metrics_output
+        assert 'ssl_cert_monitor_health_check{name="network_scanner"} 0' in metrics_output
+        await cache.close()
+
+    @pytest.mark.asyncio
+    async def test_health_metrics_after_scan(self, tmp_path):
+        """Test health check metrics are evaluated after scans, not on /metrics scrapes."""
+        config = Config(
+            certificate_directories=[str(tmp_path)], cache_dir="", enable_ip_whitelist=False
+        )
+        cache = CacheManager(config)
+        await cache.initialize()
+        metrics = MetricsCollector()
+        scanner = CertificateScanner(config=config, cache=cache, metrics=metrics)
+        checker = HealthChecker()
+        calls = []
+
+        async def probe():
+            calls.append(time.monotonic())
+            return {}
+
+        checker.register("probe", probe)
+        client = TestClient(
+            create_app(
+                scanner=scanner, metrics=metrics, cache=cache, config=config, health_checker=checker
+            )
+        )
+
+        assert "ssl_cert_monitor_health_check{" not in client.get("/metrics").text
+        assert calls == []
+
+        await scanner.scan_once()
+        for _ in range(100):
+            if 'ssl_cert_monitor_health_check{name="probe"} 0' in metrics.get_metrics():
+                break
+            await asyncio.sleep(0.05)
+
+        metrics_output = client.get("/metrics").text
+        assert 'ssl_cert_monitor_health_check{name="probe"} 0' in metrics_output
+        assert len(calls) == 1
+        await scanner.stop()
+        await cache.close()
@@ -0,0 +1,152 @@
+"""
+Tests for the certificate history.
+"""
+
+from unittest.mock import MagicMock
+
+import pytest
+from fastapi.testclient import TestClient
+from pydantic import ValidationError
+
+from tls_cert_monitor.api import create_app
+from tls_cert_monitor.cache import CacheManager
+from tls_cert_monitor.config import Config, HistoryConfig
+from tls_cert_monitor.history import CertificateHistory
+from tls_cert_monitor.metrics import MetricsCollector
+from tls_cert_monitor.scanner import CertificateScanner
+
+DAY = 86400
+
+
+def _cert(fingerprint, path, not_after="2027-01-01T00:00:00+00:00"):
+    """Certificate data as found by a scan."""
+    return {
+        "fingerprint_sha256": fingerprint,
+        "path": path,
+        "common_name": "api.example.com",
+        "issuer": "Example CA",
+        "serial": fingerprint.upper(),
+        "not_before": "2026-01-01T00:00:00+00:00",
+        "not_after": not_after,
+    }
+
+
+def _scan(*certs):
+    """Scan results of certificates in /etc/ssl."""
+    return {"directories": {"/etc/ssl": {"certificates": list(certs)}}}
+
+
+def _history(tmp_path, **settings):
+    return CertificateHistory(
+        Config(cache_dir=str(tmp_path), history=HistoryConfig(enabled=True, **settings))
+    )
+
+
+class TestHistory:
+    """Test recording and querying certificate history."""
+
+    def test_config(self):
+        """Test an enabled history needs a database path or cache directory."""
+        with pytest.raises(ValidationError):
+            Config(cache_dir="", history=HistoryConfig(enabled=True))
+        Config(cache_dir="", history=HistoryConfig(enabled=True, path="/tmp/history.db"))
+        with pytest.raises(ValidationError):
+            HistoryConfig(retention="forever")
+
+    def test_renewal(self, tmp_path):
+        """Test a renewal shows as the next certificate first seen in the file."""
+        history = _history(tmp_path)
+        history.record(_scan(_cert("aa", "/etc/ssl/api.pem")), now=1000 * DAY)
+        history.record(_scan(_cert("aa", "/etc/ssl/api.pem")), now=1001 * DAY)
+        history.record(
+            _scan(_cert("bb", "/etc/ssl/api.pem", "2028-01-01T00:00:00+00:00")), now=1002 * DAY
+        )
+
+        renewed, old = history.query(path="/etc/ssl/api.pem")
+        history.close()
+
+        assert renewed["fingerprint"] == "bb"
+        assert renewed["present"] is True
+        assert renewed["not_after"] == "2028-01-01T00:00:00+00:00"
+        assert old["fingerprint"] == "aa"
+        assert old["present"] is False
+        assert old["first_seen"].startswith("1972-09-27")
+        assert old["last_seen"].startswith("1972-09-28")
+        assert old["locations"][0]["path"] == "/etc/ssl/api.pem"
+        assert old["locations"][0]["directory"] == "/etc/ssl"
+
+    def test_locations(self, tmp_path):
+        """Test a certificate copied to another file keeps one entry with both files."""
+        history = _history(tmp_path)
+        history.record(_scan(_cert("aa", "/etc/ssl/api.pem")), now=DAY)
+        history.record(
+            _scan(_cert("aa", "/etc/ssl/api.pem"), _cert("aa", "/etc/ssl/copy.pem")), now=2 * DAY
+        )
+
+        [cert] = history.query(fingerprint="aa")
+
+        assert [location["path"] for location in cert["locations"]] == [
+            "/etc/ssl/api.pem",
+            "/etc/ssl/copy.pem",
+        ]
+        assert history.query(common_name="*.example.com")[0]["fingerprint"] == "aa"
+        assert history.query(common_name="*.example.org") == []
+        history.close()
+
+    def test_retention(self, tmp_path):
+        """Test certificates not seen within the retention are dropped."""
+        history = _history(tmp_path, retention="30d")
+        history.record(_scan(_cert("aa", "/etc/ssl/api.pem")), now=DAY)
+        history.record(_scan(_cert("bb", "/etc/ssl/api.pem")), now=40 * DAY)
+
+        assert [cert["fingerprint"] for cert in history.query()] == ["bb"]
+        history.close()
+
+    @pytest.mark.asyncio
+    async def test_after_scan(self, tmp_path):
+        """Test scans are recorded by the listener, also across restarts."""
+        config = Config(cache_dir=str(tmp_path), history=HistoryConfig(enabled=True))
+        scanner = MagicMock()
+        history = CertificateHistory(config, scanner)
+        await scanner.add_scan_listener.call_args[0][0](_scan(_cert("aa", "/etc/ssl/a.pem")))
+        history.close()
+
+        reopened = CertificateHistory(config)
+        assert reopened.query()[0]["fingerprint"] == "aa"
+        reopened.close()
+        assert (tmp_path / "history.db").exists()
+
+    @pytest.mark.asyncio
+    async def test_api(self, tmp_path):
+        """Test the history endpoints, and the conflict when history is disabled."""
+        config = Config(
+            certificate_directories=[str(tmp_path)],
+            cache_dir=str(tmp_path),
+            enable_ip_whitelist=False,
+            history=HistoryConfig(enabled=True),
+        )
+        cache = CacheManager(config)
+        await cache.initialize()
+        metrics = MetricsCollector()
+        scanner = CertificateScanner(config=config, cache=cache, metrics=metrics)
+        history = CertificateHistory(config)
+        history.record(_scan(_cert("aa", "/etc/ssl/api.pem")))
+
+        client = TestClient(
+            create_app(
+                scanner=scanner, metrics=metrics, cache=cache, config=config, history=history
+            )
+        )
+        listed = client.get("/api/v1/history/certificates", params={"path": "/etc/ssl/*"})
+        entry = client.get("/api/v1/history/certificates/aa")
+        missing = client.get("/api/v1/history/certificates/zz")
+        disabled = TestClient(
+            create_app(scanner=scanner, metrics=metrics, cache=cache, config=config)
+        ).get("/api/v1/history/certificates")
+        history.close()
+        await cache.close()
+
+        assert listed.json()["certificates"][0]["fingerprint"] == "aa"
+        assert entry.json()["locations"][0]["present"] is True
+        assert missing.status_code == 404
+        assert disabled.status_code == 409
@@ -2,9 +2,12 @@
 Tests for hot reload functionality.
 """
 
+import asyncio
+import errno
+import logging
 import tempfile
 from pathlib import Path
-from unittest.mock import AsyncMock, MagicMock
+from unittest.mock import AsyncMock, MagicMock, patch
 
 import pytest
 import pytest_asyncio
@@ -12,6 +15,7 @@ from watchdog.events import FileSystemEvent
 
 from tls_cert_monitor.cache import CacheManager
 from tls_cert_monitor.config import Config
+from tls_cert_monitor.health import HealthCheckFailure
 from tls_cert_monitor.hot_reload import CertificateFileHandler, ConfigFileHandler, HotReloadManager
 from tls_cert_monitor.metrics import MetricsCollector
 from tls_cert_monitor.scanner import CertificateScanner
@@ -72,6 +76,7 @@ class TestCertificateFileHandler:
     def test_handler_ignores_directories(self):
         """Test that directory events are ignored."""
         manager = MagicMock()
+        manager.config = Config()
         handler = CertificateFileHandler(manager)
 
         event = MagicMock(spec=FileSystemEvent)
@@ -85,6 +90,7 @@ class TestCertificateFileHandler:
     def test_handler_ignores_non_meaningful_events(self):
         """Test that non-meaningful events are ignored."""
         manager = MagicMock()
+        manager.config = Config()
         handler = CertificateFileHandler(manager)
 
         event = MagicMock(spec=FileSystemEvent)
@@ -99,6 +105,7 @@ class TestCertificateFileHandler:
     def test_handler_ignores_non_certificate_files(self):
         """Test that non-certificate files are ignored."""
         manager = MagicMock()
+        manager.config = Config()
         handler = CertificateFileHandler(manager)
 
         event = MagicMock(spec=FileSystemEvent)
@@ -117,6 +124,7 @@ class TestCertificateFileHandler:
     def test_handler_processes_certificate_files(self, extension):
         """Test that certificate files with supported extensions are processed."""
         manager = MagicMock()
+        manager.config = Config()
         manager._event_loop = MagicMock()
         handler = CertificateFileHandler(manager)
 
@@ -136,6 +144,7 @@ class TestCertificateFileHandler:
     def test_handler_processes_meaningful_events(self, event_type):
         """Test that all meaningful events are processed."""
         manager = MagicMock()
+        manager.config = Config()
         manager._event_loop = MagicMock()
         handler = CertificateFileHandler(manager)
 
@@ -151,6 +160,7 @@ class TestCertificateFileHandler:
     def test_handler_maps_closed_to_created(self):
         """Test that 'closed' events are mapped to 'created' events."""
         manager = MagicMock()
+        manager.config = Config()
         manager._event_loop = MagicMock()
         handler = CertificateFileHandler(manager)
 
@@ -165,6 +175,49 @@ class TestCertificateFileHandler:
         manager._schedule_coro.assert_called_once()
         # Note: The coroutine is called with "created" event type internally
 
+    @pytest.mark.parametrize(
+        "src_path, dest_path, expected",
+        [
+            # A temporary file renamed over the certificate (certbot, ansible)
+            ("/test/.cert.pem.tmp", "/test/cert.pem", ("/test/cert.pem", "created")),
+            # A certificate renamed away
+            ("/test/cert.pem", "/test/cert.pem.old", ("/test/cert.pem", "deleted")),
+        ],
+    )
+    def test_handler_processes_renames(self, src_path, dest_path, expected):
+        """Test that renames are handled as the deletion and the creation of their paths."""
+        manager = MagicMock()
+        manager.config = Config()
+        manager._event_loop = MagicMock()
+        handler = CertificateFileHandler(manager)
+
+        event = MagicMock(spec=FileSystemEvent)
+        event.is_directory = False
+        event.event_type = "moved"
+        event.src_path = src_path
+        event.dest_path = dest_path
+
+        handler.on_any_event(event)
+
+        manager._handle_certificate_change.assert_called_once_with(*expected)
+        manager._schedule_coro.assert_called_once()
+
+    def test_handler_ignores_excluded_files(self):
+        """Test that files matching exclude_files globs are ignored."""
+        manager = MagicMock()
+        manager.config = Config(exclude_files=["*-backup.pem"])
+        manager._event_loop = MagicMock()
+        handler = CertificateFileHandler(manager)
+
+        event = MagicMock(spec=FileSystemEvent)
+        event.is_directory = False
+        event.event_type = "created"
+        event.src_path = "/test/site-BACKUP.pem"
+
+        handler.on_any_event(event)
+
+        manager._schedule_coro.assert_not_called()
+
 
 class TestConfigFileHandler:
     """Tests for ConfigFileHandler."""
@@ -213,6 +266,44 @@ class TestConfigFileHandler:
         manager._schedule_coro.assert_called_once()
 
 
+    @pytest.mark.parametrize("event_type", ["modified", "created", "deleted"])
+    def test_handler_processes_config_directory_files(self, event_type):
+        """Test that changes to *.yaml files in a config directory are processed."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            config_file = Path(temp_dir) / "10-team.yaml"
+            config_file.write_text("port: 3200\n")
+
+            manager = MagicMock()
+            manager.config_path = Path(temp_dir)
+            handler = ConfigFileHandler(manager)
+
+            event = MagicMock(spec=FileSystemEvent)
+            event.is_directory = False
+            event.src_path = str(config_file)
+
+            getattr(handler, f"on_{event_type}")(event)
+
+            manager._schedule_coro.assert_called_once()
+
+    def test_handler_ignores_other_files_in_config_directory(self):
+        """Test that non-YAML files in a config directory are ignored."""
+        with tempfile.TemporaryDirectory() as temp_dir:
+            other_file = Path(temp_dir) / "notes.txt"
+            other_file.write_text("not configuration")
+
+            manager = MagicMock()
+            manager.config_path = Path(temp_dir)
+            handler = ConfigFileHandler(manager)
+
+            event = MagicMock(spec=FileSystemEvent)
+            event.is_directory = False
+            event.src_path = str(other_file)
+
+            handler.on_modified(event)
+            handler.on_created(event)
+
+            manager._schedule_coro.assert_not_called()
+
 class TestHotReloadManager:
     """Tests for HotReloadManager."""
 
@@ -231,6 +322,31 @@ class TestHotReloadManager:
         assert hot_reload_manager._watching is True
         assert len(hot_reload_manager._watched_paths) > 0
 
+    @pytest.mark.asyncio
+    async def test_watch_files_disabled(self, hot_reload_manager, temp_cert_dir):
+        """Test that watch_files: false skips certificate directories and can be toggled."""
+        hot_reload_manager.config.watch_files = False
+        await hot_reload_manager.start()
+
+        cert_path = str(Path(temp_cert_dir).resolve())
+        assert hot_reload_manager._watching is True
+        assert cert_path not in hot_reload_manager._watched_paths
+        assert hot_reload_manager.get_status()["watch_files"] is False
+
+        Path(hot_reload_manager.config_path).write_text(
+            f"certificate_directories:\n  - {temp_cert_dir}\nscan_interval: 5m\nworkers: 2\n"
+        )
+        hot_reload_manager.scanner.scan_once = AsyncMock()
+        await hot_reload_manager.reload_config()
+        assert cert_path in hot_reload_manager._watched_paths
+
+        Path(hot_reload_manager.config_path).write_text(
+            f"certificate_directories:\n  - {temp_cert_dir}\nscan_interval: 5m\nworkers: 2\n"
+            "watch_files: false\n"
+        )
+        await hot_reload_manager.reload_config()
+        assert cert_path not in hot_reload_manager._watched_paths
+
     @pytest.mark.asyncio
     async def test_stop_disables_watching(self, hot_reload_manager):
         """Test that stopping hot reload disables watching."""
@@ -241,8 +357,75 @@ class TestHotReloadManager:
         assert hot_reload_manager._watching is False
 
     @pytest.mark.asyncio
-    async def test_certificate_created_clears_cache_and_metrics(self, hot_reload_manager):
-        """Test that creating a certificate clears cache and metrics."""
+    async def test_restart_watching(self, hot_reload_manager):
+        """Test that watching can be restarted (as on removed directories)."""
+        await hot_reload_manager.start()
+        await hot_reload_manager.stop()
+        await hot_reload_manager.start()
+
+        assert hot_reload_manager._observer.is_alive()
+        await hot_reload_manager.get_health_status()
+
+    @pytest.mark.asyncio
+    async def test_watcher_liveness(self, hot_reload_manager, temp_cert_dir):
+        """Test that a dead watcher or an unwatched directory fails the health check."""
+        with pytest.raises(HealthCheckFailure, match="not running"):
+            await hot_reload_manager.get_health_status()
+
+        await hot_reload_manager.start()
+        status = await hot_reload_manager.get_health_status()
+        assert status["watching"] is True
+
+        new_dir = Path(temp_cert_dir) / "new"
+        new_dir.mkdir()
+        hot_reload_manager.config.certificate_directories.append(str(new_dir))
+        with pytest.raises(HealthCheckFailure, match="not watched: .*new") as failure:
+            await hot_reload_manager.get_health_status()
+        assert failure.value.data["watching"] is True
+
+        hot_reload_manager._observer.stop()
+        hot_reload_manager._observer.join(timeout=5.0)
+        with pytest.raises(HealthCheckFailure, match="thread died"):
+            await hot_reload_manager.get_health_status()
+
+    @pytest.mark.asyncio
+    @pytest.mark.parametrize("poll_interval", ["30s", "0s"])
+    async def test_watch_limit_fallback(self, hot_reload_manager, temp_cert_dir, poll_interval):
+        """Test that directories over the watch limit are polled and reported."""
+        hot_reload_manager.config.watch_poll_interval = poll_interval
+        schedule = hot_reload_manager._observer.schedule
+
+        def schedule_over_limit(handler, path, recursive=False):
+            if path == temp_cert_dir:
+                raise OSError(errno.ENOSPC, "inotify watch limit reached")
+            return schedule(handler, path, recursive=recursive)
+
+        with patch.object(
+            hot_reload_manager._observer, "schedule", side_effect=schedule_over_limit
+        ), patch("tls_cert_monitor.hot_reload.PollingObserver") as polling_observer:
+            await hot_reload_manager.start()
+            status = hot_reload_manager.get_status()
+            with pytest.raises(HealthCheckFailure, match="watch limit reached .*for: "):
+                await hot_reload_manager.get_health_status()
+            await hot_reload_manager.stop()
+
+        metrics = hot_reload_manager.scanner.metrics.get_metrics()
+        assert status["watching"] is True
+        assert status["watch_limit_reached"] == [temp_cert_dir]
+        if poll_interval == "30s":
+            polling_observer.assert_called_once_with(timeout=30)
+            polling_observer.return_value.schedule.assert_called_once()
+            polling_observer.return_value.stop.assert_called_once()
+            assert status["polled_paths"] == [temp_cert_dir]
+            assert 'ssl_cert_watched_directories{mode="polling"} 1' in metrics
+        else:
+            polling_observer.assert_not_called()
+            assert status["polled_paths"] == []
+            assert 'ssl_cert_watched_directories{mode="polling"} 0' in metrics
+
+    @pytest.mark.asyncio
+    async def test_certificate_created_clears_metrics_not_cache(self, hot_reload_manager):
+        """Test that creating a certificate clears metrics but keeps the cache."""
         await hot_reload_manager.start()
 
         # Mock the scanner methods
@@ -253,10 +436,10 @@ class TestHotReloadManager:
 
         # Simulate certificate creation
         test_file = str(Path(hot_reload_manager.config.certificate_directories[0]) / "test.pem")
-        await hot_reload_manager._debounced_cert_change(test_file, "created")
+        await hot_reload_manager._process_certificate_changes({test_file: "created"})
 
-        # Verify cache was cleared
-        hot_reload_manager.scanner.cache.clear.assert_called_once()
+        # Verify cache was kept: changed files get new cache keys
+        hot_reload_manager.scanner.cache.clear.assert_not_called()
 
         # Verify metrics were cleared
         hot_reload_manager.scanner.metrics.clear_all_certificate_metrics.assert_called_once()
@@ -266,8 +449,8 @@ class TestHotReloadManager:
         hot_reload_manager.scanner.scan_once.assert_called_once()
 
     @pytest.mark.asyncio
-    async def test_certificate_deleted_clears_cache_and_metrics(self, hot_reload_manager):
-        """Test that deleting a certificate clears cache and metrics."""
+    async def test_certificate_deleted_clears_metrics_not_cache(self, hot_reload_manager):
+        """Test that deleting a certificate clears metrics but keeps the cache."""
         await hot_reload_manager.start()
 
         # Mock the scanner methods
@@ -278,10 +461,10 @@ class TestHotReloadManager:
 
         # Simulate certificate deletion
         test_file = str(Path(hot_reload_manager.config.certificate_directories[0]) / "test.pem")
-        await hot_reload_manager._debounced_cert_change(test_file, "deleted")
+        await hot_reload_manager._process_certificate_changes({test_file: "deleted"})
 
-        # Verify cache was cleared
-        hot_reload_manager.scanner.cache.clear.assert_called_once()
+        # Verify cache was kept: changed files get new cache keys
+        hot_reload_manager.scanner.cache.clear.assert_not_called()
 
         # Verify metrics were cleared
         hot_reload_manager.scanner.metrics.clear_all_certificate_metrics.assert_called_once()
@@ -291,8 +474,8 @@ class TestHotReloadManager:
         hot_reload_manager.scanner.scan_once.assert_called_once()
 
     @pytest.mark.asyncio
-    async def test_certificate_moved_clears_cache_and_metrics(self, hot_reload_manager):
-        """Test that moving a certificate clears cache and metrics."""
+    async def test_certificate_moved_clears_metrics_not_cache(self, hot_reload_manager):
+        """Test that moving a certificate clears metrics but keeps the cache."""
         await hot_reload_manager.start()
 
         # Mock the scanner methods
@@ -303,10 +486,10 @@ class TestHotReloadManager:
 
         # Simulate certificate move
         test_file = str(Path(hot_reload_manager.config.certificate_directories[0]) / "test.pem")
-        await hot_reload_manager._debounced_cert_change(test_file, "moved")
+        await hot_reload_manager._process_certificate_changes({test_file: "moved"})
 
-        # Verify cache was cleared
-        hot_reload_manager.scanner.cache.clear.assert_called_once()
+        # Verify cache was kept: changed files get new cache keys
+        hot_reload_manager.scanner.cache.clear.assert_not_called()
 
         # Verify metrics were cleared
         hot_reload_manager.scanner.metrics.clear_all_certificate_metrics.assert_called_once()
@@ -319,7 +502,7 @@ class TestHotReloadManager:
     async def test_certificate_modified_only_invalidates_cache_entry(
         self, hot_reload_manager, temp_cert_dir
     ):
-        """Test that modifying a certificate triggers cache clear, metrics clear, and immediate re-scan."""
+        """Test that modifying a certificate keeps the cache, clears metrics and re-scans."""
         await hot_reload_manager.start()
 
         # Create a test certificate file
@@ -334,10 +517,11 @@ class TestHotReloadManager:
         hot_reload_manager.scanner.scan_once = AsyncMock()
 
         # Simulate certificate modification
-        await hot_reload_manager._debounced_cert_change(str(test_file), "modified")
+        await hot_reload_manager._process_certificate_changes({str(test_file): "modified"})
 
-        # Verify entire cache was cleared (not just single entry)
-        hot_reload_manager.scanner.cache.clear.assert_called_once()
+        # Verify the cache was not cleared: the modified file's cache key changed
+        hot_reload_manager.scanner.cache.clear.assert_not_called()
+        hot_reload_manager.scanner.cache.delete.assert_not_called()
 
         # Verify metrics were cleared (modifications now trigger immediate re-scan)
         hot_reload_manager.scanner.metrics.clear_all_certificate_metrics.assert_called_once()
@@ -346,6 +530,173 @@ class TestHotReloadManager:
         # Verify re-scan was triggered
         hot_reload_manager.scanner.scan_once.assert_called_once()
 
+    @pytest.mark.asyncio
+    async def test_certificate_change_restats_path(self, hot_reload_manager, temp_cert_dir):
+        """Test that the paths are re-stat'ed after the debounce, as events may be outdated."""
+        renamed_back = Path(temp_cert_dir) / "cert.pem"
+        renamed_back.write_text("test cert content")
+        hot_reload_manager.scanner.scan_once = AsyncMock()
+
+        with patch("tls_cert_monitor.hot_reload.asyncio.sleep", AsyncMock()), patch(
+            "tls_cert_monitor.hot_reload.log_hot_reload"
+        ) as log:
+            await hot_reload_manager._process_certificate_changes(
+                {str(renamed_back): "deleted", str(Path(temp_cert_dir) / ".tmp.pem"): "created"}
+            )
+
+        assert [call.args[2] for call in log.call_args_list] == ["created", "deleted"]
+        hot_reload_manager.scanner.scan_once.assert_called_once_with(
+            directories={temp_cert_dir}
+        )
+
+    @pytest.mark.asyncio
+    async def test_certificate_changes_are_batched(self, hot_reload_manager, temp_cert_dir):
+        """Test that a burst of events is coalesced by path into one batch."""
+        hot_reload_manager.CERT_CHANGE_DEBOUNCE = 0.05
+        hot_reload_manager._process_certificate_changes = AsyncMock()
+        cert_a = str(Path(temp_cert_dir) / "a.pem")
+        cert_b = str(Path(temp_cert_dir) / "b.pem")
+
+        for _ in range(50):
+            await hot_reload_manager._handle_certificate_change(cert_a, "modified")
+        await hot_reload_manager._handle_certificate_change(cert_b, "created")
+        await hot_reload_manager._handle_certificate_change(cert_a, "deleted")
+        assert hot_reload_manager.get_status()["pending_cert_changes"] == 2
+        await hot_reload_manager._cert_change_timer
+        await asyncio.gather(*hot_reload_manager._cert_change_tasks)
+
+        hot_reload_manager._process_certificate_changes.assert_awaited_once_with(
+            {cert_a: "deleted", cert_b: "created"}
+        )
+        assert hot_reload_manager.get_status()["pending_cert_changes"] == 0
+
+    @pytest.mark.asyncio
+    async def test_reload_and_rescan_forces_scan(self, hot_reload_manager, temp_cert_dir):
+        """Test that a forced reload applies the config and re-scans once."""
+        Path(hot_reload_manager.config_path).write_text(
+            f"certificate_directories:\n  - {temp_cert_dir}\nworkers: 6\nhot_reload: false\n"
+        )
+        hot_reload_manager.scanner.scan_once = AsyncMock()
+
+        await hot_reload_manager.reload_and_rescan()
+
+        assert hot_reload_manager.config.workers == 6
+        assert hot_reload_manager.scanner.config.workers == 6
+        hot_reload_manager.scanner.scan_once.assert_called_once()
+
+    @pytest.mark.asyncio
+    async def test_reload_and_rescan_keeps_config_overrides(self, hot_reload_manager):
+        """Test that command line overrides survive a reload."""
+        hot_reload_manager.config_overrides = {"workers": 3}
+        hot_reload_manager.scanner.scan_once = AsyncMock()
+
+        await hot_reload_manager.reload_and_rescan()
+
+        assert hot_reload_manager.config.workers == 3
+
+    @pytest.mark.asyncio
+    async def test_threshold_change_triggers_rescan(self, hot_reload_manager, temp_cert_dir):
+        """Test that changed expiry thresholds re-evaluate certificate severities."""
+        Path(hot_reload_manager.config_path).write_text(
+            f"certificate_directories:\n  - {temp_cert_dir}\nscan_interval: 5m\nworkers: 2\n"
+            "expiry_thresholds:\n  warning: 60d\n  critical: 14d\n"
+        )
+        hot_reload_manager.scanner.scan_once = AsyncMock()
+
+        assert await hot_reload_manager.reload_config() is True
+
+        assert hot_reload_manager.scanner.config.expiry_thresholds.warning == "60d"
+        hot_reload_manager.scanner.scan_once.assert_called_once()
+
+    @pytest.mark.asyncio
+    async def test_log_level_change_applied(self, hot_reload_manager, temp_cert_dir):
+        """Test that a changed log level is applied without a restart."""
+        root_logger = logging.getLogger()
+        previous_level = root_logger.level
+        Path(hot_reload_manager.config_path).write_text(
+            f"certificate_directories:\n  - {temp_cert_dir}\nscan_interval: 5m\nworkers: 2\n"
+            "log_level: DEBUG\n"
+        )
+        hot_reload_manager.scanner.scan_once = AsyncMock()
+
+        try:
+            root_logger.setLevel(logging.INFO)
+            await hot_reload_manager.reload_config()
+            assert root_logger.level == logging.DEBUG
+        finally:
+            root_logger.setLevel(previous_level)
+
+        hot_reload_manager.scanner.scan_once.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_cache_setting_change_replaces_cache(self, hot_reload_manager, temp_cert_dir):
+        """Test that changed cache settings recreate the cache the scanner and health use."""
+        old_cache = hot_reload_manager.scanner.cache
+        Path(hot_reload_manager.config_path).write_text(
+            f"certificate_directories:\n  - {temp_cert_dir}\nscan_interval: 5m\nworkers: 2\n"
+            "cache_max_entries: 10\n"
+        )
+        hot_reload_manager.scanner.scan_once = AsyncMock()
+
+        await hot_reload_manager.reload_config()
+
+        new_cache = hot_reload_manager.scanner.cache
+        try:
+            assert new_cache is not old_cache
+            assert new_cache.max_entries == 10
+            stats = await new_cache.get_stats()
+            assert stats["max_entries"] == 10
+            health = await new_cache.get_health_status()
+            assert health["cache_entries_total"] == 0
+        finally:
+            await new_cache.close()
+
+        # Unchanged cache settings keep the current cache
+        await hot_reload_manager.reload_config()
+        assert hot_reload_manager.scanner.cache is new_cache
+
+    @pytest.mark.asyncio
+    async def test_reload_history(self, hot_reload_manager, temp_cert_dir):
+        """Test that applied reloads are recorded with their redacted setting changes."""
+        hot_reload_manager.scanner.scan_once = AsyncMock()
+        for workers in (3, 5):
+            Path(hot_reload_manager.config_path).write_text(
+                f"certificate_directories:\n  - {temp_cert_dir}\nscan_interval: 5m\n"
+                f"workers: {workers}\np12_passwords: ['secret{workers}']\n"
+            )
+            await hot_reload_manager.reload_config()
+
+        history = hot_reload_manager.get_history()
+
+        assert len(history) == 2
+        assert history[0]["source"] == str(hot_reload_manager.config_path)
+        changes = {change["setting"]: change for change in history[0]["changes"]}
+        assert changes["workers"]["old"] == 3
+        assert changes["workers"]["new"] == 5
+        assert "secret" not in str(history)
+
+    def test_remote_config_change_schedules_reload(self, hot_reload_manager):
+        """Test that a remote configuration change schedules a reload."""
+        manager = hot_reload_manager
+        manager.remote_config = "consul://consul:8500/tls/config.yaml"
+        manager._schedule_coro = MagicMock(side_effect=lambda coro: coro.close())
+
+        source = MagicMock()
+        source.fetch.return_value = ({}, "1")
+
+        def wait_for_change(version):
+            # Report one change, then stop the watch on the next call
+            if version == "2":
+                manager._remote_watch_stop.set()
+            return "2"
+
+        source.wait_for_change.side_effect = wait_for_change
+
+        with patch("tls_cert_monitor.hot_reload.create_remote_source", return_value=source):
+            manager._watch_remote_config()
+
+        manager._schedule_coro.assert_called_once()
+
     @pytest.mark.asyncio
     async def test_get_status(self, hot_reload_manager):
         """Test getting hot reload status."""
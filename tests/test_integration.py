@@ -14,6 +14,8 @@ Or: make test-integration
 """
 
 import asyncio
+import json
+import logging
 import time
 from datetime import datetime, timedelta, timezone
 from pathlib import Path
@@ -52,11 +54,11 @@ def test_config(test_certs_dir: Path, tmp_path: Path) -> Config:
         certificate_directories=[str(test_certs_dir)],
         port=9999,  # High port for testing
         bind_address="127.0.0.1",
-        scan_interval_seconds=300,  # Long interval, we'll trigger manually
+        scan_interval="5m",  # Long interval, we'll trigger manually
         workers=2,
         log_level="DEBUG",
         cache_dir=str(cache_dir),
-        cache_ttl_seconds=300,
+        cache_ttl="5m",
         hot_reload=False,  # Disable for most tests
         dry_run=False,
         enable_ip_whitelist=False,  # Disable for testing
@@ -158,6 +160,59 @@ class TestFullApplicationIntegration:
         assert result["summary"]["total_parsed"] == 3
         assert result["summary"]["total_errors"] == 0
 
+    async def test_server_certificate_monitored(self, app_components, test_certs_dir, tmp_path):
+        """Test the server's tls_cert is scanned unless a certificate directory holds it."""
+        config, scanner, metrics, cache = app_components
+        tls_cert = tmp_path / "server.crt"
+        generate_test_certificate(tls_cert, "monitor.example.com")
+        generate_test_certificate(test_certs_dir / "cert1.pem", "example1.com")
+        config.tls_cert = str(tls_cert)
+
+        result = await scanner.scan_once()
+
+        assert result["summary"]["total_parsed"] == 2
+        server = result["directories"][str(tls_cert.resolve())]
+        assert server["tls_cert"] is True
+        assert server["certificates"][0]["common_name"] == "monitor.example.com"
+        assert 'common_name="monitor.example.com"' in metrics.get_metrics()
+
+        config.monitor_tls_cert = False
+        assert (await scanner.scan_once())["summary"]["total_parsed"] == 1
+
+        config.monitor_tls_cert = True
+        config.tls_cert = str(test_certs_dir / "cert1.pem")
+        assert (await scanner.scan_once())["summary"]["total_parsed"] == 1
+
+    async def test_scan_summary_records(self, app_components, test_certs_dir, caplog):
+        """Test one structured summary record is logged per scan, with certificate changes."""
+        config, scanner, metrics, cache = app_components
+        generate_test_certificate(test_certs_dir / "cert1.pem", "example1.com")
+        generate_test_certificate(test_certs_dir / "cert2.pem", "example2.com")
+
+        def summaries():
+            return [r.scan_summary for r in caplog.records if hasattr(r, "scan_summary")]
+
+        with caplog.at_level(logging.INFO):
+            await scanner.scan_once()
+            (test_certs_dir / "cert2.pem").unlink()
+            generate_test_certificate(test_certs_dir / "cert3.pem", "example3.com")
+            await scanner.scan_once()
+
+        first, second = summaries()
+        assert first["schema_version"] == 1
+        assert first["baseline"] is True
+        assert first["certificates"] == 2
+        assert first["new_certificates"] == 0
+        assert second["baseline"] is False
+        assert second["scan_number"] == first["scan_number"] + 1
+        assert second["files"] == 2
+        assert second["errors"] == 0
+        assert second["new_paths"] == [str(test_certs_dir / "cert3.pem")]
+        assert second["removed_paths"] == [str(test_certs_dir / "cert2.pem")]
+        assert (second["new_certificates"], second["removed_certificates"]) == (1, 1)
+        record = [r for r in caplog.records if hasattr(r, "scan_summary")][-1]
+        assert json.loads(record.getMessage().split(": ", 1)[1]) == second
+
     async def test_api_endpoints_with_running_server(self, app_components, test_certs_dir):
         """Test all API endpoints with a running server."""
         config, scanner, metrics, cache = app_components
@@ -287,7 +342,7 @@ class TestFullApplicationIntegration:
         generate_test_certificate(cert_path2, "added.example.com")
 
         # Trigger hot reload manually (simulate file system event)
-        await hot_reload._debounced_cert_change(str(cert_path2), "created")
+        await hot_reload._process_certificate_changes({str(cert_path2): "created"})
 
         # Small delay for processing
         await asyncio.sleep(0.5)
@@ -0,0 +1,444 @@
+"""
+Tests for the log file, external log sinks, log sampling and request IDs.
+"""
+
+import json
+import logging
+import os
+import re
+import socket
+import struct
+import sys
+import threading
+
+import pytest
+from pydantic import ValidationError
+
+from tls_cert_monitor import logger
+from tls_cert_monitor.config import (
+    Config,
+    EventLogConfig,
+    LogFilePermissionsConfig,
+    LogSamplingConfig,
+    SyslogConfig,
+    load_config,
+)
+from tls_cert_monitor.log_handlers import (
+    EVENTLOG_ERROR_TYPE,
+    EVENTLOG_INFORMATION_TYPE,
+    EVENTLOG_WARNING_TYPE,
+    JournaldHandler,
+    RFC5424Formatter,
+    StreamSyslogHandler,
+    create_event_log_handler,
+    create_log_file_handler,
+    create_syslog_handler,
+    event_log_type,
+    journal_field_name,
+    stderr_is_journal,
+)
+
+RFC5424_INFO = re.compile(
+    r"^<30>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{3}Z \S+ tls-cert-monitor \d+ "
+    r"tls_cert_monitor\.test - Scan completed$"
+)
+
+
+def _record(level=logging.INFO, message="Scan completed"):
+    return logging.LogRecord("tls_cert_monitor.test", level, __file__, 1, message, None, None)
+
+
+@pytest.mark.skipif(sys.platform == "win32", reason="Requires POSIX permissions")
+class TestLogFile:
+    """Test the log file and its permissions."""
+
+    def test_config(self):
+        """Test permission modes are octal strings or numbers within 0777."""
+        assert LogFilePermissionsConfig(mode="0640").mode == 0o640
+        assert LogFilePermissionsConfig(mode=0o640, owner=0).owner == "0"
+        for mode in ("rw-r-----", 640, "1777"):
+            with pytest.raises(ValidationError):
+                LogFilePermissionsConfig(mode=mode)
+
+    def test_modes(self, tmp_path):
+        """Test the file mode applies to rotated files too, and the directory mode."""
+        path = tmp_path / "logs" / "monitor.log"
+        permissions = LogFilePermissionsConfig(mode="0640", directory_mode="0750")
+        handler = create_log_file_handler(str(path), permissions)
+
+        try:
+            handler.handle(_record())
+            handler.doRollover()
+            handler.handle(_record())
+        finally:
+            handler.close()
+
+        assert handler.permission_error is None
+        assert os.stat(path).st_mode & 0o777 == 0o640
+        assert os.stat(f"{path}.1").st_mode & 0o777 == 0o640
+        assert os.stat(path.parent).st_mode & 0o777 == 0o750
+
+    def test_owner(self, tmp_path):
+        """Test the owner is applied, and an unknown one reported without stopping logging."""
+        path = tmp_path / "monitor.log"
+        handler = create_log_file_handler(
+            str(path), LogFilePermissionsConfig(owner=str(os.getuid()), group=str(os.getgid()))
+        )
+        handler.close()
+        assert handler.permission_error is None
+
+        handler = create_log_file_handler(
+            str(path), LogFilePermissionsConfig(owner="no-such-user-tls-monitor")
+        )
+        try:
+            handler.handle(_record())
+        finally:
+            handler.close()
+        assert "no-such-user-tls-monitor" in str(handler.permission_error)
+        assert "Scan completed" in path.read_text()
+
+    def test_env_overrides(self, monkeypatch):
+        """Test log file permissions from the environment."""
+        monkeypatch.setenv("TLS_MONITOR_LOG_FILE_MODE", "0640")
+        monkeypatch.setenv("TLS_MONITOR_LOG_FILE_GROUP", "adm")
+
+        permissions = load_config(None).log_file_permissions
+
+        assert permissions.mode == 0o640
+        assert permissions.group == "adm"
+        assert permissions.owner is None
+
+
+class TestSyslog:
+    """Test the syslog sink."""
+
+    def test_config(self):
+        """Test syslog settings are validated, with per-protocol default ports."""
+        assert SyslogConfig(address="logs.example.com").server_address() == (
+            "logs.example.com",
+            514,
+        )
+        assert SyslogConfig(address="logs.example.com", protocol="tls").server_address() == (
+            "logs.example.com",
+            6514,
+        )
+        assert SyslogConfig(address="[::1]:1514").server_address() == ("::1", 1514)
+
+        with pytest.raises(ValidationError):
+            SyslogConfig(protocol="relp")
+        with pytest.raises(ValidationError):
+            SyslogConfig(facility="kernel-ish")
+        with pytest.raises(ValidationError):
+            SyslogConfig(address="logs.example.com:syslog")
+        with pytest.raises(ValidationError):
+            SyslogConfig(app_name="tls cert monitor")
+
+    def test_env_overrides(self, monkeypatch):
+        """Test syslog settings from the environment."""
+        monkeypatch.setenv("TLS_MONITOR_SYSLOG_ENABLED", "true")
+        monkeypatch.setenv("TLS_MONITOR_SYSLOG_ADDRESS", "logs.example.com:601")
+        monkeypatch.setenv("TLS_MONITOR_SYSLOG_PROTOCOL", "tcp")
+
+        config = load_config(None)
+
+        assert config.syslog.enabled
+        assert config.syslog.server_address() == ("logs.example.com", 601)
+        assert config.syslog.protocol == "tcp"
+
+    def test_rfc5424_format(self):
+        """Test records are formatted per RFC 5424 behind the priority."""
+        message = RFC5424Formatter("tls-cert-monitor").format(_record())
+
+        assert RFC5424_INFO.match("<30>" + message)
+
+    def test_udp(self):
+        """Test messages are sent to a remote server over UDP."""
+        server = socket.socket(socket.AF_INET, socket.SOCK_DGRAM)
+        server.bind(("127.0.0.1", 0))
+        server.settimeout(5)
+        config = SyslogConfig(address=f"127.0.0.1:{server.getsockname()[1]}")
+        handler = create_syslog_handler(config)
+
+        try:
+            handler.handle(_record())
+            data, _ = server.recvfrom(4096)
+        finally:
+            handler.close()
+            server.close()
+
+        assert RFC5424_INFO.match(data.decode())
+
+    def test_tcp_octet_counting(self):
+        """Test messages are framed by octet counting over TCP, with the error severity."""
+        server = socket.create_server(("127.0.0.1", 0))
+        server.settimeout(5)
+        received = []
+
+        def accept():
+            connection, _ = server.accept()
+            with connection:
+                connection.settimeout(5)
+                data = b""
+                while data.count(b"<") < 2:
+                    data += connection.recv(4096)
+                received.append(data)
+
+        thread = threading.Thread(target=accept)
+        thread.start()
+        config = SyslogConfig(address=f"127.0.0.1:{server.getsockname()[1]}", protocol="tcp")
+        handler = create_syslog_handler(config)
+        assert isinstance(handler, StreamSyslogHandler)
+
+        try:
+            handler.handle(_record())
+            handler.handle(_record(logging.ERROR, "Scan failed"))
+            thread.join(5)
+        finally:
+            handler.close()
+            server.close()
+
+        frames = []
+        data = received[0]
+        while data:
+            length, _, rest = data.partition(b" ")
+            frames.append(rest[: int(length)].decode())
+            data = rest[int(length) :]
+        assert RFC5424_INFO.match(frames[0])
+        # daemon (3) * 8 + err (3)
+        assert frames[1].startswith("<27>1 ")
+        assert frames[1].endswith(" Scan failed")
+
+    def test_unreachable_server(self, monkeypatch):
+        """Test an unreachable server drops records instead of retrying every call."""
+        monkeypatch.setattr(logging, "raiseExceptions", False)
+        with socket.create_server(("127.0.0.1", 0)) as server:
+            port = server.getsockname()[1]
+        handler = StreamSyslogHandler(("127.0.0.1", port), facility=3)
+        connects = []
+        original_connect = handler._connect
+
+        def connect():
+            connects.append(1)
+            return original_connect()
+
+        handler._connect = connect
+
+        handler.handle(_record())
+        handler.handle(_record())
+
+        assert len(connects) == 1
+        handler.close()
+
+    def test_setup_logging(self, monkeypatch):
+        """Test a syslog sink that cannot be set up does not prevent logging."""
+
+        def fail(config):
+            raise OSError("No such file or directory: /dev/log")
+
+        monkeypatch.setattr(logger, "create_syslog_handler", fail)
+        root_handlers = list(logging.getLogger().handlers)
+        try:
+            logger.setup_logging(Config(syslog={"enabled": True}))
+            assert len(logging.getLogger().handlers) == 1
+        finally:
+            logging.getLogger().handlers[:] = root_handlers
+
+
+def _parse_journal_fields(datagram):
+    """Parse a native journal protocol datagram."""
+    fields = {}
+    while datagram:
+        line, _, rest = datagram.partition(b"\n")
+        if b"=" in line:
+            name, _, value = line.partition(b"=")
+            datagram = rest
+        else:
+            name = line
+            (length,) = struct.unpack("<Q", rest[:8])
+            value = rest[8 : 8 + length]
+            datagram = rest[8 + length + 1 :]
+        fields[name.decode()] = value.decode()
+    return fields
+
+
+@pytest.mark.skipif(not hasattr(socket, "AF_UNIX"), reason="Requires Unix sockets")
+class TestJournald:
+    """Test the journald sink."""
+
+    def test_structured_fields(self, tmp_path):
+        """Test records are sent with their extra attributes as journal fields."""
+        socket_path = str(tmp_path / "journal.socket")
+        server = socket.socket(socket.AF_UNIX, socket.SOCK_DGRAM)
+        server.bind(socket_path)
+        server.settimeout(5)
+        handler = JournaldHandler("tls-cert-monitor", socket_path)
+        record = _record(logging.ERROR, "Certificate processing failed:\nbad PEM")
+        record.cert_path = "/etc/ssl/example.pem"
+        record.config_changes = [{"setting": "workers"}]
+
+        try:
+            handler.handle(record)
+            datagram = server.recv(65536)
+        finally:
+            handler.close()
+            server.close()
+
+        fields = _parse_journal_fields(datagram)
+        assert fields["MESSAGE"] == "Certificate processing failed:\nbad PEM"
+        assert fields["PRIORITY"] == "3"
+        assert fields["SYSLOG_IDENTIFIER"] == "tls-cert-monitor"
+        assert fields["LOGGER"] == "tls_cert_monitor.test"
+        assert fields["CERT_PATH"] == "/etc/ssl/example.pem"
+        assert fields["CONFIG_CHANGES"] == '[{"setting": "workers"}]'
+
+    def test_field_names(self):
+        """Test attribute names are mapped to valid journal field names."""
+        assert journal_field_name("cert_path") == "CERT_PATH"
+        assert journal_field_name("_private") == "PRIVATE"
+        assert journal_field_name("cache-key") == "CACHE_KEY"
+
+    def test_journal_stream_detection(self, monkeypatch, tmp_path):
+        """Test running under systemd is detected from JOURNAL_STREAM."""
+        stat = os.fstat(2)
+        monkeypatch.setattr("sys.stderr", open(os.devnull, "w"))
+        monkeypatch.setattr("sys.stdout", open(os.devnull, "w"))
+        monkeypatch.delenv("JOURNAL_STREAM", raising=False)
+        assert not stderr_is_journal()
+
+        monkeypatch.setenv("JOURNAL_STREAM", f"{stat.st_dev}:{stat.st_ino}")
+        assert not stderr_is_journal()
+
+        devnull = os.fstat(os.open(os.devnull, os.O_RDONLY))
+        monkeypatch.setenv("JOURNAL_STREAM", f"{devnull.st_dev}:{devnull.st_ino}")
+        assert stderr_is_journal()
+
+    def test_setup_logging(self, monkeypatch):
+        """Test journald replaces the console, which is kept if it cannot be set up."""
+        root_handlers = list(logging.getLogger().handlers)
+        monkeypatch.setattr(logger, "journald_available", lambda: True)
+        monkeypatch.setattr(logger, "stderr_is_journal", lambda: True)
+        monkeypatch.setattr(logger, "create_journald_handler", lambda: JournaldHandler("test"))
+        try:
+            logger.setup_logging(Config())
+            handlers = logging.getLogger().handlers
+            assert [type(h) for h in handlers] == [JournaldHandler]
+
+            logger.setup_logging(Config(log_journald=False))
+            assert not any(isinstance(h, JournaldHandler) for h in logging.getLogger().handlers)
+
+            def fail():
+                raise OSError("Journal socket not found")
+
+            monkeypatch.setattr(logger, "create_journald_handler", fail)
+            logger.setup_logging(Config(log_journald=True))
+            assert [type(h) for h in logging.getLogger().handlers] == [logging.StreamHandler]
+        finally:
+            for handler in logging.getLogger().handlers:
+                if isinstance(handler, JournaldHandler):
+                    handler.close()
+            logging.getLogger().handlers[:] = root_handlers
+
+
+class TestEventLog:
+    """Test the Windows Event Log sink."""
+
+    def test_config(self, monkeypatch):
+        """Test Event Log settings are validated and read from the environment."""
+        assert EventLogConfig(level="error").level == "ERROR"
+        with pytest.raises(ValidationError):
+            EventLogConfig(level="NOTICE")
+        with pytest.raises(ValidationError):
+            EventLogConfig(source="TLS\\Monitor")
+
+        monkeypatch.setenv("TLS_MONITOR_EVENT_LOG_ENABLED", "true")
+        monkeypatch.setenv("TLS_MONITOR_EVENT_LOG_LEVEL", "ERROR")
+        config = load_config(None)
+        assert config.event_log.enabled
+        assert config.event_log.level == "ERROR"
+
+    def test_event_types(self):
+        """Test record levels map to event types and IDs."""
+        assert event_log_type(logging.INFO) == (EVENTLOG_INFORMATION_TYPE, 1)
+        assert event_log_type(logging.WARNING) == (EVENTLOG_WARNING_TYPE, 2)
+        assert event_log_type(logging.ERROR) == (EVENTLOG_ERROR_TYPE, 3)
+        assert event_log_type(logging.CRITICAL) == (EVENTLOG_ERROR_TYPE, 3)
+
+    @pytest.mark.skipif(sys.platform == "win32", reason="Tests non-Windows platforms")
+    def test_unavailable(self):
+        """Test the Event Log is not set up off Windows, and logging carries on without it."""
+        with pytest.raises(OSError):
+            create_event_log_handler(EventLogConfig(enabled=True))
+
+        root_handlers = list(logging.getLogger().handlers)
+        try:
+            logger.setup_logging(Config(event_log={"enabled": True}, log_journald=False))
+            assert len(logging.getLogger().handlers) == 1
+        finally:
+            logging.getLogger().handlers[:] = root_handlers
+
+
+class TestSampling:
+    """Test log sampling."""
+
+    def test_sampling_per_statement(self, monkeypatch):
+        """Test records are sampled per statement and second, warnings always kept."""
+        now = [1000.0]
+        monkeypatch.setattr(logger.time, "monotonic", lambda: now[0])
+        sampling = logger.SamplingFilter(LogSamplingConfig(enabled=True, initial=2, thereafter=3))
+
+        kept = [sampling.filter(_record(message=f"Excluding file {i}")) for i in range(8)]
+        assert kept == [True, True, False, False, True, False, False, True]
+
+        # Another statement is counted on its own
+        other = _record()
+        other.lineno = 2
+        assert sampling.filter(other)
+        assert all(sampling.filter(_record(logging.WARNING)) for _ in range(5))
+
+        now[0] += 1
+        assert sampling.filter(_record())
+
+    def test_shared_by_handlers(self):
+        """Test a record passed to several handlers is counted once."""
+        sampling = logger.SamplingFilter(LogSamplingConfig(enabled=True, initial=1, thereafter=0))
+        record = _record()
+
+        assert sampling.filter(record) and sampling.filter(record)
+        assert not sampling.filter(_record())
+
+    def test_env_overrides(self, monkeypatch):
+        """Test log sampling settings from the environment."""
+        monkeypatch.setenv("TLS_MONITOR_LOG_SAMPLING_ENABLED", "true")
+        monkeypatch.setenv("TLS_MONITOR_LOG_SAMPLING_THEREAFTER", "0")
+
+        config = load_config(None)
+
+        assert config.log_sampling.enabled
+        assert config.log_sampling.initial == 100
+        assert config.log_sampling.thereafter == 0
+
+
+class TestRequestIds:
+    """Test records logged while handling an API request carry its ID."""
+
+    def test_request_id_filter(self):
+        """Test the ID of the request being handled is added to records."""
+        request_filter = logger.RequestIdFilter()
+        token = logger.request_id_var.set("lb-4711")
+        try:
+            record = _record()
+            assert request_filter.filter(record)
+        finally:
+            logger.request_id_var.reset(token)
+
+        assert record.request_id == "lb-4711"
+        console_line = logger.CustomFormatter(use_color=False).format(record)
+        assert console_line.endswith("| [lb-4711] Scan completed")
+        assert json.loads(logger.StructuredFormatter().format(record))["request_id"] == "lb-4711"
+        message = RFC5424Formatter("tls-cert-monitor").format(record)
+        assert message.endswith(" - [lb-4711] Scan completed")
+
+        # Records logged outside a request are left alone
+        other = _record()
+        assert request_filter.filter(other)
+        assert not hasattr(other, "request_id")
@@ -4,8 +4,11 @@ Tests for metrics collection.
 
 import time
 
+from tls_cert_monitor.config import ExpiryThresholds
 from tls_cert_monitor.metrics import (
     MetricsCollector,
+    count_certificates_by_severity,
+    get_expiry_severity,
     is_deprecated_signature_algorithm,
     is_weak_key,
 )
@@ -50,6 +53,77 @@ class TestMetricsCollector:
         assert "ssl_cert_expiration_timestamp" in metrics_output
         assert "test.example.com" in metrics_output
 
+    def test_update_severity_metrics(self):
+        """Test severity series is replaced when the severity changes."""
+        metrics = MetricsCollector()
+
+        cert_data = {
+            "common_name": "test.example.com",
+            "path": "/test/cert.pem",
+            "expiration_timestamp": time.time() + 86400,
+            "severity": "warning",
+        }
+        metrics.update_certificate_metrics(cert_data)
+        assert 'severity="warning"' in metrics.get_metrics()
+
+        cert_data["severity"] = "critical"
+        metrics.update_certificate_metrics(cert_data)
+        metrics_output = metrics.get_metrics()
+        assert 'severity="critical"' in metrics_output
+        assert 'severity="warning"' not in metrics_output
+
+    def test_update_expiry_metrics(self):
+        """Test expiry bucket counts and thresholds are exported."""
+        metrics = MetricsCollector()
+
+        metrics.update_expiry_metrics(
+            {"ok": 3, "warning": 1}, ExpiryThresholds(warning="45d", critical="14d")
+        )
+
+        metrics_output = metrics.get_metrics()
+        assert 'ssl_certs_by_severity{severity="ok"} 3' in metrics_output
+        assert 'ssl_certs_by_severity{severity="warning"} 1' in metrics_output
+        assert 'ssl_certs_by_severity{severity="expired"} 0' in metrics_output
+        assert 'ssl_cert_expiry_threshold_seconds{severity="warning"} 3888000' in metrics_output
+        assert 'ssl_cert_expiry_threshold_seconds{severity="critical"} 1209600' in metrics_output
+
+    def test_update_cache_metrics(self):
+        """Test cache size, evictions by reason and entries by source are exported."""
+        metrics = MetricsCollector()
+
+        metrics.update_cache_metrics(
+            {
+                "entries_total": 12,
+                "current_size_bytes": 4096,
+                "evictions_size": 3,
+                "evictions_count": 5,
+                "evictions_memory": 0,
+                "oversized_skips": 2,
+                "sources": {"file": {"entries": 12, "size_bytes": 4096}},
+            }
+        )
+
+        metrics_output = metrics.get_metrics()
+        assert "app_cache_entries 12" in metrics_output
+        assert "app_cache_size_bytes 4096" in metrics_output
+        assert 'app_cache_evictions_total{reason="size"} 3' in metrics_output
+        assert 'app_cache_evictions_total{reason="count"} 5' in metrics_output
+        assert "app_cache_oversized_skips_total 2" in metrics_output
+        assert 'app_cache_source_entries{source="file"} 12' in metrics_output
+        assert 'app_cache_source_size_bytes{source="file"} 4096' in metrics_output
+
+    def test_update_health_metrics(self):
+        """Test health check statuses are exported as 0/1/2, dropping checks no longer run."""
+        metrics = MetricsCollector()
+        metrics.update_health_metrics({"scanner": "healthy", "disk /old": "degraded"})
+
+        metrics.update_health_metrics({"scanner": "degraded", "cache": "unhealthy"})
+
+        metrics_output = metrics.get_metrics()
+        assert 'ssl_cert_monitor_health_check{name="scanner"} 1' in metrics_output
+        assert 'ssl_cert_monitor_health_check{name="cache"} 2' in metrics_output
+        assert "disk /old" not in metrics_output
+
     def test_update_scan_metrics(self):
         """Test updating scan metrics."""
         metrics = MetricsCollector()
@@ -103,6 +177,29 @@ class TestMetricsCollector:
         metrics_output = metrics.get_metrics()
         assert "ssl_cert_duplicate_count" in metrics_output
 
+    def test_scan_metrics_published_at_once(self):
+        """Test scrapes during a scan serve the previous scan until the scan publishes."""
+        metrics = MetricsCollector()
+        metrics.update_certificate_metrics({"common_name": "old.example.com", "path": "/old.pem"})
+        metrics.ssl_certs_parsed_total.set(1)
+
+        metrics.reset_scan_metrics()
+        metrics.update_certificate_metrics({"common_name": "new.example.com", "path": "/new.pem"})
+        metrics.record_parse_error("broken.pem", "ValueError", "Unable to load certificate")
+
+        during = metrics.get_metrics()
+        metrics.publish_scan_metrics()
+        after = metrics.get_metrics()
+
+        assert "old.example.com" in during
+        assert "new.example.com" not in during
+        assert "ssl_certs_parsed_total 1" in during
+        assert "broken.pem" not in during
+        assert "old.example.com" not in after
+        assert "new.example.com" in after
+        assert "ssl_certs_parsed_total 0" in after
+        assert "broken.pem" in after
+
     def test_system_metrics(self):
         """Test system metrics update."""
         metrics = MetricsCollector()
@@ -161,6 +258,42 @@ class TestMetricHelpers:
         assert is_deprecated_signature_algorithm("MD5WithRSAEncryption") is True
         assert is_deprecated_signature_algorithm("SHA1WithRSAEncryption") is True
 
+    def test_get_expiry_severity(self):
+        """Test expiry severity classification."""
+        thresholds = ExpiryThresholds(warning="30d", critical="7d")
+        now = 1_000_000_000.0
+        day = 86400
+
+        assert get_expiry_severity(now + 60 * day, thresholds, now) == "ok"
+        assert get_expiry_severity(now + 30 * day, thresholds, now) == "warning"
+        assert get_expiry_severity(now + 10 * day, thresholds, now) == "warning"
+        assert get_expiry_severity(now + 7 * day, thresholds, now) == "critical"
+        assert get_expiry_severity(now + 1, thresholds, now) == "critical"
+        assert get_expiry_severity(now, thresholds, now) == "expired"
+        assert get_expiry_severity(now - day, thresholds, now) == "expired"
+
+        # The safety margin is subtracted from the time remaining
+        assert get_expiry_severity(now + 8 * day, thresholds, now, margin=day) == "critical"
+        assert get_expiry_severity(now + 60, thresholds, now, margin=3600) == "expired"
+
+
+    def test_count_certificates_by_severity(self):
+        """Test certificates are counted per severity across directories."""
+        results = {
+            "/a": {"certificates": [{"severity": "ok"}, {"severity": "critical"}]},
+            "/b": {"certificates": [{"severity": "critical"}]},
+            "/c": {"certificates": [{"severity": "expired", "silenced": True}]},
+            "/missing": {"error": "Directory does not exist"},
+        }
+
+        assert count_certificates_by_severity(results) == {
+            "ok": 1,
+            "warning": 0,
+            "critical": 2,
+            "expired": 1,
+        }
+        assert count_certificates_by_severity(results, include_silenced=False)["expired"] == 0
+
 
 class TestIssuerCodes:
     """Test issuer code classification."""
@@ -0,0 +1,514 @@
+"""
+Tests for notification delivery.
+"""
+
+import json
+import sys
+import threading
+import urllib.parse
+from http.server import BaseHTTPRequestHandler, HTTPServer
+from unittest.mock import MagicMock, patch
+
+import pytest
+
+from tls_cert_monitor.config import NotificationTemplateConfig, NotifierConfig
+from tls_cert_monitor.notifiers import (
+    AlertmanagerNotifier,
+    DiscordNotifier,
+    EmailNotifier,
+    JiraNotifier,
+    Notification,
+    NotificationTemplate,
+    ServiceNowNotifier,
+    SNSNotifier,
+    TelegramNotifier,
+    WebhookNotifier,
+    create_notifiers,
+    template_context,
+)
+
+
+@pytest.fixture
+def webhook_server():
+    """Local HTTP server recording POSTed JSON bodies."""
+    received = []
+
+    class Handler(BaseHTTPRequestHandler):
+        def do_POST(self):  # noqa: N802
+            length = int(self.headers["Content-Length"])
+            received.append((self.path, dict(self.headers), json.loads(self.rfile.read(length))))
+            self.send_response(200)
+            self.end_headers()
+
+        def log_message(self, *args):
+            pass
+
+    server = HTTPServer(("127.0.0.1", 0), Handler)
+    thread = threading.Thread(target=server.serve_forever, daemon=True)
+    thread.start()
+    yield f"http://127.0.0.1:{server.server_port}", received
+    server.shutdown()
+    server.server_close()
+
+
+@pytest.fixture
+def api_server():
+    """Local HTTP server answering JSON by (method, path) and recording requests."""
+    responses = {}
+    requests = []
+
+    class Handler(BaseHTTPRequestHandler):
+        def _handle(self):
+            length = int(self.headers.get("Content-Length") or 0)
+            body = json.loads(self.rfile.read(length)) if length else None
+            parsed = urllib.parse.urlparse(self.path)
+            requests.append((self.command, parsed.path, urllib.parse.parse_qs(parsed.query), body))
+
+            response = json.dumps(responses.get((self.command, parsed.path), {})).encode()
+            self.send_response(200)
+            self.send_header("Content-Length", str(len(response)))
+            self.end_headers()
+            self.wfile.write(response)
+
+        do_GET = do_POST = do_PATCH = _handle  # noqa: N815
+
+        def log_message(self, *args):
+            pass
+
+    server = HTTPServer(("127.0.0.1", 0), Handler)
+    thread = threading.Thread(target=server.serve_forever, daemon=True)
+    thread.start()
+    yield f"http://127.0.0.1:{server.server_port}", responses, requests
+    server.shutdown()
+    server.server_close()
+
+
+def _expiry_alert(status="firing", fingerprint="ab" * 32, severity="critical"):
+    return Notification(
+        title="Certificate expires in 3 days: api.example.com (/certs/api.pem)",
+        body="details",
+        severity=severity,
+        status=status,
+        labels={"rule": "expiry", "path": "/certs/api.pem", "fingerprint": fingerprint},
+    )
+
+
+class TestNotifiers:
+    """Test notifier implementations."""
+
+    def test_create_notifiers(self):
+        """Test notifiers are created by type and keyed by name."""
+        notifiers = create_notifiers(
+            [
+                NotifierConfig(name="hook", type="webhook", url="http://localhost/hook"),
+                NotifierConfig(
+                    name="mail",
+                    type="email",
+                    smtp_host="localhost",
+                    from_address="monitor@example.com",
+                    to_addresses=["ops@example.com"],
+                ),
+            ]
+        )
+
+        assert isinstance(notifiers["hook"], WebhookNotifier)
+        assert isinstance(notifiers["mail"], EmailNotifier)
+
+    @pytest.mark.asyncio
+    async def test_webhook_notifier(self, webhook_server):
+        """Test webhook notifier posts JSON with custom headers."""
+        url, received = webhook_server
+        notifier = WebhookNotifier(
+            NotifierConfig(
+                name="hook", type="webhook", url=f"{url}/hook", headers={"X-Token": "secret"}
+            )
+        )
+
+        await notifier.send(
+            Notification(title="Digest", body="text", severity="warning", data={"count": 1})
+        )
+
+        path, headers, payload = received[0]
+        assert path == "/hook"
+        assert headers["X-Token"] == "secret"
+        assert payload == {
+            "title": "Digest",
+            "text": "text",
+            "severity": "warning",
+            "status": "firing",
+            "labels": {},
+            "data": {"count": 1},
+        }
+
+    @pytest.mark.asyncio
+    async def test_alertmanager_notifier(self, webhook_server):
+        """Test Alertmanager notifier posts v2 alerts with labels and annotations."""
+        url, received = webhook_server
+        notifier = AlertmanagerNotifier(
+            NotifierConfig(
+                name="am",
+                type="alertmanager",
+                url=f"{url}/",
+                generator_url="https://monitor.example.com:3200/healthz",
+            )
+        )
+
+        await notifier.send(
+            Notification(
+                title="Certificate renewed",
+                body="details",
+                severity="info",
+                labels={"rule": "content_change", "path": "/certs/a.pem", "common_name": ""},
+            )
+        )
+
+        path, _, alerts = received[0]
+        assert path == "/api/v2/alerts"
+        assert alerts[0]["labels"] == {
+            "alertname": "TLSCertContentChange",
+            "severity": "info",
+            "rule": "content_change",
+            "path": "/certs/a.pem",
+        }
+        assert alerts[0]["annotations"] == {
+            "summary": "Certificate renewed",
+            "description": "details",
+        }
+        assert alerts[0]["generatorURL"] == "https://monitor.example.com:3200/healthz"
+        assert "startsAt" in alerts[0]
+        assert "endsAt" not in alerts[0]
+
+        await notifier.send(
+            Notification(title="Firing", body="", starts_at=1_800_000_000, ends_at=1_800_000_900)
+        )
+        _, _, alerts = received[1]
+        assert alerts[0]["startsAt"] == "2027-01-15T08:00:00+00:00"
+        assert alerts[0]["endsAt"] == "2027-01-15T08:15:00+00:00"
+
+        await notifier.send(
+            Notification(title="Resolved", body="", status="resolved", labels={"rule": "expiry"})
+        )
+        _, _, alerts = received[2]
+        assert "endsAt" in alerts[0]
+
+    @pytest.mark.asyncio
+    async def test_email_notifier(self):
+        """Test email notifier builds and sends the message over SMTP."""
+        notifier = EmailNotifier(
+            NotifierConfig(
+                name="mail",
+                type="email",
+                smtp_host="smtp.example.com",
+                smtp_username="monitor",
+                smtp_password="secret",
+                from_address="monitor@example.com",
+                to_addresses=["ops@example.com", "sec@example.com"],
+            )
+        )
+
+        with patch("tls_cert_monitor.notifiers.smtplib.SMTP") as mock_smtp:
+            smtp = MagicMock()
+            mock_smtp.return_value.__enter__.return_value = smtp
+
+            await notifier.send(Notification(title="Digest", body="text"))
+
+        mock_smtp.assert_called_once_with("smtp.example.com", 587, timeout=10)
+        smtp.starttls.assert_called_once()
+        smtp.login.assert_called_once_with("monitor", "secret")
+        message = smtp.send_message.call_args[0][0]
+        assert message["Subject"] == "Digest"
+        assert message["To"] == "ops@example.com, sec@example.com"
+
+    @pytest.mark.asyncio
+    async def test_sns_notifier(self):
+        """Test SNS notifier publishes to the topic with a truncated subject."""
+        notifier = SNSNotifier(
+            NotifierConfig(
+                name="sns",
+                type="sns",
+                topic_arn="arn:aws:sns:us-east-1:123456789012:certs",
+                region="us-east-1",
+            )
+        )
+        boto3 = MagicMock()
+        client = boto3.Session.return_value.client.return_value
+
+        with patch.dict(sys.modules, {"boto3": boto3}):
+            await notifier.send(
+                Notification(
+                    title="x" * 150,
+                    body="details",
+                    severity="critical",
+                    labels={"rule": "weak_key"},
+                )
+            )
+
+        boto3.Session.assert_called_once_with(profile_name=None, region_name="us-east-1")
+        kwargs = client.publish.call_args.kwargs
+        assert kwargs["TopicArn"] == "arn:aws:sns:us-east-1:123456789012:certs"
+        assert len(kwargs["Subject"]) == 100
+        assert kwargs["Message"] == "details"
+        assert kwargs["MessageAttributes"]["severity"]["StringValue"] == "critical"
+        assert kwargs["MessageAttributes"]["rule"]["StringValue"] == "weak_key"
+
+    @pytest.mark.asyncio
+    async def test_sns_notifier_assumes_role(self):
+        """Test SNS notifier assumes the configured IAM role."""
+        notifier = SNSNotifier(
+            NotifierConfig(
+                name="sns",
+                type="sns",
+                topic_arn="arn:aws:sns:us-east-1:123456789012:certs",
+                role_arn="arn:aws:iam::123456789012:role/notify",
+            )
+        )
+        boto3 = MagicMock()
+        sts = boto3.Session.return_value.client.return_value
+        sts.assume_role.return_value = {
+            "Credentials": {"AccessKeyId": "a", "SecretAccessKey": "s", "SessionToken": "t"}
+        }
+
+        with patch.dict(sys.modules, {"boto3": boto3}):
+            await notifier.send(Notification(title="t", body="b"))
+
+        sts.assume_role.assert_called_once()
+        assert boto3.Session.call_args.kwargs["aws_session_token"] == "t"
+
+    def test_sns_requires_topic(self):
+        """Test SNS notifier requires a topic ARN."""
+        with pytest.raises(ValueError):
+            NotifierConfig(name="sns", type="sns")
+
+    @pytest.mark.asyncio
+    async def test_telegram_notifier(self, webhook_server):
+        """Test Telegram notifier calls sendMessage for the bot."""
+        url, received = webhook_server
+        notifier = TelegramNotifier(
+            NotifierConfig(name="tg", type="telegram", url=url, bot_token="123:abc", chat_id="-42")
+        )
+
+        await notifier.send(Notification(title="Digest", body="y" * 5000))
+
+        path, _, payload = received[0]
+        assert path == "/bot123:abc/sendMessage"
+        assert payload["chat_id"] == "-42"
+        assert payload["text"].startswith("Digest\n\n")
+        assert len(payload["text"]) == 4096
+
+    @pytest.mark.asyncio
+    async def test_discord_bot_notifier(self, webhook_server):
+        """Test Discord notifier posts channel messages with the bot token."""
+        url, received = webhook_server
+        notifier = DiscordNotifier(
+            NotifierConfig(
+                name="dc", type="discord", url=url, bot_token="token", channel_id="987"
+            )
+        )
+
+        await notifier.send(Notification(title="Digest", body="text"))
+
+        path, headers, payload = received[0]
+        assert path == "/channels/987/messages"
+        assert headers["Authorization"] == "Bot token"
+        assert payload["content"] == "**Digest**\ntext"
+        assert payload["allowed_mentions"] == {"parse": []}
+
+    @pytest.mark.asyncio
+    async def test_discord_webhook_notifier(self, webhook_server):
+        """Test Discord notifier posts to a channel webhook URL."""
+        url, received = webhook_server
+        notifier = DiscordNotifier(
+            NotifierConfig(name="dc", type="discord", url=f"{url}/api/webhooks/1/secret")
+        )
+
+        await notifier.send(Notification(title="Digest", body="text"))
+
+        path, headers, _ = received[0]
+        assert path == "/api/webhooks/1/secret"
+        assert "Authorization" not in headers
+
+    def test_bot_notifiers_require_credentials(self):
+        """Test Telegram and Discord notifiers validate their settings."""
+        with pytest.raises(ValueError):
+            NotifierConfig(name="tg", type="telegram", bot_token="123:abc")
+        with pytest.raises(ValueError):
+            NotifierConfig(name="dc", type="discord", bot_token="token")
+
+
+class TestTicketNotifiers:
+    """Test Jira and ServiceNow ticket lifecycle."""
+
+    @pytest.mark.asyncio
+    async def test_jira_opens_ticket(self, api_server):
+        """Test a critical expiry alert opens a labeled Jira issue."""
+        url, responses, requests = api_server
+        responses[("GET", "/rest/api/2/search")] = {"issues": []}
+        responses[("POST", "/rest/api/2/issue")] = {"key": "OPS-1"}
+        notifier = JiraNotifier(
+            NotifierConfig(
+                name="jira", type="jira", url=url, project="OPS", username="bot", password="token"
+            )
+        )
+
+        await notifier.send(_expiry_alert())
+
+        method, path, query, _ = requests[0]
+        assert (method, path) == ("GET", "/rest/api/2/search")
+        assert 'labels = "tls-cert-abababababababababababababababab"' in query["jql"][0]
+        method, path, _, body = requests[1]
+        assert (method, path) == ("POST", "/rest/api/2/issue")
+        assert body["fields"]["project"] == {"key": "OPS"}
+        assert body["fields"]["issuetype"] == {"name": "Task"}
+        assert "tls-cert-abababababababababababababababab" in body["fields"]["labels"]
+
+    @pytest.mark.asyncio
+    async def test_jira_deduplicates(self, api_server):
+        """Test no second issue is opened while one is open for the fingerprint."""
+        url, responses, requests = api_server
+        responses[("GET", "/rest/api/2/search")] = {"issues": [{"key": "OPS-1"}]}
+        notifier = JiraNotifier(NotifierConfig(name="jira", type="jira", url=url, project="OPS"))
+
+        await notifier.send(_expiry_alert())
+
+        assert [r[0] for r in requests] == ["GET"]
+
+    @pytest.mark.asyncio
+    async def test_jira_comments_and_closes(self, api_server):
+        """Test a resolved alert comments on and transitions the open issue."""
+        url, responses, requests = api_server
+        responses[("GET", "/rest/api/2/search")] = {"issues": [{"key": "OPS-1"}]}
+        responses[("GET", "/rest/api/2/issue/OPS-1/transitions")] = {
+            "transitions": [{"id": "11", "name": "In Progress"}, {"id": "31", "name": "Done"}]
+        }
+        notifier = JiraNotifier(NotifierConfig(name="jira", type="jira", url=url, project="OPS"))
+
+        await notifier.send(_expiry_alert(status="resolved"))
+
+        calls = [(method, path, body) for method, path, _, body in requests]
+        assert ("POST", "/rest/api/2/issue/OPS-1/comment", {"body": "details"}) in calls
+        transition = {"transition": {"id": "31"}}
+        assert ("POST", "/rest/api/2/issue/OPS-1/transitions", transition) in calls
+
+    @pytest.mark.asyncio
+    async def test_ignores_other_rules(self, api_server):
+        """Test only critical expiry alerts create tickets."""
+        url, _, requests = api_server
+        notifier = JiraNotifier(NotifierConfig(name="jira", type="jira", url=url, project="OPS"))
+
+        await notifier.send(Notification(title="weak", body="", labels={"rule": "weak_key"}))
+        await notifier.send(_expiry_alert(severity="warning"))
+
+        assert requests == []
+
+    @pytest.mark.asyncio
+    async def test_servicenow_lifecycle(self, api_server):
+        """Test ServiceNow records are created with correlation ID and resolved."""
+        url, responses, requests = api_server
+        notifier = ServiceNowNotifier(
+            NotifierConfig(name="snow", type="servicenow", url=url, queue="PKI Team")
+        )
+
+        responses[("GET", "/api/now/table/incident")] = {"result": []}
+        responses[("POST", "/api/now/table/incident")] = {"result": {"number": "INC0010001"}}
+        await notifier.send(_expiry_alert(fingerprint="/certs/api.pem#123"))
+
+        _, _, query, _ = requests[0]
+        correlation_id = query["sysparm_query"][0].split("^")[0].split("=")[1]
+        assert correlation_id.startswith("tls-cert-")
+        _, _, _, body = requests[1]
+        assert body["correlation_id"] == correlation_id
+        assert body["assignment_group"] == "PKI Team"
+
+        requests.clear()
+        responses[("GET", "/api/now/table/incident")] = {"result": [{"sys_id": "abc123"}]}
+        await notifier.send(_expiry_alert(status="resolved", fingerprint="/certs/api.pem#123"))
+
+        method, path, _, body = requests[1]
+        assert (method, path) == ("PATCH", "/api/now/table/incident/abc123")
+        assert body["state"] == "6"
+        assert body["work_notes"] == "details"
+
+
+class TestNotificationTemplates:
+    """Test notification template overrides."""
+
+    @staticmethod
+    def _notification():
+        return Notification(
+            title="Certificate expires in 3 days",
+            body="default body",
+            severity="critical",
+            labels={"rule": "expiry", "path": "/certs/api.pem"},
+            data={
+                "common_name": "api.example.com",
+                "serial": "123",
+                "san_list": ["api.example.com", "www.example.com"],
+                "old": {"serial": "1"},
+            },
+        )
+
+    def test_template_context(self):
+        """Test the documented template data model."""
+        context = template_context(self._notification(), "ops")
+
+        assert context["common_name"] == "api.example.com"
+        assert context["san_list"] == "api.example.com, www.example.com"
+        assert context["old_serial"] == "1"
+        assert context["path"] == "/certs/api.pem"
+        assert context["rule"] == "expiry"
+        assert context["severity"] == "critical"
+        assert context["status"] == "firing"
+        assert context["title"] == "Certificate expires in 3 days"
+        assert context["notifier"] == "ops"
+        assert context["host"]
+
+    def test_inline_template(self):
+        """Test inline templates and unknown placeholders."""
+        template = NotificationTemplate(
+            NotificationTemplateConfig(
+                title="[$severity] $common_name on $host", body="$body\nSerial: $serial $missing"
+            )
+        )
+
+        rendered = template.render(self._notification())
+
+        assert rendered.title.startswith("[critical] api.example.com on ")
+        assert rendered.body == "default body\nSerial: 123 $missing"
+        assert rendered.labels == self._notification().labels
+
+    def test_template_files(self, tmp_path):
+        """Test templates loaded from files, overriding inline templates."""
+        body_file = tmp_path / "body.txt"
+        body_file.write_text("Renew ${common_name} (${path})\n", encoding="utf-8")
+
+        template = NotificationTemplate(
+            NotificationTemplateConfig(body="inline", body_file=str(body_file))
+        )
+        rendered = template.render(self._notification())
+
+        assert rendered.title == "Certificate expires in 3 days"
+        assert rendered.body == "Renew api.example.com (/certs/api.pem)\n"
+
+    def test_missing_template_file(self, tmp_path):
+        """Test missing template files are rejected at configuration load."""
+        with pytest.raises(ValueError):
+            NotificationTemplateConfig(body_file=str(tmp_path / "missing.txt"))
+
+    @pytest.mark.asyncio
+    async def test_notifier_applies_template(self, webhook_server):
+        """Test notifiers render their template before sending."""
+        url, received = webhook_server
+        notifier = WebhookNotifier(
+            NotifierConfig(
+                name="hook",
+                type="webhook",
+                url=f"{url}/hook",
+                template={"title": "TLS: $common_name", "body": "$notifier/$rule"},
+            )
+        )
+
+        await notifier.send(self._notification())
+
+        _, _, payload = received[0]
+        assert payload["title"] == "TLS: api.example.com"
+        assert payload["text"] == "hook/expiry"
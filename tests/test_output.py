@@ -0,0 +1,90 @@
+"""
+Tests for machine-readable output of the scan and check commands.
+"""
+
+import csv
+import io
+import json
+
+from tls_cert_monitor.output import (
+    INVENTORY_COLUMNS,
+    certificates_of,
+    format_csv,
+    format_results,
+    format_table,
+    inventory_row,
+)
+
+
+def _results():
+    """Scan results with an ok and a critical certificate."""
+    return {
+        "directories": {
+            "/etc/ssl/certs": {
+                "certificates": [
+                    {
+                        "path": "/etc/ssl/certs/www.pem",
+                        "common_name": "www.example.com",
+                        "severity": "ok",
+                        "days_until_expiry": 200,
+                        "not_after": "2027-05-01T00:00:00+00:00",
+                        "san_list": ["www.example.com", "example.com"],
+                    },
+                    {
+                        "path": "/etc/ssl/certs/api.pem",
+                        "common_name": "api.example.com",
+                        "severity": "critical",
+                        "days_until_expiry": 3,
+                        "not_after": "2026-10-17T00:00:00+00:00",
+                        "san_list": [],
+                        "silenced": True,
+                    },
+                ]
+            },
+            "/srv/missing": {"skipped": "Directory does not exist: /srv/missing"},
+        },
+        "summary": {},
+    }
+
+
+class TestOutput:
+    """Test json, table and csv output."""
+
+    def test_inventory_row(self):
+        """Test certificate data is flattened into text columns with its findings."""
+        worst = certificates_of(_results())[0]
+        row = inventory_row(worst)
+
+        assert list(row) == list(INVENTORY_COLUMNS)
+        assert row["path"] == "/etc/ssl/certs/api.pem"
+        assert row["days_until_expiry"] == "3"
+        assert row["silenced"] == "yes"
+        assert row["findings"] == "Expires in 3 days (critical threshold)"
+        assert row["issuer"] == ""
+
+    def test_table(self):
+        """Test the table lists the worst certificates first, in aligned columns."""
+        lines = format_table(certificates_of(_results())).splitlines()
+
+        assert lines[0].split() == [
+            "SEVERITY",
+            "DAYS",
+            "NOT_AFTER",
+            "COMMON_NAME",
+            "PATH",
+            "FINDINGS",
+        ]
+        assert lines[1].startswith("critical  3 ")
+        assert lines[2].startswith("ok        200")
+        assert lines[1].index("api.example.com") == lines[0].index("COMMON_NAME")
+
+    def test_csv(self):
+        """Test CSV has a header row and quotes values as needed."""
+        rows = list(csv.DictReader(io.StringIO(format_csv(certificates_of(_results())))))
+
+        assert [row["common_name"] for row in rows] == ["api.example.com", "www.example.com"]
+        assert rows[1]["san_list"] == "www.example.com example.com"
+
+    def test_json(self):
+        """Test JSON output is the scan results."""
+        assert json.loads(format_results(_results(), "json")) == _results()
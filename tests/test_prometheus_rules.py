@@ -0,0 +1,73 @@
+"""
+Tests for the Prometheus alerting rules.
+"""
+
+import re
+
+import yaml
+
+from tls_cert_monitor.config import Config, ExpiryThresholds
+from tls_cert_monitor.metrics import MetricsCollector
+from tls_cert_monitor.prometheus_rules import build_rules, format_rules
+
+
+def _rules(config, expiry_days=None):
+    """Get the rules by alert name."""
+    groups = build_rules(config, expiry_days)["groups"]
+    return {rule["alert"]: rule for rule in groups[0]["rules"]}
+
+
+class TestPrometheusRules:
+    """Test rendering the alerting rules."""
+
+    def test_metric_names(self):
+        """Test every rule uses metrics the exporter exposes."""
+        collector = MetricsCollector()
+        exposed = {getattr(metric, "_name", None) for metric in vars(collector).values()}
+
+        for rule in _rules(Config()).values():
+            used = set(re.findall(r"\bssl_[a-z_]+\b", rule["expr"]))
+            assert used and used <= exposed, (rule["alert"], used - exposed)
+
+    def test_thresholds(self):
+        """Test expiry windows follow the configured thresholds and do not overlap."""
+        config = Config(expiry_thresholds=ExpiryThresholds(warning="45d", critical="10d"))
+        rules = _rules(config)
+
+        assert list(rules) == [
+            "TLSCertExpiringIn45Days",
+            "TLSCertExpiringIn10Days",
+            "TLSCertExpired",
+            "TLSCertWeakKey",
+            "TLSCertDeprecatedSignatureAlgorithm",
+            "TLSCertScanStalled",
+        ]
+        assert "<= 3888000 and" in rules["TLSCertExpiringIn45Days"]["expr"]
+        assert "> 864000 unless" in rules["TLSCertExpiringIn45Days"]["expr"]
+        assert rules["TLSCertExpiringIn45Days"]["labels"]["severity"] == "warning"
+        assert rules["TLSCertExpiringIn10Days"]["labels"]["severity"] == "critical"
+        assert "ssl_cert_silenced == 1" in rules["TLSCertExpired"]["expr"]
+
+    def test_expiry_days(self):
+        """Test custom expiry days, critical at or below the critical threshold."""
+        rules = _rules(Config(), [7, 30, 14])
+
+        severities = [
+            rules[f"TLSCertExpiringIn{days}Days"]["labels"]["severity"] for days in (30, 14, 7)
+        ]
+        assert severities == ["warning", "warning", "critical"]
+
+    def test_scan_stalled(self):
+        """Test scans stall after two intervals of the slowest directory."""
+        config = Config(
+            scan_interval="5m",
+            certificate_directories=["/tmp", {"path": "/var/tmp", "interval": "1h"}],
+        )
+
+        assert _rules(config)["TLSCertScanStalled"]["expr"].endswith("> 7200")
+
+    def test_format(self):
+        """Test the rule file is YAML that loads back."""
+        rules = build_rules(Config())
+
+        assert yaml.safe_load(format_rules(rules)) == rules
@@ -0,0 +1,197 @@
+"""
+Tests for remote configuration backends.
+"""
+
+import base64
+import json
+import threading
+import urllib.parse
+import urllib.request
+from http.server import BaseHTTPRequestHandler, ThreadingHTTPServer
+
+import pytest
+
+from tls_cert_monitor.config import load_config
+from tls_cert_monitor.remote_config import (
+    ConsulSource,
+    EtcdSource,
+    RemoteConfigError,
+    check_remote_config,
+    create_remote_source,
+    load_remote_config,
+)
+
+DOCUMENT = b"port: 9443\nworkers: 6\n"
+
+
+@pytest.fixture
+def kv_server():
+    """Local server emulating the Consul KV and etcd v3 gateway APIs for one key."""
+    state = {"value": DOCUMENT, "index": 7, "requests": []}
+
+    class Handler(BaseHTTPRequestHandler):
+        def _send_json(self, payload, headers=None):
+            response = json.dumps(payload).encode()
+            self.send_response(200)
+            for name, value in (headers or {}).items():
+                self.send_header(name, value)
+            self.send_header("Content-Length", str(len(response)))
+            self.end_headers()
+            self.wfile.write(response)
+
+        def do_GET(self):  # noqa: N802
+            parsed = urllib.parse.urlparse(self.path)
+            query = urllib.parse.parse_qs(parsed.query)
+            state["requests"].append((parsed.path, query, dict(self.headers)))
+            if parsed.path != "/v1/kv/tls/config.yaml":
+                self.send_response(404)
+                self.send_header("Content-Length", "0")
+                self.end_headers()
+                return
+            if "index" in query:
+                # Blocking query: report a change
+                state["index"] += 1
+            entry = {"Value": base64.b64encode(state["value"]).decode()}
+            self._send_json([entry], {"X-Consul-Index": str(state["index"])})
+
+        def do_POST(self):  # noqa: N802
+            length = int(self.headers.get("Content-Length") or 0)
+            body = json.loads(self.rfile.read(length))
+            state["requests"].append((self.path, body, dict(self.headers)))
+            if self.path == "/v3/kv/range":
+                key = base64.b64decode(body["key"]).decode()
+                kvs = []
+                if key == "tls/config.yaml":
+                    kvs = [
+                        {
+                            "value": base64.b64encode(state["value"]).decode(),
+                            "mod_revision": str(state["index"]),
+                        }
+                    ]
+                self._send_json({"kvs": kvs})
+            elif self.path == "/v3/watch":
+                revision = body["create_request"]["start_revision"]
+                lines = [
+                    {"result": {"created": True}},
+                    {"result": {"events": [{"kv": {"mod_revision": str(revision)}}]}},
+                ]
+                response = b"".join(json.dumps(line).encode() + b"\n" for line in lines)
+                self.send_response(200)
+                self.send_header("Content-Length", str(len(response)))
+                self.end_headers()
+                self.wfile.write(response)
+
+        def log_message(self, *args):
+            pass
+
+    server = ThreadingHTTPServer(("127.0.0.1", 0), Handler)
+    thread = threading.Thread(target=server.serve_forever, daemon=True)
+    thread.start()
+    yield f"127.0.0.1:{server.server_port}", state
+    server.shutdown()
+    server.server_close()
+
+
+class TestRemoteConfig:
+    """Test remote configuration sources."""
+
+    def test_create_remote_source(self, monkeypatch):
+        """Test sources are created from URLs."""
+        monkeypatch.setenv("TLS_MONITOR_REMOTE_CONFIG_TOKEN", "env-token")
+
+        consul = create_remote_source("consul://consul:8500/tls/config.yaml")
+        assert isinstance(consul, ConsulSource)
+        assert consul.base_url == "http://consul:8500"
+        assert consul.key == "tls/config.yaml"
+        assert consul.token == "env-token"
+
+        etcd = create_remote_source("etcd+https://etcd:2379/tls/config.yaml?token=url-token")
+        assert isinstance(etcd, EtcdSource)
+        assert etcd.base_url == "https://etcd:2379"
+        assert etcd.token == "url-token"
+
+        with pytest.raises(ValueError):
+            create_remote_source("zookeeper://zk:2181/tls")
+        with pytest.raises(ValueError):
+            create_remote_source("consul://consul:8500/")
+
+    def test_consul_fetch_and_watch(self, kv_server):
+        """Test Consul KV fetch and blocking query watch."""
+        address, state = kv_server
+        source = create_remote_source(f"consul://{address}/tls/config.yaml?token=secret")
+
+        data, index = source.fetch()
+        assert data == {"port": 9443, "workers": 6}
+        assert index == "7"
+        assert state["requests"][-1][2]["X-Consul-Token"] == "secret"
+
+        assert source.wait_for_change(index) == "8"
+        path, query, _ = state["requests"][-1]
+        assert query["index"] == ["7"]
+        assert "wait" in query
+
+    def test_consul_missing_key(self, kv_server):
+        """Test a missing Consul key is reported."""
+        address, _ = kv_server
+        with pytest.raises(RemoteConfigError, match="not found"):
+            load_remote_config(f"consul://{address}/tls/missing.yaml")
+
+    def test_etcd_fetch_and_watch(self, kv_server):
+        """Test etcd range fetch and watch stream."""
+        address, state = kv_server
+        source = create_remote_source(f"etcd://{address}/tls/config.yaml")
+
+        data, revision = source.fetch()
+        assert data == {"port": 9443, "workers": 6}
+        assert revision == 7
+
+        assert source.wait_for_change(revision) == 8
+        path, body, _ = state["requests"][-1]
+        assert path == "/v3/watch"
+        assert body["create_request"]["start_revision"] == 8
+
+    def test_check_remote_config(self, kv_server, monkeypatch):
+        """Test the health check reports the key version without the token, or fails."""
+        address, _ = kv_server
+        timeouts = []
+        urlopen = urllib.request.urlopen
+
+        def recording_urlopen(request, timeout=None):
+            timeouts.append(timeout)
+            return urlopen(request, timeout=timeout)
+
+        monkeypatch.setattr(urllib.request, "urlopen", recording_urlopen)
+
+        assert check_remote_config(f"consul://{address}/tls/config.yaml?token=secret") == {
+            "url": f"consul://{address}/tls/config.yaml",
+            "key": "tls/config.yaml",
+            "version": "7",
+        }
+        with pytest.raises(RemoteConfigError, match="not found"):
+            check_remote_config(f"etcd://{address}/tls/missing.yaml", timeout=5)
+        # Within the health check timeout when given
+        assert timeouts == [10, 5]
+
+    def test_invalid_document(self, kv_server):
+        """Test a remote document that is not a mapping is rejected."""
+        address, state = kv_server
+        state["value"] = b"- not\n- a mapping\n"
+        with pytest.raises(RemoteConfigError, match="mapping"):
+            load_remote_config(f"etcd://{address}/tls/config.yaml")
+
+    def test_load_config_merges_remote(self, kv_server, tmp_path, monkeypatch):
+        """Test remote configuration is merged over the file and under flags."""
+        address, _ = kv_server
+        monkeypatch.setenv("TLS_MONITOR_LOG_LEVEL", "DEBUG")
+        config_file = tmp_path / "config.yaml"
+        config_file.write_text("port: 3200\nworkers: 2\nlog_level: INFO\n")
+
+        config = load_config(
+            str(config_file),
+            overrides={"workers": 3},
+            remote_config=f"consul://{address}/tls/config.yaml",
+        )
+
+        assert config.port == 9443  # remote > file
+        assert config.workers == 3  # flags > remote
+        assert config.log_level == "DEBUG"  # env > remote
@@ -0,0 +1,121 @@
+"""
+Tests for keeping the scan state across restarts.
+"""
+
+import json
+import time
+from unittest.mock import MagicMock
+
+import pytest
+
+from tls_cert_monitor.cache import CacheManager
+from tls_cert_monitor.config import Config, ScanStateConfig
+from tls_cert_monitor.metrics import MetricsCollector
+from tls_cert_monitor.scan_state import ScanStateStore
+from tls_cert_monitor.scanner import CertificateScanner
+
+
+def _scan(directory, timestamp=None):
+    """Scan results of an expired certificate in a directory."""
+    timestamp = time.time() if timestamp is None else timestamp
+    return {
+        "directories": {
+            directory: {
+                "files_processed": 3,
+                "certificates_parsed": 1,
+                "parse_errors": 2,
+                "scanned_at": timestamp,
+                "certificates": [
+                    {
+                        "common_name": "old.example.com",
+                        "path": f"{directory}/old.pem",
+                        "issuer": "CN=Example CA",
+                        "serial": "1234",
+                        "expiration_timestamp": 0,
+                        "severity": "ok",
+                    }
+                ],
+            },
+            "/srv/removed": {"files_processed": 1, "certificates": []},
+        },
+        "summary": {},
+        "timestamp": timestamp,
+    }
+
+
+class TestScanState:
+    """Test saving the last scan and restoring it at startup."""
+
+    def test_path(self, tmp_path):
+        """Test the state is kept in cache_dir unless given a path, and not without either."""
+        assert Config(cache_dir=str(tmp_path)).scan_state_path() == tmp_path / "scan_state.json"
+        assert Config(
+            cache_dir=str(tmp_path), scan_state=ScanStateConfig(path=str(tmp_path / "s.json"))
+        ).scan_state_path() == (tmp_path / "s.json")
+        assert Config(cache_dir="").scan_state_path() is None
+        assert (
+            Config(cache_dir=str(tmp_path), scan_state=ScanStateConfig(enabled=False))
+            .scan_state_path()
+            is None
+        )
+
+    def test_save_and_load(self, tmp_path):
+        """Test saved results load back, unless unreadable, of another version or too old."""
+        store = ScanStateStore(Config(cache_dir=str(tmp_path / "cache")))
+        scan = _scan("/etc/ssl", timestamp=1000)
+
+        store.save(scan)
+
+        assert store.load(now=1000) == scan
+        assert list((tmp_path / "cache").iterdir()) == [store.path]
+        assert store.load(now=1000 + 8 * 86400) is None
+
+        store.path.write_text(json.dumps({"version": 0, "results": scan}))
+        assert store.load(now=1000) is None
+        store.path.write_text("{")
+        assert store.load(now=1000) is None
+
+    @pytest.mark.asyncio
+    async def test_saved_after_scans(self, tmp_path):
+        """Test every completed scan is saved by the scan listener."""
+        scanner = MagicMock()
+        store = ScanStateStore(Config(cache_dir=str(tmp_path)), scanner)
+        listener = scanner.add_scan_listener.call_args[0][0]
+
+        await listener(_scan("/etc/ssl"))
+
+        assert store.load()["directories"]["/etc/ssl"]["files_processed"] == 3
+
+    @pytest.mark.asyncio
+    async def test_restore(self, tmp_path):
+        """Test restored results are served and exported as stale until the first scan."""
+        config = Config(
+            certificate_directories=[str(tmp_path)], cache_dir="", enable_ip_whitelist=False
+        )
+        metrics = MetricsCollector()
+        scanner = CertificateScanner(config=config, cache=CacheManager(config), metrics=metrics)
+
+        restored = scanner.restore_scan_results(_scan(str(tmp_path), timestamp=1000))
+
+        output = metrics.get_metrics()
+        assert list(restored["directories"]) == [str(tmp_path)]
+        assert restored["stale"] is True
+        # Re-evaluated against the current time
+        assert restored["directories"][str(tmp_path)]["certificates"][0]["severity"] == "expired"
+        assert "ssl_cert_scan_state_stale 1" in output
+        assert f'ssl_cert_last_scan_timestamp{{directory="{tmp_path}"}} 1000' in output
+        assert "ssl_cert_parse_errors_total 2.0" in output
+        assert 'ssl_certs_by_severity{severity="expired"} 1' in output
+        assert 'common_name="old.example.com"' in output
+        readiness = scanner.get_readiness()
+        assert readiness["ready"] is True
+        assert readiness["stale"] is True
+
+        try:
+            results = await scanner.scan_once()
+        finally:
+            await scanner.stop()
+
+        assert "stale" not in results
+        assert "ssl_cert_scan_state_stale 0" in metrics.get_metrics()
+        assert scanner.get_readiness()["stale"] is False
@@ -2,14 +2,39 @@
 Simplified scanner tests to verify basic functionality.
 """
 
+import asyncio
+import os
+import threading
+from datetime import datetime, timedelta, timezone
 from unittest.mock import MagicMock, patch
 
 import pytest
+from cryptography import x509
+from cryptography.hazmat.primitives import hashes, serialization
+from cryptography.hazmat.primitives.asymmetric import ec
+from cryptography.x509.oid import NameOID
 
 from tls_cert_monitor.cache import CacheManager
-from tls_cert_monitor.config import Config
+from tls_cert_monitor.config import Config, ExpiryThresholds
 from tls_cert_monitor.metrics import MetricsCollector
-from tls_cert_monitor.scanner import CertificateScanner
+from tls_cert_monitor.scanner import CertificateScanner, FairScheduler
+
+
+def _certificate(name):
+    """Self-signed certificate for name.example.com."""
+    key = ec.generate_private_key(ec.SECP256R1())
+    subject = x509.Name([x509.NameAttribute(NameOID.COMMON_NAME, f"{name}.example.com")])
+    now = datetime.now(timezone.utc)
+    return (
+        x509.CertificateBuilder()
+        .subject_name(subject)
+        .issuer_name(subject)
+        .public_key(key.public_key())
+        .serial_number(x509.random_serial_number())
+        .not_valid_before(now - timedelta(days=1))
+        .not_valid_after(now + timedelta(days=90))
+        .sign(key, hashes.SHA256())
+    )
 
 
 class TestCertificateScanner:
@@ -24,6 +49,13 @@ class TestCertificateScanner:
         config.p12_passwords = ["", "password", "test"]
         config.scan_interval = 300
         config.workers = 2
+        config.silences = []
+        config.directory_settings = {}
+        config.expiry_thresholds = ExpiryThresholds()
+        config.allow_missing_directories = False
+        config.not_yet_valid_grace_seconds = 300
+        config.expiry_grace_seconds = 0
+        config.monitored_tls_cert.return_value = None
         return config
 
     @pytest.fixture
@@ -68,3 +100,441 @@ class TestCertificateScanner:
         assert is_weak_key(2048, "RSA") is False
         assert is_deprecated_signature_algorithm("md5WithRSAEncryption") is True
         assert is_deprecated_signature_algorithm("sha256WithRSAEncryption") is False
+
+    @pytest.mark.asyncio
+    async def test_scan_listeners(self, scanner, mock_config):
+        """Test scan listeners receive results and failures are isolated."""
+        mock_config.certificate_directories = []
+        received = []
+
+        async def failing_listener(results):
+            raise RuntimeError("listener failed")
+
+        async def listener(results):
+            received.append(results)
+
+        scanner.add_scan_listener(failing_listener)
+        scanner.add_scan_listener(listener)
+
+        results = await scanner.scan_once()
+
+        assert received == [results]
+        assert scanner.last_scan_results is results
+
+    @pytest.mark.asyncio
+    async def test_per_directory_interval(self, tmp_path, mock_cache, mock_metrics):
+        """Test that due_only scans reuse results of directories that are not due."""
+        tmp_path = tmp_path.resolve()
+        fast_dir = tmp_path / "fast"
+        slow_dir = tmp_path / "slow"
+        fast_dir.mkdir()
+        slow_dir.mkdir()
+        config = Config(
+            certificate_directories=[str(fast_dir), {"path": str(slow_dir), "interval": "1h"}],
+            scan_interval="5m",
+        )
+
+        with patch("tls_cert_monitor.scanner.get_logger"):
+            scanner = CertificateScanner(config=config, cache=mock_cache, metrics=mock_metrics)
+
+        async def scan_directory(directory):
+            return {
+                "directory": directory,
+                "files_processed": 0,
+                "certificates_parsed": 0,
+                "parse_errors": 0,
+                "certificates": [],
+            }
+
+        with patch.object(scanner, "_scan_directory", side_effect=scan_directory) as mock_scan:
+            await scanner.scan_once()
+            assert mock_scan.call_count == 2
+
+            # Pretend the global interval elapsed for both directories
+            for result in scanner._directory_results.values():
+                result["scanned_at"] -= 301
+            mock_scan.reset_mock()
+
+            results = await scanner.scan_once(due_only=True)
+
+        mock_scan.assert_called_once_with(str(fast_dir))
+        assert list(results["directories"]) == [str(fast_dir), str(slow_dir)]
+        assert results["summary"]["directories_scanned"] == 1
+        assert results["summary"]["directories_reused"] == 1
+        assert scanner._loop_interval_seconds() == 300
+
+    @pytest.mark.asyncio
+    async def test_allow_missing_directories(self, tmp_path, mock_cache, mock_metrics):
+        """Test that missing directories are skipped instead of reported as errors."""
+        missing = tmp_path.resolve() / "missing"
+        config = Config(certificate_directories=[str(missing)], allow_missing_directories=True)
+
+        with patch("tls_cert_monitor.scanner.get_logger"):
+            scanner = CertificateScanner(config=config, cache=mock_cache, metrics=mock_metrics)
+
+        results = await scanner.scan_once()
+        result = results["directories"][str(missing)]
+        assert "skipped" in result
+        assert "error" not in result
+        assert results["summary"]["total_errors"] == 0
+        assert results["summary"]["directories_skipped"] == 1
+        scanner.logger.warning.assert_called_once()
+
+        # The directory is scanned once it appears
+        missing.mkdir()
+        results = await scanner.scan_once()
+        assert results["directories"][str(missing)]["parse_errors"] == 0
+        assert results["summary"]["directories_skipped"] == 0
+
+        config.allow_missing_directories = False
+        missing.rmdir()
+        results = await scanner.scan_once()
+        assert "error" in results["directories"][str(missing)]
+
+    def test_per_directory_excludes(self, tmp_path, mock_cache, mock_metrics):
+        """Test that directory excludes apply in addition to global patterns."""
+        (tmp_path / "current.pem").write_text("")
+        (tmp_path / "old-site.pem").write_text("")
+        (tmp_path / "dhparam.pem").write_text("")
+        config = Config(certificate_directories=[{"path": str(tmp_path), "excludes": ["^old-"]}])
+
+        with patch("tls_cert_monitor.scanner.get_logger"):
+            scanner = CertificateScanner(config=config, cache=mock_cache, metrics=mock_metrics)
+
+        settings = config.get_directory_config(str(tmp_path.resolve()))
+        files = scanner._find_certificate_files(tmp_path, settings.excludes)
+
+        assert [f.name for f in files] == ["current.pem"]
+
+    @pytest.mark.asyncio
+    async def test_simulate_scan(self, tmp_path, mock_cache, mock_metrics):
+        """Test a simulated scan reports files without touching cache or metrics."""
+        (tmp_path / "broken.pem").write_text("not a certificate")
+        (tmp_path / "dhparam.pem").write_text("")
+        missing = tmp_path / "missing"
+        config = Config(certificate_directories=[str(tmp_path), str(missing)])
+
+        with patch("tls_cert_monitor.scanner.get_logger"):
+            scanner = CertificateScanner(config=config, cache=mock_cache, metrics=mock_metrics)
+        try:
+            results = await scanner.simulate_scan()
+        finally:
+            await scanner.stop()
+
+        result = results["directories"][str(tmp_path)]
+        assert result["matched"] == [str(tmp_path / "broken.pem")]
+        assert result["excluded"] == [
+            {"path": str(tmp_path / "dhparam.pem"), "reason": "pattern dhparam.pem"}
+        ]
+        assert result["certificates"] == []
+        assert result["errors"][0]["path"] == str(tmp_path / "broken.pem")
+        assert "error" in results["directories"][str(missing)]
+        assert mock_cache.mock_calls == []
+        assert mock_metrics.mock_calls == []
+
+    def test_clock_skew_grace(self, mock_cache, mock_metrics):
+        """Test not-yet-valid and expiry evaluation honour the clock skew grace settings."""
+        config = Config(not_yet_valid_grace="5m", expiry_grace="1h")
+        with patch("tls_cert_monitor.scanner.get_logger"):
+            scanner = CertificateScanner(config=config, cache=mock_cache, metrics=mock_metrics)
+
+        now = 1_000_000_000.0
+        skewed = {"not_before_timestamp": now + 60, "expiration_timestamp": now + 86400}
+        future = {"not_before_timestamp": now + 3600, "expiration_timestamp": now + 86400}
+        expiring = {"not_before_timestamp": now - 86400, "expiration_timestamp": now + 1800}
+        with patch("tls_cert_monitor.scanner.time.time", return_value=now):
+            for cert_data in (skewed, future, expiring):
+                scanner._annotate_severity(cert_data)
+
+        assert skewed["not_yet_valid"] is False
+        assert future["not_yet_valid"] is True
+        assert expiring["not_yet_valid"] is False
+        # Within expiry_grace of NotAfter: treated as expired
+        assert expiring["severity"] == "expired"
+        assert skewed["severity"] == "critical"
+
+    @pytest.mark.asyncio
+    async def test_cache_key_tracks_file_versions(self, tmp_path, mock_metrics):
+        """Test a replaced file misses the cache even with its mtime preserved."""
+        cert_file = tmp_path / "site.pem"
+        cert_file.write_text("version 1")
+        cache = CacheManager(Config(cache_dir=str(tmp_path / "cache")))
+        with patch("tls_cert_monitor.scanner.get_logger"):
+            scanner = CertificateScanner(config=Config(), cache=cache, metrics=mock_metrics)
+        scanner._parse_certificate_file = MagicMock(return_value={"common_name": "v1"})
+
+        try:
+            semaphore = asyncio.Semaphore(1)
+            assert await scanner._process_certificate_file(cert_file, semaphore) == {
+                "common_name": "v1"
+            }
+            first_key = scanner._file_cache_key(cert_file)
+
+            # Replace the content, restoring the original modification time
+            stat = cert_file.stat()
+            cert_file.write_text("version 2 (longer)")
+            os.utime(cert_file, ns=(stat.st_atime_ns, stat.st_mtime_ns))
+            scanner._parse_certificate_file.return_value = {"common_name": "v2"}
+
+            assert scanner._file_cache_key(cert_file) != first_key
+            assert await scanner._process_certificate_file(cert_file, semaphore) == {
+                "common_name": "v2"
+            }
+            # The entry of the replaced version is dropped
+            assert await cache.get(first_key) is None
+            assert (await cache.get_stats())["entries_total"] == 1
+        finally:
+            await scanner.stop()
+
+    def test_exclude_files(self, tmp_path, mock_cache, mock_metrics):
+        """Test that exclude_files globs match file names and full paths."""
+        (tmp_path / "archive").mkdir()
+        (tmp_path / "site.pem").write_text("")
+        (tmp_path / "site-Backup.pem").write_text("")
+        (tmp_path / "archive" / "old.pem").write_text("")
+        config = Config(
+            certificate_directories=[str(tmp_path)],
+            exclude_files=["*-backup.pem", "*/archive/*.pem"],
+        )
+
+        with patch("tls_cert_monitor.scanner.get_logger"):
+            scanner = CertificateScanner(config=config, cache=mock_cache, metrics=mock_metrics)
+
+        excluded = []
+        files = scanner._find_certificate_files(tmp_path, excluded=excluded)
+
+        assert [f.name for f in files] == ["site.pem"]
+        assert sorted(item["reason"] for item in excluded) == [
+            "glob *-backup.pem",
+            "glob */archive/*.pem",
+        ]
+
+    def test_parallel_walk(self, tmp_path, mock_cache, mock_metrics):
+        """Test that a concurrent walk finds the files of every subdirectory, as os.walk."""
+        for index in range(20):
+            (tmp_path / f"site{index}" / "nested").mkdir(parents=True)
+            (tmp_path / f"site{index}" / "nested" / "cert.pem").write_text("")
+        (tmp_path / "excluded").mkdir()
+        (tmp_path / "excluded" / "old.pem").write_text("")
+        (tmp_path / "root.crt").write_text("")
+        # Symlinked directories are not followed
+        (tmp_path / "link").symlink_to(tmp_path / "site0")
+        config = Config(
+            certificate_directories=[str(tmp_path)],
+            exclude_directories=[str(tmp_path / "excluded")],
+        )
+
+        with patch("tls_cert_monitor.scanner.get_logger"):
+            scanner = CertificateScanner(config=config, cache=mock_cache, metrics=mock_metrics)
+
+        excluded = []
+        files = scanner._find_certificate_files(tmp_path, excluded=excluded, workers=4)
+
+        assert files == sorted(
+            [tmp_path / "root.crt"]
+            + [tmp_path / f"site{index}" / "nested" / "cert.pem" for index in range(20)]
+        )
+        assert [entry["path"] for entry in excluded] == [str(tmp_path / "excluded" / "old.pem")]
+
+    def test_walk_prunes_excluded_directories(self, tmp_path, mock_cache, mock_metrics):
+        """Test that the subdirectories of an excluded directory are never listed."""
+        (tmp_path / "archive" / "2019" / "q1").mkdir(parents=True)
+        (tmp_path / "archive" / "2019" / "q1" / "old.pem").write_text("")
+        (tmp_path / "live").mkdir()
+        (tmp_path / "live" / "cert.pem").write_text("")
+        config = Config(
+            certificate_directories=[str(tmp_path)],
+            exclude_directories=[str(tmp_path / "archive")],
+        )
+
+        with patch("tls_cert_monitor.scanner.get_logger"):
+            scanner = CertificateScanner(config=config, cache=mock_cache, metrics=mock_metrics)
+
+        listed = []
+        scandir = os.scandir
+
+        def recording_scandir(path):
+            listed.append(os.fspath(path))
+            return scandir(path)
+
+        with patch("tls_cert_monitor.scanner.os.scandir", recording_scandir):
+            files = scanner._find_certificate_files(tmp_path, workers=2)
+
+        assert files == [tmp_path / "live" / "cert.pem"]
+        assert sorted(listed) == [str(tmp_path / name) for name in ("", "archive", "live")]
+
+    @pytest.mark.parametrize(
+        "content",
+        [
+            # certbot and openssl x509 -text write text before the certificate
+            lambda leaf, chain: b"Certificate for leaf.example.com\nsubject=CN = leaf\n\x00\xff\n"
+            + leaf.public_bytes(serialization.Encoding.PEM),
+            # A key before the chain
+            lambda leaf, chain: ec.generate_private_key(ec.SECP256R1()).private_bytes(
+                serialization.Encoding.PEM,
+                serialization.PrivateFormat.PKCS8,
+                serialization.NoEncryption(),
+            )
+            + leaf.public_bytes(serialization.Encoding.PEM)
+            + chain.public_bytes(serialization.Encoding.PEM),
+            # Concatenated DER
+            lambda leaf, chain: leaf.public_bytes(serialization.Encoding.DER)
+            + chain.public_bytes(serialization.Encoding.DER),
+            # DER with a trailing newline
+            lambda leaf, chain: leaf.public_bytes(serialization.Encoding.DER) + b"\n",
+        ],
+        ids=["pem-banner", "pem-key-first", "der-concatenated", "der-trailing-newline"],
+    )
+    def test_parse_pem_der_files(self, tmp_path, scanner, content):
+        """Test files with text around PEM blocks, or several DER certificates, report the first."""
+        cert_path = tmp_path / "leaf.crt"
+        cert_path.write_bytes(content(_certificate("leaf"), _certificate("chain")))
+
+        assert scanner._parse_pem_der_file(cert_path)["common_name"] == "leaf.example.com"
+
+    def test_parse_pem_der_garbage(self, tmp_path, scanner):
+        """Test files without a certificate still fail to parse."""
+        cert_path = tmp_path / "garbage.pem"
+        cert_path.write_bytes(b"0\x82\x01\x00not a certificate")
+
+        with pytest.raises(ValueError, match="Could not parse as PEM or DER"):
+            scanner._parse_pem_der_file(cert_path)
+
+    @pytest.mark.asyncio
+    async def test_fair_scheduler(self):
+        """Test slots go to the directories with files waiting in turn."""
+        scheduler = FairScheduler(2)
+        big = scheduler.directory("/big", workers=6)
+        small = scheduler.directory("/small", workers=2)
+        order = []
+        release = asyncio.Event()
+
+        async def parse(slots, name):
+            async with slots:
+                order.append(name)
+                await release.wait()
+
+        # The big directory queues all its files before the small one
+        tasks = [asyncio.create_task(parse(big, f"big{index}")) for index in range(6)]
+        await asyncio.sleep(0)
+        tasks += [asyncio.create_task(parse(small, f"small{index}")) for index in range(2)]
+        await asyncio.sleep(0)
+        release.set()
+        await asyncio.gather(*tasks)
+
+        # Waiting first in line, the big directory's files would all have gone first
+        assert order == ["big0", "big1", "big2", "small0", "big3", "small1", "big4", "big5"]
+
+    def test_parse_error_ratio_exemptions(self, tmp_path, mock_metrics):
+        """Test parse errors of exempted directories do not count toward the ratio."""
+        certs, junk = tmp_path / "certs", tmp_path / "junk"
+        certs.mkdir()
+        junk.mkdir()
+        config = Config(
+            certificate_directories=[
+                str(certs),
+                {"path": str(junk), "parse_errors_degrade": False},
+            ],
+            cache_dir="",
+        )
+        with patch("tls_cert_monitor.scanner.get_logger"):
+            scanner = CertificateScanner(
+                config=config, cache=CacheManager(config), metrics=mock_metrics
+            )
+        scanner.last_scan_results = {
+            "directories": {
+                str(certs): {"files_processed": 10, "parse_errors": 1},
+                str(junk): {"files_processed": 10, "parse_errors": 10},
+            }
+        }
+
+        assert scanner._parse_error_ratio() == 0.1
+
+        config.health_thresholds.parse_errors_degrade = False
+        assert scanner._parse_error_ratio() is None
+
+        config.directory_settings[str(junk)].parse_errors_degrade = True
+        assert scanner._parse_error_ratio() == 1.0
+
+    @pytest.mark.asyncio
+    async def test_scan_targeted_directories(self, tmp_path, mock_metrics):
+        """Test only the given directories are rescanned, the others reused."""
+        (tmp_path / "changed").mkdir()
+        (tmp_path / "unchanged").mkdir()
+        changed, unchanged = str(tmp_path / "changed"), str(tmp_path / "unchanged")
+        config = Config(certificate_directories=[changed, unchanged], cache_dir="")
+        with patch("tls_cert_monitor.scanner.get_logger"):
+            scanner = CertificateScanner(
+                config=config, cache=CacheManager(config), metrics=mock_metrics
+            )
+
+        try:
+            with patch.object(scanner, "_scan_directory", wraps=scanner._scan_directory) as scan:
+                first = await scanner.scan_once(directories=[changed])
+                targeted = await scanner.scan_once(directories=[changed])
+        finally:
+            await scanner.stop()
+
+        # Directories without previous results are scanned regardless
+        assert first["summary"]["directories_scanned"] == 2
+        assert targeted["summary"]["directories_scanned"] == 1
+        assert targeted["summary"]["directories_reused"] == 1
+        assert [call.args[0] for call in scan.call_args_list] == [changed, unchanged, changed]
+
+    @pytest.mark.asyncio
+    async def test_ready_after_first_scan(self, tmp_path, mock_metrics):
+        """Test readiness and waiting for the first scan."""
+        config = Config(certificate_directories=[str(tmp_path)], cache_dir="")
+        with patch("tls_cert_monitor.scanner.get_logger"):
+            scanner = CertificateScanner(
+                config=config, cache=CacheManager(config), metrics=mock_metrics
+            )
+
+        try:
+            assert scanner.get_readiness()["ready"] is False
+            assert scanner.get_readiness()["last_scan_age_seconds"] is None
+            assert not await scanner.wait_for_first_scan(0.01)
+
+            waiter = asyncio.create_task(scanner.wait_for_first_scan(5))
+            await scanner.scan_once()
+
+            assert await waiter
+            readiness = scanner.get_readiness()
+            assert readiness["ready"] is True
+            assert readiness["last_scan_age_seconds"] >= 0
+            assert readiness["first_scan_completed_at"] == scanner.last_scan_completed_at
+        finally:
+            await scanner.stop()
+
+    @pytest.mark.asyncio
+    async def test_scan_progress(self, tmp_path, mock_metrics):
+        """Test health reports the running scan and the file being parsed."""
+        (tmp_path / "stuck.pem").write_text("")
+        config = Config(certificate_directories=[str(tmp_path)], cache_dir="")
+        cache = CacheManager(config)
+        released = threading.Event()
+        with patch("tls_cert_monitor.scanner.get_logger"):
+            scanner = CertificateScanner(config=config, cache=cache, metrics=mock_metrics)
+        scanner._parse_certificate_file = lambda path: released.wait(5) and None
+
+        try:
+            assert (await scanner.get_health_status())["current_scan"] is None
+            scan = asyncio.create_task(scanner.scan_once())
+            for _ in range(100):
+                progress = (await scanner.get_health_status())["current_scan"]
+                if progress and "oldest_file" in progress:
+                    break
+                await asyncio.sleep(0.01)
+
+            assert progress["directory"] == str(tmp_path)
+            assert progress["files_processed"] == 0
+            assert progress["oldest_file"] == str(tmp_path / "stuck.pem")
+
+            released.set()
+            await scan
+            health = await scanner.get_health_status()
+            assert health["current_scan"] is None
+            assert health["average_scan_duration"] is not None
+        finally:
+            released.set()
+            await scanner.stop()
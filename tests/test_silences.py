@@ -0,0 +1,172 @@
+"""
+Tests for silences and maintenance windows.
+"""
+
+from datetime import datetime, timedelta, timezone
+
+import pytest
+
+from tls_cert_monitor.config import Config, SilenceConfig
+from tls_cert_monitor.silences import Silence, SilenceManager
+
+
+@pytest.fixture
+def cert_data():
+    """Sample certificate data as produced by the scanner."""
+    return {
+        "common_name": "test.example.com",
+        "issuer": "Fake LE Intermediate X1",
+        "path": "/etc/ssl/test/expired.pem",
+        "serial": "12345",
+    }
+
+
+class TestSilenceConfig:
+    """Test silence configuration validation."""
+
+    def test_requires_matchers(self):
+        """Test that a silence without matchers is rejected."""
+        with pytest.raises(ValueError):
+            SilenceConfig(matchers={})
+
+    def test_rejects_inverted_window(self):
+        """Test that ends_at before starts_at is rejected."""
+        now = datetime.now(timezone.utc)
+        with pytest.raises(ValueError):
+            SilenceConfig(matchers={"path": "*"}, starts_at=now, ends_at=now - timedelta(hours=1))
+
+    def test_rejects_unknown_matchers(self):
+        """Test that matchers on unknown fields, which would never match, are rejected."""
+        for field in ("team", "comon_name", "labels."):
+            with pytest.raises(ValueError, match="Unknown silence matcher"):
+                SilenceConfig(matchers={field: "*"})
+        assert SilenceConfig(matchers={"labels.team": "payments"}).matchers
+
+    def test_loaded_from_config(self):
+        """Test silences parsed as part of the main configuration."""
+        config = Config(
+            silences=[{"matchers": {"issuer": "*Staging*"}, "ends_at": "2030-01-01T00:00:00Z"}]
+        )
+        assert len(config.silences) == 1
+        assert config.silences[0].matchers == {"issuer": "*Staging*"}
+
+
+class TestSilence:
+    """Test silence matching and windows."""
+
+    def test_glob_match_is_case_insensitive(self, cert_data):
+        """Test glob matchers against certificate fields."""
+        silence = Silence(SilenceConfig(matchers={"path": "/etc/ssl/TEST/*"}))
+        assert silence.matches(cert_data) is True
+
+    def test_all_matchers_must_match(self, cert_data):
+        """Test that matchers are combined with AND."""
+        silence = Silence(
+            SilenceConfig(matchers={"path": "/etc/ssl/test/*", "issuer": "DigiCert*"})
+        )
+        assert silence.matches(cert_data) is False
+
+    def test_unknown_field_does_not_match(self, cert_data):
+        """Test that matching on a missing field never matches."""
+        silence = Silence(SilenceConfig(matchers={"fingerprint_sha256": "*"}))
+        assert silence.matches(cert_data) is False
+        silence = Silence(SilenceConfig(matchers={"labels.team": "payments"}))
+        assert silence.matches(cert_data) is False
+
+    def test_label_match(self, cert_data):
+        """Test labels.<name> matchers against directory and group labels."""
+        config = Config(
+            certificate_directories=[
+                {"path": "/srv/pay", "group": "payments", "labels": {"env": "staging"}}
+            ],
+            groups={"payments": {"labels": {"team": "payments"}}},
+        )
+        labelled = {**cert_data, "labels": config.directory_labels("/srv/pay")}
+
+        by_directory_label = Silence(SilenceConfig(matchers={"labels.env": "STAGING"}))
+        by_group_label = Silence(SilenceConfig(matchers={"labels.team": "pay*"}))
+        by_group = Silence(SilenceConfig(matchers={"labels.group": "payments"}))
+        other_team = Silence(SilenceConfig(matchers={"labels.team": "web"}))
+
+        assert by_directory_label.matches(labelled) is True
+        assert by_group_label.matches(labelled) is True
+        assert by_group.matches(labelled) is True
+        assert other_team.matches(labelled) is False
+
+    def test_window(self):
+        """Test active/expired checks against the time window."""
+        now = datetime.now(timezone.utc)
+        future = Silence(SilenceConfig(matchers={"path": "*"}, starts_at=now + timedelta(hours=1)))
+        past = Silence(SilenceConfig(matchers={"path": "*"}, ends_at=now - timedelta(hours=1)))
+        open_ended = Silence(SilenceConfig(matchers={"path": "*"}))
+
+        assert future.is_active(now) is False
+        assert past.is_active(now) is False
+        assert past.is_expired(now) is True
+        assert open_ended.is_active(now) is True
+
+    def test_naive_datetimes_are_utc(self):
+        """Test that naive datetimes from YAML are treated as UTC."""
+        silence = Silence(SilenceConfig(matchers={"path": "*"}, ends_at=datetime(2030, 1, 1)))
+        assert silence.ends_at == datetime(2030, 1, 1, tzinfo=timezone.utc)
+
+    def test_config_silence_id_is_stable(self):
+        """Test that config silences keep the same ID across reloads."""
+        silence_config = SilenceConfig(matchers={"path": "/etc/ssl/test/*"}, comment="lab")
+        assert Silence(silence_config).id == Silence(silence_config).id
+
+
+class TestSilenceManager:
+    """Test silence manager functionality."""
+
+    def test_find_silence(self, cert_data):
+        """Test finding an active silence for a certificate."""
+        config = Config(silences=[{"matchers": {"path": "/etc/ssl/test/*"}}])
+        manager = SilenceManager(config)
+
+        assert manager.find_silence(cert_data) is not None
+        assert manager.find_silence({**cert_data, "path": "/etc/ssl/prod/a.pem"}) is None
+
+    def test_add_and_remove_api_silence(self, cert_data):
+        """Test runtime silences can be added and removed."""
+        manager = SilenceManager(Config())
+
+        silence = manager.add_silence(SilenceConfig(matchers={"common_name": "*.example.com"}))
+        assert silence.source == "api"
+        assert manager.find_silence(cert_data) is silence
+
+        assert manager.remove_silence(silence.id) is True
+        assert manager.find_silence(cert_data) is None
+        assert manager.remove_silence(silence.id) is False
+
+    def test_config_silences_cannot_be_removed(self):
+        """Test that config silences are only removable via config."""
+        manager = SilenceManager(Config(silences=[{"matchers": {"path": "*"}}]))
+        silence_id = manager.list_silences()[0].id
+
+        assert manager.remove_silence(silence_id) is False
+        assert manager.get_silence(silence_id) is not None
+
+    def test_reload_replaces_config_silences_only(self):
+        """Test that config reloads keep runtime silences."""
+        manager = SilenceManager(Config(silences=[{"matchers": {"path": "/a/*"}}]))
+        manager.add_silence(SilenceConfig(matchers={"path": "/b/*"}))
+
+        manager.load_config_silences([])
+
+        silences = manager.list_silences()
+        assert len(silences) == 1
+        assert silences[0].source == "api"
+
+    def test_expired_api_silences_are_pruned(self):
+        """Test that expired runtime silences are dropped."""
+        manager = SilenceManager(Config())
+        manager.add_silence(
+            SilenceConfig(
+                matchers={"path": "*"},
+                starts_at=datetime.now(timezone.utc) - timedelta(hours=2),
+                ends_at=datetime.now(timezone.utc) - timedelta(hours=1),
+            )
+        )
+
+        assert manager.list_silences() == []
@@ -0,0 +1,217 @@
+"""
+Tests for the SNMP agent.
+"""
+
+import asyncio
+import socket
+from unittest.mock import MagicMock
+
+import pytest
+from pydantic import ValidationError
+
+from tls_cert_monitor.config import Config, SnmpConfig, load_config, redact_config
+from tls_cert_monitor.snmp import (
+    COUNTER32,
+    END_OF_MIB_VIEW,
+    GAUGE32,
+    GET_BULK_REQUEST,
+    GET_NEXT_REQUEST,
+    GET_REQUEST,
+    INTEGER,
+    NO_SUCH_OBJECT,
+    NULL,
+    OCTET_STRING,
+    SEQUENCE,
+    VERSION_1,
+    VERSION_2C,
+    SnmpAgent,
+    decode_oid,
+    decode_sequence,
+    decode_tlv,
+    encode_integer,
+    encode_oid,
+    encode_tlv,
+    parse_oid,
+)
+
+BASE = "1.3.6.1.4.1.8072.9999.9999"
+
+
+def _scanner():
+    """Scanner whose last scan found a critical, an ok and a silenced expired certificate."""
+    scanner = MagicMock()
+    scanner.last_scan_results = {
+        "directories": {
+            "/etc/ssl": {
+                "certificates": [
+                    {"severity": "critical", "days_until_expiry": 3},
+                    {"severity": "ok", "days_until_expiry": 200},
+                    {"severity": "expired", "days_until_expiry": -2, "silenced": True},
+                ]
+            }
+        },
+        "summary": {"total_errors": 1},
+    }
+    scanner.last_scan_completed_at = 1790000000.5
+    scanner.scans_completed = 7
+    return scanner
+
+
+def _request(pdu_tag, oids, version=VERSION_2C, community=b"public", fields=(0, 0)):
+    """Encode a request message for OIDs."""
+    bindings = b"".join(
+        encode_tlv(SEQUENCE, encode_oid(parse_oid(oid)) + encode_tlv(NULL, b"")) for oid in oids
+    )
+    pdu = encode_tlv(
+        pdu_tag,
+        encode_integer(42)
+        + encode_integer(fields[0])
+        + encode_integer(fields[1])
+        + encode_tlv(SEQUENCE, bindings),
+    )
+    return encode_tlv(
+        SEQUENCE, encode_integer(version) + encode_tlv(OCTET_STRING, community) + pdu
+    )
+
+
+def _response(data):
+    """Decode a response into (error status, error index, [(OID, tag, value)])."""
+    _, message, _ = decode_tlv(data)
+    _, _, (_, pdu) = decode_sequence(message)
+    (_, request_id), (_, status), (_, index), (_, bindings) = decode_sequence(pdu)
+    assert int.from_bytes(request_id, "big") == 42
+    variables = []
+    for _, binding in decode_sequence(bindings):
+        (_, oid), (tag, value) = decode_sequence(binding)
+        number = int.from_bytes(value, "big", signed=tag == INTEGER) if value else None
+        variables.append((".".join(map(str, decode_oid(oid))), tag, number))
+    return int.from_bytes(status, "big"), int.from_bytes(index, "big"), variables
+
+
+class TestSnmp:
+    """Test answering SNMP requests."""
+
+    def test_config(self):
+        """Test the base OID and community are validated, and the community redacted."""
+        with pytest.raises(ValidationError):
+            SnmpConfig(base_oid="1.3.6.x")
+        with pytest.raises(ValidationError):
+            SnmpConfig(community="")
+
+        redacted = redact_config(Config(snmp=SnmpConfig(community="s3cret")))
+        assert redacted["snmp"]["community"] != "s3cret"
+
+    def test_env_overrides(self, monkeypatch):
+        """Test SNMP settings from environment variables."""
+        monkeypatch.setenv("TLS_MONITOR_SNMP_ENABLED", "true")
+        monkeypatch.setenv("TLS_MONITOR_SNMP_PORT", "10161")
+        monkeypatch.setenv("TLS_MONITOR_SNMP_COMMUNITY", "monitoring")
+
+        config = load_config(None).snmp
+
+        assert config.enabled is True
+        assert config.port == 10161
+        assert config.community == "monitoring"
+
+    def test_ber(self):
+        """Test OIDs with large arcs and unsigned values round-trip."""
+        assert decode_oid(decode_tlv(encode_oid(parse_oid(BASE)))[1]) == parse_oid(BASE)
+        assert encode_integer(0xFFFFFFFF, COUNTER32) == b"\x41\x05\x00\xff\xff\xff\xff"
+        assert encode_integer(-2) == b"\x02\x01\xfe"
+
+    def test_get(self):
+        """Test scalars hold the summary of the last scan, silenced certificates excluded."""
+        agent = SnmpAgent(Config(), _scanner())
+        oids = [f"{BASE}.{n}.0" for n in range(1, 10)]
+
+        status, _, variables = _response(agent.handle(_request(GET_REQUEST, oids)))
+
+        assert status == 0
+        assert [value for _, _, value in variables] == [2, 1, 0, 1, 0, 1, 3, 1790000000, 7]
+        assert variables[0][1] == GAUGE32
+        assert variables[6][1] == INTEGER
+        assert variables[8][1] == COUNTER32
+
+    def test_get_missing(self):
+        """Test a missing object is noSuchObject in v2c and a noSuchName error in v1."""
+        agent = SnmpAgent(Config(), _scanner())
+        oids = [f"{BASE}.1.0", f"{BASE}.1"]
+
+        _, _, variables = _response(agent.handle(_request(GET_REQUEST, oids)))
+        assert variables[1][1] == NO_SUCH_OBJECT
+
+        status, index, variables = _response(
+            agent.handle(_request(GET_REQUEST, oids, version=VERSION_1))
+        )
+        assert (status, index) == (2, 2)
+        assert [tag for _, tag, _ in variables] == [NULL, NULL]
+
+    def test_walk(self):
+        """Test GetNext walks the objects in order and ends with endOfMibView."""
+        agent = SnmpAgent(Config(), _scanner())
+        walked = []
+        oid = "1.3.6.1"
+
+        while True:
+            _, _, [(oid, tag, _)] = _response(agent.handle(_request(GET_NEXT_REQUEST, [oid])))
+            if tag == END_OF_MIB_VIEW:
+                break
+            walked.append(oid)
+
+        assert walked == [f"{BASE}.{n}.0" for n in range(1, 10)]
+
+    def test_get_bulk(self):
+        """Test GetBulk returns repetitions of GetNext and is refused in v1."""
+        agent = SnmpAgent(Config(), _scanner())
+
+        _, _, variables = _response(
+            agent.handle(_request(GET_BULK_REQUEST, [BASE], fields=(0, 20)))
+        )
+        assert [oid for oid, _, _ in variables][:9] == [f"{BASE}.{n}.0" for n in range(1, 10)]
+        assert variables[-1][1] == END_OF_MIB_VIEW
+
+        assert agent.handle(_request(GET_BULK_REQUEST, [BASE], version=VERSION_1)) is None
+
+    def test_dropped(self):
+        """Test requests with a wrong community and malformed messages are dropped."""
+        agent = SnmpAgent(Config(), _scanner())
+
+        assert agent.handle(_request(GET_REQUEST, [f"{BASE}.1.0"], community=b"other")) is None
+        assert agent.handle(b"\x30\x10garbage") is None
+        assert agent.handle(b"") is None
+
+    def test_before_first_scan(self):
+        """Test objects are zero before the first scan."""
+        scanner = MagicMock(last_scan_results=None, last_scan_completed_at=None)
+        scanner.scans_completed = 0
+        agent = SnmpAgent(Config(), scanner)
+
+        _, _, variables = _response(agent.handle(_request(GET_REQUEST, [f"{BASE}.7.0"])))
+
+        assert variables[0][2] == 0
+
+    @pytest.mark.asyncio
+    async def test_udp(self):
+        """Test the agent answers requests over UDP."""
+        with socket.socket(socket.AF_INET, socket.SOCK_DGRAM) as probe:
+            probe.bind(("127.0.0.1", 0))
+            port = probe.getsockname()[1]
+        loop = asyncio.get_running_loop()
+        agent = SnmpAgent(Config(snmp=SnmpConfig(enabled=True, port=port)), _scanner())
+
+        await agent.start()
+        received: asyncio.Future = loop.create_future()
+
+        class Client(asyncio.DatagramProtocol):
+            def datagram_received(self, data, addr):
+                received.set_result(data)
+
+        client, _ = await loop.create_datagram_endpoint(Client, local_addr=("127.0.0.1", 0))
+        try:
+            client.sendto(_request(GET_REQUEST, [f"{BASE}.4.0"]), ("127.0.0.1", port))
+            _, _, variables = _response(await asyncio.wait_for(received, 5))
+        finally:
+            client.close()
+            await agent.stop()
+
+        assert variables == [(f"{BASE}.4.0", GAUGE32, 1)]
@@ -0,0 +1,114 @@
+"""
+Tests for systemd readiness notifications and watchdog pings.
+"""
+
+import asyncio
+import os
+import socket
+from unittest.mock import MagicMock
+
+import pytest
+
+from tls_cert_monitor import systemd
+from tls_cert_monitor.systemd import SystemdNotifier, notify, watchdog_interval
+
+
+@pytest.fixture
+def notify_socket(tmp_path, monkeypatch):
+    """Datagram socket receiving notifications, as systemd's NOTIFY_SOCKET."""
+    path = str(tmp_path / "notify")
+    sock = socket.socket(socket.AF_UNIX, socket.SOCK_DGRAM)
+    sock.bind(path)
+    sock.settimeout(2)
+    monkeypatch.setenv("NOTIFY_SOCKET", path)
+    yield sock
+    sock.close()
+
+
+def _first_scan_scanner(done: asyncio.Event):
+    """Scanner whose first wait for the first scan times out, then completes once done is set."""
+    waits = []
+
+    async def wait_for_first_scan(timeout):
+        waits.append(timeout)
+        if len(waits) == 1:
+            return False
+        await done.wait()
+        return True
+
+    scanner = MagicMock()
+    scanner.wait_for_first_scan = wait_for_first_scan
+    return scanner
+
+
+class TestSystemd:
+    """Test systemd notifications."""
+
+    def test_notify(self, notify_socket):
+        """Test states are sent to the notification socket."""
+        assert notify("READY=1") is True
+        assert notify_socket.recv(1024) == b"READY=1"
+
+    def test_outside_systemd(self, monkeypatch):
+        """Test nothing is sent without NOTIFY_SOCKET."""
+        monkeypatch.delenv("NOTIFY_SOCKET", raising=False)
+
+        assert notify("READY=1") is False
+
+    def test_watchdog_interval(self, monkeypatch):
+        """Test the watchdog is pinged at half its interval, only by the process it is meant for."""
+        monkeypatch.delenv("WATCHDOG_PID", raising=False)
+        monkeypatch.delenv("WATCHDOG_USEC", raising=False)
+        assert watchdog_interval() is None
+
+        monkeypatch.setenv("WATCHDOG_USEC", "30000000")
+        assert watchdog_interval() == 15
+
+        monkeypatch.setenv("WATCHDOG_PID", str(os.getppid()))
+        assert watchdog_interval() == 15
+
+        monkeypatch.setenv("WATCHDOG_PID", str(max(os.getpid(), os.getppid()) + 1))
+        assert watchdog_interval() is None
+
+    @pytest.mark.asyncio
+    async def test_ready_after_first_scan(self, monkeypatch):
+        """Test READY=1 is sent once the first scan completed and the server listens."""
+        monkeypatch.setenv("NOTIFY_SOCKET", "/run/systemd/notify")
+        monkeypatch.setenv("WATCHDOG_USEC", "60000000")
+        monkeypatch.delenv("WATCHDOG_PID", raising=False)
+        monkeypatch.setattr(systemd, "READY_POLL_SECONDS", 0)
+        states = []
+        sent = {"WATCHDOG=1": asyncio.Event(), "READY=1": asyncio.Event()}
+
+        def record(state):
+            states.append(state)
+            sent.get(state.split("\n")[0], asyncio.Event()).set()
+            return True
+
+        monkeypatch.setattr(systemd, "notify", record)
+        done = asyncio.Event()
+        polled = asyncio.Event()
+        started = []
+
+        def server_started():
+            polled.set()
+            return bool(started)
+
+        notifier = SystemdNotifier(_first_scan_scanner(done), server_started)
+
+        await notifier.start()
+        await asyncio.wait_for(sent["WATCHDOG=1"].wait(), 5)
+        done.set()
+        await asyncio.wait_for(polled.wait(), 5)
+        assert not sent["READY=1"].is_set()
+        started.append(True)
+        await asyncio.wait_for(sent["READY=1"].wait(), 5)
+        await notifier.stop()
+
+        assert states.count("WATCHDOG=1") == 1
+        waiting = f"STATUS=Waiting for the first scan ({systemd.FIRST_SCAN_WAIT_SECONDS}s)"
+        assert [state for state in states if state != "WATCHDOG=1"] == [
+            waiting,
+            "READY=1\nSTATUS=Serving metrics",
+            "STOPPING=1",
+        ]
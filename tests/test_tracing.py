@@ -0,0 +1,145 @@
+"""
+Tests for OpenTelemetry tracing.
+"""
+
+from contextlib import contextmanager
+from unittest.mock import MagicMock, patch
+
+import pytest
+from pydantic import ValidationError
+
+from tls_cert_monitor import tracing
+from tls_cert_monitor.cache import CacheManager
+from tls_cert_monitor.config import Config, TracingConfig, load_config, redact_config
+from tls_cert_monitor.metrics import MetricsCollector
+from tls_cert_monitor.scanner import CertificateScanner
+
+
+class FakeSpan:
+    """Span recording its name, attributes and parent."""
+
+    def __init__(self, name, attributes, parent):
+        self.name = name
+        self.attributes = dict(attributes or {})
+        self.parent = parent
+
+    def set_attribute(self, key, value):
+        self.attributes[key] = value
+
+
+class FakeTracer:
+    """Tracer recording the spans started, nested by the current span."""
+
+    def __init__(self):
+        self.spans = []
+        self._current = None
+
+    @contextmanager
+    def start_as_current_span(self, name, attributes=None, **kwargs):
+        span = FakeSpan(name, attributes, self._current)
+        self.spans.append(span)
+        parent, self._current = self._current, span
+        try:
+            yield span
+        finally:
+            self._current = parent
+
+
+@pytest.fixture
+def tracer(monkeypatch):
+    """Fake tracer recording spans, as set up by setup_tracing."""
+    fake = FakeTracer()
+    monkeypatch.setattr(tracing, "_tracer", fake)
+    monkeypatch.setattr(tracing, "_file_spans", True)
+    return fake
+
+
+class TestTracing:
+    """Test OpenTelemetry tracing."""
+
+    def test_config(self):
+        """Test tracing settings are validated."""
+        assert TracingConfig().protocol == "http/protobuf"
+        with pytest.raises(ValidationError):
+            TracingConfig(protocol="thrift")
+        with pytest.raises(ValidationError):
+            TracingConfig(sample_ratio=1.5)
+
+    def test_env_overrides(self, monkeypatch):
+        """Test tracing settings from environment variables."""
+        monkeypatch.setenv("TLS_MONITOR_TRACING_ENABLED", "true")
+        monkeypatch.setenv("TLS_MONITOR_TRACING_ENDPOINT", "otel-collector:4317")
+        monkeypatch.setenv("TLS_MONITOR_TRACING_PROTOCOL", "grpc")
+
+        config = load_config(None).tracing
+
+        assert config.enabled is True
+        assert config.endpoint == "otel-collector:4317"
+        assert config.protocol == "grpc"
+
+    def test_disabled(self):
+        """Test spans are no-ops while tracing is disabled."""
+        tracing.setup_tracing(TracingConfig())
+
+        assert not tracing.tracing_enabled()
+        with tracing.start_span("scan") as span:
+            assert span is None
+        with tracing.start_request_span("GET", "/metrics", {}) as span:
+            assert span is None
+
+    def test_missing_sdk(self):
+        """Test enabling tracing without the OpenTelemetry packages fails clearly."""
+        with patch.dict("sys.modules", {"opentelemetry": None}):
+            with pytest.raises(RuntimeError, match=r"pip install \.\[tracing\]"):
+                tracing.setup_tracing(TracingConfig(enabled=True))
+        assert not tracing.tracing_enabled()
+
+    def test_redacted_headers(self):
+        """Test collector headers are redacted from the reported configuration."""
+        config = Config(tracing=TracingConfig(headers={"x-api-key": "secret"}))
+
+        assert "secret" not in str(redact_config(config))
+
+    @pytest.mark.asyncio
+    async def test_scan_spans(self, tmp_path, tracer):
+        """Test a scan is traced with spans per directory and file."""
+        (tmp_path / "site.pem").write_text("not a certificate")
+        config = Config(certificate_directories=[str(tmp_path)], cache_dir="")
+        with patch("tls_cert_monitor.scanner.get_logger"):
+            scanner = CertificateScanner(
+                config=config, cache=CacheManager(config), metrics=MagicMock(spec=MetricsCollector)
+            )
+
+        try:
+            await scanner.scan_once()
+        finally:
+            await scanner.stop()
+
+        scan, directory, file = tracer.spans
+        assert scan.name == "scan"
+        assert scan.parent is None
+        assert directory.name == "scan.directory"
+        assert directory.parent is scan
+        assert directory.attributes["scan.files"] == 1
+        assert directory.attributes["scan.errors"] == 1
+        assert file.name == "scan.file"
+        assert file.attributes["file.path"] == str(tmp_path / "site.pem")
+        assert "error.type" in file.attributes
+
+    @pytest.mark.asyncio
+    async def test_file_spans_off(self, tmp_path, tracer, monkeypatch):
+        """Test file spans can be turned off."""
+        monkeypatch.setattr(tracing, "_file_spans", False)
+        (tmp_path / "site.pem").write_text("not a certificate")
+        config = Config(certificate_directories=[str(tmp_path)], cache_dir="")
+        with patch("tls_cert_monitor.scanner.get_logger"):
+            scanner = CertificateScanner(
+                config=config, cache=CacheManager(config), metrics=MagicMock(spec=MetricsCollector)
+            )
+
+        try:
+            await scanner.scan_once()
+        finally:
+            await scanner.stop()
+
+        assert [span.name for span in tracer.spans] == ["scan", "scan.directory"]
@@ -0,0 +1,161 @@
+"""
+Tests for the Zabbix sender.
+"""
+
+import asyncio
+import json
+import struct
+from unittest.mock import MagicMock
+
+import pytest
+from pydantic import ValidationError
+
+from tls_cert_monitor.config import Config, ZabbixConfig, load_config
+from tls_cert_monitor.zabbix import HEADER, ZabbixSender, build_items, item_key, send_items
+
+
+def _results():
+    """Scan results with a critical and a silenced expired certificate."""
+    return {
+        "directories": {
+            "/etc/ssl": {
+                "certificates": [
+                    {
+                        "path": "/etc/ssl/api.pem",
+                        "common_name": "api.example.com",
+                        "severity": "critical",
+                        "days_until_expiry": 3,
+                        "expiration_timestamp": 1790000000.5,
+                    },
+                    {
+                        "path": '/etc/ssl/old "v1".pem',
+                        "common_name": "old.example.com",
+                        "severity": "expired",
+                        "days_until_expiry": -2,
+                        "expiration_timestamp": 1780000000,
+                        "silenced": True,
+                    },
+                ]
+            }
+        },
+        "summary": {"total_errors": 1},
+    }
+
+
+async def _trapper(response, received):
+    """Start a fake Zabbix trapper recording the data it receives."""
+
+    async def handle(reader, writer):
+        header = await reader.readexactly(len(HEADER) + 8)
+        length = struct.unpack("<I", header[len(HEADER) : len(HEADER) + 4])[0]
+        received.append(json.loads(await reader.readexactly(length)))
+        payload = json.dumps(response).encode()
+        writer.write(HEADER + struct.pack("<II", len(payload), 0) + payload)
+        await writer.drain()
+        writer.close()
+
+    return await asyncio.start_server(handle, "127.0.0.1", 0)
+
+
+class TestZabbix:
+    """Test pushing items to Zabbix."""
+
+    def test_config(self):
+        """Test an enabled sender needs a valid server address."""
+        assert ZabbixConfig(server="zabbix.example.com").server_address() == (
+            "zabbix.example.com",
+            10051,
+        )
+        with pytest.raises(ValidationError):
+            ZabbixConfig(enabled=True)
+        with pytest.raises(ValidationError):
+            ZabbixConfig(server="zabbix.example.com:port")
+        with pytest.raises(ValidationError):
+            ZabbixConfig(key_prefix="tls cert")
+
+    def test_env_overrides(self, monkeypatch):
+        """Test Zabbix settings from environment variables."""
+        monkeypatch.setenv("TLS_MONITOR_ZABBIX_ENABLED", "true")
+        monkeypatch.setenv("TLS_MONITOR_ZABBIX_SERVER", "zabbix.example.com:10052")
+        monkeypatch.setenv("TLS_MONITOR_ZABBIX_HOST", "web-01")
+
+        config = load_config(None).zabbix
+
+        assert config.enabled is True
+        assert config.server_address() == ("zabbix.example.com", 10052)
+        assert config.host == "web-01"
+
+    def test_items(self):
+        """Test discovery, per-certificate and summary items are built."""
+        items = {item["key"]: item for item in build_items(_results(), "web-01", "tls_cert", 100)}
+
+        discovery = json.loads(items["tls_cert.discovery"]["value"])
+        assert discovery[0] == {
+            "{#PATH}": "/etc/ssl/api.pem",
+            "{#CN}": "api.example.com",
+            "{#DIRECTORY}": "/etc/ssl",
+        }
+        assert items['tls_cert.days["/etc/ssl/api.pem"]']["value"] == "3"
+        assert items['tls_cert.expiry["/etc/ssl/api.pem"]']["value"] == "1790000000"
+        assert items['tls_cert.severity["/etc/ssl/api.pem"]']["value"] == "2"
+        assert item_key("tls_cert", "days", '/etc/ssl/old "v1".pem') in items
+        # The silenced certificate is not counted
+        assert items['tls_cert.certificates["critical"]']["value"] == "1"
+        assert items['tls_cert.certificates["expired"]']["value"] == "0"
+        assert items["tls_cert.parse_errors"]["value"] == "1"
+        assert {item["host"] for item in items.values()} == {"web-01"}
+        assert {item["clock"] for item in items.values()} == {100}
+
+    def test_item_key_quoting(self):
+        """Test quotes in parameters are escaped."""
+        assert item_key("p", "days", 'a "b"') == 'p.days["a \\"b\\""]'
+
+    @pytest.mark.asyncio
+    async def test_send(self):
+        """Test items are sent with the sender protocol."""
+        received = []
+        info = "processed: 2; failed: 0; total: 2; seconds spent: 0.000050"
+        server = await _trapper({"response": "success", "info": info}, received)
+        port = server.sockets[0].getsockname()[1]
+        items = build_items(_results(), "web-01", "tls_cert", 100)
+
+        try:
+            response = await send_items(("127.0.0.1", port), items, timeout=5)
+        finally:
+            server.close()
+
+        assert response["info"] == info
+        assert received == [{"request": "sender data", "data": items}]
+
+    @pytest.mark.asyncio
+    async def test_rejected(self):
+        """Test a failed response raises."""
+        server = await _trapper({"response": "failed", "info": "bad data"}, [])
+        port = server.sockets[0].getsockname()[1]
+
+        try:
+            with pytest.raises(ValueError, match="bad data"):
+                await send_items(("127.0.0.1", port), [], timeout=5)
+        finally:
+            server.close()
+
+    @pytest.mark.asyncio
+    async def test_sender_after_scan(self):
+        """Test the sender pushes after every scan and logs failures instead of raising."""
+        received = []
+        server = await _trapper({"response": "success", "info": "processed: 1"}, received)
+        port = server.sockets[0].getsockname()[1]
+        config = Config(zabbix=ZabbixConfig(enabled=True, server=f"127.0.0.1:{port}", host="h"))
+        scanner = MagicMock()
+        sender = ZabbixSender(config, scanner)
+        listener = scanner.add_scan_listener.call_args[0][0]
+
+        try:
+            await listener(_results())
+        finally:
+            server.close()
+            await server.wait_closed()
+
+        assert received[0]["data"][0]["host"] == "h"
+        assert sender.last_sent is not None
+        assert await sender.send(_results()) is False
@@ -0,0 +1,469 @@
+"""
+Built-in alert evaluation for TLS Certificate Monitor.
+"""
+
+import fnmatch
+import time
+from dataclasses import replace
+from pathlib import Path
+from typing import Any, Dict, Iterator, List, Optional, Tuple
+
+from tls_cert_monitor.config import AlertsConfig, Config
+from tls_cert_monitor.logger import get_logger
+from tls_cert_monitor.notifiers import AlertmanagerNotifier, Notification, Notifier, dispatch
+from tls_cert_monitor.scanner import CertificateScanner
+
+# Certificate fields reported for old and new content in change alerts
+_CHANGE_FIELDS = ("common_name", "serial", "not_after", "fingerprint_sha256")
+
+# Scan intervals a firing alert stays open in Alertmanager without being sent again,
+# so a late or failed scan doesn't resolve it
+ALERT_HOLD_SCANS = 3
+
+
+def _iter_certificates(scan_results: Dict[str, Any]) -> Iterator[Dict[str, Any]]:
+    """Iterate over certificates from all directories in scan results."""
+    for directory_result in scan_results.get("directories", {}).values():
+        yield from directory_result.get("certificates", [])
+
+
+def _describe_certificate(cert: Dict[str, Any]) -> List[str]:
+    """Common certificate detail lines for alert bodies."""
+    return [
+        f"Common name: {cert.get('common_name', 'unknown')}",
+        f"Path: {cert.get('path', '')}",
+        f"Issuer: {cert.get('issuer', '')}",
+        f"Serial: {cert.get('serial', '')}",
+        f"Not after: {cert.get('not_after', '')}",
+    ]
+
+
+class AlertRouter:
+    """
+    Select notifiers for an alert from the configured routes.
+
+    Routes are evaluated in order and the first matching route wins unless it
+    sets continue. Alerts matching no route go to the default notifiers.
+    """
+
+    def __init__(self, alerts_config: AlertsConfig, notifiers: Dict[str, Notifier]):
+        self.default = [notifiers[name] for name in alerts_config.notifiers]
+        self.routes = [
+            (
+                route,
+                [notifiers[name] for name in route.notifiers],
+                [Path(directory).resolve() for directory in route.directories],
+            )
+            for route in alerts_config.routes
+        ]
+
+    @staticmethod
+    def _matches_directories(notification: Notification, directories: List[Path]) -> bool:
+        """Check if the alert's file or directory lies within any of the directories."""
+        if not directories:
+            return True
+        target = notification.labels.get("path") or notification.labels.get("directory")
+        if not target:
+            return False
+        target_path = Path(target)
+        return any(target_path.is_relative_to(directory) for directory in directories)
+
+    @staticmethod
+    def _matches_labels(notification: Notification, matchers: Dict[str, str]) -> bool:
+        """Check if all matchers match the alert labels or severity (case-insensitive globs)."""
+        values = {**notification.labels, "severity": notification.severity}
+        for label, pattern in matchers.items():
+            value = values.get(label)
+            if value is None or not fnmatch.fnmatchcase(str(value).lower(), pattern.lower()):
+                return False
+        return True
+
+    def route(self, notification: Notification) -> List[Notifier]:
+        """
+        Get the notifiers an alert should be sent to.
+
+        Args:
+            notification: Alert notification
+
+        Returns:
+            Notifiers from matching routes, or the default notifiers
+        """
+        selected: List[Notifier] = []
+
+        for route, targets, directories in self.routes:
+            if not self._matches_directories(notification, directories):
+                continue
+            if not self._matches_labels(notification, route.matchers):
+                continue
+
+            selected.extend(target for target in targets if target not in selected)
+            if not route.continue_matching:
+                break
+
+        return selected or self.default
+
+
+class AlertEngine:
+    """
+    Evaluate alert rules against each completed scan and notify on new conditions.
+
+    Rules fire once when a condition first appears and are re-armed when it
+    goes away; the expiry rule fires again when a certificate moves between the
+    warning and critical tiers, and sends a resolved notification. Silenced
+    certificates never fire; they are evaluated again once their silence ends.
+    Alertmanager notifiers also get every still-firing alert on each evaluation,
+    since Alertmanager resolves alerts that aren't pushed again.
+    """
+
+    def __init__(
+        self,
+        config: Config,
+        scanner: CertificateScanner,
+        notifiers: Dict[str, Notifier],
+    ):
+        self.config = config
+        self.scanner = scanner
+        self.router = AlertRouter(config.alerts, notifiers)
+        self.logger = get_logger("alerts")
+
+        # (path, serial, problem) -> alert raised for weak crypto still present
+        self._weak_crypto_firing: Dict[Tuple[str, str, str], Notification] = {}
+        # path -> certificate last seen in that file (None until the first scan)
+        self._file_contents: Optional[Dict[str, Dict[str, Any]]] = None
+        # Certificate key -> alert for the certificate currently in the warning or critical tier
+        self._expiry_firing: Dict[str, Notification] = {}
+        # Expiry alerts of the previous tier of certificates that changed tiers in this evaluation
+        self._expiry_superseded: List[Notification] = []
+        # Directory -> alert for the directory currently over the parse error threshold
+        self._parse_error_firing: Dict[str, Notification] = {}
+
+        scanner.add_scan_listener(self.evaluate)
+
+    async def evaluate(self, scan_results: Dict[str, Any]) -> List[Notification]:
+        """
+        Evaluate alert rules against scan results and send new alerts.
+
+        Args:
+            scan_results: Results from CertificateScanner.scan_once()
+
+        Returns:
+            Notifications raised by this evaluation
+        """
+        notifications: List[Notification] = []
+        self._expiry_superseded = []
+
+        if self.config.alerts.expiry:
+            notifications.extend(self._check_expiry(scan_results))
+        if self.config.alerts.weak_crypto:
+            notifications.extend(self._check_weak_crypto(scan_results))
+        if self.config.alerts.content_change:
+            notifications.extend(self._check_content_change(scan_results))
+        if self.config.alerts.parse_error_count or self.config.alerts.parse_error_ratio:
+            notifications.extend(self._check_parse_errors(scan_results))
+
+        firing = self._firing_alerts()
+        ends_at = time.time() + ALERT_HOLD_SCANS * self.config.scan_interval_seconds
+        for notification in firing:
+            notification.ends_at = ends_at
+
+        for notification in notifications:
+            await dispatch(
+                self.router.route(notification), notification, dry_run=self.config.dry_run
+            )
+
+        if notifications:
+            self.logger.info(f"Raised {len(notifications)} alert(s)")
+
+        if not self.config.dry_run:
+            raised = {id(notification) for notification in notifications}
+            await self._refresh_firing(
+                self._expiry_superseded
+                + [notification for notification in firing if id(notification) not in raised]
+            )
+
+        return notifications
+
+    def _firing_alerts(self) -> List[Notification]:
+        """Get the alerts of enabled rules whose condition is still present."""
+        firing: List[Notification] = []
+        if self.config.alerts.expiry:
+            firing.extend(self._expiry_firing.values())
+        if self.config.alerts.weak_crypto:
+            firing.extend(self._weak_crypto_firing.values())
+        if self.config.alerts.parse_error_count or self.config.alerts.parse_error_ratio:
+            firing.extend(self._parse_error_firing.values())
+        return firing
+
+    async def _refresh_firing(self, firing: List[Notification]) -> None:
+        """
+        Send alerts again to Alertmanager notifiers: still-firing ones to keep them open and
+        resolved ones for the previous tier of certificates that changed expiry tiers (the
+        severity label is part of an Alertmanager alert's identity).
+        """
+        for notification in firing:
+            for notifier in self.router.route(notification):
+                if not isinstance(notifier, AlertmanagerNotifier):
+                    continue
+                try:
+                    await notifier.send(notification)
+                except Exception as e:
+                    self.logger.warning(
+                        f"Failed to refresh alert in '{notifier.name}': {notification.title}: {e}"
+                    )
+
+    @staticmethod
+    def _certificate_key(cert: Dict[str, Any]) -> str:
+        """Identify a certificate by fingerprint (path and serial for older cache entries)."""
+        return cert.get("fingerprint_sha256") or f"{cert.get('path', '')}#{cert.get('serial', '')}"
+
+    def _check_expiry(self, scan_results: Dict[str, Any]) -> List[Notification]:
+        """
+        Alert when a certificate enters the warning or critical window and resolve once it's gone.
+
+        The alert has the severity of its tier (expired certificates are critical);
+        moving to the other tier raises the alert again with the same labels and
+        the new severity. A firing certificate resolves when it is no longer in
+        either window, typically because the file now holds a renewed certificate.
+        Silenced certificates don't fire or change tiers, but a firing certificate
+        stays open while silenced.
+        """
+        notifications = []
+        in_window: Dict[str, Dict[str, Any]] = {}
+        by_path: Dict[str, Dict[str, Any]] = {}
+
+        for cert in _iter_certificates(scan_results):
+            by_path[cert.get("path", "")] = cert
+            if cert.get("severity") in ("warning", "critical", "expired"):
+                in_window[self._certificate_key(cert)] = cert
+
+        for key, cert in in_window.items():
+            tier = "warning" if cert.get("severity") == "warning" else "critical"
+            firing = self._expiry_firing.get(key)
+            if cert.get("silenced") or (firing is not None and firing.severity == tier):
+                continue
+
+            if cert.get("severity") == "expired":
+                state = "has expired"
+            else:
+                days = int((cert.get("expiration_timestamp", 0) - time.time()) // 86400)
+                state = f"expires in {days} days"
+            threshold = getattr(self.config.expiry_thresholds, tier)
+            body = [f"The certificate {state} ({tier} threshold {threshold})."]
+            if firing is not None:
+                body.append(f"Previously alerted as {firing.severity}.")
+                self._expiry_superseded.append(replace(firing, status="resolved"))
+            self._expiry_firing[key] = Notification(
+                title=f"Certificate {state}: {cert.get('common_name')} ({cert.get('path')})",
+                body="\n".join(body + _describe_certificate(cert)),
+                severity=tier,
+                labels=self._expiry_labels(key, cert),
+                data=cert,
+                starts_at=firing.starts_at if firing is not None else time.time(),
+            )
+            notifications.append(self._expiry_firing[key])
+
+        for key in list(self._expiry_firing):
+            if key in in_window:
+                continue
+
+            firing = self._expiry_firing.pop(key)
+            cert = firing.data
+            replacement = by_path.get(cert.get("path", ""))
+            if replacement is not None:
+                resolution = (
+                    f"Renewed: serial {replacement.get('serial')}, "
+                    f"not after {replacement.get('not_after')}"
+                )
+            else:
+                resolution = "The certificate is no longer present"
+
+            notifications.append(
+                Notification(
+                    title=f"Resolved: certificate {cert.get('common_name')} ({cert.get('path')})",
+                    body="\n".join(
+                        [f"The {firing.severity} expiry condition has cleared. {resolution}."]
+                        + _describe_certificate(cert)
+                    ),
+                    severity=firing.severity,
+                    status="resolved",
+                    labels=self._expiry_labels(key, cert),
+                    data={"previous": cert, "current": replacement},
+                    starts_at=firing.starts_at,
+                )
+            )
+
+        return notifications
+
+    @staticmethod
+    def _expiry_labels(key: str, cert: Dict[str, Any]) -> Dict[str, str]:
+        """Labels shared by firing and resolved expiry alerts, used for deduplication."""
+        return {
+            **cert.get("labels", {}),
+            "rule": "expiry",
+            "path": cert.get("path", ""),
+            "common_name": cert.get("common_name", ""),
+            "fingerprint": key,
+        }
+
+    def _check_weak_crypto(self, scan_results: Dict[str, Any]) -> List[Notification]:
+        """Alert the first time a weak key or deprecated signature algorithm appears."""
+        notifications = []
+        present: Dict[Tuple[str, str, str], Notification] = {}
+
+        for cert in _iter_certificates(scan_results):
+            if cert.get("silenced"):
+                continue
+
+            problems = []
+            if cert.get("is_weak_key"):
+                key_description = f"{cert.get('key_algorithm')} key ({cert.get('key_size')} bits)"
+                problems.append(("weak_key", f"weak {key_description}"))
+            if cert.get("is_deprecated_algorithm"):
+                problems.append(
+                    (
+                        "deprecated_algorithm",
+                        f"deprecated signature algorithm {cert.get('signature_algorithm')}",
+                    )
+                )
+
+            for problem, description in problems:
+                key = (cert.get("path", ""), cert.get("serial", ""), problem)
+                if key in self._weak_crypto_firing:
+                    present[key] = self._weak_crypto_firing[key]
+                    continue
+
+                present[key] = Notification(
+                    title=f"Certificate with {description}: {cert.get('common_name')}",
+                    body="\n".join(
+                        [f"A certificate with a {description} was detected."]
+                        + _describe_certificate(cert)
+                    ),
+                    severity="warning",
+                    labels={
+                        **cert.get("labels", {}),
+                        "rule": problem,
+                        "path": cert.get("path", ""),
+                        "common_name": cert.get("common_name", ""),
+                    },
+                    data=cert,
+                )
+                notifications.append(present[key])
+
+        # Forget conditions that are gone so they alert again if they come back
+        self._weak_crypto_firing = present
+        return notifications
+
+    def _check_content_change(self, scan_results: Dict[str, Any]) -> List[Notification]:
+        """Alert when the certificate stored in a monitored file changes."""
+        notifications = []
+        current = {cert.get("path", ""): cert for cert in _iter_certificates(scan_results)}
+
+        # The first scan only records the baseline
+        previous_contents = self._file_contents
+        self._file_contents = current
+        if previous_contents is None:
+            return notifications
+
+        for path, cert in current.items():
+            previous = previous_contents.get(path)
+            if previous is None or cert.get("silenced"):
+                continue
+
+            # Older cache entries may lack a fingerprint; fall back to the serial
+            if "fingerprint_sha256" in cert and "fingerprint_sha256" in previous:
+                changed = cert["fingerprint_sha256"] != previous["fingerprint_sha256"]
+            else:
+                changed = cert.get("serial") != previous.get("serial")
+            if not changed:
+                continue
+
+            renewed = cert.get("common_name") == previous.get("common_name") and (
+                cert.get("expiration_timestamp", 0) > previous.get("expiration_timestamp", 0)
+            )
+            change = "renewed" if renewed else "replaced"
+
+            notifications.append(
+                Notification(
+                    title=f"Certificate {change}: {cert.get('common_name')} ({path})",
+                    body="\n".join(
+                        [
+                            f"The certificate in {path} was {change}.",
+                            f"Old: {previous.get('common_name')} serial {previous.get('serial')}, "
+                            f"not after {previous.get('not_after')}",
+                            f"New: {cert.get('common_name')} serial {cert.get('serial')}, "
+                            f"not after {cert.get('not_after')}",
+                            f"Issuer: {cert.get('issuer', '')}",
+                        ]
+                    ),
+                    severity="info" if renewed else "warning",
+                    labels={
+                        **cert.get("labels", {}),
+                        "rule": "content_change",
+                        "path": path,
+                        "common_name": cert.get("common_name", ""),
+                        "change": change,
+                    },
+                    data={
+                        "path": path,
+                        "change": change,
+                        "old": {key: previous.get(key) for key in _CHANGE_FIELDS},
+                        "new": {key: cert.get(key) for key in _CHANGE_FIELDS},
+                    },
+                )
+            )
+
+        return notifications
+
+    def _check_parse_errors(self, scan_results: Dict[str, Any]) -> List[Notification]:
+        """Alert when a directory's parse errors exceed the configured count or ratio."""
+        notifications = []
+        count_threshold = self.config.alerts.parse_error_count
+        ratio_threshold = self.config.alerts.parse_error_ratio
+        breached: Dict[str, Notification] = {}
+
+        for directory, directory_result in scan_results.get("directories", {}).items():
+            errors = directory_result.get("parse_errors", 0)
+            files = directory_result.get("files_processed", 0)
+            ratio = errors / files if files else 0.0
+
+            reasons = []
+            if count_threshold and errors >= count_threshold:
+                reasons.append(f"{errors} parse errors (threshold {count_threshold})")
+            if ratio_threshold and files and ratio >= ratio_threshold:
+                reasons.append(
+                    f"{ratio:.0%} of files failed to parse (threshold {ratio_threshold:.0%})"
+                )
+            if not reasons:
+                continue
+
+            if directory in self._parse_error_firing:
+                breached[directory] = self._parse_error_firing[directory]
+                continue
+
+            body = [
+                f"Parse errors in {directory} exceeded the alert threshold: {', '.join(reasons)}.",
+                f"Files processed: {files}, parse errors: {errors}",
+            ]
+            if directory_result.get("error"):
+                body.append(f"Scan error: {directory_result['error']}")
+
+            breached[directory] = Notification(
+                title=f"Certificate parse errors in {directory}: {errors}/{files} files",
+                body="\n".join(body),
+                severity="warning",
+                labels={
+                    **directory_result.get("labels", {}),
+                    "rule": "parse_errors",
+                    "directory": directory,
+                },
+                data={
+                    "directory": directory,
+                    "files_processed": files,
+                    "parse_errors": errors,
+                    "error_ratio": ratio,
+                },
+            )
+            notifications.append(breached[directory])
+
+        self._parse_error_firing = breached
+        return notifications
@@ -6,20 +6,54 @@ import asyncio
 import html
 import ipaddress
 import os
-import shutil
+import re
+import time
+import uuid
 from contextlib import asynccontextmanager
-from typing import Any, AsyncGenerator, Awaitable, Callable, Dict, Optional, Union
+from functools import partial
+from typing import Any, AsyncGenerator, Awaitable, Callable, Dict, List, Optional
 
 from fastapi import FastAPI, HTTPException, Request, Response
 from fastapi.middleware.cors import CORSMiddleware
-from fastapi.responses import JSONResponse, PlainTextResponse
+from fastapi.responses import JSONResponse, PlainTextResponse, StreamingResponse
 
 from tls_cert_monitor import __version__
+from tls_cert_monitor.audit import audit
 from tls_cert_monitor.cache import CacheManager, bytes_to_mib
-from tls_cert_monitor.config import Config
-from tls_cert_monitor.logger import get_logger
-from tls_cert_monitor.metrics import MetricsCollector
+from tls_cert_monitor.changes import ChangeTracker
+from tls_cert_monitor.cleanup import DEFAULT_EXPIRED_DAYS, build_cleanup
+from tls_cert_monitor.config import LOG_LEVELS, Config, SilenceConfig, redact_config
+from tls_cert_monitor.cyclonedx import BOM_MEDIA_TYPE, build_bom
+from tls_cert_monitor.grafana import build_dashboard
+from tls_cert_monitor.health import (
+    HEALTHY,
+    UNHEALTHY,
+    HealthCheck,
+    HealthChecker,
+    check_statuses,
+    disk_usage,
+    disk_usage_key,
+    evaluate_health,
+    run_blocking,
+    run_checks,
+)
+from tls_cert_monitor.history import CertificateHistory
+from tls_cert_monitor.hot_reload import HotReloadManager
+from tls_cert_monitor.logger import get_log_level, get_logger, request_id_var, set_log_level
+from tls_cert_monitor.metrics import MetricsCollector, count_certificates_by_severity
+from tls_cert_monitor.output import certificates_of
+from tls_cert_monitor.remote_config import RemoteConfigError
 from tls_cert_monitor.scanner import CertificateScanner
+from tls_cert_monitor.tracing import start_request_span
+
+# Keys of the terse /healthz response (the default; ?verbose=true returns all health data)
+TERSE_HEALTH_KEYS = ("status", "health_reasons", "failing_checks", "version")
+
+# Request ID header, honored when set by the client or a reverse proxy and echoed in responses
+REQUEST_ID_HEADER = "X-Request-ID"
+
+# Client request IDs are kept if they are short and safe to log; others are replaced
+REQUEST_ID_PATTERN = re.compile(r"^[A-Za-z0-9._:+/=-]{1,128}$")
 
 
 @asynccontextmanager
@@ -48,6 +82,10 @@ def create_app(
     cache: CacheManager,
     config: Config,
     lifespan_override: Optional[Any] = None,
+    hot_reload: Optional[HotReloadManager] = None,
+    health_checker: Optional[HealthChecker] = None,
+    history: Optional[CertificateHistory] = None,
+    changes: Optional[ChangeTracker] = None,
 ) -> FastAPI:
     """
     Create and configure FastAPI application.
@@ -57,10 +95,18 @@ def create_app(
         metrics: Metrics collector instance
         cache: Cache manager instance
         config: Configuration instance
+        hot_reload: Hot reload manager, for the configuration reload history
+        health_checker: Health checks registered by other subsystems, run by /healthz
+        history: Certificate history, queried by /api/v1/history
+        changes: Scan change tracker, served by /api/v1/changes and /api/v1/events
 
     Returns:
         Configured FastAPI application
     """
+    def current_cache() -> CacheManager:
+        # Hot reload replaces the scanner's cache when cache settings change
+        return getattr(scanner, "cache", None) or cache
+
     app = FastAPI(
         title="TLS Certificate Monitor",
         description="Cross-platform TLS certificate monitoring application",
@@ -80,6 +126,27 @@ def create_app(
 
     logger = get_logger("api")
 
+    def audit_request(
+        request: Request, action: str, outcome: str = "success", **details: Any
+    ) -> None:
+        """Record an API operation in the audit log, with the client and its principal."""
+        principal_header = scanner.config.audit_log.principal_header
+        principal = request.headers.get(principal_header.lower()) if principal_header else None
+        forwarded_for = request.headers.get("x-forwarded-for")
+        if forwarded_for:
+            details["forwarded_for"] = forwarded_for
+        remote_addr = request.client.host if request.client else None
+        audit(action, remote_addr, principal, outcome, **details)
+
+    @app.exception_handler(HTTPException)
+    async def http_exception_handler(request: Request, exc: HTTPException) -> Response:
+        """Return HTTP errors with the request ID, to match them with log lines."""
+        return JSONResponse(
+            status_code=exc.status_code,
+            content={"detail": exc.detail, "request_id": request_id_var.get()},
+            headers=exc.headers,
+        )
+
     @app.middleware("http")
     async def ip_whitelist_middleware(
         request: Request, call_next: Callable[[Request], Awaitable[Response]]
@@ -126,6 +193,7 @@ def create_app(
                     "error": "Access forbidden",
                     "message": "Your IP address is not allowed to access this service",
                     "client_ip": client_ip,
+                    "request_id": request_id_var.get(),
                 },
             )
 
@@ -134,9 +202,105 @@ def create_app(
         response = await call_next(request)
         return response
 
+    # Added after the IP whitelist, so it runs first and denied requests are traced too
+    @app.middleware("http")
+    async def tracing_middleware(
+        request: Request, call_next: Callable[[Request], Awaitable[Response]]
+    ) -> Response:
+        """Middleware tracing requests (a no-op while tracing is disabled)."""
+        with start_request_span(request.method, request.url.path, request.headers) as span:
+            response = await call_next(request)
+            if span is not None:
+                span.set_attribute("http.response.status_code", response.status_code)
+                route = getattr(request, "scope", {}).get("route")
+                if route is not None:
+                    span.set_attribute("http.route", route.path)
+                    span.update_name(f"{request.method} {route.path}")
+            return response
+
+    # Added last, so it runs first and every log line of a request carries its ID
+    @app.middleware("http")
+    async def request_id_middleware(
+        request: Request, call_next: Callable[[Request], Awaitable[Response]]
+    ) -> Response:
+        """Middleware assigning requests an ID, the client's X-Request-ID if it is valid."""
+        request_id = request.headers.get(REQUEST_ID_HEADER.lower())
+        if not request_id or not REQUEST_ID_PATTERN.match(request_id):
+            request_id = uuid.uuid4().hex
+        token = request_id_var.set(request_id)
+        start_time = time.monotonic()
+        try:
+            response = await call_next(request)
+            logger.debug(
+                f"{request.method} {request.url.path} {response.status_code} "
+                f"({(time.monotonic() - start_time) * 1000:.1f} ms)"
+            )
+            response.headers[REQUEST_ID_HEADER] = request_id
+            return response
+        finally:
+            request_id_var.reset(token)
+
+    async def collect_health() -> Dict[str, Any]:
+        """Run the health checks, judge them and update the health check metric."""
+        config = scanner.config
+
+        async def metrics_health() -> Dict[str, Any]:
+            return metrics.get_registry_status()
+
+        checks: Dict[str, HealthCheck] = {
+            "scanner": scanner.get_health_status,
+            "cache": current_cache().get_health_status,
+            "metrics": metrics_health,
+            "system": partial(run_blocking, _get_system_health, config),
+        }
+        # One check per directory, so a hung mount only hides its own disk usage
+        for directory in config.certificate_directories:
+            checks[f"disk {directory}"] = partial(run_blocking, _get_disk_health, directory)
+        if health_checker:
+            checks.update(health_checker.checks)
+
+        health_status = await run_checks(checks, config.health_check_timeout_seconds)
+        health_status.update(_disk_space_summary(health_status))
+        status, reasons = evaluate_health(health_status, config.health_thresholds)
+        statuses = check_statuses(checks, health_status, config.health_thresholds)
+        health_status.update(
+            {
+                "status": status,
+                "health_reasons": reasons,
+                "failing_checks": {
+                    name: check_status
+                    for name, check_status in statuses.items()
+                    if check_status != HEALTHY
+                },
+                "version": __version__,
+            }
+        )
+        metrics.update_health_metrics(statuses)
+        return health_status
+
+    health_refresh: Optional["asyncio.Task[None]"] = None
+
+    async def refresh_health_metrics() -> None:
+        try:
+            await collect_health()
+        except Exception as e:
+            logger.warning(f"Failed to update health check metrics: {e}")
+
+    async def schedule_health_refresh(_scan_results: Dict[str, Any]) -> None:
+        """Evaluate the health checks in the background after every scan, for /metrics."""
+        nonlocal health_refresh
+        # A hung probe must not pile up evaluations
+        if health_refresh is None or health_refresh.done():
+            health_refresh = asyncio.create_task(refresh_health_metrics())
+
+    scanner.add_scan_listener(schedule_health_refresh)
+
     @app.get("/metrics", response_class=PlainTextResponse)
     async def get_metrics() -> PlainTextResponse:
         try:
+            # Health check statuses are those of the last evaluation (after a scan or /healthz):
+            # scrapes must not run blocking probes
+            metrics.update_cache_metrics(await current_cache().get_stats())
             metrics_data: str = metrics.get_metrics()
             return PlainTextResponse(content=metrics_data, media_type=metrics.get_content_type())
         except Exception as e:
@@ -144,29 +308,35 @@ def create_app(
             raise HTTPException(status_code=500, detail="Failed to generate metrics") from e
 
     @app.get("/healthz", response_class=JSONResponse)
-    async def get_health() -> JSONResponse:
+    async def get_health(verbose: bool = False) -> JSONResponse:
         try:
-            scanner_health = await scanner.get_health_status()
-            cache_health = await cache.get_health_status()
-            metrics_health = metrics.get_registry_status()
-            system_health = await _get_system_health(scanner.config)
-
-            health_status = {
-                **scanner_health,
-                **cache_health,
-                **metrics_health,
-                **system_health,
-                "status": "healthy",
-                "version": __version__,
-            }
+            health_status = await collect_health()
+            if not verbose:
+                # Frequent load balancer probes only need the verdict
+                health_status = {key: health_status[key] for key in TERSE_HEALTH_KEYS}
 
-            return JSONResponse(content=health_status)
+            # Load balancers and orchestrators take a 503 as "take out of rotation"
+            return JSONResponse(
+                content=health_status,
+                status_code=503 if health_status["status"] == UNHEALTHY else 200,
+            )
         except Exception as e:
             logger.error(f"Failed to get health status: {e}")
-            return JSONResponse(content={"status": "error", "error": str(e)}, status_code=500)
+            return JSONResponse(
+                content={"status": "error", "error": str(e), "request_id": request_id_var.get()},
+                status_code=500,
+            )
+
+    @app.get("/readyz", response_class=JSONResponse)
+    async def get_ready() -> JSONResponse:
+        # Until the first scan completes /metrics has no certificates: scraping it then would
+        # record every certificate as gone, so startup/readiness probes wait for it (or for the
+        # scan before a restart to be restored, flagged stale)
+        readiness = scanner.get_readiness()
+        return JSONResponse(content=readiness, status_code=200 if readiness["ready"] else 503)
 
     @app.get("/scan", response_class=JSONResponse)
-    async def trigger_scan() -> JSONResponse:
+    async def trigger_scan(request: Request) -> JSONResponse:
         if scanner.config.dry_run:
             return JSONResponse(
                 content={"message": "Scan not performed - dry run mode enabled"}, status_code=200
@@ -174,69 +344,301 @@ def create_app(
         try:
             logger.info("Manual scan triggered via API")
             scan_results = await scanner.scan_once()
+            audit_request(request, "scan.trigger")
             return JSONResponse(content=scan_results)
         except Exception as e:
             logger.error(f"Manual scan failed: {e}")
+            audit_request(request, "scan.trigger", "failure", error=str(e))
             raise HTTPException(status_code=500, detail=f"Scan failed: {e}") from e
 
     @app.get("/config", response_class=JSONResponse)
     async def get_config() -> JSONResponse:
         try:
             # Use current config from scanner (updated by hot reload)
-            current_config = scanner.config
-            config_dict: Dict[str, Any] = current_config.model_dump()
-
-            # Always redact sensitive information
-            sensitive_keys = ["p12_passwords", "tls_key", "allowed_ips"]
-            for key in sensitive_keys:
-                if key in config_dict:
-                    if key == "p12_passwords":
-                        config_dict[key] = [f"***REDACTED*** ({len(config_dict[key])} passwords)"]
-                    elif key == "allowed_ips":
-                        config_dict[key] = [
-                            f"***REDACTED*** ({len(config_dict[key])} IPs/networks)"
-                        ]
-                    else:
-                        config_dict[key] = "***REDACTED***"
-
-            # Mask certificate directory paths to prevent information disclosure
-            if "certificate_directories" in config_dict:
-                masked_dirs = []
-                for dir_path in config_dict["certificate_directories"]:
-                    # Show only the basename, not full paths
-                    from pathlib import Path
-
-                    masked_dirs.append(f"***/{Path(dir_path).name}")
-                config_dict["certificate_directories"] = masked_dirs
-
+            config_dict = redact_config(scanner.config)
             return JSONResponse(content=config_dict)
         except Exception as e:
             logger.error(f"Failed to get configuration: {e}")
             raise HTTPException(status_code=500, detail="Failed to get configuration") from e
 
+    @app.get("/api/v1/grafana/dashboard", response_class=JSONResponse)
+    async def get_grafana_dashboard() -> JSONResponse:
+        # Built from the current config, so the thresholds follow hot reloads
+        return JSONResponse(
+            content=build_dashboard(scanner.config),
+            headers={"Content-Disposition": 'attachment; filename="tls-cert-monitor.json"'},
+        )
+
+    @app.get("/api/v1/groups", response_class=JSONResponse)
+    async def get_groups() -> JSONResponse:
+        # Groups of the current config (hot reload may change them), certificates of the last scan
+        current_config = scanner.config
+        directory_results = (scanner.last_scan_results or {}).get("directories", {})
+        groups = []
+        for name, group in current_config.groups.items():
+            directories = [
+                directory
+                for directory in current_config.certificate_directories
+                if current_config.get_directory_config(directory).group == name
+            ]
+            groups.append(
+                {
+                    "name": name,
+                    "description": group.description,
+                    "labels": group.labels,
+                    "directories": directories,
+                    "certificates_by_severity": count_certificates_by_severity(
+                        {
+                            directory: directory_results[directory]
+                            for directory in directories
+                            if directory in directory_results
+                        }
+                    ),
+                }
+            )
+        return JSONResponse(content={"groups": groups})
+
+    @app.get("/api/v1/inventory/cyclonedx", response_class=JSONResponse)
+    async def get_cyclonedx_inventory() -> JSONResponse:
+        if scanner.last_scan_results is None:
+            raise HTTPException(status_code=503, detail="No scan has completed yet")
+        return JSONResponse(
+            content=build_bom(certificates_of(scanner.last_scan_results)),
+            media_type=BOM_MEDIA_TYPE,
+        )
+
+    @app.get("/api/v1/cleanup", response_class=JSONResponse)
+    async def get_cleanup(expired_days: int = DEFAULT_EXPIRED_DAYS) -> JSONResponse:
+        if expired_days < 0:
+            raise HTTPException(status_code=400, detail="expired_days must not be negative")
+        if scanner.last_scan_results is None:
+            raise HTTPException(status_code=503, detail="No scan has completed yet")
+        return JSONResponse(content=build_cleanup(scanner.last_scan_results, expired_days))
+
+    def require_history() -> CertificateHistory:
+        if history is None:
+            raise HTTPException(status_code=409, detail="Certificate history is not enabled")
+        return history
+
+    @app.get("/api/v1/history/certificates", response_class=JSONResponse)
+    async def get_certificate_history(
+        fingerprint: Optional[str] = None,
+        common_name: Optional[str] = None,
+        path: Optional[str] = None,
+        limit: int = 100,
+    ) -> JSONResponse:
+        certificates = await asyncio.to_thread(
+            require_history().query, fingerprint, common_name, path, limit
+        )
+        return JSONResponse(content={"certificates": certificates})
+
+    @app.get("/api/v1/history/certificates/{fingerprint}", response_class=JSONResponse)
+    async def get_certificate_history_entry(fingerprint: str) -> JSONResponse:
+        certificates = await asyncio.to_thread(require_history().query, fingerprint)
+        if not certificates:
+            raise HTTPException(status_code=404, detail=f"Certificate {fingerprint} not found")
+        return JSONResponse(content=certificates[0])
+
+    def require_changes() -> ChangeTracker:
+        if changes is None:
+            raise HTTPException(status_code=409, detail="Scan changes are not enabled")
+        return changes
+
+    @app.get("/api/v1/changes", response_class=JSONResponse)
+    async def get_changes(since: int = 0, limit: int = 100) -> JSONResponse:
+        return JSONResponse(content=require_changes().get_changes(since, limit))
+
+    @app.get("/api/v1/events")
+    async def get_events(request: Request, since: Optional[int] = None) -> StreamingResponse:
+        tracker = require_changes()
+        # EventSource clients reconnect with the id of the last event they received
+        last_event_id = request.headers.get("last-event-id", "")
+        if since is None and last_event_id.isdigit():
+            since = int(last_event_id)
+        return StreamingResponse(
+            tracker.events(since),
+            media_type="text/event-stream",
+            headers={"Cache-Control": "no-cache", "X-Accel-Buffering": "no"},
+        )
+
+    @app.get("/config/history", response_class=JSONResponse)
+    async def get_config_history() -> JSONResponse:
+        history = hot_reload.get_history() if hot_reload else []
+        return JSONResponse(content={"reloads": history})
+
+    @app.post("/config/reload", response_class=JSONResponse)
+    async def reload_config(request: Request) -> JSONResponse:
+        if hot_reload is None:
+            raise HTTPException(status_code=409, detail="Configuration reloading is not available")
+        reloads = len(hot_reload.get_history())
+        try:
+            await hot_reload.reload_and_rescan(trigger="API", raise_errors=True)
+        except Exception as e:
+            audit_request(request, "config.reload", "failure", error=str(e))
+            # The current configuration stays in effect
+            raise HTTPException(
+                status_code=502 if isinstance(e, RemoteConfigError) else 400,
+                detail=f"Configuration reload failed: {e}",
+            ) from e
+        history = hot_reload.get_history()
+        changes = history[0]["changes"] if len(history) > reloads else []
+        audit_request(
+            request, "config.reload", settings=[change["setting"] for change in changes]
+        )
+        return JSONResponse(content={"message": "Configuration reloaded", "changes": changes})
+
+    @app.get("/loglevel", response_class=JSONResponse)
+    async def get_loglevel() -> JSONResponse:
+        return JSONResponse(
+            content={"level": get_log_level(), "configured_level": scanner.config.log_level}
+        )
+
+    @app.put("/loglevel", response_class=JSONResponse)
+    async def put_loglevel(request: Request, body: Dict[str, Any]) -> JSONResponse:
+        level = str(body.get("level", "")).upper()
+        if level not in LOG_LEVELS:
+            raise HTTPException(
+                status_code=400, detail=f"Log level must be one of: {list(LOG_LEVELS)}"
+            )
+        # Runtime only: kept until the next restart or a reload changing log_level
+        previous = set_log_level(level)
+        audit_request(request, "loglevel.set", level=level, previous_level=previous)
+        return JSONResponse(
+            content={
+                "level": level,
+                "previous_level": previous,
+                "configured_level": scanner.config.log_level,
+            }
+        )
+
     @app.get("/cache/stats", response_class=JSONResponse)
     async def get_cache_stats() -> JSONResponse:
         try:
-            stats = await cache.get_stats()
+            stats = await current_cache().get_stats()
             return JSONResponse(content=stats)
         except Exception as e:
             logger.error(f"Failed to get cache stats: {e}")
             raise HTTPException(status_code=500, detail="Failed to get cache stats") from e
 
     @app.post("/cache/clear", response_class=JSONResponse)
-    async def clear_cache() -> JSONResponse:
+    async def clear_cache(request: Request) -> JSONResponse:
         if scanner.config.dry_run:
             return JSONResponse(
                 content={"message": "Cache not cleared - dry run mode enabled"}, status_code=200
             )
         try:
-            await cache.clear()
+            await current_cache().clear()
             logger.info("Cache cleared via API")
+            audit_request(request, "cache.clear")
             return JSONResponse(content={"message": "Cache cleared successfully"})
         except Exception as e:
             logger.error(f"Failed to clear cache: {e}")
+            audit_request(request, "cache.clear", "failure", error=str(e))
             raise HTTPException(status_code=500, detail="Failed to clear cache") from e
 
+    @app.post("/cache/save", response_class=JSONResponse)
+    async def save_cache(request: Request) -> JSONResponse:
+        if scanner.config.dry_run:
+            return JSONResponse(
+                content={"message": "Cache not saved - dry run mode enabled"}, status_code=200
+            )
+        cache_manager = current_cache()
+        if not cache_manager.persistent:
+            return JSONResponse(
+                content={"saved": False, "message": "Cache is not persisted to disk"}
+            )
+        try:
+            saved = await cache_manager.save_to_disk()
+        except Exception as e:
+            logger.error(f"Failed to save cache: {e}")
+            audit_request(request, "cache.save", "failure", error=str(e))
+            raise HTTPException(status_code=500, detail="Failed to save cache") from e
+        if not saved and cache_manager.last_save_error:
+            audit_request(request, "cache.save", "failure", error=cache_manager.last_save_error)
+            raise HTTPException(
+                status_code=500, detail=f"Failed to save cache: {cache_manager.last_save_error}"
+            )
+        if saved:
+            logger.info("Cache saved via API")
+        audit_request(request, "cache.save", saved=saved)
+        message = "Cache saved successfully" if saved else "No changes to save"
+        return JSONResponse(content={"saved": saved, "message": message})
+
+    @app.get("/cache/export", response_class=JSONResponse)
+    async def export_cache() -> JSONResponse:
+        try:
+            snapshot = await current_cache().export_snapshot()
+            return JSONResponse(
+                content=snapshot,
+                headers={"Content-Disposition": 'attachment; filename="cache-snapshot.json"'},
+            )
+        except Exception as e:
+            logger.error(f"Failed to export cache: {e}")
+            raise HTTPException(status_code=500, detail="Failed to export cache") from e
+
+    @app.post("/cache/import", response_class=JSONResponse)
+    async def import_cache(request: Request, snapshot: Dict[str, Any]) -> JSONResponse:
+        if scanner.config.dry_run:
+            return JSONResponse(
+                content={"message": "Cache not imported - dry run mode enabled"}, status_code=200
+            )
+        try:
+            result = await current_cache().import_snapshot(snapshot)
+        except ValueError as e:
+            audit_request(request, "cache.import", "failure", error=str(e))
+            raise HTTPException(status_code=400, detail=str(e)) from e
+        except Exception as e:
+            logger.error(f"Failed to import cache: {e}")
+            audit_request(request, "cache.import", "failure", error=str(e))
+            raise HTTPException(status_code=500, detail="Failed to import cache") from e
+        logger.info(f"Cache snapshot imported via API: {result['imported']} entries")
+        audit_request(request, "cache.import", imported=result["imported"])
+        return JSONResponse(content=result)
+
+    @app.get("/silences", response_class=JSONResponse)
+    async def list_silences() -> JSONResponse:
+        try:
+            silences = [silence.to_dict() for silence in scanner.silences.list_silences()]
+            return JSONResponse(content={"silences": silences})
+        except Exception as e:
+            logger.error(f"Failed to list silences: {e}")
+            raise HTTPException(status_code=500, detail="Failed to list silences") from e
+
+    @app.post("/silences", response_class=JSONResponse)
+    async def create_silence(request: Request, silence_config: SilenceConfig) -> JSONResponse:
+        if scanner.config.dry_run:
+            return JSONResponse(
+                content={"message": "Silence not created - dry run mode enabled"}, status_code=200
+            )
+        try:
+            silence = scanner.silences.add_silence(silence_config)
+            logger.info(f"Silence {silence.id} created via API")
+            audit_request(request, "silence.create", silence=silence.to_dict())
+            return JSONResponse(content=silence.to_dict(), status_code=201)
+        except Exception as e:
+            logger.error(f"Failed to create silence: {e}")
+            audit_request(request, "silence.create", "failure", error=str(e))
+            raise HTTPException(status_code=500, detail="Failed to create silence") from e
+
+    @app.delete("/silences/{silence_id}", response_class=JSONResponse)
+    async def delete_silence(request: Request, silence_id: str) -> JSONResponse:
+        if scanner.config.dry_run:
+            return JSONResponse(
+                content={"message": "Silence not deleted - dry run mode enabled"}, status_code=200
+            )
+        silence = scanner.silences.get_silence(silence_id)
+        if silence is None:
+            raise HTTPException(status_code=404, detail=f"Silence not found: {silence_id}")
+        if silence.source == "config":
+            raise HTTPException(
+                status_code=409,
+                detail="Silence is defined in the configuration file and cannot be deleted via API",
+            )
+        scanner.silences.remove_silence(silence_id)
+        logger.info(f"Silence {silence_id} deleted via API")
+        audit_request(request, "silence.delete", silence_id=silence_id)
+        return JSONResponse(content={"message": f"Silence {silence_id} deleted"})
+
     @app.get("/favicon.ico")
     async def get_favicon() -> Response:
         """Serve favicon."""
@@ -414,10 +816,22 @@ def create_app(
         <div class="endpoint">
             <div class="endpoint-title">
                 <span class="endpoint-method">GET</span>
-                <a href="/healthz" target="_blank">/healthz</a>
+                <a href="/healthz?verbose=true" target="_blank">/healthz</a>
             </div>
             <div class="endpoint-description">
-                Health check endpoint with detailed system status, cache information, and disk usage
+                Health check endpoint: overall status and failing checks (?verbose=true adds detailed system status, cache information, and disk usage)
+            </div>
+            <small>Content-Type: application/json</small>
+        </div>
+
+        <div class="endpoint">
+            <div class="endpoint-title">
+                <span class="endpoint-method">GET</span>
+                <a href="/readyz" target="_blank">/readyz</a>
+            </div>
+            <div class="endpoint-description">
+                Readiness probe: 503 until the first certificate scan has completed (or the
+                last scan before a restart was restored)
             </div>
             <small>Content-Type: application/json</small>
         </div>
@@ -448,6 +862,17 @@ def create_app(
             <small>Shows all configuration settings except passwords and keys</small>
         </div>
 
+        <div class="endpoint">
+            <div class="endpoint-title">
+                <span class="endpoint-method">GET</span>
+                <a href="/config/history" target="_blank">/config/history</a>
+            </div>
+            <div class="endpoint-description">
+                Recent configuration reloads and the settings they changed
+            </div>
+            <small>Old and new values are redacted like /config</small>
+        </div>
+
         <div class="endpoint">
             <div class="endpoint-title">
                 <span class="endpoint-method">GET</span>
@@ -459,6 +884,93 @@ def create_app(
             <small>Includes hit rate, entry count, and memory usage</small>
         </div>
 
+        <div class="endpoint">
+            <div class="endpoint-title">
+                <span class="endpoint-method">GET</span>
+                <a href="/silences" target="_blank">/silences</a>
+            </div>
+            <div class="endpoint-description">
+                List silences and maintenance windows (create with POST, remove with DELETE /silences/&lt;id&gt;)
+            </div>
+            <small>Silenced certificates are exported with ssl_cert_silenced=1</small>
+        </div>
+
+        <div class="endpoint">
+            <div class="endpoint-title">
+                <span class="endpoint-method">GET</span>
+                <a href="/loglevel" target="_blank">/loglevel</a>
+            </div>
+            <div class="endpoint-description">
+                Current log level (change with PUT {"level": "DEBUG"}, without a restart)
+            </div>
+        </div>
+
+        <div class="endpoint">
+            <div class="endpoint-title">
+                <span class="endpoint-method">GET</span>
+                <a href="/api/v1/history/certificates" target="_blank">/api/v1/history/certificates</a>
+            </div>
+            <div class="endpoint-description">
+                Every certificate observed by the scans, with first/last seen times and files
+            </div>
+            <small>Filter with ?fingerprint=, ?common_name= and ?path= (globs); needs history.enabled</small>
+        </div>
+
+        <div class="endpoint">
+            <div class="endpoint-title">
+                <span class="endpoint-method">GET</span>
+                <a href="/api/v1/changes" target="_blank">/api/v1/changes</a>
+            </div>
+            <div class="endpoint-description">
+                Certificates added, removed, renewed or newly problematic, per scan
+            </div>
+            <small>Poll with ?since=&lt;id&gt;, or stream them from /api/v1/events (server-sent events)</small>
+        </div>
+
+        <div class="endpoint">
+            <div class="endpoint-title">
+                <span class="endpoint-method">GET</span>
+                <a href="/api/v1/grafana/dashboard" target="_blank">/api/v1/grafana/dashboard</a>
+            </div>
+            <div class="endpoint-description">
+                Grafana dashboard of the exporter's metrics, ready to import
+            </div>
+            <small>Colored with the configured expiry thresholds</small>
+        </div>
+
+        <div class="endpoint">
+            <div class="endpoint-title">
+                <span class="endpoint-method">GET</span>
+                <a href="/api/v1/inventory/cyclonedx" target="_blank">/api/v1/inventory/cyclonedx</a>
+            </div>
+            <div class="endpoint-description">
+                Certificates of the last scan as a CycloneDX 1.6 BOM, for asset and compliance tools
+            </div>
+            <small>The same BOM as scan -o cyclonedx</small>
+        </div>
+
+        <div class="endpoint">
+            <div class="endpoint-title">
+                <span class="endpoint-method">GET</span>
+                <a href="/api/v1/cleanup" target="_blank">/api/v1/cleanup</a>
+            </div>
+            <div class="endpoint-description">
+                Certificates expired for a while, with their duplicates and renewals, to clean up
+            </div>
+            <small>Expired for more than ?expired_days=30 days by default</small>
+        </div>
+
+        <div class="endpoint">
+            <div class="endpoint-title">
+                <span class="endpoint-method">GET</span>
+                <a href="/api/v1/groups" target="_blank">/api/v1/groups</a>
+            </div>
+            <div class="endpoint-description">
+                Directory groups with their owner labels, directories and certificates by severity
+            </div>
+            <small>Groups are defined in the groups setting</small>
+        </div>
+
         <div class="endpoint">
             <div class="endpoint-title">
                 <span class="endpoint-method post">POST</span>
@@ -469,6 +981,16 @@ def create_app(
             </div>
             <small>Click to clear cache via JavaScript POST request</small>
         </div>
+
+        <div class="endpoint">
+            <div class="endpoint-title">
+                <span class="endpoint-method post">POST</span>
+                <a href="#" onclick="saveCache(); return false;">/cache/save</a>
+            </div>
+            <div class="endpoint-description">
+                Save the file cache to disk now (skipped when nothing changed)
+            </div>
+        </div>
     </div>
 
     <div class="container">
@@ -567,6 +1089,16 @@ def create_app(
             }}
         }}
 
+        async function saveCache() {{
+            try {{
+                const response = await fetch('/cache/save', {{ method: 'POST' }});
+                const result = await response.json();
+                alert(result.message || result.detail);
+            }} catch (error) {{
+                alert('Error saving cache: ' + error.message);
+            }}
+        }}
+
         // Auto-refresh page every 5 minutes to show updated status
         setTimeout(() => {{
             location.reload();
@@ -580,7 +1112,7 @@ def create_app(
     return app
 
 
-async def _get_system_health(config: Config) -> Dict[str, Any]:
+def _get_system_health(config: Config) -> Dict[str, Any]:
     health_data: Dict[str, Any] = {}
     try:
         config_file_exists = False
@@ -605,46 +1137,37 @@ async def _get_system_health(config: Config) -> Dict[str, Any]:
             log_dir = os.path.dirname(config.log_file)
             log_file_writable = os.access(log_dir if log_dir else ".", os.W_OK)
         health_data["log_file_writable"] = log_file_writable
-
-        for directory in config.certificate_directories:
-            try:
-                if os.path.exists(directory):
-                    usage = shutil.disk_usage(directory)
-                    dir_key = directory.replace("/", "_").replace("\\", "_")
-                    health_data[f"diskspace_{dir_key}"] = {
-                        "total_bytes": usage.total,
-                        "total_mib": round(bytes_to_mib(usage.total), 2),
-                        "used_bytes": usage.used,
-                        "used_mib": round(bytes_to_mib(usage.used), 2),
-                        "free_bytes": usage.free,
-                        "free_mib": round(bytes_to_mib(usage.free), 2),
-                        "percent_used": round((usage.used / usage.total) * 100, 2),
-                    }
-            except Exception as e:
-                dir_key = directory.replace("/", "_").replace("\\", "_")
-                health_data[f"diskspace_{dir_key}_error"] = str(e)
-
-        total_disk_usage: list[dict[str, Union[str, int]]] = []
-        for directory in config.certificate_directories:
-            if os.path.exists(directory):
-                usage = shutil.disk_usage(directory)
-                total_disk_usage.append(
-                    {
-                        "directory": directory,
-                        "free": int(usage.free),
-                        "total": int(usage.total),
-                    }
-                )
-
-        if total_disk_usage:
-            min_free: int = min(int(usage["free"]) for usage in total_disk_usage)
-            health_data["diskspace"] = {
-                "status": "ok" if min_free > 1024**3 else "warning",
-                "min_free_bytes": min_free,
-                "min_free_mib": round(bytes_to_mib(min_free), 2),
-                "directories_checked": len(total_disk_usage),
-            }
     except Exception as e:
         health_data["system_health_error"] = str(e)
 
     return health_data
+
+
+def _get_disk_health(directory: str) -> Dict[str, Any]:
+    """Get the disk usage of a certificate directory (nothing if it does not exist)."""
+    try:
+        if not os.path.exists(directory):
+            return {}
+        return {disk_usage_key(directory): disk_usage(directory)}
+    except Exception as e:
+        return {f"{disk_usage_key(directory)}_error": str(e)}
+
+
+def _disk_space_summary(health_data: Dict[str, Any]) -> Dict[str, Any]:
+    """Summarize the free space of the checked certificate directories."""
+    total_disk_usage: List[Dict[str, Any]] = [
+        usage
+        for key, usage in health_data.items()
+        if key.startswith("diskspace_") and isinstance(usage, dict)
+    ]
+    if not total_disk_usage:
+        return {}
+    min_free: int = min(usage["free_bytes"] for usage in total_disk_usage)
+    return {
+        "diskspace": {
+            "status": "ok" if min_free > 1024**3 else "warning",
+            "min_free_bytes": min_free,
+            "min_free_mib": round(bytes_to_mib(min_free), 2),
+            "directories_checked": len(total_disk_usage),
+        }
+    }
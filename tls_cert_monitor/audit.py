@@ -0,0 +1,108 @@
+"""
+Audit log for TLS Certificate Monitor.
+
+Mutating API operations (manual scans, cache changes, configuration reloads, silences, log
+level changes) are recorded as one JSON object per line, separately from the application log:
+in their own file and/or sent to the configured syslog destination (authpriv facility).
+"""
+
+import json
+import logging
+import logging.handlers
+import ssl
+from datetime import datetime, timezone
+from pathlib import Path
+from typing import Any, Optional
+
+from tls_cert_monitor.config import AuditLogConfig, SyslogConfig
+from tls_cert_monitor.log_handlers import create_syslog_handler
+from tls_cert_monitor.logger import get_logger, request_id_var
+
+# Distinct from get_logger("audit"), which logs about the audit log to the application log
+AUDIT_LOGGER_NAME = "tls_cert_monitor_audit"
+
+# Principal of requests without one (no principal_header configured, or not sent)
+ANONYMOUS = "anonymous"
+
+
+def get_audit_logger() -> logging.Logger:
+    """Get the audit logger, which does not propagate to the application log."""
+    audit_logger = logging.getLogger(AUDIT_LOGGER_NAME)
+    audit_logger.propagate = False
+    return audit_logger
+
+
+def setup_audit_log(config: AuditLogConfig, syslog: SyslogConfig) -> None:
+    """
+    Set up the audit log destinations; a destination that fails is logged and skipped.
+
+    Args:
+        config: Audit log settings
+        syslog: Syslog destination, used if the audit log is sent to syslog
+    """
+    audit_logger = get_audit_logger()
+    for handler in list(audit_logger.handlers):
+        audit_logger.removeHandler(handler)
+        handler.close()
+    audit_logger.setLevel(logging.INFO)
+
+    logger = get_logger("audit")
+    if not config.enabled:
+        return
+
+    if config.file:
+        try:
+            Path(config.file).parent.mkdir(parents=True, exist_ok=True)
+            file_handler = logging.handlers.RotatingFileHandler(
+                config.file, maxBytes=10 * 1024 * 1024, backupCount=5, encoding="utf-8"
+            )
+            file_handler.setFormatter(logging.Formatter("%(message)s"))
+            audit_logger.addHandler(file_handler)
+            logger.info(f"Audit log file: {config.file}")
+        except OSError as e:
+            logger.error(f"Failed to open audit log file: {e}")
+
+    if config.syslog:
+        try:
+            audit_syslog = syslog.model_copy(update={"facility": config.syslog_facility})
+            audit_logger.addHandler(create_syslog_handler(audit_syslog))
+            destination = f"{syslog.address} ({syslog.protocol})" if syslog.address else "local"
+            logger.info(f"Audit log syslog: {destination}")
+        except (OSError, ssl.SSLError) as e:
+            logger.error(f"Failed to set up audit syslog logging: {e}")
+
+
+def audit(
+    action: str,
+    remote_addr: Optional[str],
+    principal: Optional[str] = None,
+    outcome: str = "success",
+    **details: Any,
+) -> None:
+    """
+    Record an operation in the audit log.
+
+    Args:
+        action: Operation, e.g. cache.clear or silence.create
+        remote_addr: Address of the client requesting it
+        principal: Authenticated user (anonymous if unknown)
+        outcome: success or failure
+        details: Operation specifics (silence ID, new log level, ...)
+    """
+    audit_logger = get_audit_logger()
+    if not audit_logger.handlers:
+        return
+    event = {
+        "timestamp": datetime.now(timezone.utc).isoformat(),
+        "action": action,
+        "outcome": outcome,
+        "remote_addr": remote_addr,
+        "principal": principal or ANONYMOUS,
+    }
+    # Operations requested over the API are matched with its log lines by request ID
+    request_id = request_id_var.get()
+    if request_id:
+        event["request_id"] = request_id
+    if details:
+        event["details"] = details
+    audit_logger.info(json.dumps(event, default=str, ensure_ascii=False))
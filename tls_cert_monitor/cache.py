@@ -6,20 +6,100 @@ import asyncio
 import hashlib
 import json
 import os
+import sys
 import time
+from collections import OrderedDict
 from dataclasses import asdict, dataclass
+from datetime import datetime, timezone
 from pathlib import Path
-from typing import Any, Dict, Optional
+from typing import Any, Dict, List, Optional, Set, Tuple, Union
 
-from tls_cert_monitor.config import Config
+import psutil
+
+from tls_cert_monitor.cache_backends import (
+    CacheChanges,
+    CacheLockTimeout,
+    create_cache_backend,
+)
+from tls_cert_monitor.config import Config, parse_duration
 from tls_cert_monitor.logger import get_logger, log_cache_operation
 
 
+# Loaded entries are merged in batches, so lookups are not held up by a large cache
+WARMUP_BATCH_SIZE = 500
+
+# Process memory is checked against cache_memory_limit at most this often (seconds)
+MEMORY_CHECK_INTERVAL = 5.0
+# Share of the container memory limit used with cache_memory_limit: auto
+AUTO_MEMORY_LIMIT_FRACTION = 0.9
+# Memory limit of the cgroup (v2, v1); v1 reports "no limit" as a huge number
+CGROUP_MEMORY_LIMIT_FILES = (
+    "/sys/fs/cgroup/memory.max",
+    "/sys/fs/cgroup/memory/memory.limit_in_bytes",
+)
+CGROUP_UNLIMITED = 2**60
+
+# Keys made for a source (see CacheManager.make_key) are "<source>:<hash>"
+KEY_SOURCE_SEPARATOR = ":"
+# Source reported for keys made without one
+UNKNOWN_KEY_SOURCE = "other"
+
+# Portable cache snapshots (see CacheManager.export_snapshot)
+SNAPSHOT_FORMAT = "tls-cert-monitor-cache"
+SNAPSHOT_VERSION = 1
+
+
+def container_memory_limit() -> Optional[int]:
+    """Get the memory limit of the container (cgroup) the process runs in, if any."""
+    for limit_file in CGROUP_MEMORY_LIMIT_FILES:
+        try:
+            value = Path(limit_file).read_text(encoding="utf-8").strip()
+        except OSError:
+            continue
+        if value.isdigit() and int(value) < CGROUP_UNLIMITED:
+            return int(value)
+        return None
+    return None
+
+
+def key_source(key: str) -> str:
+    """Get the source type (file, endpoint, ...) a cache key was made for."""
+    source, separator, _ = key.partition(KEY_SOURCE_SEPARATOR)
+    return source if separator else UNKNOWN_KEY_SOURCE
+
+
 def bytes_to_mib(bytes_value: int) -> float:
     """Convert bytes to MiB (mebibytes)."""
     return bytes_value / (1024 * 1024)
 
 
+def estimate_size(value: Any) -> int:
+    """
+    Approximate the memory used by a value, including nested containers.
+
+    Args:
+        value: Value to measure (JSON-like data: dicts, lists, strings, numbers)
+
+    Returns:
+        Size in bytes
+    """
+    seen: Set[int] = set()
+
+    def sizeof(obj: Any) -> int:
+        # Shared objects (interned strings, small ints) are counted once
+        if id(obj) in seen:
+            return 0
+        seen.add(id(obj))
+        size = sys.getsizeof(obj)
+        if isinstance(obj, dict):
+            size += sum(sizeof(k) + sizeof(v) for k, v in obj.items())
+        elif isinstance(obj, (list, tuple, set, frozenset)):
+            size += sum(sizeof(item) for item in obj)
+        return size
+
+    return sizeof(value)
+
+
 @dataclass
 class CacheEntry:
     """Cache entry with metadata."""
@@ -41,6 +121,22 @@ class CacheEntry:
         self.last_access = time.time()
 
 
+def _entry_overhead() -> int:
+    """Memory used per entry besides its key and value."""
+    probe = CacheEntry(value=None, timestamp=0.0, ttl=0, size=0)
+    # Entry object and its attribute dict, plus ~200 bytes for the attribute values
+    # and the ordered dict slot and node (attribute names are shared by all entries)
+    return sys.getsizeof(probe) + sys.getsizeof(vars(probe)) + 200
+
+
+ENTRY_OVERHEAD_BYTES = _entry_overhead()
+
+
+def entry_size(key: str, value: Any) -> int:
+    """Approximate the memory used by a cache entry, so cache_max_size bounds memory."""
+    return sys.getsizeof(key) + estimate_size(value) + ENTRY_OVERHEAD_BYTES
+
+
 class CacheManager:
     """
     Cache manager for certificate data and scan results.
@@ -53,30 +149,119 @@ class CacheManager:
         self.logger = get_logger("cache")
         self.cache_type = config.cache_type
         self.cache_dir = Path(config.cache_dir)
-        self.cache_file = self.cache_dir / "cache.json"
+        self.backend = create_cache_backend(
+            config.cache_backend, self.cache_dir, config.cache_compression
+        )
+        self.cache_file = self.backend.path
         self.ttl = config.cache_ttl_seconds
+        # 0 disables periodic saves: the cache is then saved on demand and on shutdown
+        self.save_interval = config.cache_save_interval_seconds
+        self.compact_threshold = config.cache_compact_threshold
         self.max_size = config.cache_max_size
-
-        # In-memory cache
-        self._memory_cache: Dict[str, CacheEntry] = {}
+        # 0: no limit on the number of entries
+        self.max_entries = config.cache_max_entries
+        # Larger values bypass the cache instead of evicting everything else
+        self.max_entry_size = min(config.cache_max_entry_size, self.max_size)
+        # 0: memory pressure is ignored
+        self.memory_limit = self._resolve_memory_limit(config.cache_memory_limit)
+        self.enabled = config.cache_enabled
+        # Only touch the filesystem when a file-backed cache is configured
+        self.persistent = (
+            self.enabled and self.cache_type in ("file", "both") and bool(config.cache_dir)
+        )
+
+        # In-memory cache, ordered from least to most recently used
+        self._memory_cache: "OrderedDict[str, CacheEntry]" = OrderedDict()
         self._current_size = 0
         self._access_count = 0
         self._hit_count = 0
-
-        # Lock for thread safety
+        # Evictions by the limit that caused them
+        self._evictions = {"size": 0, "count": 0, "memory": 0}
+        self._oversized_skips = 0
+
+        # Cache size cap while the process is over memory_limit, and the memory seen then
+        self._memory_cap: Optional[int] = None
+        self._pressure_rss = 0
+        self._last_memory_check = 0.0
+
+        # Keys changed since the last save (for incremental backends), and the stats saved
+        self._changes = CacheChanges()
+        self._saved_stats: Optional[Dict[str, int]] = None
+        # Error of the last save, if it failed, saves failed in a row, the error of the load
+        # (until a save succeeds) and files quarantined as corrupted on load
+        self.last_save_error: Optional[str] = None
+        self.save_failures = 0
+        self.last_load_error: Optional[str] = None
+        self.quarantined_files: List[str] = []
+        # Another process uses the same cache_dir (warned about once)
+        self.shared_cache_dir = False
+
+        # Lock for thread safety; saves are serialized separately so disk writes
+        # don't block cache reads. All cache access runs on the event loop, so the lock
+        # is only contended if a critical section yields: keep them short and free of
+        # awaits (serialization and sizing happen before the lock is taken). With that,
+        # sharding the entries would not reduce contention, only split the LRU order.
         self._lock = asyncio.Lock()
+        self._save_lock = asyncio.Lock()
+
+        # Background load of the persistent cache (see initialize) and periodic saves
+        self._warmup_task: Optional["asyncio.Task[None]"] = None
+        self._maintenance_task: Optional["asyncio.Task[None]"] = None
+        self._compaction_task: Optional["asyncio.Task[None]"] = None
 
     async def initialize(self) -> None:
-        """Initialize cache manager."""
-        if self.cache_type in ("file", "both"):
+        """
+        Initialize cache manager.
+
+        The persistent cache is loaded in the background: until it is loaded, lookups of
+        entries not loaded yet are misses, so the first scan never waits for a large cache.
+        """
+        if not self.enabled:
+            self.logger.info("Cache disabled - every scan parses all certificates")
+            return
+
+        if self.persistent:
             self.cache_dir.mkdir(parents=True, exist_ok=True)
-            await self._load_persistent_cache()
+            self._warmup_task = asyncio.create_task(self._load_persistent_cache())
+            if self.save_interval > 0:
+                self._maintenance_task = asyncio.create_task(
+                    cache_maintenance_task(self, self.save_interval)
+                )
+        elif self.cache_type in ("file", "both"):
+            self.logger.warning(
+                f"cache_dir is empty - cache_type '{self.cache_type}' falls back to memory only"
+            )
 
         cache_info = f"Cache initialized - Type: {self.cache_type}, TTL: {self.ttl}s, Max size: {self.max_size} bytes"
-        if self.cache_type == "file":
+        if self.persistent:
             cache_info += f", File: {self.cache_file}"
+            if self.save_interval > 0:
+                cache_info += f", Save interval: {self.save_interval}s"
         self.logger.info(cache_info)
 
+    def _resolve_memory_limit(self, setting: Union[int, str]) -> int:
+        """Get the process memory limit in bytes from cache_memory_limit (0: none)."""
+        if setting != "auto":
+            return int(setting)
+        limit = container_memory_limit()
+        if limit is None:
+            self.logger.warning(
+                "cache_memory_limit is auto but no container memory limit was found - "
+                "memory pressure eviction disabled"
+            )
+            return 0
+        return int(limit * AUTO_MEMORY_LIMIT_FRACTION)
+
+    @property
+    def loading(self) -> bool:
+        """Whether the persistent cache is still being loaded."""
+        return self._warmup_task is not None and not self._warmup_task.done()
+
+    async def wait_until_loaded(self) -> None:
+        """Wait for the background load of the persistent cache to finish."""
+        if self._warmup_task is not None:
+            await self._warmup_task
+
     async def get(self, key: str) -> Optional[Any]:
         """
         Get value from cache.
@@ -87,6 +272,9 @@ class CacheManager:
         Returns:
             Cached value or None if not found/expired
         """
+        if not self.enabled:
+            return None
+
         async with self._lock:
             self._access_count += 1
 
@@ -97,49 +285,79 @@ class CacheManager:
             entry = self._memory_cache[key]
 
             if entry.is_expired():
-                del self._memory_cache[key]
-                self._current_size -= entry.size
+                self._remove_entry(key)
                 log_cache_operation(self.logger, "miss", key)
                 return None
 
             entry.update_access()
+            self._memory_cache.move_to_end(key)
+            self._changes.touched.add(key)
             self._hit_count += 1
             log_cache_operation(self.logger, "hit", key)
             return entry.value
 
-    async def set(self, key: str, value: Any, ttl: Optional[int] = None) -> None:
+    async def set(self, key: str, value: Any, ttl: Optional[Union[int, str]] = None) -> None:
         """
         Set value in cache.
 
         Args:
             key: Cache key
             value: Value to cache
-            ttl: Time to live in seconds (uses default if None)
+            ttl: Time to live in seconds or as a duration like "10m" (uses default if None)
+
+        Raises:
+            ValueError: If ttl is negative or not a valid duration
         """
-        async with self._lock:
-            entry_ttl = ttl if ttl is not None else self.ttl
+        if not self.enabled:
+            return
 
-            # Calculate size
-            try:
-                serialized = json.dumps(value, ensure_ascii=False)
-                size = len(serialized.encode("utf-8"))
-            except (TypeError, ValueError) as e:
-                self.logger.warning(f"Failed to serialize value for key {key}: {e}")
-                return
+        entry_ttl = self.ttl if ttl is None else ttl
+        if isinstance(entry_ttl, str):
+            entry_ttl = parse_duration(entry_ttl)
+        if entry_ttl < 0:
+            raise ValueError(f"Cache TTL must not be negative, got {entry_ttl}")
 
-            # Check if we need to evict entries
-            await self._ensure_space(size)
+        # Values must be JSON serializable to be persisted
+        try:
+            json.dumps(value, ensure_ascii=False)
+        except (TypeError, ValueError) as e:
+            self.logger.warning(f"Failed to serialize value for key {key}: {e}")
+            return
+        size = entry_size(key, value)
+        memory_overage = self._memory_overage()
 
-            # Create cache entry
-            entry = CacheEntry(value=value, timestamp=time.time(), ttl=entry_ttl, size=size)
+        async with self._lock:
+            if memory_overage is not None:
+                self._apply_memory_pressure(memory_overage)
+
+            if size > self.max_entry_size:
+                # Drop the previous value too: it is out of date
+                self._remove_entry(key)
+                self._oversized_skips += 1
+                self.logger.debug(
+                    f"Not caching {key}: {size} bytes exceeds cache_max_entry_size "
+                    f"{self.max_entry_size}"
+                )
+                return
 
-            # Remove old entry if exists
-            if key in self._memory_cache:
-                old_entry = self._memory_cache[key]
+            # Remove old entry if exists (before eviction, so it is not counted twice)
+            old_entry = self._memory_cache.pop(key, None)
+            if old_entry is not None:
                 self._current_size -= old_entry.size
 
-            # Add new entry
+            # Check if we need to evict entries
+            self._ensure_space(size, new_entries=1)
+
+            # Create cache entry; a new entry counts as just used
+            now = time.time()
+            entry = CacheEntry(
+                value=value, timestamp=now, ttl=entry_ttl, size=size, last_access=now
+            )
+
+            # Add new entry as most recently used
             self._memory_cache[key] = entry
+            self._changes.upserted.add(key)
+            self._changes.removed.discard(key)
             self._current_size += size
 
             log_cache_operation(self.logger, "set", key)
@@ -155,25 +373,120 @@ class CacheManager:
             True if key was deleted, False if not found
         """
         async with self._lock:
-            if key in self._memory_cache:
-                entry = self._memory_cache[key]
-                del self._memory_cache[key]
-                self._current_size -= entry.size
+            if self._remove_entry(key) is not None:
                 log_cache_operation(self.logger, "invalidate", key)
                 return True
+            if self.loading:
+                # The entry may not be loaded yet: keep it from being loaded
+                self._changes.removed.add(key)
             return False
 
+    def _remove_entry(self, key: str) -> Optional[CacheEntry]:
+        """Remove an entry (lock held), recording the removal for the next save."""
+        entry = self._memory_cache.pop(key, None)
+        if entry is not None:
+            self._current_size -= entry.size
+            self._changes.upserted.discard(key)
+            self._changes.touched.discard(key)
+            self._changes.removed.add(key)
+        return entry
+
     async def clear(self) -> None:
         """Clear all cache entries."""
         async with self._lock:
             self._memory_cache.clear()
             self._current_size = 0
+            self._changes = CacheChanges(cleared=True)
             self.logger.info("Cache cleared")
 
+    async def export_snapshot(self) -> Dict[str, Any]:
+        """
+        Export the live cache entries as a portable snapshot.
+
+        Returns:
+            JSON-serializable snapshot (see import_snapshot)
+        """
+        await self.wait_until_loaded()
+        async with self._lock:
+            entries = {
+                key: asdict(entry)
+                for key, entry in self._memory_cache.items()
+                if not entry.is_expired()
+            }
+        return {
+            "format": SNAPSHOT_FORMAT,
+            "version": SNAPSHOT_VERSION,
+            "exported_at": datetime.now(timezone.utc).isoformat(),
+            "entries": entries,
+        }
+
+    async def import_snapshot(self, snapshot: Dict[str, Any]) -> Dict[str, int]:
+        """
+        Import entries from a snapshot of another cache, e.g. to pre-seed a new instance.
+
+        Entries keep their original age and TTL; expired and oversized entries are skipped,
+        and so are entries already cached with a newer value.
+
+        Args:
+            snapshot: Snapshot produced by export_snapshot
+
+        Returns:
+            Number of entries imported and skipped
+
+        Raises:
+            ValueError: If the snapshot is not a cache snapshot of a supported version
+        """
+        if not isinstance(snapshot, dict) or snapshot.get("format") != SNAPSHOT_FORMAT:
+            raise ValueError("Not a TLS Certificate Monitor cache snapshot")
+        if snapshot.get("version") != SNAPSHOT_VERSION:
+            raise ValueError(f"Unsupported cache snapshot version: {snapshot.get('version')}")
+        stored_entries = snapshot.get("entries")
+        if not isinstance(stored_entries, dict):
+            raise ValueError("Cache snapshot has no entries")
+
+        entries = []
+        try:
+            for key, entry_data in stored_entries.items():
+                entry = CacheEntry(**entry_data)
+                entry.size = entry_size(key, entry.value)
+                entries.append((key, entry))
+        except TypeError as e:
+            raise ValueError(f"Invalid cache snapshot entry: {e}") from e
+
+        if not self.enabled:
+            return {"imported": 0, "skipped": len(entries)}
+
+        await self.wait_until_loaded()
+        imported = 0
+        async with self._lock:
+            for key, entry in sorted(entries, key=lambda item: item[1].last_access):
+                current = self._memory_cache.get(key)
+                if (
+                    entry.is_expired()
+                    or entry.size > self.max_entry_size
+                    or (current is not None and current.timestamp >= entry.timestamp)
+                ):
+                    continue
+                self._remove_entry(key)
+                self._memory_cache[key] = entry
+                self._current_size += entry.size
+                self._changes.upserted.add(key)
+                self._changes.removed.discard(key)
+                imported += 1
+            self._ensure_space(0, new_entries=0)
+
+        self.logger.info(f"Imported {imported} of {len(entries)} entries from cache snapshot")
+        return {"imported": imported, "skipped": len(entries) - imported}
+
     async def get_stats(self) -> Dict[str, Any]:
         """Get cache statistics."""
         async with self._lock:
             hit_rate = (self._hit_count / self._access_count) if self._access_count > 0 else 0.0
+            sources: Dict[str, Dict[str, int]] = {}
+            for key, entry in self._memory_cache.items():
+                source_stats = sources.setdefault(key_source(key), {"entries": 0, "size_bytes": 0})
+                source_stats["entries"] += 1
+                source_stats["size_bytes"] += entry.size
 
             return {
                 "entries_total": len(self._memory_cache),
@@ -181,10 +494,17 @@ class CacheManager:
                 "current_size_mib": round(bytes_to_mib(self._current_size), 2),
                 "max_size_bytes": self.max_size,
                 "max_size_mib": round(bytes_to_mib(self.max_size), 2),
+                "max_entries": self.max_entries,
+                "evictions_size": self._evictions["size"],
+                "evictions_count": self._evictions["count"],
+                "evictions_memory": self._evictions["memory"],
+                "max_entry_size_bytes": self.max_entry_size,
+                "oversized_skips": self._oversized_skips,
                 "hit_rate": hit_rate,
                 "total_accesses": self._access_count,
                 "cache_hits": self._hit_count,
                 "cache_misses": self._access_count - self._hit_count,
+                "sources": sources,
             }
 
     async def cleanup_expired(self) -> int:
@@ -203,184 +523,347 @@ class CacheManager:
                     expired_keys.append(key)
 
             for key in expired_keys:
-                entry = self._memory_cache[key]
-                del self._memory_cache[key]
-                self._current_size -= entry.size
+                self._remove_entry(key)
 
             if expired_keys:
                 self.logger.info(f"Cleaned up {len(expired_keys)} expired cache entries")
 
             return len(expired_keys)
 
-    async def save_to_disk(self) -> None:
-        """Save cache to disk."""
-        if self.cache_type == "memory":
-            return  # Skip disk operations for memory-only cache
+    async def save_to_disk(self) -> bool:
+        """
+        Save cache to disk, unless nothing changed since the last save.
 
-        try:
-            async with self._lock:
-                # Convert cache to serializable format
-                cache_data = {
-                    "entries": {
-                        key: asdict(entry)
-                        for key, entry in self._memory_cache.items()
-                        if not entry.is_expired()
-                    },
-                    "stats": {
-                        "access_count": self._access_count,
-                        "hit_count": self._hit_count,
-                    },
-                }
+        Returns:
+            True if the cache was saved
+        """
+        if not self.persistent:
+            return False  # Skip disk operations for memory-only or disabled cache
 
-            # Write to temporary file first, then rename for atomicity
-            temp_file = self.cache_file.with_suffix(".tmp")
+        # Saving a partially loaded cache would drop the entries not loaded yet
+        await self.wait_until_loaded()
 
-            # Clean up any existing temp file first
-            if temp_file.exists():
-                try:
-                    temp_file.unlink()
-                except OSError:
-                    pass  # Ignore cleanup failures
+        async with self._save_lock:
+            async with self._lock:
+                stats = {"access_count": self._access_count, "hit_count": self._hit_count}
+                if self._changes.empty and stats == self._saved_stats:
+                    self.logger.debug("Cache unchanged - skipping save")
+                    return False
 
-            with open(temp_file, "w", encoding="utf-8") as f:
-                json.dump(cache_data, f, ensure_ascii=False, indent=2)
+                # Convert cache to serializable format
+                entries = {}
+                for key, entry in self._memory_cache.items():
+                    if entry.is_expired():
+                        self._changes.removed.add(key)
+                    else:
+                        entries[key] = asdict(entry)
+                changes, self._changes = self._changes, CacheChanges()
 
-            # Cross-platform atomic file replacement
-            self._atomic_replace(temp_file, self.cache_file)
+            try:
+                # Waiting for another process to release the cache lock runs off the event loop
+                loop = asyncio.get_running_loop()
+                await loop.run_in_executor(None, self._locked_save, entries, stats, changes)
+                self._saved_stats = stats
+                self.last_save_error = None
+                self.save_failures = 0
+                # A failed load is over once the store is written again
+                self.last_load_error = None
+                self.logger.debug("Cache saved to disk")
+                return True
+            except Exception as e:
+                self.logger.error(f"Failed to save cache to disk: {e}")
+                self.last_save_error = str(e)
+                self.save_failures += 1
+                # Keep the changes for the next save
+                async with self._lock:
+                    self._restore_changes(changes)
+                return False
+
+    def _locked_save(
+        self, entries: Dict[str, Dict[str, Any]], stats: Dict[str, int], changes: CacheChanges
+    ) -> None:
+        """Save through the backend, holding the cache directory lock."""
+        with self.backend.lock:
+            self._check_lock_contention()
+            self.backend.save(entries, stats, changes)
+
+    def _check_lock_contention(self) -> None:
+        """Warn once if another process holds the cache directory lock too."""
+        if self.backend.lock.contended and not self.shared_cache_dir:
+            self.shared_cache_dir = True
+            self.logger.warning(
+                f"Cache directory {self.cache_dir} is used by another process - saves of each "
+                "process replace the other's entries; configure a cache_dir per instance"
+            )
 
-            self.logger.debug("Cache saved to disk")
+    def _restore_changes(self, changes: CacheChanges) -> None:
+        """Merge changes of a failed save back into the pending changes (lock held)."""
+        pending = self._changes
+        if pending.cleared:
+            return  # A clear after the failed save supersedes its changes
+        self._changes = CacheChanges(
+            upserted=(changes.upserted - pending.removed) | pending.upserted,
+            touched=(changes.touched - pending.removed) | pending.touched,
+            removed=(changes.removed - pending.upserted) | pending.removed,
+            cleared=changes.cleared,
+        )
 
+    async def _load_persistent_cache(self) -> None:
+        """Load cache from disk in the background, merging it into entries set meanwhile."""
+        loop = asyncio.get_running_loop()
+        try:
+            # Reading and decoding a large cache runs off the event loop
+            entries, stats = await loop.run_in_executor(None, self._read_persistent_cache)
+        except CacheLockTimeout as e:
+            # The data is not known to be corrupted: keep it, saves retry the lock
+            self.logger.error(f"Failed to load persistent cache: {e}")
+            self.last_load_error = str(e)
+            return
         except Exception as e:
-            self.logger.error(f"Failed to save cache to disk: {e}")
-            # Clean up temp file on error
-            temp_file = self.cache_file.with_suffix(".tmp")
-            if temp_file.exists():
-                try:
-                    temp_file.unlink()
-                except OSError:
-                    pass
-
-    def _atomic_replace(self, temp_file: Path, target_file: Path) -> None:
-        """Atomically replace target file with temp file, handling Windows limitations."""
-        import platform
-
-        if platform.system() == "Windows":
-            # Windows doesn't support atomic rename over existing files
-            # Use a backup approach to minimize the window of corruption
-            backup_file = None
+            self.logger.error(f"Failed to load persistent cache: {e}")
+            self.last_load_error = str(e)
+            # Keep corrupted files for inspection (saves wait for the load, so none is running)
             try:
-                if target_file.exists():
-                    backup_file = target_file.with_suffix(".backup")
-                    if backup_file.exists():
-                        backup_file.unlink()
-                    target_file.rename(backup_file)
-
-                temp_file.rename(target_file)
-
-                # Clean up backup file on success
-                if backup_file and backup_file.exists():
-                    backup_file.unlink()
-
-            except OSError as e:
-                # Restore backup if something went wrong
-                if backup_file and backup_file.exists():
-                    try:
-                        if target_file.exists():
-                            target_file.unlink()
-                        backup_file.rename(target_file)
-                    except OSError:
-                        pass  # Best effort recovery
-                raise e
-        else:
-            # Unix-like systems support atomic replace
-            temp_file.replace(target_file)
-
-    async def _load_persistent_cache(self) -> None:
-        """Load cache from disk."""
-        if not self.cache_file.exists():
+                with self.backend.lock:
+                    self.quarantined_files = [str(path) for path in self.backend.quarantine()]
+                self.logger.warning(
+                    f"Quarantined corrupted cache files: {', '.join(self.quarantined_files)}"
+                )
+            except OSError as os_error:
+                self.logger.warning(f"Could not quarantine corrupted cache file: {os_error}")
+            async with self._lock:
+                # Entries set meanwhile must all be written to the fresh store
+                self._changes.upserted.update(self._memory_cache)
             return
 
-        try:
-            with open(self.cache_file, "r", encoding="utf-8") as f:
-                cache_data = json.load(f)
+        expired = sum(1 for _, entry in entries if entry.is_expired())
+        loaded = 0
+        for start in range(0, len(entries), WARMUP_BATCH_SIZE):
+            async with self._lock:
+                loaded += self._merge_loaded(entries[start : start + WARMUP_BATCH_SIZE])
+            await asyncio.sleep(0)
 
-            # Restore cache entries
-            for key, entry_data in cache_data.get("entries", {}).items():
-                entry = CacheEntry(**entry_data)
-                if not entry.is_expired():
-                    self._memory_cache[key] = entry
-                    self._current_size += entry.size
+        async with self._lock:
+            # Restore stats, keeping accesses made while loading
+            self._access_count += stats.get("access_count", 0)
+            self._hit_count += stats.get("hit_count", 0)
+            # Loaded entries are older than anything set meanwhile
+            entries_before = len(self._memory_cache)
+            self._ensure_space(0, new_entries=0)
+            pruned = expired + entries_before - len(self._memory_cache)
+
+            if self._changes.empty:
+                # Nothing changed yet: what was loaded is what is stored
+                self._saved_stats = {
+                    "access_count": self._access_count,
+                    "hit_count": self._hit_count,
+                }
 
-            # Restore stats
-            stats = cache_data.get("stats", {})
-            self._access_count = stats.get("access_count", 0)
-            self._hit_count = stats.get("hit_count", 0)
+        if entries:
+            self.logger.info(f"Loaded {loaded} entries from persistent cache")
 
-            self.logger.info(f"Loaded {len(self._memory_cache)} entries from persistent cache")
+        # Pruned entries are only dropped from the file by the next save: rewrite it now
+        if self.compact_threshold and pruned * 100 > self.compact_threshold * len(entries):
+            self.logger.info(
+                f"Pruned {pruned} of {len(entries)} persisted cache entries - compacting"
+            )
+            self._compaction_task = asyncio.create_task(self.compact())
 
+    async def compact(self) -> None:
+        """Rewrite the persisted cache without removed entries, reclaiming their space."""
+        if not self.persistent:
+            return
+        await self.save_to_disk()
+        try:
+            async with self._save_lock:
+                loop = asyncio.get_running_loop()
+                await loop.run_in_executor(None, self._locked_compact)
+            self.logger.info("Persistent cache compacted")
         except Exception as e:
-            self.logger.warning(f"Failed to load persistent cache: {e}")
-            # Remove corrupted cache file
-            try:
-                self.cache_file.unlink()
-                self.logger.info("Removed corrupted cache file")
-            except OSError as os_error:
-                self.logger.warning(f"Could not remove corrupted cache file: {os_error}")
+            self.logger.error(f"Failed to compact persistent cache: {e}")
+
+    def _locked_compact(self) -> None:
+        """Compact through the backend, holding the cache directory lock."""
+        with self.backend.lock:
+            self.backend.compact()
+
+    def _read_persistent_cache(self) -> Tuple[List[Tuple[str, CacheEntry]], Dict[str, int]]:
+        """Read persisted entries, most recently used first, with recomputed sizes."""
+        with self.backend.lock:
+            self._check_lock_contention()
+            stored_entries, stats = self.backend.load()
+        entries = [(key, CacheEntry(**entry_data)) for key, entry_data in stored_entries.items()]
+        entries.sort(key=lambda item: item[1].last_access or item[1].timestamp, reverse=True)
+        for key, entry in entries:
+            # Stored sizes may come from an older accounting method
+            entry.size = entry_size(key, entry.value)
+        return entries, stats
+
+    def _merge_loaded(self, entries: List[Tuple[str, CacheEntry]]) -> int:
+        """
+        Merge loaded entries (most recently used first) behind the current ones (lock held).
+
+        Entries set, deleted or cleared since startup take precedence over loaded ones.
+
+        Returns:
+            Number of entries merged
+        """
+        merged = 0
+        for key, entry in entries:
+            if key in self._memory_cache or key in self._changes.removed:
+                continue
+            if self._changes.cleared or entry.is_expired():
+                self._changes.removed.add(key)
+                continue
+            self._memory_cache[key] = entry
+            self._memory_cache.move_to_end(key, last=False)
+            self._current_size += entry.size
+            merged += 1
+        return merged
+
+    def _over_entry_limit(self, new_entries: int) -> bool:
+        return 0 < self.max_entries < len(self._memory_cache) + new_entries
+
+    def _memory_overage(self) -> Optional[int]:
+        """
+        Get how far process memory is over memory_limit (negative when below).
 
-    async def _ensure_space(self, needed_size: int) -> None:
-        """Ensure there's enough space in cache by evicting LRU entries."""
-        if self._current_size + needed_size <= self.max_size:
+        Returns:
+            Bytes over the limit, or None if not checked (disabled or checked recently)
+        """
+        if not self.memory_limit:
+            return None
+        now = time.monotonic()
+        if now - self._last_memory_check < MEMORY_CHECK_INTERVAL:
+            return None
+        self._last_memory_check = now
+        try:
+            rss = int(psutil.Process().memory_info().rss)
+        except psutil.Error as e:
+            self.logger.debug(f"Could not read process memory: {e}")
+            return None
+        return rss - self.memory_limit
+
+    def _apply_memory_pressure(self, overage: int) -> None:
+        """Cap the cache size while process memory is over memory_limit (lock held)."""
+        rss = self.memory_limit + overage
+        if overage <= 0:
+            # Lift the cap once memory is comfortably below the limit
+            if self._memory_cap is not None and rss < self.memory_limit * 0.9:
+                self._memory_cap = None
+                self.logger.info("Process memory below cache_memory_limit - cache cap lifted")
             return
 
-        # Sort entries by last access time (LRU first)
-        entries_by_access = sorted(self._memory_cache.items(), key=lambda item: item[1].last_access)
+        # Freed memory is not always returned to the OS: only shrink further if it grew
+        if self._memory_cap is not None and rss <= self._pressure_rss:
+            return
+        self._pressure_rss = rss
+        self._memory_cap = max(0, self._current_size - overage)
+        self.logger.warning(
+            f"Process memory {rss} bytes exceeds cache_memory_limit {self.memory_limit} - "
+            f"capping cache at {self._memory_cap} bytes"
+        )
+        self._ensure_space(0, new_entries=0)
+
+    def _ensure_space(self, needed_size: int, new_entries: int) -> None:
+        """
+        Evict LRU entries until the size and entry count limits leave room (lock held).
 
+        Args:
+            needed_size: Size of the entries about to be added
+            new_entries: Number of entries about to be added
+        """
         freed_space = 0
-        evicted_count = 0
-
-        for key, entry in entries_by_access:
-            if self._current_size + needed_size - freed_space <= self.max_size:
+        evicted = {reason: 0 for reason in self._evictions}
+
+        # Entries are kept in LRU order: evict from the least recently used end
+        while self._memory_cache:
+            if self._over_entry_limit(new_entries):
+                reason = "count"
+            elif self._current_size + needed_size > self.max_size:
+                reason = "size"
+            elif (
+                self._memory_cap is not None
+                and self._current_size + needed_size > self._memory_cap
+            ):
+                reason = "memory"
+            else:
                 break
-
-            del self._memory_cache[key]
+            key, entry = next(iter(self._memory_cache.items()))
+            self._remove_entry(key)
             freed_space += entry.size
-            evicted_count += 1
-
-        self._current_size -= freed_space
+            evicted[reason] += 1
 
-        if evicted_count > 0:
+        if any(evicted.values()):
+            for reason, count in evicted.items():
+                self._evictions[reason] += count
+            reasons = ", ".join(f"{reason}: {count}" for reason, count in evicted.items() if count)
             self.logger.info(
-                f"Evicted {evicted_count} LRU cache entries to free {freed_space} bytes"
+                f"Evicted {sum(evicted.values())} LRU cache entries to free {freed_space} bytes "
+                f"({reasons})"
             )
 
     async def close(self) -> None:
         """Close cache manager and save to disk."""
+        if self._maintenance_task is not None:
+            self._maintenance_task.cancel()
+            try:
+                await self._maintenance_task
+            except asyncio.CancelledError:
+                pass
+            self._maintenance_task = None
+        await self.wait_until_loaded()
+        if self._compaction_task is not None:
+            await self._compaction_task
+            self._compaction_task = None
         await self.save_to_disk()
+        self.backend.close()
         self.logger.info("Cache manager closed")
 
-    def make_key(self, *args: Any) -> str:
+    def make_key(self, *args: Any, source: Optional[str] = None) -> str:
         """
         Create a cache key from arguments.
 
         Args:
             *args: Arguments to create key from
+            source: Source type (file, endpoint, ...) the key is namespaced by, so keys of
+                different sources never collide and stats are reported per source
 
         Returns:
-            Cache key string (full SHA256 hash for security)
+            Cache key string (full SHA256 hash for security, prefixed with the source)
+
+        Raises:
+            ValueError: If the source contains the key separator
         """
         # Create a hash of the arguments - use full hash to prevent collisions
         key_data = str(args).encode("utf-8")
-        return hashlib.sha256(key_data).hexdigest()
+        digest = hashlib.sha256(key_data).hexdigest()
+        if source is None:
+            return digest
+        if not source or KEY_SOURCE_SEPARATOR in source:
+            raise ValueError(f"Invalid cache key source: {source!r}")
+        return f"{source}{KEY_SOURCE_SEPARATOR}{digest}"
 
     async def get_health_status(self) -> Dict[str, Any]:
         """Get cache health status."""
         stats = await self.get_stats()
 
         return {
+            "cache_enabled": self.enabled,
+            "cache_persistent": self.persistent,
             "cache_entries_total": stats["entries_total"],
-            "cache_file_path": str(self.cache_file),
+            "cache_loading": self.loading,
+            "cache_quarantined_files": self.quarantined_files,
+            "cache_save_failures": self.save_failures,
+            "cache_last_save_error": self.last_save_error,
+            "cache_last_load_error": self.last_load_error,
+            "cache_memory_pressure": self._memory_cap is not None,
+            "cache_dir_shared": self.shared_cache_dir,
+            "cache_file_path": str(self.cache_file) if self.persistent else None,
             "cache_file_writable": (
-                os.access(self.cache_dir, os.W_OK) if self.cache_dir.exists() else False
+                os.access(self.cache_dir, os.W_OK)
+                if self.persistent and self.cache_dir.exists()
+                else None
             ),
             "cache_hit_rate": round(stats["hit_rate"], 3),
             "cache_total_accesses": stats["total_accesses"],
@@ -0,0 +1,457 @@
+"""
+Persistent cache backends for TLS Certificate Monitor.
+
+Backends store cache entries as plain dictionaries (see CacheEntry) between restarts:
+
+- json: a single cache.json file, rewritten atomically on every save (default)
+- sqlite: a cache.db SQLite database, updated incrementally in one transaction per save,
+  so a crash mid-save leaves the previous state intact
+
+With gzip compression the JSON file is stored as cache.json.gz and SQLite values are stored
+as gzip-compressed blobs.
+
+Persisted data is verified on load (a SHA-256 checksum embedded in the JSON file, an
+integrity check of the database); corrupted data is quarantined, i.e. renamed with a
+timestamp suffix and kept for inspection.
+
+Loads and saves hold an advisory lock on cache.lock in the cache directory, so processes
+sharing a cache_dir never read or write the cache while another one is writing it.
+"""
+
+import gzip
+import hashlib
+import json
+import platform
+import re
+import sqlite3
+import sys
+import time
+import zlib
+from dataclasses import dataclass, field
+from pathlib import Path
+from types import TracebackType
+from typing import IO, Any, Dict, List, Optional, Set, Tuple, Type, Union
+
+# sys.platform (rather than platform.system) lets type checkers skip the other branch
+if sys.platform == "win32":
+    import msvcrt
+else:
+    import fcntl
+
+# Entry fields stored next to the value (see CacheEntry)
+ENTRY_METADATA_FIELDS = ("timestamp", "ttl", "size", "access_count", "last_access")
+
+LOCK_FILE_NAME = "cache.lock"
+# How long a load or save waits for another process to release the lock
+LOCK_TIMEOUT_SECONDS = 10.0
+LOCK_POLL_SECONDS = 0.05
+
+
+@dataclass
+class CacheChanges:
+    """Keys changed since the last save, for incremental backends."""
+
+    # Entries added or replaced
+    upserted: Set[str] = field(default_factory=set)
+    # Entries read (only their access statistics changed)
+    touched: Set[str] = field(default_factory=set)
+    # Entries deleted, evicted or expired
+    removed: Set[str] = field(default_factory=set)
+    # All entries were removed before the changes above
+    cleared: bool = False
+
+    @property
+    def empty(self) -> bool:
+        """Whether nothing changed."""
+        return not (self.upserted or self.touched or self.removed or self.cleared)
+
+
+class CacheCorruptedError(ValueError):
+    """Persisted cache data failed verification."""
+
+
+class CacheLockTimeout(OSError):
+    """The cache lock is held by another process for too long."""
+
+
+class CacheFileLock:
+    """Exclusive advisory lock on a file, held across processes (flock or msvcrt.locking)."""
+
+    def __init__(self, path: Path, timeout: float = LOCK_TIMEOUT_SECONDS):
+        self.path = path
+        self.timeout = timeout
+        self._file: Optional[IO[bytes]] = None
+        # Whether the last acquire had to wait for another holder
+        self.contended = False
+
+    def _try_lock(self, lock_file: IO[bytes]) -> bool:
+        try:
+            if sys.platform == "win32":
+                lock_file.seek(0)
+                msvcrt.locking(lock_file.fileno(), msvcrt.LK_NBLCK, 1)
+            else:
+                fcntl.flock(lock_file.fileno(), fcntl.LOCK_EX | fcntl.LOCK_NB)
+            return True
+        except OSError:
+            return False
+
+    def acquire(self) -> None:
+        """
+        Acquire the lock, waiting up to the timeout.
+
+        Raises:
+            CacheLockTimeout: If another holder keeps the lock past the timeout
+        """
+        lock_file = open(self.path, "a+b")
+        self.contended = False
+        deadline = time.monotonic() + self.timeout
+        while not self._try_lock(lock_file):
+            self.contended = True
+            if time.monotonic() >= deadline:
+                lock_file.close()
+                raise CacheLockTimeout(f"Timed out waiting for cache lock {self.path}")
+            time.sleep(LOCK_POLL_SECONDS)
+        self._file = lock_file
+
+    def release(self) -> None:
+        """Release the lock."""
+        if self._file is None:
+            return
+        try:
+            if sys.platform == "win32":
+                self._file.seek(0)
+                msvcrt.locking(self._file.fileno(), msvcrt.LK_UNLCK, 1)
+            else:
+                fcntl.flock(self._file.fileno(), fcntl.LOCK_UN)
+        finally:
+            self._file.close()
+            self._file = None
+
+    def __enter__(self) -> "CacheFileLock":
+        self.acquire()
+        return self
+
+    def __exit__(
+        self,
+        exc_type: Optional[Type[BaseException]],
+        exc: Optional[BaseException],
+        traceback: Optional[TracebackType],
+    ) -> None:
+        self.release()
+
+
+class CacheBackend:
+    """Persistent storage for cache entries."""
+
+    name = ""
+    file_name = ""
+
+    def __init__(self, cache_dir: Path, compression: str = "none"):
+        self.compress = compression == "gzip"
+        self.path = cache_dir / self.file_name
+        self.lock = CacheFileLock(cache_dir / LOCK_FILE_NAME)
+
+    def load(self) -> Tuple[Dict[str, Dict[str, Any]], Dict[str, int]]:
+        """
+        Load persisted entries.
+
+        Returns:
+            Tuple of (entries by key, statistics)
+
+        Raises:
+            Exception: If the stored data is corrupted (the caller quarantines it)
+        """
+        raise NotImplementedError
+
+    def save(
+        self, entries: Dict[str, Dict[str, Any]], stats: Dict[str, int], changes: CacheChanges
+    ) -> None:
+        """
+        Persist the cache.
+
+        Args:
+            entries: All live entries by key
+            stats: Cache statistics
+            changes: Keys changed since the last save
+        """
+        raise NotImplementedError
+
+    def compact(self) -> None:
+        """Reclaim the space of removed entries (saves of whole-file backends already do)."""
+
+    def files(self) -> List[Path]:
+        """Files holding the persisted data."""
+        return [self.path]
+
+    def quarantine(self) -> List[Path]:
+        """
+        Move corrupted persisted data aside, so the next save starts afresh.
+
+        Returns:
+            New paths of the quarantined files
+        """
+        self.close()
+        stamp = time.strftime("%Y%m%dT%H%M%SZ", time.gmtime())
+        quarantined = []
+        for path in self.files():
+            if path.exists():
+                target = path.with_name(f"{path.name}.corrupt-{stamp}")
+                path.replace(target)
+                quarantined.append(target)
+        return quarantined
+
+    def close(self) -> None:
+        """Release backend resources."""
+
+
+class JsonFileBackend(CacheBackend):
+    """
+    Single JSON file, rewritten wholesale on every save.
+
+    The file starts with a "checksum" member holding the SHA-256 of the rest of the document
+    (after its opening brace), so data and checksum are replaced together in one rename.
+    """
+
+    name = "json"
+    file_name = "cache.json"
+
+    CHECKSUM_PATTERN = re.compile(r'\{"checksum": "([0-9a-f]{64})",')
+
+    def __init__(self, cache_dir: Path, compression: str = "none"):
+        super().__init__(cache_dir, compression)
+        if self.compress:
+            self.path = cache_dir / f"{self.file_name}.gz"
+        # Separate checksum manifest of earlier versions, removed on the next save
+        self.legacy_manifest_path = self.path.with_name(f"{self.path.name}.sha256")
+
+    def files(self) -> List[Path]:
+        return [self.path, self.legacy_manifest_path]
+
+    def _open(self, path: Path, mode: str) -> IO[str]:
+        if self.compress:
+            return gzip.open(path, f"{mode}t", encoding="utf-8")  # type: ignore[return-value]
+        return open(path, mode, encoding="utf-8")
+
+    @staticmethod
+    def _checksum(text: str) -> str:
+        return hashlib.sha256(text.encode("utf-8")).hexdigest()
+
+    def load(self) -> Tuple[Dict[str, Dict[str, Any]], Dict[str, int]]:
+        if not self.path.exists():
+            return {}, {}
+        try:
+            with self._open(self.path, "r") as f:
+                text = f.read()
+        except (EOFError, gzip.BadGzipFile, zlib.error, UnicodeDecodeError) as e:
+            raise CacheCorruptedError(f"Unreadable cache file {self.path}: {e}") from e
+        # Files written before checksums were embedded are loaded unverified
+        match = self.CHECKSUM_PATTERN.match(text)
+        if match and match.group(1) != self._checksum(text[match.end() :]):
+            raise CacheCorruptedError(f"Checksum mismatch for {self.path}")
+        try:
+            cache_data = json.loads(text)
+        except ValueError as e:
+            raise CacheCorruptedError(f"Invalid cache file {self.path}: {e}") from e
+        return cache_data.get("entries", {}), cache_data.get("stats", {})
+
+    def save(
+        self, entries: Dict[str, Dict[str, Any]], stats: Dict[str, int], changes: CacheChanges
+    ) -> None:
+        # Write to a temporary file first, then rename for atomicity
+        temp_file = self.path.with_suffix(".tmp")
+
+        # Clean up any existing temp file first
+        if temp_file.exists():
+            try:
+                temp_file.unlink()
+            except OSError:
+                pass  # Ignore cleanup failures
+
+        # Indentation only helps uncompressed files be read by people
+        document = json.dumps(
+            {"entries": entries, "stats": stats},
+            ensure_ascii=False,
+            indent=None if self.compress else 2,
+        )
+        body = document[1:]
+        try:
+            with self._open(temp_file, "w") as f:
+                f.write(f'{{"checksum": "{self._checksum(body)}",{body}')
+
+            # Cross-platform atomic file replacement
+            self._atomic_replace(temp_file, self.path)
+        except Exception:
+            # Clean up temp file on error
+            if temp_file.exists():
+                try:
+                    temp_file.unlink()
+                except OSError:
+                    pass
+            raise
+
+        if self.legacy_manifest_path.exists():
+            try:
+                self.legacy_manifest_path.unlink()
+            except OSError:
+                pass  # Ignored by load
+
+    def _atomic_replace(self, temp_file: Path, target_file: Path) -> None:
+        """Atomically replace target file with temp file, handling Windows limitations."""
+        if platform.system() == "Windows":
+            # Windows doesn't support atomic rename over existing files
+            # Use a backup approach to minimize the window of corruption
+            backup_file = None
+            try:
+                if target_file.exists():
+                    backup_file = target_file.with_suffix(".backup")
+                    if backup_file.exists():
+                        backup_file.unlink()
+                    target_file.rename(backup_file)
+
+                temp_file.rename(target_file)
+
+                # Clean up backup file on success
+                if backup_file and backup_file.exists():
+                    backup_file.unlink()
+
+            except OSError as e:
+                # Restore backup if something went wrong
+                if backup_file and backup_file.exists():
+                    try:
+                        if target_file.exists():
+                            target_file.unlink()
+                        backup_file.rename(target_file)
+                    except OSError:
+                        pass  # Best effort recovery
+                raise e
+        else:
+            # Unix-like systems support atomic replace
+            temp_file.replace(target_file)
+
+
+class SQLiteBackend(CacheBackend):
+    """SQLite database, updated incrementally."""
+
+    name = "sqlite"
+    file_name = "cache.db"
+
+    def __init__(self, cache_dir: Path, compression: str = "none"):
+        super().__init__(cache_dir, compression)
+        self._connection: Optional[sqlite3.Connection] = None
+
+    def _encode_value(self, value: Any) -> Union[str, bytes]:
+        serialized = json.dumps(value, ensure_ascii=False)
+        return gzip.compress(serialized.encode("utf-8")) if self.compress else serialized
+
+    @staticmethod
+    def _decode_value(stored: Union[str, bytes]) -> Any:
+        # Rows keep the encoding they were written with, so compression can be toggled
+        if isinstance(stored, bytes):
+            return json.loads(gzip.decompress(stored).decode("utf-8"))
+        return json.loads(stored)
+
+    def _connect(self) -> sqlite3.Connection:
+        if self._connection is None:
+            # The cache is loaded on a worker thread and saved on the event loop thread;
+            # the cache manager never uses the connection from both at once
+            connection = sqlite3.connect(str(self.path), check_same_thread=False)
+            try:
+                # Write-ahead logging keeps the database consistent if the process dies
+                connection.execute("PRAGMA journal_mode=WAL")
+                connection.execute(
+                    "CREATE TABLE IF NOT EXISTS entries ("
+                    "key TEXT PRIMARY KEY, value TEXT NOT NULL, timestamp REAL NOT NULL, "
+                    "ttl INTEGER NOT NULL, size INTEGER NOT NULL, "
+                    "access_count INTEGER NOT NULL, last_access REAL NOT NULL)"
+                )
+                connection.execute(
+                    "CREATE TABLE IF NOT EXISTS stats (name TEXT PRIMARY KEY, value INTEGER)"
+                )
+                connection.commit()
+            except sqlite3.Error:
+                connection.close()
+                raise
+            self._connection = connection
+        return self._connection
+
+    def load(self) -> Tuple[Dict[str, Dict[str, Any]], Dict[str, int]]:
+        if not self.path.exists():
+            return {}, {}
+        connection = self._connect()
+        problems = [row[0] for row in connection.execute("PRAGMA quick_check")]
+        if problems != ["ok"]:
+            raise CacheCorruptedError(f"Integrity check of {self.path} failed: {problems[0]}")
+        entries = {}
+        for row in connection.execute(
+            f"SELECT key, value, {', '.join(ENTRY_METADATA_FIELDS)} FROM entries"
+        ):
+            entry = dict(zip(ENTRY_METADATA_FIELDS, row[2:]))
+            entry["value"] = self._decode_value(row[1])
+            entries[row[0]] = entry
+        stats = dict(connection.execute("SELECT name, value FROM stats").fetchall())
+        return entries, stats
+
+    def save(
+        self, entries: Dict[str, Dict[str, Any]], stats: Dict[str, int], changes: CacheChanges
+    ) -> None:
+        connection = self._connect()
+        # One transaction: either the whole save is applied or none of it
+        with connection:
+            if changes.cleared:
+                connection.execute("DELETE FROM entries")
+            connection.executemany(
+                "DELETE FROM entries WHERE key = ?", [(key,) for key in changes.removed]
+            )
+            connection.executemany(
+                f"INSERT OR REPLACE INTO entries (key, value, {', '.join(ENTRY_METADATA_FIELDS)})"
+                " VALUES (?, ?, ?, ?, ?, ?, ?)",
+                [
+                    (key, self._encode_value(entries[key]["value"]))
+                    + tuple(entries[key][name] for name in ENTRY_METADATA_FIELDS)
+                    for key in changes.upserted
+                    if key in entries
+                ],
+            )
+            connection.executemany(
+                "UPDATE entries SET access_count = ?, last_access = ? WHERE key = ?",
+                [
+                    (entries[key]["access_count"], entries[key]["last_access"], key)
+                    for key in changes.touched - changes.upserted
+                    if key in entries
+                ],
+            )
+            connection.executemany(
+                "INSERT OR REPLACE INTO stats (name, value) VALUES (?, ?)", list(stats.items())
+            )
+
+    def compact(self) -> None:
+        connection = self._connect()
+        # Deleted rows only free pages inside the database: rebuild it, then empty the WAL
+        connection.execute("VACUUM")
+        connection.execute("PRAGMA wal_checkpoint(TRUNCATE)")
+
+    def files(self) -> List[Path]:
+        return [Path(f"{self.path}{suffix}") for suffix in ("", "-wal", "-shm")]
+
+    def close(self) -> None:
+        if self._connection is not None:
+            self._connection.close()
+            self._connection = None
+
+
+CACHE_BACKENDS = {backend.name: backend for backend in (JsonFileBackend, SQLiteBackend)}
+
+
+def create_cache_backend(name: str, cache_dir: Path, compression: str = "none") -> CacheBackend:
+    """
+    Create a persistent cache backend.
+
+    Args:
+        name: Backend name (json or sqlite)
+        cache_dir: Directory holding the cache file
+        compression: Compression of stored entries (none or gzip)
+
+    Returns:
+        Cache backend
+    """
+    return CACHE_BACKENDS[name](cache_dir, compression)
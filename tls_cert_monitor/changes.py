@@ -0,0 +1,231 @@
+"""
+Scan-to-scan changes for TLS Certificate Monitor.
+
+After every scan the certificates are compared, file by file, with those of the previous scan:
+certificates added, removed, renewed (same common name, later expiry) or replaced, and those that
+became problematic (a worse expiry severity, not yet valid, a weak key or a deprecated signature
+algorithm). Each scan with changes is recorded as a changeset with an increasing id, served by
+/api/v1/changes and streamed to /api/v1/events subscribers, so other systems can consume only
+what changed instead of polling full scan results.
+"""
+
+import asyncio
+import json
+import time
+from collections import deque
+from datetime import datetime, timezone
+from typing import Any, AsyncIterator, Deque, Dict, List, Optional, Set, Tuple
+
+from tls_cert_monitor.config import Config
+from tls_cert_monitor.logger import get_logger
+from tls_cert_monitor.metrics import SEVERITY_LEVELS
+from tls_cert_monitor.scanner import CertificateScanner
+
+# Certificate fields reported in changes
+CHANGE_FIELDS = (
+    "common_name",
+    "issuer",
+    "serial",
+    "fingerprint_sha256",
+    "not_after",
+    "severity",
+    "silenced",
+    "labels",
+)
+
+# Kinds of changes in a changeset, in report order
+CHANGE_KINDS = ("added", "removed", "renewed", "replaced", "problematic")
+
+# Most changesets returned by one query
+MAX_QUERY_LIMIT = 1000
+
+# Seconds between keepalive comments on idle event streams, so proxies keep them open
+KEEPALIVE_SECONDS = 15.0
+
+
+def _same_certificate(cert: Dict[str, Any], previous: Dict[str, Any]) -> bool:
+    """Check if two certificates are the same, by fingerprint (serial for older cache entries)."""
+    if "fingerprint_sha256" in cert and "fingerprint_sha256" in previous:
+        return bool(cert["fingerprint_sha256"] == previous["fingerprint_sha256"])
+    return bool(cert.get("serial") == previous.get("serial"))
+
+
+def _problems(cert: Dict[str, Any]) -> Set[str]:
+    """Problems of a certificate, other than its expiry severity."""
+    problems = set()
+    if cert.get("not_yet_valid"):
+        problems.add("not_yet_valid")
+    if cert.get("is_weak_key"):
+        problems.add("weak_key")
+    if cert.get("is_deprecated_algorithm"):
+        problems.add("deprecated_algorithm")
+    return problems
+
+
+def _severity_rank(cert: Optional[Dict[str, Any]]) -> int:
+    """Rank of the expiry severity of a certificate (0: ok or unknown)."""
+    severity = (cert or {}).get("severity")
+    return SEVERITY_LEVELS.index(severity) if severity in SEVERITY_LEVELS else 0
+
+
+def format_event(changeset: Dict[str, Any]) -> str:
+    """Format a changeset as a server-sent event."""
+    return f"id: {changeset['id']}\nevent: changes\ndata: {json.dumps(changeset)}\n\n"
+
+
+class ChangeTracker:
+    """Diff of every scan against the previous one, kept as numbered changesets."""
+
+    def __init__(self, config: Config, scanner: Optional[CertificateScanner] = None):
+        self.config = config.changes
+        self.logger = get_logger("changes")
+        # Certificates of the previous scan by file, with their directory
+        self._previous: Optional[Dict[str, Tuple[str, Dict[str, Any]]]] = None
+        self._changesets: Deque[Dict[str, Any]] = deque(maxlen=config.changes.max_changesets)
+        self._last_id = 0
+        self._subscribers: Set["asyncio.Queue[Optional[Dict[str, Any]]]"] = set()
+
+        if scanner:
+            scanner.add_scan_listener(self._on_scan_complete)
+
+    async def _on_scan_complete(self, scan_results: Dict[str, Any]) -> None:
+        """Record the changes of the scan and publish them to event stream subscribers."""
+        changeset = self.record(scan_results)
+        if changeset is None:
+            return
+        self.logger.info(
+            "Certificate changes since the previous scan: "
+            + ", ".join(f"{len(changeset[kind])} {kind}" for kind in CHANGE_KINDS)
+        )
+        for queue in self._subscribers:
+            queue.put_nowait(changeset)
+
+    def record(
+        self, scan_results: Dict[str, Any], now: Optional[float] = None
+    ) -> Optional[Dict[str, Any]]:
+        """
+        Compare scan results with those of the previous scan.
+
+        The first scan only records the baseline.
+
+        Args:
+            scan_results: Results from CertificateScanner.scan_once()
+            now: Time of the scan (default: now)
+
+        Returns:
+            The changeset recorded, or None if nothing changed
+        """
+        current = {
+            cert.get("path", ""): (directory, cert)
+            for directory, result in scan_results.get("directories", {}).items()
+            for cert in result.get("certificates", [])
+        }
+        previous, self._previous = self._previous, current
+        if previous is None:
+            return None
+
+        changes: Dict[str, List[Dict[str, Any]]] = {kind: [] for kind in CHANGE_KINDS}
+        for path, (directory, cert) in current.items():
+            old = previous.get(path, (None, None))[1]
+            summary = {"path": path, "directory": directory}
+            summary.update({key: cert.get(key) for key in CHANGE_FIELDS})
+
+            if old is None:
+                changes["added"].append(summary)
+            elif not _same_certificate(cert, old):
+                renewed = cert.get("common_name") == old.get("common_name") and (
+                    cert.get("expiration_timestamp", 0) > old.get("expiration_timestamp", 0)
+                )
+                changes["renewed" if renewed else "replaced"].append(
+                    {
+                        "path": path,
+                        "directory": directory,
+                        "old": {key: old.get(key) for key in CHANGE_FIELDS},
+                        "new": {key: cert.get(key) for key in CHANGE_FIELDS},
+                    }
+                )
+
+            # Compared with what the file held before, so a renewal to a certificate that is
+            # still in the warning window is not news, but one with a weak key is
+            problems = sorted(_problems(cert) - _problems(old or {}))
+            if _severity_rank(cert) > _severity_rank(old):
+                problems.insert(0, cert["severity"])
+            if problems:
+                changes["problematic"].append({**summary, "problems": problems})
+
+        for path, (directory, old) in previous.items():
+            if path not in current:
+                summary = {"path": path, "directory": directory}
+                summary.update({key: old.get(key) for key in CHANGE_FIELDS})
+                changes["removed"].append(summary)
+
+        if not any(changes.values()):
+            return None
+
+        self._last_id += 1
+        changeset = {
+            "id": self._last_id,
+            "timestamp": datetime.fromtimestamp(
+                time.time() if now is None else now, timezone.utc
+            ).isoformat(),
+            **changes,
+        }
+        self._changesets.append(changeset)
+        return changeset
+
+    def get_changes(self, since: int = 0, limit: int = 100) -> Dict[str, Any]:
+        """
+        Get the changesets recorded after a changeset, oldest first.
+
+        Args:
+            since: Id of the last changeset the caller has seen (0: all retained)
+            limit: Most changesets returned (at most MAX_QUERY_LIMIT)
+
+        Returns:
+            The changesets, the id of the last changeset recorded, and whether changesets
+            after since were missed (truncated): already dropped, or since is from before a
+            restart
+        """
+        changesets = [changeset for changeset in self._changesets if changeset["id"] > since]
+        oldest = self._changesets[0]["id"] if self._changesets else self._last_id + 1
+        return {
+            "changes": changesets[: max(1, min(limit, MAX_QUERY_LIMIT))],
+            "last_id": self._last_id,
+            "truncated": since < oldest - 1 or since > self._last_id,
+        }
+
+    async def events(self, since: Optional[int] = None) -> AsyncIterator[str]:
+        """
+        Stream changesets as server-sent events until close().
+
+        Args:
+            since: Replay retained changesets after this id first (the Last-Event-ID of a
+                reconnecting client); None only streams new ones
+        """
+        queue: "asyncio.Queue[Optional[Dict[str, Any]]]" = asyncio.Queue()
+        # Subscribe before the replay, so no changeset falls between the two
+        self._subscribers.add(queue)
+        try:
+            last_sent = self._last_id if since is None else since
+            for changeset in list(self._changesets):
+                if changeset["id"] > last_sent:
+                    last_sent = changeset["id"]
+                    yield format_event(changeset)
+            while True:
+                try:
+                    next_changeset = await asyncio.wait_for(queue.get(), KEEPALIVE_SECONDS)
+                except asyncio.TimeoutError:
+                    yield ": keepalive\n\n"
+                    continue
+                if next_changeset is None:
+                    return
+                if next_changeset["id"] > last_sent:
+                    last_sent = next_changeset["id"]
+                    yield format_event(next_changeset)
+        finally:
+            self._subscribers.discard(queue)
+
+    def close(self) -> None:
+        """End the event streams."""
+        for queue in self._subscribers:
+            queue.put_nowait(None)
@@ -0,0 +1,182 @@
+"""
+Ad-hoc checks of a single certificate for TLS Certificate Monitor (the check command).
+
+A target is a certificate file or a TLS server (host:port). It is analyzed with the scanner's
+code, so the details and findings match what the daemon reports for it.
+"""
+
+import re
+import socket
+import ssl
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+
+from cryptography import x509
+
+from tls_cert_monitor.scanner import CertificateScanner
+
+# Seconds to connect to and complete the handshake with a TLS server
+CHECK_TIMEOUT_SECONDS = 10.0
+
+# host:port, with IPv6 addresses in brackets ([::1]:443)
+ENDPOINT_PATTERN = re.compile(
+    r"^(?:\[(?P<ipv6>[0-9A-Fa-f:.]+)\]|(?P<host>[^:\s/\\]+)):(?P<port>\d+)$"
+)
+
+# Finding severities, best first
+FINDING_SEVERITIES = ("ok", "warning", "critical")
+
+
+def parse_endpoint(target: str) -> Optional[Tuple[str, int]]:
+    """Get the host and port of a host:port target (None if it is not one)."""
+    match = ENDPOINT_PATTERN.match(target)
+    if not match or not 0 < int(match.group("port")) < 65536:
+        return None
+    return match.group("ipv6") or match.group("host"), int(match.group("port"))
+
+
+def fetch_certificate(
+    host: str, port: int, timeout: float = CHECK_TIMEOUT_SECONDS
+) -> Tuple[x509.Certificate, Optional[str]]:
+    """
+    Get the certificate of a TLS server.
+
+    The handshake is first verified against the system CAs and the host name; a server failing
+    verification is connected to again without it, so its certificate can still be analyzed.
+
+    Args:
+        host: Server host name or address (also sent as SNI)
+        port: Server port
+        timeout: Seconds to connect and complete each handshake
+
+    Returns:
+        Tuple of (server certificate, verification error or None)
+
+    Raises:
+        OSError: If the server cannot be connected to or the handshake fails
+    """
+    verify_error = None
+    try:
+        der = _get_peer_certificate(host, port, timeout, ssl.create_default_context())
+    except ssl.SSLCertVerificationError as e:
+        verify_error = e.verify_message or str(e)
+        context = ssl.create_default_context()
+        context.check_hostname = False
+        context.verify_mode = ssl.CERT_NONE
+        der = _get_peer_certificate(host, port, timeout, context)
+    return x509.load_der_x509_certificate(der), verify_error
+
+
+def _get_peer_certificate(host: str, port: int, timeout: float, context: ssl.SSLContext) -> bytes:
+    """Complete a TLS handshake and get the server certificate (DER)."""
+    with socket.create_connection((host, port), timeout=timeout) as sock:
+        with context.wrap_socket(sock, server_hostname=host) as tls_sock:
+            der = tls_sock.getpeercert(binary_form=True)
+    if not der:
+        raise OSError(f"{host}:{port} sent no certificate")
+    return der
+
+
+def check_target(
+    scanner: CertificateScanner, target: str, timeout: float = CHECK_TIMEOUT_SECONDS
+) -> Dict[str, Any]:
+    """
+    Analyze a certificate file or the certificate of a TLS server.
+
+    Args:
+        scanner: Scanner analyzing the certificate (its thresholds, P12 passwords, silences)
+        target: File path or host:port
+        timeout: Seconds to connect to a TLS server
+
+    Returns:
+        Certificate data as in scan results (verify_error set for TLS servers)
+
+    Raises:
+        RuntimeError: If a file cannot be parsed
+        OSError: If a TLS server cannot be connected to
+        ValueError: If the target is neither an existing file nor host:port
+    """
+    path = Path(target)
+    if path.is_file():
+        return scanner.check_file(path)
+
+    endpoint = parse_endpoint(target)
+    if endpoint is None:
+        raise ValueError(f"No such file, and not a host:port: {target}")
+    cert, verify_error = fetch_certificate(*endpoint, timeout=timeout)
+    cert_data = scanner.check_certificate(cert, target)
+    cert_data["verify_error"] = verify_error
+    return cert_data
+
+
+def certificate_findings(cert: Dict[str, Any]) -> List[Tuple[str, str]]:
+    """
+    Get the problems of an analyzed certificate.
+
+    Args:
+        cert: Certificate data from check_target
+
+    Returns:
+        List of (finding severity: warning or critical, description), worst first
+    """
+    findings = []
+    severity = cert.get("severity", "ok")
+    days = cert.get("days_until_expiry")
+    if severity == "expired":
+        findings.append(("critical", f"Expired on {cert.get('not_after')}"))
+    elif severity in ("critical", "warning"):
+        findings.append((severity, f"Expires in {days} days ({severity} threshold)"))
+    if cert.get("not_yet_valid"):
+        findings.append(("critical", f"Not valid before {cert.get('not_before')}"))
+    if cert.get("verify_error"):
+        findings.append(("warning", f"Verification failed: {cert['verify_error']}"))
+    if cert.get("is_weak_key"):
+        findings.append(
+            ("warning", f"Weak {cert.get('key_algorithm')} key ({cert.get('key_size')} bits)")
+        )
+    if cert.get("is_deprecated_algorithm"):
+        findings.append(
+            ("warning", f"Deprecated signature algorithm {cert.get('signature_algorithm')}")
+        )
+    findings.sort(key=lambda finding: -FINDING_SEVERITIES.index(finding[0]))
+    return findings
+
+
+def worst_finding(findings: List[Tuple[str, str]]) -> str:
+    """Get the worst severity of findings (ok if there are none)."""
+    return max((severity for severity, _ in findings), key=FINDING_SEVERITIES.index, default="ok")
+
+
+def format_check(cert: Dict[str, Any], findings: List[Tuple[str, str]]) -> str:
+    """
+    Format an analyzed certificate and its findings for the terminal.
+
+    Args:
+        cert: Certificate data from check_target
+        findings: Findings from certificate_findings
+
+    Returns:
+        Human-readable report
+    """
+    lines = [
+        f"Target:              {cert.get('path')}",
+        f"Common name:         {cert.get('common_name', 'unknown')}",
+        f"Subject:             {cert.get('subject', '')}",
+        f"Issuer:              {cert.get('issuer', '')}",
+        f"Serial:              {cert.get('serial', '')}",
+        f"SHA-256 fingerprint: {cert.get('fingerprint_sha256', '')}",
+        f"Not before:          {cert.get('not_before', '')}",
+        f"Not after:           {cert.get('not_after', '')} "
+        f"({cert.get('days_until_expiry')} days, {cert.get('severity', 'ok')})",
+        f"Key:                 {cert.get('key_algorithm')} ({cert.get('key_size')} bits)",
+        f"Signature algorithm: {cert.get('signature_algorithm')}",
+        f"SANs:                {', '.join(cert.get('san_list', [])) or 'none'}",
+    ]
+    if cert.get("silenced"):
+        lines.append(f"Silenced:            yes ({cert.get('silence_id')})")
+    if findings:
+        lines.append("Findings:")
+        lines.extend(f"  {severity.upper():<9} {description}" for severity, description in findings)
+    else:
+        lines.append("Findings:            none")
+    return "\n".join(lines)
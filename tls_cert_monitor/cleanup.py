@@ -0,0 +1,96 @@
+"""
+Expired certificate clean-up suggestions for TLS Certificate Monitor.
+
+Certificates expired for more than a number of days are listed with their counterparts in the
+scan results: other files holding the same certificate (duplicates, which are as expired) and
+certificates of the same common name that expire later and are still valid (renewals, whose
+presence shows the expired file is most likely left over). Operators get a list of files to
+review and remove, served by /api/v1/cleanup, instead of hunting through metrics.
+"""
+
+import time
+from collections import defaultdict
+from datetime import datetime, timezone
+from typing import Any, Dict, List, Optional
+
+from tls_cert_monitor.history import certificate_fingerprint
+from tls_cert_monitor.output import certificates_of
+
+# Days a certificate must have been expired for to be suggested, unless given
+DEFAULT_EXPIRED_DAYS = 30
+
+# Certificate fields reported for renewals
+RENEWAL_FIELDS = ("path", "common_name", "fingerprint_sha256", "not_after", "days_until_expiry")
+
+
+def build_cleanup(
+    scan_results: Dict[str, Any],
+    expired_days: int = DEFAULT_EXPIRED_DAYS,
+    now: Optional[float] = None,
+) -> Dict[str, Any]:
+    """
+    List the certificates of scan results expired for more than a number of days.
+
+    Args:
+        scan_results: Results from CertificateScanner.scan_once()
+        expired_days: Days since expiry after which certificates are suggested
+        now: Current time (default: now)
+
+    Returns:
+        The suggestions, longest expired first, each with its duplicates and renewals
+    """
+    now = time.time() if now is None else now
+    directories = {
+        cert.get("path", ""): directory
+        for directory, result in scan_results.get("directories", {}).items()
+        for cert in result.get("certificates", [])
+    }
+    certificates = certificates_of(scan_results)
+    by_fingerprint: Dict[str, List[Dict[str, Any]]] = defaultdict(list)
+    by_common_name: Dict[str, List[Dict[str, Any]]] = defaultdict(list)
+    for cert in certificates:
+        by_fingerprint[certificate_fingerprint(cert)].append(cert)
+        if cert.get("common_name"):
+            by_common_name[cert["common_name"]].append(cert)
+
+    suggestions: List[Dict[str, Any]] = []
+    for cert in certificates:
+        expiration = cert.get("expiration_timestamp")
+        if expiration is None:
+            continue
+        days_expired = int((now - expiration) // 86400)
+        if now <= expiration or days_expired < expired_days:
+            continue
+
+        path = cert.get("path", "")
+        suggestions.append(
+            {
+                "path": path,
+                "directory": directories.get(path, ""),
+                "common_name": cert.get("common_name"),
+                "issuer": cert.get("issuer"),
+                "serial": cert.get("serial"),
+                "fingerprint_sha256": cert.get("fingerprint_sha256"),
+                "not_after": cert.get("not_after"),
+                "days_expired": days_expired,
+                "silenced": bool(cert.get("silenced")),
+                "labels": cert.get("labels") or {},
+                "duplicates": sorted(
+                    other.get("path", "")
+                    for other in by_fingerprint[certificate_fingerprint(cert)]
+                    if other.get("path") != path
+                ),
+                "renewed_by": [
+                    {key: other.get(key) for key in RENEWAL_FIELDS}
+                    for other in by_common_name.get(cert.get("common_name") or "", [])
+                    if other.get("expiration_timestamp", 0) > now
+                ],
+            }
+        )
+
+    suggestions.sort(key=lambda suggestion: (-suggestion["days_expired"], suggestion["path"]))
+    return {
+        "generated_at": datetime.fromtimestamp(now, timezone.utc).isoformat(),
+        "expired_days": expired_days,
+        "certificates": suggestions,
+    }
@@ -2,19 +2,847 @@
 Configuration management for TLS Certificate Monitor.
 """
 
+import difflib
+import fnmatch
 import logging
+import logging.handlers
 import os
+import platform
 import re
+from datetime import datetime
 from pathlib import Path
-from typing import Any, Callable, Dict, List, Optional
+from typing import (
+    Any,
+    Callable,
+    ClassVar,
+    Dict,
+    List,
+    Optional,
+    Set,
+    Tuple,
+    Union,
+    get_origin,
+)
 
 import yaml
-from pydantic import BaseModel, Field, field_validator
+from pydantic import BaseModel, ConfigDict, Field, field_validator, model_validator
 
+from tls_cert_monitor.encrypted_config import read_config_file
+from tls_cert_monitor.remote_config import load_remote_config
+from tls_cert_monitor.schedule import CronSchedule
 
-class Config(BaseModel):
+
+FILE_REFERENCE_SUFFIX = "_file"
+
+LOG_LEVELS = ("DEBUG", "INFO", "WARNING", "ERROR", "CRITICAL")
+
+# Certificate fields silences can match, besides labels.<name> for directory and group labels
+SILENCE_MATCHER_FIELDS = (
+    "path",
+    "filename",
+    "common_name",
+    "subject",
+    "issuer",
+    "serial",
+    "fingerprint_sha256",
+    "san_list",
+    "not_before",
+    "not_after",
+    "severity",
+    "key_algorithm",
+    "key_size",
+    "signature_algorithm",
+    "is_weak_key",
+    "is_deprecated_algorithm",
+)
+SILENCE_LABEL_PREFIX = "labels."
+
+
+def read_file_reference(key: str, path: Any) -> str:
+    """Read the value of a *_file reference, dropping the trailing newline."""
+    try:
+        with open(str(path), "r", encoding="utf-8") as f:
+            content = f.read()
+    except OSError as e:
+        raise ValueError(f"Cannot read {key} '{path}': {e}") from e
+    return content[:-1] if content.endswith("\n") else content
+
+
+def program_data_dir() -> Path:
+    """Get the system-wide application data directory on Windows (%ProgramData%)."""
+    return Path(os.getenv("PROGRAMDATA") or r"C:\ProgramData") / "tls-cert-monitor"
+
+
+def default_certificate_directories() -> List[str]:
+    """Get the platform's default certificate directories."""
+    if platform.system() == "Windows":
+        # Windows keeps certificates in the certificate store; exported files go here
+        return [str(program_data_dir() / "certs")]
+    return ["/etc/ssl/certs"]
+
+
+def default_cache_dir() -> str:
+    """Get the platform's default cache directory."""
+    if platform.system() == "Windows":
+        # Services start in C:\Windows\System32, so a relative path is not usable
+        return str(program_data_dir() / "cache")
+    return "./cache"
+
+
+class StrictModel(BaseModel):
+    """Base for configuration models: unknown keys (typos) are rejected, not ignored."""
+
+    model_config = ConfigDict(extra="forbid")
+
+    # Fields that may be given as <field>_file instead of inline
+    secret_fields: ClassVar[Set[str]] = set()
+
+    @classmethod
+    def is_file_reference(cls, key: Any) -> bool:
+        """Check if a key is a <field>_file reference to a secret field of the model."""
+        key = str(key)
+        return (
+            key.endswith(FILE_REFERENCE_SUFFIX)
+            and key[: -len(FILE_REFERENCE_SUFFIX)] in cls.secret_fields
+        )
+
+    @model_validator(mode="before")
+    @classmethod
+    def read_file_references(cls, data: Any) -> Any:
+        """
+        Replace <field>_file keys with the contents of the referenced file.
+
+        Secrets (passwords, tokens) can then be mounted from files, e.g. Kubernetes
+        Secrets, instead of being inlined. List fields read one value per line.
+        The file is read at load time; reload (e.g. SIGHUP) to pick up rotations.
+        """
+        if not isinstance(data, dict):
+            return data
+
+        data = dict(data)
+        for key in [k for k in data if cls.is_file_reference(k)]:
+            field_name = key[: -len(FILE_REFERENCE_SUFFIX)]
+            if data.get(field_name) is not None:
+                raise ValueError(f"Set either '{field_name}' or '{key}', not both")
+
+            value = read_file_reference(key, data.pop(key))
+            if get_origin(cls.model_fields[field_name].annotation) is list:
+                data[field_name] = value.splitlines()
+            else:
+                data[field_name] = value
+        return data
+
+
+def validate_duration_format(v: str) -> str:
+    """Validate duration format (e.g., '5m', '1h', '30s', '7d')."""
+    if not v:
+        raise ValueError("Duration cannot be empty")
+
+    # Simple validation for duration format
+    # Use raw string to avoid escaping issues
+    pattern = r"^\d+[smhd]$"
+    if not re.match(pattern, v):
+        raise ValueError("Duration must be in format like '5m', '1h', '30s', '1d'")
+    return v
+
+
+def parse_duration(duration: str) -> int:
+    """Parse duration string to seconds."""
+    match = re.match(r"^(\d+)([smhd])$", duration)
+    if not match:
+        raise ValueError(f"Invalid duration format: {duration}")
+
+    value, unit = match.groups()
+
+    multipliers = {"s": 1, "m": 60, "h": 3600, "d": 86400}
+
+    return int(value) * multipliers[unit]
+
+
+def split_host_port(address: str, default_port: int, kind: str) -> Tuple[str, int]:
+    """
+    Split a host[:port] address ([::1]:514 for IPv6) into (host, port).
+
+    Args:
+        address: Address to split
+        default_port: Port of an address without one
+        kind: What the address is of, for error messages (e.g. syslog)
+
+    Raises:
+        ValueError: If the address is invalid
+    """
+    if address.startswith("["):
+        host, _, rest = address[1:].partition("]")
+        port = rest[1:] if rest.startswith(":") else ""
+        if rest and not port:
+            raise ValueError(f"Invalid {kind} address: {address}")
+    elif address.count(":") == 1:
+        host, _, port = address.partition(":")
+    else:
+        host, port = address, ""
+    if not host or (port and not port.isdigit()):
+        raise ValueError(f"Invalid {kind} address: {address}")
+    return host, int(port) if port else default_port
+
+
+class ExpiryThresholds(StrictModel):
+    """Expiry thresholds for warning and critical severities."""
+
+    warning: str = Field(default="30d")
+    critical: str = Field(default="7d")
+
+    @field_validator("warning", "critical")
+    @classmethod
+    def validate_duration(cls, v: str) -> str:
+        """Validate threshold duration format."""
+        return validate_duration_format(v)
+
+    @model_validator(mode="after")
+    def validate_order(self) -> "ExpiryThresholds":
+        """Critical must not be further out than warning."""
+        if self.critical_seconds > self.warning_seconds:
+            raise ValueError("expiry_thresholds.critical must not exceed expiry_thresholds.warning")
+        return self
+
+    @property
+    def warning_seconds(self) -> int:
+        """Get warning threshold in seconds."""
+        return parse_duration(self.warning)
+
+    @property
+    def critical_seconds(self) -> int:
+        """Get critical threshold in seconds."""
+        return parse_duration(self.critical)
+
+
+class HealthThresholds(StrictModel):
+    """Thresholds at which /healthz reports degraded or unhealthy."""
+
+    # Percentage of disk space used on a certificate directory's filesystem
+    disk_usage_degraded: float = Field(default=80, ge=0, le=100)
+    disk_usage_unhealthy: float = Field(default=90, ge=0, le=100)
+    # Cache hit rate (0-1) below which the cache is not doing its job
+    cache_hit_rate_degraded: float = Field(default=0.5, ge=0, le=1)
+    # Persistent cache saves failing in a row (a full disk, a read-only cache_dir, ...)
+    cache_save_failures_degraded: int = Field(default=2, ge=1)
+    # Share (0-1) of the files of the last scan that failed to parse
+    parse_error_ratio_degraded: float = Field(default=0.1, ge=0, le=1)
+    # Whether parse errors count toward that share at all (directories can override this with
+    # their own parse_errors_degrade, e.g. to exempt one that intentionally holds other files)
+    parse_errors_degrade: bool = Field(default=True)
+    # Status caused by (unsilenced) certificates in each expiry bucket: healthy (ignore them),
+    # degraded or unhealthy
+    certificates_warning: str = Field(default="degraded")
+    certificates_critical: str = Field(default="degraded")
+    certificates_expired: str = Field(default="unhealthy")
+    # A scan running longer than scan_stall_factor times the average of recent scans (and at
+    # least scan_stall_min) is stalled, e.g. on a hung network filesystem
+    scan_stall_factor: float = Field(default=5, ge=1)
+    scan_stall_min: str = Field(default="15m")
+
+    @field_validator("scan_stall_min")
+    @classmethod
+    def validate_duration(cls, v: str) -> str:
+        """Validate the minimum stall duration format."""
+        return validate_duration_format(v)
+
+    @property
+    def scan_stall_min_seconds(self) -> int:
+        """Get the minimum stall duration in seconds."""
+        return parse_duration(self.scan_stall_min)
+
+    @field_validator("certificates_warning", "certificates_critical", "certificates_expired")
+    @classmethod
+    def validate_status(cls, v: str) -> str:
+        """Validate the health status caused by expiring certificates."""
+        if v not in ("healthy", "degraded", "unhealthy"):
+            raise ValueError("Status must be 'healthy', 'degraded' or 'unhealthy'")
+        return v
+
+    @model_validator(mode="after")
+    def validate_order(self) -> "HealthThresholds":
+        """Disk usage must degrade before it is unhealthy."""
+        if self.disk_usage_degraded > self.disk_usage_unhealthy:
+            raise ValueError(
+                "health_thresholds.disk_usage_degraded must not exceed "
+                "health_thresholds.disk_usage_unhealthy"
+            )
+        return self
+
+
+class SyslogConfig(StrictModel):
+    """Syslog log sink: the local syslog socket or a remote server."""
+
+    # Default ports of remote syslog servers, by protocol
+    DEFAULT_PORTS: ClassVar[Dict[str, int]] = {"udp": 514, "tcp": 514, "tls": 6514}
+
+    enabled: bool = Field(default=False)
+    # Remote server as host[:port] ([::1]:514 for IPv6); empty logs to the local socket
+    address: str = Field(default="")
+    # Transport to a remote server: udp, tcp or tls
+    protocol: str = Field(default="udp")
+    facility: str = Field(default="daemon")
+    # APP-NAME of the RFC 5424 messages
+    app_name: str = Field(default="tls-cert-monitor")
+    # CA bundle verifying a tls server (system CAs by default)
+    ca_file: Optional[str] = None
+
+    @field_validator("protocol")
+    @classmethod
+    def validate_protocol(cls, v: str) -> str:
+        """Validate the syslog transport."""
+        if v not in cls.DEFAULT_PORTS:
+            raise ValueError(f"Syslog protocol must be one of {sorted(cls.DEFAULT_PORTS)}")
+        return v
+
+    @field_validator("facility")
+    @classmethod
+    def validate_facility(cls, v: str) -> str:
+        """Validate the syslog facility name."""
+        if v not in logging.handlers.SysLogHandler.facility_names:
+            raise ValueError(f"Unknown syslog facility: {v}")
+        return v
+
+    @field_validator("app_name")
+    @classmethod
+    def validate_app_name(cls, v: str) -> str:
+        """APP-NAME is up to 48 printable ASCII characters (RFC 5424)."""
+        if not 0 < len(v) <= 48 or not all(33 <= ord(c) <= 126 for c in v):
+            raise ValueError("app_name must be 1-48 printable ASCII characters without spaces")
+        return v
+
+    @model_validator(mode="after")
+    def validate_address(self) -> "SyslogConfig":
+        """The remote address must parse."""
+        if self.address:
+            self.server_address()
+        return self
+
+    def server_address(self) -> Tuple[str, int]:
+        """
+        Get the remote server as (host, port), with the protocol's default port.
+
+        Raises:
+            ValueError: If the address is invalid
+        """
+        return split_host_port(self.address, self.DEFAULT_PORTS[self.protocol], "syslog")
+
+
+class LogSamplingConfig(StrictModel):
+    """Sampling of repetitive DEBUG and INFO records (e.g. a message logged per file)."""
+
+    enabled: bool = Field(default=False)
+    # Each second, the first records of a log statement are all kept...
+    initial: int = Field(default=100, ge=1)
+    # ...then every Nth (0: none) until the next second
+    thereafter: int = Field(default=100, ge=0)
+
+
+class LogFilePermissionsConfig(StrictModel):
+    """
+    Permissions of log_file and its directory (POSIX), e.g. for a log shipper in the log group.
+
+    Unset settings leave the umask and the running user in charge.
+    """
+
+    # Octal, quoted ("0640"): YAML reads unquoted 0640 as an octal number as well
+    mode: Optional[int] = None
+    directory_mode: Optional[int] = None
+    # User and group (names or IDs) owning the file and directory; changing the owner needs root
+    owner: Optional[str] = None
+    group: Optional[str] = None
+
+    @field_validator("mode", "directory_mode", mode="before")
+    @classmethod
+    def validate_mode(cls, v: Optional[Union[int, str]]) -> Optional[int]:
+        """Validate a permission mode, given as an octal string or an already parsed number."""
+        if v is None:
+            return None
+        if isinstance(v, str):
+            try:
+                v = int(v, 8)
+            except ValueError as e:
+                raise ValueError(f"Invalid permission mode (octal, e.g. '0640'): {v}") from e
+        if not 0 <= v <= 0o777:
+            raise ValueError(f"Invalid permission mode (octal, e.g. '0640'): {v:o}")
+        return v
+
+    @field_validator("owner", "group", mode="before")
+    @classmethod
+    def validate_account(cls, v: Any) -> Optional[str]:
+        """Accept numeric user and group IDs."""
+        return str(v) if isinstance(v, int) else v
+
+
+class EventLogConfig(StrictModel):
+    """Windows Event Log sink (Application log), for warnings and errors by default."""
+
+    enabled: bool = Field(default=False)
+    # Event source shown in Event Viewer
+    source: str = Field(default="TLSCertMonitor")
+    # Records of this level and above are reported (independent of log_level)
+    level: str = Field(default="WARNING")
+
+    @field_validator("level")
+    @classmethod
+    def validate_level(cls, v: str) -> str:
+        """Validate the minimum level."""
+        if v.upper() not in LOG_LEVELS:
+            raise ValueError(f"Event Log level must be one of: {list(LOG_LEVELS)}")
+        return v.upper()
+
+    @field_validator("source")
+    @classmethod
+    def validate_source(cls, v: str) -> str:
+        """The source is a registry key name."""
+        if not v or "\\" in v:
+            raise ValueError("Event Log source must be a non-empty name without backslashes")
+        return v
+
+
+class TracingConfig(StrictModel):
+    """OpenTelemetry tracing of scans and HTTP requests, exported over OTLP."""
+
+    # Protocols of OTLP exporters
+    PROTOCOLS: ClassVar[Tuple[str, ...]] = ("http/protobuf", "grpc")
+
+    enabled: bool = Field(default=False)
+    # Collector endpoint; empty: OTEL_EXPORTER_OTLP_ENDPOINT or localhost (4318 http, 4317 grpc)
+    endpoint: str = Field(default="")
+    protocol: str = Field(default="http/protobuf")
+    # Headers sent to the collector (e.g. an authorization token)
+    headers: Dict[str, str] = Field(default_factory=dict)
+    service_name: str = Field(default="tls-cert-monitor")
+    # Share of traces recorded (a caller's sampling decision is kept)
+    sample_ratio: float = Field(default=1.0, ge=0.0, le=1.0)
+    # Span per certificate file (turn off for very large trees)
+    file_spans: bool = Field(default=True)
+
+    @field_validator("protocol")
+    @classmethod
+    def validate_protocol(cls, v: str) -> str:
+        """Validate the OTLP protocol."""
+        if v not in cls.PROTOCOLS:
+            raise ValueError(f"Tracing protocol must be one of {list(cls.PROTOCOLS)}")
+        return v
+
+
+class AuditLogConfig(StrictModel):
+    """Audit log of mutating API operations, separate from the application log."""
+
+    enabled: bool = Field(default=False)
+    # JSON lines file (rotated like log_file)
+    file: Optional[str] = None
+    # Also send audit records to the syslog destination (syslog.address, syslog.protocol)
+    syslog: bool = Field(default=False)
+    syslog_facility: str = Field(default="authpriv")
+    # Header carrying the user authenticated by a reverse proxy (e.g. X-Forwarded-User)
+    principal_header: Optional[str] = None
+
+    @field_validator("syslog_facility")
+    @classmethod
+    def validate_facility(cls, v: str) -> str:
+        """Validate the syslog facility name."""
+        if v not in logging.handlers.SysLogHandler.facility_names:
+            raise ValueError(f"Unknown syslog facility: {v}")
+        return v
+
+    @model_validator(mode="after")
+    def validate_destination(self) -> "AuditLogConfig":
+        """An enabled audit log needs a destination."""
+        if self.enabled and not self.file and not self.syslog:
+            raise ValueError("audit_log needs a file or syslog: true when enabled")
+        return self
+
+
+class SilenceConfig(StrictModel):
+    """Silence (maintenance window) matching certificates by field patterns."""
+
+    id: Optional[str] = None
+    # Certificate field or labels.<name> -> glob pattern (e.g. path: "/etc/ssl/test/*",
+    # issuer: "*Staging*", labels.team: "payments")
+    matchers: Dict[str, str]
+    starts_at: Optional[datetime] = None
+    ends_at: Optional[datetime] = None
+    comment: str = ""
+    created_by: str = ""
+
+    @field_validator("matchers")
+    @classmethod
+    def validate_matchers(cls, v: Dict[str, str]) -> Dict[str, str]:
+        """
+        Require at least one matcher so a silence can't match everything by accident, and
+        known fields only, since a misspelled one would never match.
+        """
+        if not v:
+            raise ValueError("Silence must define at least one matcher")
+        for field in v:
+            if field.startswith(SILENCE_LABEL_PREFIX) and len(field) > len(SILENCE_LABEL_PREFIX):
+                continue
+            if field not in SILENCE_MATCHER_FIELDS:
+                raise ValueError(
+                    f"Unknown silence matcher '{field}' (expected labels.<name> or one of "
+                    f"{', '.join(SILENCE_MATCHER_FIELDS)})"
+                )
+        return v
+
+    @model_validator(mode="after")
+    def validate_window(self) -> "SilenceConfig":
+        """Validate that the silence window is not inverted."""
+        if self.starts_at and self.ends_at and self.ends_at <= self.starts_at:
+            raise ValueError("Silence ends_at must be after starts_at")
+        return self
+
+
+class NotificationTemplateConfig(StrictModel):
+    """Title/body template overrides for a notifier ($placeholder syntax)."""
+
+    title: Optional[str] = None
+    body: Optional[str] = None
+    # Files take precedence over the inline templates above
+    title_file: Optional[str] = None
+    body_file: Optional[str] = None
+
+    @field_validator("title_file", "body_file")
+    @classmethod
+    def validate_template_file(cls, v: Optional[str]) -> Optional[str]:
+        """Validate template files exist."""
+        if v is not None and not Path(v).is_file():
+            raise ValueError(f"Template file not found: {v}")
+        return v
+
+
+class NotifierConfig(StrictModel):
+    """Named notification target (email, webhook, Alertmanager, SNS, chat or ticketing)."""
+
+    # Webhook URLs (Discord, chat integrations) often embed tokens
+    secret_fields: ClassVar[Set[str]] = {"smtp_password", "password", "bot_token", "url"}
+
+    name: str
+    type: str
+
+    # Email (SMTP) settings
+    smtp_host: Optional[str] = None
+    smtp_port: int = Field(default=587, ge=1, le=65535)
+    smtp_username: Optional[str] = None
+    smtp_password: Optional[str] = None
+    smtp_starttls: bool = Field(default=True)
+    from_address: Optional[str] = None
+    to_addresses: List[str] = Field(default_factory=list)
+
+    # Webhook (JSON POST) and Alertmanager (base URL) settings
+    url: Optional[str] = None
+    headers: Dict[str, str] = Field(default_factory=dict)
+    # Link shown for alerts in Alertmanager (e.g. the monitor's /healthz page)
+    generator_url: Optional[str] = None
+
+    # AWS SNS settings (credentials from the standard AWS chain: env, profile, instance role)
+    topic_arn: Optional[str] = None
+    region: Optional[str] = None
+    aws_profile: Optional[str] = None
+    # Optional IAM role assumed before publishing (cross-account topics)
+    role_arn: Optional[str] = None
+
+    # Telegram / Discord bot settings (Discord also accepts a channel webhook as url)
+    bot_token: Optional[str] = None
+    chat_id: Optional[str] = None
+    channel_id: Optional[str] = None
+
+    # Jira / ServiceNow ticket settings (url is the instance base URL)
+    username: Optional[str] = None
+    password: Optional[str] = None  # Password or API token
+    project: Optional[str] = None  # Jira project key
+    issue_type: str = Field(default="Task")
+    close_transition: str = Field(default="Done")  # Jira transition applied after renewal
+    table: str = Field(default="incident")  # ServiceNow table
+    queue: Optional[str] = None  # ServiceNow assignment group
+    close_state: str = Field(default="6")  # ServiceNow state applied after renewal (Resolved)
+
+    timeout: int = Field(default=10, ge=1, le=300)
+
+    # Optional message template overrides
+    template: Optional[NotificationTemplateConfig] = None
+
+    @field_validator("type")
+    @classmethod
+    def validate_type(cls, v: str) -> str:
+        """Validate notifier type."""
+        valid_types = {
+            "email",
+            "webhook",
+            "alertmanager",
+            "sns",
+            "telegram",
+            "discord",
+            "jira",
+            "servicenow",
+        }
+        if v.lower() not in valid_types:
+            raise ValueError(f"Notifier type must be one of {valid_types}, got '{v}'")
+        return v.lower()
+
+    @model_validator(mode="after")
+    def validate_required_fields(self) -> "NotifierConfig":
+        """Validate that the fields required by the notifier type are set."""
+        if self.type == "email":
+            if not self.smtp_host or not self.from_address or not self.to_addresses:
+                raise ValueError(
+                    f"Email notifier '{self.name}' requires smtp_host, from_address "
+                    "and to_addresses"
+                )
+        elif self.type in ("webhook", "alertmanager", "servicenow") and not self.url:
+            raise ValueError(f"{self.type.capitalize()} notifier '{self.name}' requires url")
+        elif self.type == "jira" and not (self.url and self.project):
+            raise ValueError(f"Jira notifier '{self.name}' requires url and project")
+        elif self.type == "sns" and not self.topic_arn:
+            raise ValueError(f"SNS notifier '{self.name}' requires topic_arn")
+        elif self.type == "telegram" and not (self.bot_token and self.chat_id):
+            raise ValueError(f"Telegram notifier '{self.name}' requires bot_token and chat_id")
+        elif self.type == "discord" and not (self.url or (self.bot_token and self.channel_id)):
+            raise ValueError(
+                f"Discord notifier '{self.name}' requires a webhook url or bot_token and channel_id"
+            )
+        return self
+
+
+class DigestConfig(StrictModel):
+    """Scheduled digest report settings."""
+
+    enabled: bool = Field(default=False)
+    # Cron expression (minute hour day-of-month month day-of-week, local time)
+    # or one of @hourly, @daily, @weekly
+    schedule: str = Field(default="@daily")
+    # Names of notifiers (from the top-level notifiers list) receiving the digest
+    notifiers: List[str] = Field(default_factory=list)
+
+    @field_validator("schedule")
+    @classmethod
+    def validate_schedule(cls, v: str) -> str:
+        """Validate cron schedule expression."""
+        CronSchedule(v)
+        return v
+
+
+class AlertRouteConfig(StrictModel):
+    """Route sending matching alerts to specific notifiers."""
+
+    model_config = ConfigDict(populate_by_name=True)
+
+    # Certificate directories the route applies to (alerts for files below them)
+    directories: List[str] = Field(default_factory=list)
+    # Alert label -> glob pattern (e.g. rule: "content_change", common_name: "*.team-a.com")
+    matchers: Dict[str, str] = Field(default_factory=dict)
+    notifiers: List[str]
+    # Keep evaluating later routes after this one matched
+    continue_matching: bool = Field(default=False, alias="continue")
+
+
+class AlertsConfig(StrictModel):
+    """Built-in alert rules evaluated after every scan."""
+
+    enabled: bool = Field(default=False)
+    # Default notifiers (from the top-level notifiers list) for alerts matching no route
+    notifiers: List[str] = Field(default_factory=list)
+    # Routes evaluated in order; the first match wins unless it sets continue
+    routes: List[AlertRouteConfig] = Field(default_factory=list)
+    # Alert the first time a certificate with a weak key or deprecated algorithm is seen
+    weak_crypto: bool = Field(default=True)
+    # Alert when a certificate enters the warning or critical expiry window (expiry_thresholds),
+    # again when it moves to the other one, and resolve after renewal
+    expiry: bool = Field(default=True)
+    # Alert when the certificate in a monitored file changes (renewal or replacement)
+    content_change: bool = Field(default=True)
+    # Alert when a directory's parse errors in one scan reach a count and/or a ratio
+    # of processed files (unset disables the condition)
+    parse_error_count: Optional[int] = Field(default=None, ge=1)
+    parse_error_ratio: Optional[float] = Field(default=None, gt=0.0, le=1.0)
+
+
+class ZabbixConfig(StrictModel):
+    """Zabbix sender: certificate expiry items pushed to a Zabbix trapper after every scan."""
+
+    # Default port of Zabbix server and proxy trappers
+    DEFAULT_PORT: ClassVar[int] = 10051
+
+    enabled: bool = Field(default=False)
+    # Zabbix server or proxy as host[:port] ([::1]:10051 for IPv6)
+    server: str = Field(default="")
+    # Zabbix host the items belong to; empty: this machine's host name
+    host: str = Field(default="")
+    # Prefix of the item keys: <prefix>.discovery, <prefix>.days[<path>], ...
+    key_prefix: str = Field(default="tls_cert")
+    # Time to connect to the trapper and get its response
+    timeout: str = Field(default="10s")
+
+    @field_validator("timeout")
+    @classmethod
+    def validate_timeout(cls, v: str) -> str:
+        """Validate the timeout duration."""
+        validate_duration_format(v)
+        if parse_duration(v) <= 0:
+            raise ValueError("zabbix.timeout must be positive")
+        return v
+
+    @field_validator("key_prefix")
+    @classmethod
+    def validate_key_prefix(cls, v: str) -> str:
+        """Item keys are made of letters, digits, underscores, dashes and dots."""
+        if not re.match(r"^[A-Za-z0-9_.-]+$", v):
+            raise ValueError(f"Invalid zabbix.key_prefix: {v!r}")
+        return v
+
+    @model_validator(mode="after")
+    def validate_server(self) -> "ZabbixConfig":
+        """An enabled sender needs a server address that parses."""
+        if self.enabled and not self.server:
+            raise ValueError("zabbix.server is required when zabbix.enabled is true")
+        if self.server:
+            self.server_address()
+        return self
+
+    def server_address(self) -> Tuple[str, int]:
+        """
+        Get the trapper as (host, port), with the default port.
+
+        Raises:
+            ValueError: If the address is invalid
+        """
+        return split_host_port(self.server, self.DEFAULT_PORT, "Zabbix server")
+
+    @property
+    def timeout_seconds(self) -> int:
+        """Get the timeout in seconds."""
+        return parse_duration(self.timeout)
+
+
+class SnmpConfig(StrictModel):
+    """SNMP agent: read-only summary objects of the last scan for SNMPv1/v2c managers."""
+
+    secret_fields: ClassVar[Set[str]] = {"community"}
+
+    enabled: bool = Field(default=False)
+    bind_address: str = Field(default="127.0.0.1")
+    # 161 needs privileges; map it with a firewall rule or grant CAP_NET_BIND_SERVICE
+    port: int = Field(default=1161, ge=1, le=65535)
+    # Requests with another community are dropped
+    community: str = Field(default="public")
+    # Objects are <base_oid>.<n>.0; the default is the net-snmp experimental placeholder,
+    # use an arc of your organization's enterprise number in production
+    base_oid: str = Field(default="1.3.6.1.4.1.8072.9999.9999")
+
+    @field_validator("base_oid")
+    @classmethod
+    def validate_base_oid(cls, v: str) -> str:
+        """The base OID is dotted numbers starting with 0, 1 or 2."""
+        if not re.match(r"^[012](\.\d+)+$", v):
+            raise ValueError(f"Invalid snmp.base_oid: {v!r}")
+        return v
+
+    @field_validator("community")
+    @classmethod
+    def validate_community(cls, v: str) -> str:
+        """Validate the community is not empty."""
+        if not v:
+            raise ValueError("snmp.community must not be empty")
+        return v
+
+
+class HistoryConfig(StrictModel):
+    """Certificate history: every certificate observed by the scans, in an SQLite database."""
+
+    enabled: bool = Field(default=False)
+    # Database file; empty: history.db in cache_dir
+    path: str = Field(default="")
+    # Drop certificates (and files they were found in) not seen for this long; 0s keeps all
+    retention: str = Field(default="365d")
+
+    @field_validator("retention")
+    @classmethod
+    def validate_retention(cls, v: str) -> str:
+        """Validate the retention duration."""
+        validate_duration_format(v)
+        return v
+
+    @property
+    def retention_seconds(self) -> int:
+        """Get the retention in seconds (0: keep everything)."""
+        return parse_duration(self.retention)
+
+
+class ScanStateConfig(StrictModel):
+    """The last scan's results, kept across restarts and served (flagged stale) until a scan."""
+
+    enabled: bool = Field(default=True)
+    # State file; empty: scan_state.json in cache_dir (without cache_dir it is not kept)
+    path: str = Field(default="")
+    # State older than this is not restored; 0s restores any
+    max_age: str = Field(default="7d")
+
+    @field_validator("max_age")
+    @classmethod
+    def validate_max_age(cls, v: str) -> str:
+        """Validate the maximum age duration."""
+        validate_duration_format(v)
+        return v
+
+    @property
+    def max_age_seconds(self) -> int:
+        """Get the maximum age in seconds (0: any age)."""
+        return parse_duration(self.max_age)
+
+
+class ChangesConfig(StrictModel):
+    """Changes between consecutive scans, served by /api/v1/changes and /api/v1/events."""
+
+    enabled: bool = Field(default=True)
+    # Changesets (scans with changes) kept for /api/v1/changes and event stream replays
+    max_changesets: int = Field(default=100, ge=1, le=10000)
+
+
+class GroupConfig(StrictModel):
+    """A named group of certificate directories, with the labels of the team owning them."""
+
+    description: str = Field(default="")
+    # Attached to the certificates and alerts of the group's directories (e.g. team, service)
+    labels: Dict[str, str] = Field(default_factory=dict)
+
+
+class DirectoryConfig(StrictModel):
+    """Per-directory settings for a certificate_directories entry given as an object."""
+
+    path: str
+    interval: Optional[str] = None  # Scan interval (defaults to scan_interval)
+    excludes: List[str] = Field(default_factory=list)  # Extra exclude_file_patterns
+    labels: Dict[str, str] = Field(default_factory=dict)  # Attached to certs and alerts
+    group: Optional[str] = None  # Name of a groups entry, whose labels apply too
+    workers: Optional[int] = Field(default=None, ge=1, le=32)  # Defaults to workers
+    # Count parse errors toward the /healthz parse error ratio (defaults to
+    # health_thresholds.parse_errors_degrade)
+    parse_errors_degrade: Optional[bool] = None
+
+    @field_validator("interval")
+    @classmethod
+    def validate_interval(cls, v: Optional[str]) -> Optional[str]:
+        """Validate scan interval duration format."""
+        return validate_duration_format(v) if v is not None else v
+
+    @field_validator("excludes")
+    @classmethod
+    def validate_excludes(cls, v: List[str]) -> List[str]:
+        """Validate exclude patterns are valid regex."""
+        for pattern in v:
+            try:
+                re.compile(pattern)
+            except re.error as e:
+                raise ValueError(f"Invalid exclude pattern '{pattern}': {e}") from e
+        return v
+
+
+class Config(StrictModel):
     """Configuration model for TLS Certificate Monitor."""
 
+    secret_fields: ClassVar[Set[str]] = {"p12_passwords", "tls_key_password"}
+
     # Server settings
     port: int = Field(default=3200, ge=1, le=65535)
     bind_address: str = Field(default="0.0.0.0")  # nosec B104
@@ -22,11 +850,25 @@ class Config(BaseModel):
     # TLS settings for metrics endpoint
     tls_cert: Optional[str] = None
     tls_key: Optional[str] = None
-
-    # Certificate monitoring
-    certificate_directories: List[str] = Field(default_factory=lambda: ["/etc/ssl/certs"])
+    tls_key_password: Optional[str] = None  # Passphrase of an encrypted tls_key
+    # Scan tls_cert along with the certificate directories, so the monitor's own certificate
+    # is exported and alerted on like any other
+    monitor_tls_cert: bool = Field(default=True)
+
+    # Certificate monitoring (entries may be paths or {path, interval, ...} objects;
+    # object settings are kept in directory_settings keyed by resolved path)
+    certificate_directories: List[str] = Field(default_factory=default_certificate_directories)
+    directory_settings: Dict[str, DirectoryConfig] = Field(default_factory=dict)
+    # Skip configured directories that do not exist (with a warning) instead of
+    # reporting them as scan errors
+    allow_missing_directories: bool = Field(default=False)
+    # Named groups of directories (the group setting of a directory object) with owner labels
+    groups: Dict[str, GroupConfig] = Field(default_factory=dict)
     exclude_directories: List[str] = Field(default_factory=list)
     exclude_file_patterns: List[str] = Field(default_factory=lambda: ["dhparam.pem"])
+    # Globs of files to skip everywhere (scanner and watcher); globs containing "/"
+    # are matched against the full path, others against the file name
+    exclude_files: List[str] = Field(default_factory=list)
 
     # P12/PFX certificate passwords
     p12_passwords: List[str] = Field(
@@ -45,21 +887,88 @@ class Config(BaseModel):
     # Logging
     log_level: str = Field(default="INFO")
     log_file: Optional[str] = None
+    log_file_permissions: LogFilePermissionsConfig = Field(
+        default_factory=LogFilePermissionsConfig
+    )
+    # Log to journald instead of the console; None: when running as a systemd service
+    log_journald: Optional[bool] = None
+    log_sampling: LogSamplingConfig = Field(default_factory=LogSamplingConfig)
+    syslog: SyslogConfig = Field(default_factory=SyslogConfig)
+    audit_log: AuditLogConfig = Field(default_factory=AuditLogConfig)
+    event_log: EventLogConfig = Field(default_factory=EventLogConfig)
+    tracing: TracingConfig = Field(default_factory=TracingConfig)
 
     # Operation modes
     dry_run: bool = Field(default=False)
     hot_reload: bool = Field(default=True)
-
-    # Cache settings
+    # Watch certificate directories for changes; when false only periodic scans pick them up
+    watch_files: bool = Field(default=True)
+    # Interval of polling the directories that cannot be watched natively because the watch
+    # limit is reached (fs.inotify.max_user_watches); 0s leaves them to periodic scans
+    watch_poll_interval: str = Field(default="30s")
+
+    # Cache settings (cache_enabled: false disables caching entirely; an empty cache_dir
+    # keeps the cache in memory only, whatever cache_type says)
+    cache_enabled: bool = Field(default=True)
     cache_type: str = Field(default="memory")  # "memory", "file", or "both"
-    cache_dir: str = Field(default="./cache")
+    # Storage of the file cache: "json" (cache.json, rewritten on save) or "sqlite"
+    # (cache.db, updated incrementally and crash-safe)
+    cache_backend: str = Field(default="json")
+    # Compression of persisted entries: "none" or "gzip"
+    cache_compression: str = Field(default="none")
+    cache_dir: str = Field(default_factory=default_cache_dir)
     cache_ttl: str = Field(default="1h")
+    # Lifetimes of cache entries by kind, overriding cache_ttl (e.g. {"cert": "24h"})
+    cache_ttls: Dict[str, str] = Field(default_factory=dict)
     cache_max_size: int = Field(default=10485760)  # 10MB for memory default
+    # Maximum number of entries (0: unlimited), bounding the cache even if sizes are off
+    cache_max_entries: int = Field(default=0, ge=0)
+    # Values larger than this many bytes are not cached (at most cache_max_size)
+    cache_max_entry_size: int = Field(default=1048576, ge=0)
+    # Process memory (RSS) limit in bytes above which cache entries are evicted: 0 disables,
+    # "auto" uses 90% of the container (cgroup) memory limit
+    cache_memory_limit: Union[int, str] = Field(default=0)
+    # Interval of file cache saves (skipped when nothing changed); "0s" saves only on
+    # demand (POST /cache/save) and on shutdown
+    cache_save_interval: str = Field(default="1m")
+    # Compact the persisted cache after a load that pruned more than this percentage of its
+    # entries (expired or over the limits), so it does not grow across restarts; 0 disables
+    cache_compact_threshold: int = Field(default=25, ge=0, le=100)
 
     # Security settings
     allowed_ips: List[str] = Field(default_factory=lambda: ["127.0.0.1", "::1"])
     enable_ip_whitelist: bool = Field(default=True)
 
+    # Expiry severity thresholds
+    expiry_thresholds: ExpiryThresholds = Field(default_factory=ExpiryThresholds)
+
+    # What /healthz reports as degraded or unhealthy
+    health_thresholds: HealthThresholds = Field(default_factory=HealthThresholds)
+    # Time each /healthz check (scanner, cache, system, disk usage per directory) may take
+    health_check_timeout: str = Field(default="5s")
+    # Start serving only after the first scan completed (or this long passed), so /metrics is
+    # never scraped empty; 0s serves right away (/readyz still reports 503 until then)
+    initial_scan_wait: str = Field(default="0s")
+
+    # Clock skew tolerance: certificates whose NotBefore is at most not_yet_valid_grace in
+    # the future are not flagged as not yet valid; expiry_grace is a safety margin
+    # subtracted from NotAfter when evaluating expiry severities
+    not_yet_valid_grace: str = Field(default="5m")
+    expiry_grace: str = Field(default="0s")
+
+    # Silences / maintenance windows
+    silences: List[SilenceConfig] = Field(default_factory=list)
+
+    # Notification targets and scheduled digest
+    notifiers: List[NotifierConfig] = Field(default_factory=list)
+    digest: DigestConfig = Field(default_factory=DigestConfig)
+    alerts: AlertsConfig = Field(default_factory=AlertsConfig)
+    zabbix: ZabbixConfig = Field(default_factory=ZabbixConfig)
+    snmp: SnmpConfig = Field(default_factory=SnmpConfig)
+    history: HistoryConfig = Field(default_factory=HistoryConfig)
+    scan_state: ScanStateConfig = Field(default_factory=ScanStateConfig)
+    changes: ChangesConfig = Field(default_factory=ChangesConfig)
+
     @field_validator("cache_type")
     @classmethod
     def validate_cache_type(cls, v: str) -> str:
@@ -69,15 +978,90 @@ class Config(BaseModel):
             raise ValueError(f"cache_type must be one of {valid_types}, got '{v}'")
         return v.lower()
 
+    @field_validator("cache_backend")
+    @classmethod
+    def validate_cache_backend(cls, v: str) -> str:
+        """Validate cache backend."""
+        valid_backends = {"json", "sqlite"}
+        if v.lower() not in valid_backends:
+            raise ValueError(f"cache_backend must be one of {valid_backends}, got '{v}'")
+        return v.lower()
+
+    @field_validator("cache_compression")
+    @classmethod
+    def validate_cache_compression(cls, v: str) -> str:
+        """Validate cache compression."""
+        valid_compressions = {"none", "gzip"}
+        if v.lower() not in valid_compressions:
+            raise ValueError(f"cache_compression must be one of {valid_compressions}, got '{v}'")
+        return v.lower()
+
+    @field_validator("cache_memory_limit")
+    @classmethod
+    def validate_cache_memory_limit(cls, v: Union[int, str]) -> Union[int, str]:
+        """Validate the cache memory limit (bytes or "auto")."""
+        if isinstance(v, str):
+            if v.strip().lower() == "auto":
+                return "auto"
+            if not v.strip().isdigit():
+                raise ValueError(
+                    f"cache_memory_limit must be a number of bytes or 'auto', got '{v}'"
+                )
+            v = int(v)
+        if v < 0:
+            raise ValueError("cache_memory_limit must not be negative")
+        return v
+
     @field_validator("log_level")
     @classmethod
     def validate_log_level(cls, v: str) -> str:
         """Validate log level."""
-        valid_levels = ["DEBUG", "INFO", "WARNING", "ERROR", "CRITICAL"]
-        if v.upper() not in valid_levels:
-            raise ValueError(f"Log level must be one of: {valid_levels}")
+        if v.upper() not in LOG_LEVELS:
+            raise ValueError(f"Log level must be one of: {list(LOG_LEVELS)}")
         return v.upper()
 
+    @model_validator(mode="before")
+    @classmethod
+    def reject_unknown_keys(cls, data: Any) -> Any:
+        """Fail loudly on unknown top-level keys, suggesting the closest known key."""
+        if not isinstance(data, dict):
+            return data
+
+        unknown = [
+            key for key in data if key not in cls.model_fields and not cls.is_file_reference(key)
+        ]
+        if unknown:
+            messages = []
+            for key in unknown:
+                matches = difflib.get_close_matches(str(key), cls.model_fields, n=1)
+                hint = f" (did you mean '{matches[0]}'?)" if matches else ""
+                messages.append(f"'{key}'{hint}")
+            raise ValueError(f"Unknown configuration key(s): {', '.join(messages)}")
+        return data
+
+    @model_validator(mode="before")
+    @classmethod
+    def split_directory_objects(cls, data: Any) -> Any:
+        """Accept directory objects in certificate_directories alongside plain paths."""
+        if not isinstance(data, dict) or not isinstance(data.get("certificate_directories"), list):
+            return data
+
+        data = dict(data)
+        paths = []
+        settings = dict(data.get("directory_settings") or {})
+        for entry in data["certificate_directories"]:
+            if isinstance(entry, dict):
+                entry = DirectoryConfig(**entry)
+            if isinstance(entry, DirectoryConfig):
+                paths.append(entry.path)
+                settings[entry.path] = entry
+            else:
+                paths.append(entry)
+
+        data["certificate_directories"] = paths
+        data["directory_settings"] = settings
+        return data
+
     @field_validator("certificate_directories")
     @classmethod
     def validate_cert_directories(cls, v: List[str]) -> List[str]:
@@ -188,54 +1172,383 @@ class Config(BaseModel):
 
         return validated_ips
 
-    @field_validator("scan_interval", "cache_ttl")
+    @field_validator("notifiers")
+    @classmethod
+    def validate_notifier_names(cls, v: List[NotifierConfig]) -> List[NotifierConfig]:
+        """Validate notifier names are unique."""
+        names = [notifier.name for notifier in v]
+        duplicates = {name for name in names if names.count(name) > 1}
+        if duplicates:
+            raise ValueError(f"Duplicate notifier names: {sorted(duplicates)}")
+        return v
+
+    @model_validator(mode="after")
+    def validate_notifier_references(self) -> "Config":
+        """Validate that notifiers referenced by name are defined."""
+        known = {notifier.name for notifier in self.notifiers}
+        references = {
+            "digest.notifiers": self.digest.notifiers,
+            "alerts.notifiers": self.alerts.notifiers,
+        }
+        for index, route in enumerate(self.alerts.routes):
+            references[f"alerts.routes[{index}].notifiers"] = route.notifiers
+        for field_name, names in references.items():
+            unknown = [name for name in names if name not in known]
+            if unknown:
+                raise ValueError(f"{field_name} references unknown notifiers: {unknown}")
+        return self
+
+    @model_validator(mode="after")
+    def validate_history_path(self) -> "Config":
+        """The history needs a database path, or the cache directory to keep it in."""
+        if self.history.enabled and not self.history.path and not self.cache_dir:
+            raise ValueError("history.path is required when history is enabled without cache_dir")
+        return self
+
+    @model_validator(mode="after")
+    def validate_directory_groups(self) -> "Config":
+        """Directories may only be in groups defined in groups."""
+        for settings in self.directory_settings.values():
+            if settings.group is not None and settings.group not in self.groups:
+                defined = ", ".join(sorted(self.groups)) or "none"
+                raise ValueError(
+                    f"Unknown group '{settings.group}' of directory {settings.path} "
+                    f"(groups: {defined})"
+                )
+        return self
+
+    @model_validator(mode="after")
+    def resolve_directory_settings(self) -> "Config":
+        """Key directory settings by the validated (resolved) directory path."""
+        resolved = {}
+        for path, settings in self.directory_settings.items():
+            resolved_path = str(Path(path).resolve())
+            if resolved_path in self.certificate_directories:
+                resolved[resolved_path] = settings
+        self.directory_settings = resolved
+        return self
+
+    @field_validator(
+        "scan_interval",
+        "cache_ttl",
+        "cache_save_interval",
+        "not_yet_valid_grace",
+        "expiry_grace",
+        "health_check_timeout",
+        "initial_scan_wait",
+        "watch_poll_interval",
+    )
     @classmethod
     def validate_duration(cls, v: str) -> str:
         """Validate duration format (e.g., '5m', '1h', '30s')."""
-        if not v:
-            raise ValueError("Duration cannot be empty")
+        return validate_duration_format(v)
 
-        # Simple validation for duration format
-        # Use raw string to avoid escaping issues
-        pattern = r"^\d+[smhd]$"
-        if not re.match(pattern, v):
-            raise ValueError("Duration must be in format like '5m', '1h', '30s', '1d'")
+    @field_validator("cache_ttls")
+    @classmethod
+    def validate_cache_ttls(cls, v: Dict[str, str]) -> Dict[str, str]:
+        """Validate per-kind cache TTL durations."""
+        for kind, duration in v.items():
+            try:
+                validate_duration_format(duration)
+            except ValueError as e:
+                raise ValueError(f"cache_ttls.{kind}: {e}") from e
         return v
 
     def parse_duration_seconds(self, duration: str) -> int:
         """Parse duration string to seconds."""
-        match = re.match(r"^(\d+)([smhd])$", duration)
-        if not match:
-            raise ValueError(f"Invalid duration format: {duration}")
+        return parse_duration(duration)
 
-        value, unit = match.groups()
-        value = int(value)
+    @field_validator("health_check_timeout")
+    @classmethod
+    def validate_health_check_timeout(cls, v: str) -> str:
+        """A health check must be given some time."""
+        if parse_duration(v) <= 0:
+            raise ValueError("health_check_timeout must be positive")
+        return v
 
-        multipliers = {"s": 1, "m": 60, "h": 3600, "d": 86400}
+    @property
+    def health_check_timeout_seconds(self) -> int:
+        """Get the health check timeout in seconds."""
+        return self.parse_duration_seconds(self.health_check_timeout)
+
+    @property
+    def initial_scan_wait_seconds(self) -> int:
+        """Get the time to wait for the first scan before serving, in seconds."""
+        return self.parse_duration_seconds(self.initial_scan_wait)
+
+    @property
+    def watch_poll_interval_seconds(self) -> int:
+        """Get the polling interval of directories over the watch limit in seconds."""
+        return self.parse_duration_seconds(self.watch_poll_interval)
 
-        return value * multipliers[unit]
+    @property
+    def cache_save_interval_seconds(self) -> int:
+        """Get the cache save interval in seconds."""
+        return self.parse_duration_seconds(self.cache_save_interval)
+
+    def cache_ttl_seconds_for(self, kind: str) -> int:
+        """Get the lifetime in seconds of cache entries of a kind (cache_ttl by default)."""
+        if kind in self.cache_ttls:
+            return self.parse_duration_seconds(self.cache_ttls[kind])
+        return self.cache_ttl_seconds
 
     @property
     def scan_interval_seconds(self) -> int:
         """Get scan interval in seconds."""
         return self.parse_duration_seconds(self.scan_interval)
 
+    @property
+    def not_yet_valid_grace_seconds(self) -> int:
+        """Get the not-yet-valid clock skew tolerance in seconds."""
+        return self.parse_duration_seconds(self.not_yet_valid_grace)
+
+    @property
+    def expiry_grace_seconds(self) -> int:
+        """Get the expiry safety margin in seconds."""
+        return self.parse_duration_seconds(self.expiry_grace)
+
+    def scan_state_path(self) -> Optional[Path]:
+        """Get the scan state file (None if the scan state is not kept)."""
+        if not self.scan_state.enabled or not (self.scan_state.path or self.cache_dir):
+            return None
+        return Path(self.scan_state.path or Path(self.cache_dir) / "scan_state.json")
+
+    def monitored_tls_cert(self) -> Optional[str]:
+        """
+        Get the server certificate to scan along with the directories (None if there is none).
+
+        A tls_cert inside a certificate directory is already scanned with it.
+        """
+        if not self.tls_cert or not self.monitor_tls_cert:
+            return None
+        tls_cert = Path(self.tls_cert).resolve()
+        if any(tls_cert.is_relative_to(directory) for directory in self.certificate_directories):
+            return None
+        return str(tls_cert)
+
+    def get_directory_config(self, directory: str) -> DirectoryConfig:
+        """Get settings for a certificate directory (defaults if given as a plain path)."""
+        return self.directory_settings.get(directory) or DirectoryConfig(path=directory)
+
+    def directory_labels(self, directory: str) -> Dict[str, str]:
+        """
+        Get the labels of a certificate directory.
+
+        Directories in a group get a group label and the group's labels, which their own
+        labels override.
+        """
+        settings = self.get_directory_config(directory)
+        if settings.group is None:
+            return dict(settings.labels)
+        return {"group": settings.group, **self.groups[settings.group].labels, **settings.labels}
+
+    def directory_label_names(self) -> List[str]:
+        """Get the names of all labels of the certificate directories, sorted."""
+        return sorted(
+            {
+                name
+                for directory in self.certificate_directories
+                for name in self.directory_labels(directory)
+            }
+        )
+
+    def parse_errors_degrade_health(self, directory: str) -> bool:
+        """Check if parse errors in a directory count toward the /healthz parse error ratio."""
+        setting = self.get_directory_config(directory).parse_errors_degrade
+        return self.health_thresholds.parse_errors_degrade if setting is None else setting
+
+    def excluded_file_glob(self, file_path: Path) -> Optional[str]:
+        """
+        Get the exclude_files glob matching a file, if any (case-insensitive).
+
+        Args:
+            file_path: Certificate file path
+
+        Returns:
+            The first matching glob, or None if the file is not excluded
+        """
+        name = file_path.name.lower()
+        full_path = str(file_path).lower()
+        for glob in self.exclude_files:
+            target = full_path if "/" in glob else name
+            if fnmatch.fnmatchcase(target, glob.lower()):
+                return glob
+        return None
+
+    def directory_scan_interval_seconds(self, directory: str) -> int:
+        """Get the scan interval in seconds for a certificate directory."""
+        interval = self.get_directory_config(directory).interval
+        return parse_duration(interval) if interval else self.scan_interval_seconds
+
     @property
     def cache_ttl_seconds(self) -> int:
         """Get cache TTL in seconds."""
         return self.parse_duration_seconds(self.cache_ttl)
 
 
-def load_config(config_path: Optional[str] = None) -> Config:
+def config_json_schema() -> Dict[str, Any]:
+    """
+    Get the JSON Schema of the configuration file.
+
+    Returns:
+        JSON Schema dictionary
+    """
+    schema = {"$schema": "https://json-schema.org/draft/2020-12/schema"}
+    schema.update(Config.model_json_schema())
+    # Entries are normalized to paths by validation but may be written as objects
+    schema["properties"]["certificate_directories"]["items"] = {
+        "anyOf": [{"type": "string"}, {"$ref": "#/$defs/DirectoryConfig"}]
+    }
+    # Derived from certificate_directories entries, not set directly
+    schema["properties"].pop("directory_settings", None)
+    # Handled by load_config(); each profile holds top-level settings
+    schema["properties"]["profiles"] = {
+        "type": "object",
+        "description": "Named overrides selected by --profile / TLS_MONITOR_PROFILE",
+        "additionalProperties": {"type": "object"},
+    }
+
+    # Secrets may be read from a file instead (see StrictModel)
+    for model in StrictModel.__subclasses__():
+        model_schema = schema if model is Config else schema["$defs"].get(model.__name__, {})
+        for name in sorted(model.secret_fields):
+            model_schema["properties"][f"{name}{FILE_REFERENCE_SUFFIX}"] = {
+                "type": "string",
+                "description": f"File to read {name} from",
+            }
+    return schema
+
+
+REDACTED = "***REDACTED***"
+
+
+def redact_config(config: Config) -> Dict[str, Any]:
     """
-    Load configuration from file or environment variables.
+    Dump a configuration with secrets and certificate paths redacted.
+
+    Used wherever configuration leaves the process (API responses, reload audit records).
 
     Args:
-        config_path: Path to configuration file. If not provided, will search
+        config: Configuration to dump
+
+    Returns:
+        JSON-compatible configuration dictionary
+    """
+    config_dict: Dict[str, Any] = config.model_dump(mode="json")
+
+    if "p12_passwords" in config_dict:
+        config_dict["p12_passwords"] = [
+            f"{REDACTED} ({len(config_dict['p12_passwords'])} passwords)"
+        ]
+    if "allowed_ips" in config_dict:
+        config_dict["allowed_ips"] = [
+            f"{REDACTED} ({len(config_dict['allowed_ips'])} IPs/networks)"
+        ]
+    for key in ("tls_key", "tls_key_password"):
+        if key in config_dict:
+            config_dict[key] = REDACTED
+
+    # Collector headers usually carry tokens
+    if config_dict.get("tracing", {}).get("headers"):
+        config_dict["tracing"]["headers"] = {
+            name: REDACTED for name in config_dict["tracing"]["headers"]
+        }
+
+    if config_dict.get("snmp", {}).get("community"):
+        config_dict["snmp"]["community"] = REDACTED
+
+    # Notifier settings hold credentials and tokenized URLs
+    if "notifiers" in config_dict:
+        config_dict["notifiers"] = [
+            {"name": notifier["name"], "type": notifier["type"]}
+            for notifier in config_dict["notifiers"]
+        ]
+
+    # Mask certificate directory paths to prevent information disclosure
+    if "certificate_directories" in config_dict:
+        config_dict["certificate_directories"] = [
+            f"***/{Path(dir_path).name}" for dir_path in config_dict["certificate_directories"]
+        ]
+    if "directory_settings" in config_dict:
+        config_dict["directory_settings"] = {
+            f"***/{Path(dir_path).name}": {**settings, "path": f"***/{Path(dir_path).name}"}
+            for dir_path, settings in config_dict["directory_settings"].items()
+        }
+
+    return config_dict
+
+
+def diff_configs(old: Config, new: Config) -> List[Dict[str, Any]]:
+    """
+    Compute the settings that differ between two configurations.
+
+    Changes are detected on the real values, so a rotated secret is reported even
+    though its redacted value is unchanged; the reported values are redacted.
+    Nested mappings (e.g. expiry_thresholds) are compared key by key.
+
+    Args:
+        old: Previous configuration
+        new: New configuration
+
+    Returns:
+        List of {"setting", "old", "new"} records, ordered by setting name
+    """
+    changes: List[Dict[str, Any]] = []
+
+    def compare(
+        old_raw: Dict[str, Any],
+        new_raw: Dict[str, Any],
+        old_shown: Dict[str, Any],
+        new_shown: Dict[str, Any],
+        prefix: str,
+    ) -> None:
+        for key in sorted(set(old_raw) | set(new_raw)):
+            old_value, new_value = old_raw.get(key), new_raw.get(key)
+            if old_value == new_value:
+                continue
+            old_display, new_display = old_shown.get(key), new_shown.get(key)
+            # Recurse only where redaction kept the structure (not for masked path keys)
+            if all(
+                isinstance(raw, dict) and isinstance(shown, dict) and set(raw) == set(shown)
+                for raw, shown in ((old_value, old_display), (new_value, new_display))
+            ):
+                compare(old_value, new_value, old_display, new_display, f"{prefix}{key}.")
+            else:
+                changes.append(
+                    {"setting": f"{prefix}{key}", "old": old_display, "new": new_display}
+                )
+
+    compare(
+        old.model_dump(mode="json"),
+        new.model_dump(mode="json"),
+        redact_config(old),
+        redact_config(new),
+        "",
+    )
+    return changes
+
+
+def load_config(
+    config_path: Optional[str] = None,
+    overrides: Optional[Dict[str, Any]] = None,
+    remote_config: Optional[str] = None,
+    profile: Optional[str] = None,
+) -> Config:
+    """
+    Load configuration from file, remote KV store, environment variables and flags.
+
+    Precedence is flags (overrides) > environment variables > profile > remote > file.
+
+    Args:
+        config_path: Path to configuration file or to a directory of *.yaml files
+                     merged in lexical order. If not provided, will search
                      for config files in standard locations:
                      - Windows: C:\\ProgramData\\tls-cert-monitor\\config.yaml,
                                 %APPDATA%\\tls-cert-monitor\\config.yaml, .\\config.yaml
                      - Linux/macOS: /etc/tls-cert-monitor/config.yaml, ./config.yaml
+        overrides: Settings from command line flags, applied last
+        remote_config: consul:// or etcd:// URL of a YAML document merged over the file
+        profile: Entry of the profiles: section to apply (defaults to TLS_MONITOR_PROFILE)
 
     Returns:
         Config object
@@ -246,22 +1559,124 @@ def load_config(config_path: Optional[str] = None) -> Config:
     if not config_path:
         config_path = _find_default_config()
 
-    # Load from file if provided or found
+    # Load from file (or conf.d-style directory) if provided or found
     if config_path:
         config_file = Path(config_path)
-        if config_file.exists():
-            with open(config_file, "r", encoding="utf-8") as f:
-                config_data = yaml.safe_load(f) or {}
+        if config_file.is_dir():
+            config_data = _load_config_directory(config_file)
+        elif config_file.exists():
+            # SOPS- and age-encrypted files are decrypted transparently
+            config_data = read_config_file(config_file) or {}
         else:
             raise FileNotFoundError(f"Configuration file not found: {config_path}")
 
-    # Override with environment variables
-    env_overrides = _get_env_overrides()
-    config_data.update(env_overrides)
+    # Merge the centrally managed document like a conf.d file
+    if remote_config:
+        config_data = _deep_merge(config_data, load_remote_config(remote_config))
+
+    config_data = _apply_profile(config_data, profile or os.getenv("TLS_MONITOR_PROFILE"))
+
+    # Override with environment variables, then command line flags
+    config_data = _apply_overrides(config_data, _get_env_overrides())
+    if overrides:
+        config_data = _apply_overrides(config_data, overrides)
 
     return Config(**config_data)
 
 
+def _apply_profile(config_data: Dict[str, Any], profile: Optional[str]) -> Dict[str, Any]:
+    """
+    Apply the selected entry of the profiles: section and drop the section.
+
+    Raises:
+        ValueError: If the profile is not defined
+    """
+    profiles = config_data.pop("profiles", None) or {}
+    if not isinstance(profiles, dict):
+        raise ValueError("profiles must be a mapping of profile name to settings")
+    if not profile:
+        return config_data
+
+    if profile not in profiles:
+        raise ValueError(f"Unknown profile '{profile}' (defined profiles: {sorted(profiles)})")
+
+    logging.info(f"Applying configuration profile: {profile}")
+    return _apply_overrides(config_data, profiles[profile] or {})
+
+
+def _apply_overrides(config_data: Dict[str, Any], overrides: Dict[str, Any]) -> Dict[str, Any]:
+    """
+    Apply setting overrides on top of file configuration.
+
+    Unlike conf.d merging, lists are replaced rather than appended; nested
+    mappings (e.g. expiry_thresholds) are updated key by key.
+    """
+    result = dict(config_data)
+    for key, value in overrides.items():
+        # A value and its *_file reference are alternatives; the override replaces both
+        if Config.is_file_reference(key):
+            result.pop(key[: -len(FILE_REFERENCE_SUFFIX)], None)
+        elif key in Config.secret_fields:
+            result.pop(f"{key}{FILE_REFERENCE_SUFFIX}", None)
+
+        if isinstance(value, dict) and isinstance(result.get(key), dict):
+            result[key] = {**result[key], **value}
+        else:
+            result[key] = value
+    return result
+
+
+CONFIG_FILE_SUFFIXES = (".yaml", ".yml")
+
+
+def _deep_merge(base: Dict[str, Any], override: Dict[str, Any]) -> Dict[str, Any]:
+    """
+    Merge configuration mappings.
+
+    Nested mappings are merged recursively, lists are concatenated (so each
+    file can add directories, notifiers, silences, ...) and other values
+    from override replace those in base.
+    """
+    merged = dict(base)
+    for key, value in override.items():
+        if isinstance(value, dict) and isinstance(merged.get(key), dict):
+            merged[key] = _deep_merge(merged[key], value)
+        elif isinstance(value, list) and isinstance(merged.get(key), list):
+            merged[key] = merged[key] + value
+        else:
+            merged[key] = value
+    return merged
+
+
+def config_directory_files(config_dir: Path) -> List[Path]:
+    """Get configuration files from a conf.d-style directory in lexical order."""
+    return sorted(
+        path
+        for path in config_dir.iterdir()
+        if path.is_file()
+        and path.suffix.lower() in CONFIG_FILE_SUFFIXES
+        and not path.name.startswith(".")
+    )
+
+
+def _load_config_directory(config_dir: Path) -> Dict[str, Any]:
+    """Load and merge all configuration files in a directory."""
+    config_data: Dict[str, Any] = {}
+    files = config_directory_files(config_dir)
+
+    if not files:
+        logging.warning(f"No configuration files (*.yaml) found in {config_dir}")
+
+    for config_file in files:
+        file_data = read_config_file(config_file) or {}
+        if not isinstance(file_data, dict):
+            raise ValueError(f"Configuration file must contain a mapping: {config_file}")
+        config_data = _deep_merge(config_data, file_data)
+        logging.debug(f"Merged configuration file: {config_file}")
+
+    return config_data
+
+
 def _find_default_config() -> Optional[str]:
     """
     Search for configuration file in default locations.
@@ -269,14 +1684,10 @@ def _find_default_config() -> Optional[str]:
     Returns:
         Path to config file if found, None otherwise
     """
-    import platform
-
-    system = platform.system()
-
     # Define search paths based on platform
-    if system == "Windows":
+    if platform.system() == "Windows":
         search_paths = [
-            Path(r"C:\ProgramData\tls-cert-monitor\config.yaml"),
+            program_data_dir() / "config.yaml",
             Path(os.getenv("APPDATA", "")) / "tls-cert-monitor" / "config.yaml",
             Path.cwd() / "config.yaml",
         ]
@@ -304,17 +1715,48 @@ def _get_env_overrides() -> dict:
         "TLS_MONITOR_PORT": ("port", int),
         "TLS_MONITOR_BIND_ADDRESS": ("bind_address", str),
         "TLS_MONITOR_TLS_CERT": ("tls_cert", str),
+        "TLS_MONITOR_MONITOR_TLS_CERT": (
+            "monitor_tls_cert",
+            lambda x: x.lower() in ("true", "1", "yes"),
+        ),
         "TLS_MONITOR_TLS_KEY": ("tls_key", str),
+        "TLS_MONITOR_TLS_KEY_PASSWORD": ("tls_key_password", str),
+        "TLS_MONITOR_TLS_KEY_PASSWORD_FILE": ("tls_key_password_file", str),
         "TLS_MONITOR_SCAN_INTERVAL": ("scan_interval", str),
         "TLS_MONITOR_WORKERS": ("workers", int),
         "TLS_MONITOR_LOG_LEVEL": ("log_level", str),
         "TLS_MONITOR_LOG_FILE": ("log_file", str),
+        "TLS_MONITOR_LOG_JOURNALD": (
+            "log_journald",
+            lambda x: None if x.lower() == "auto" else x.lower() in ("true", "1", "yes"),
+        ),
         "TLS_MONITOR_DRY_RUN": ("dry_run", lambda x: x.lower() in ("true", "1", "yes")),
         "TLS_MONITOR_HOT_RELOAD": ("hot_reload", lambda x: x.lower() in ("true", "1", "yes")),
+        "TLS_MONITOR_WATCH_FILES": ("watch_files", lambda x: x.lower() in ("true", "1", "yes")),
+        "TLS_MONITOR_WATCH_POLL_INTERVAL": ("watch_poll_interval", str),
+        "TLS_MONITOR_ALLOW_MISSING_DIRECTORIES": (
+            "allow_missing_directories",
+            lambda x: x.lower() in ("true", "1", "yes"),
+        ),
+        "TLS_MONITOR_CACHE_ENABLED": (
+            "cache_enabled",
+            lambda x: x.lower() in ("true", "1", "yes"),
+        ),
+        "TLS_MONITOR_NOT_YET_VALID_GRACE": ("not_yet_valid_grace", str),
+        "TLS_MONITOR_EXPIRY_GRACE": ("expiry_grace", str),
+        "TLS_MONITOR_INITIAL_SCAN_WAIT": ("initial_scan_wait", str),
         "TLS_MONITOR_CACHE_TYPE": ("cache_type", str),
+        "TLS_MONITOR_CACHE_BACKEND": ("cache_backend", str),
+        "TLS_MONITOR_CACHE_COMPRESSION": ("cache_compression", str),
         "TLS_MONITOR_CACHE_DIR": ("cache_dir", str),
         "TLS_MONITOR_CACHE_TTL": ("cache_ttl", str),
+        "TLS_MONITOR_CACHE_SAVE_INTERVAL": ("cache_save_interval", str),
         "TLS_MONITOR_CACHE_MAX_SIZE": ("cache_max_size", int),
+        "TLS_MONITOR_CACHE_MAX_ENTRIES": ("cache_max_entries", int),
+        "TLS_MONITOR_CACHE_MAX_ENTRY_SIZE": ("cache_max_entry_size", int),
+        "TLS_MONITOR_CACHE_COMPACT_THRESHOLD": ("cache_compact_threshold", int),
+        "TLS_MONITOR_CACHE_MEMORY_LIMIT": ("cache_memory_limit", str),
+        "TLS_MONITOR_P12_PASSWORDS_FILE": ("p12_passwords_file", str),
         "TLS_MONITOR_ENABLE_IP_WHITELIST": (
             "enable_ip_whitelist",
             lambda x: x.lower() in ("true", "1", "yes"),
@@ -343,10 +1785,187 @@ def _get_env_overrides() -> dict:
     if exclude_patterns:
         overrides["exclude_file_patterns"] = [p.strip() for p in exclude_patterns.split(",")]
 
+    exclude_files = os.getenv("TLS_MONITOR_EXCLUDE_FILES")
+    if exclude_files:
+        overrides["exclude_files"] = [g.strip() for g in exclude_files.split(",")]
+
     p12_passwords = os.getenv("TLS_MONITOR_P12_PASSWORDS")
     if p12_passwords:
         overrides["p12_passwords"] = [p.strip() for p in p12_passwords.split(",")]
 
+    # Handle nested expiry thresholds
+    expiry_thresholds = {}
+    for env_var, key in (
+        ("TLS_MONITOR_EXPIRY_WARNING", "warning"),
+        ("TLS_MONITOR_EXPIRY_CRITICAL", "critical"),
+    ):
+        value = os.getenv(env_var)
+        if value:
+            expiry_thresholds[key] = value
+    if expiry_thresholds:
+        overrides["expiry_thresholds"] = expiry_thresholds
+
+    # Handle nested log sampling settings
+    log_sampling: Dict[str, Any] = {}
+    sampling_enabled = os.getenv("TLS_MONITOR_LOG_SAMPLING_ENABLED")
+    if sampling_enabled:
+        log_sampling["enabled"] = sampling_enabled.lower() in ("true", "1", "yes")
+    for env_var, key in (
+        ("TLS_MONITOR_LOG_SAMPLING_INITIAL", "initial"),
+        ("TLS_MONITOR_LOG_SAMPLING_THEREAFTER", "thereafter"),
+    ):
+        value = os.getenv(env_var)
+        if value:
+            try:
+                log_sampling[key] = int(value)
+            except ValueError as e:
+                logging.warning(f"Invalid value for {env_var}: {value} - {e}")
+    if log_sampling:
+        overrides["log_sampling"] = log_sampling
+
+    # Handle nested log file permission settings
+    log_file_permissions: Dict[str, Any] = {}
+    for env_var, key in (
+        ("TLS_MONITOR_LOG_FILE_MODE", "mode"),
+        ("TLS_MONITOR_LOG_FILE_DIRECTORY_MODE", "directory_mode"),
+        ("TLS_MONITOR_LOG_FILE_OWNER", "owner"),
+        ("TLS_MONITOR_LOG_FILE_GROUP", "group"),
+    ):
+        value = os.getenv(env_var)
+        if value:
+            log_file_permissions[key] = value
+    if log_file_permissions:
+        overrides["log_file_permissions"] = log_file_permissions
+
+    # Handle nested Windows Event Log settings
+    event_log: Dict[str, Any] = {}
+    event_log_enabled = os.getenv("TLS_MONITOR_EVENT_LOG_ENABLED")
+    if event_log_enabled:
+        event_log["enabled"] = event_log_enabled.lower() in ("true", "1", "yes")
+    event_log_level = os.getenv("TLS_MONITOR_EVENT_LOG_LEVEL")
+    if event_log_level:
+        event_log["level"] = event_log_level
+    if event_log:
+        overrides["event_log"] = event_log
+
+    # Handle nested tracing settings
+    tracing: Dict[str, Any] = {}
+    tracing_enabled = os.getenv("TLS_MONITOR_TRACING_ENABLED")
+    if tracing_enabled:
+        tracing["enabled"] = tracing_enabled.lower() in ("true", "1", "yes")
+    for env_var, key in (
+        ("TLS_MONITOR_TRACING_ENDPOINT", "endpoint"),
+        ("TLS_MONITOR_TRACING_PROTOCOL", "protocol"),
+    ):
+        value = os.getenv(env_var)
+        if value:
+            tracing[key] = value
+    if tracing:
+        overrides["tracing"] = tracing
+
+    # Handle nested Zabbix sender settings
+    zabbix: Dict[str, Any] = {}
+    zabbix_enabled = os.getenv("TLS_MONITOR_ZABBIX_ENABLED")
+    if zabbix_enabled:
+        zabbix["enabled"] = zabbix_enabled.lower() in ("true", "1", "yes")
+    for env_var, key in (
+        ("TLS_MONITOR_ZABBIX_SERVER", "server"),
+        ("TLS_MONITOR_ZABBIX_HOST", "host"),
+    ):
+        value = os.getenv(env_var)
+        if value:
+            zabbix[key] = value
+    if zabbix:
+        overrides["zabbix"] = zabbix
+
+    # Handle nested SNMP agent settings
+    snmp: Dict[str, Any] = {}
+    snmp_enabled = os.getenv("TLS_MONITOR_SNMP_ENABLED")
+    if snmp_enabled:
+        snmp["enabled"] = snmp_enabled.lower() in ("true", "1", "yes")
+    for env_var, key in (
+        ("TLS_MONITOR_SNMP_BIND_ADDRESS", "bind_address"),
+        ("TLS_MONITOR_SNMP_PORT", "port"),
+        ("TLS_MONITOR_SNMP_COMMUNITY", "community"),
+    ):
+        value = os.getenv(env_var)
+        if value:
+            snmp[key] = value
+    if snmp:
+        overrides["snmp"] = snmp
+
+    # Handle nested certificate history settings
+    history: Dict[str, Any] = {}
+    history_enabled = os.getenv("TLS_MONITOR_HISTORY_ENABLED")
+    if history_enabled:
+        history["enabled"] = history_enabled.lower() in ("true", "1", "yes")
+    for env_var, key in (
+        ("TLS_MONITOR_HISTORY_PATH", "path"),
+        ("TLS_MONITOR_HISTORY_RETENTION", "retention"),
+    ):
+        value = os.getenv(env_var)
+        if value:
+            history[key] = value
+    if history:
+        overrides["history"] = history
+
+    # Handle nested scan state settings
+    scan_state: Dict[str, Any] = {}
+    scan_state_enabled = os.getenv("TLS_MONITOR_SCAN_STATE_ENABLED")
+    if scan_state_enabled:
+        scan_state["enabled"] = scan_state_enabled.lower() in ("true", "1", "yes")
+    for env_var, key in (
+        ("TLS_MONITOR_SCAN_STATE_PATH", "path"),
+        ("TLS_MONITOR_SCAN_STATE_MAX_AGE", "max_age"),
+    ):
+        value = os.getenv(env_var)
+        if value:
+            scan_state[key] = value
+    if scan_state:
+        overrides["scan_state"] = scan_state
+
+    # Handle nested scan change settings
+    changes: Dict[str, Any] = {}
+    changes_enabled = os.getenv("TLS_MONITOR_CHANGES_ENABLED")
+    if changes_enabled:
+        changes["enabled"] = changes_enabled.lower() in ("true", "1", "yes")
+    changes_max_changesets = os.getenv("TLS_MONITOR_CHANGES_MAX_CHANGESETS")
+    if changes_max_changesets:
+        changes["max_changesets"] = changes_max_changesets
+    if changes:
+        overrides["changes"] = changes
+
+    # Handle nested audit log settings
+    audit_log: Dict[str, Any] = {}
+    for env_var, key in (
+        ("TLS_MONITOR_AUDIT_LOG_ENABLED", "enabled"),
+        ("TLS_MONITOR_AUDIT_LOG_SYSLOG", "syslog"),
+    ):
+        value = os.getenv(env_var)
+        if value:
+            audit_log[key] = value.lower() in ("true", "1", "yes")
+    audit_log_file = os.getenv("TLS_MONITOR_AUDIT_LOG_FILE")
+    if audit_log_file:
+        audit_log["file"] = audit_log_file
+    if audit_log:
+        overrides["audit_log"] = audit_log
+
+    # Handle nested syslog settings
+    syslog: Dict[str, Any] = {}
+    syslog_enabled = os.getenv("TLS_MONITOR_SYSLOG_ENABLED")
+    if syslog_enabled:
+        syslog["enabled"] = syslog_enabled.lower() in ("true", "1", "yes")
+    for env_var, key in (
+        ("TLS_MONITOR_SYSLOG_ADDRESS", "address"),
+        ("TLS_MONITOR_SYSLOG_PROTOCOL", "protocol"),
+        ("TLS_MONITOR_SYSLOG_FACILITY", "facility"),
+    ):
+        value = os.getenv(env_var)
+        if value:
+            syslog[key] = value
+    if syslog:
+        overrides["syslog"] = syslog
+
     # Handle allowed IPs list
     allowed_ips = os.getenv("TLS_MONITOR_ALLOWED_IPS")
     if allowed_ips:
@@ -355,27 +1974,154 @@ def _get_env_overrides() -> dict:
     return overrides
 
 
+# Sections of the generated example configuration: (title, [(setting, description), ...]).
+# Every Config field except derived ones must be listed (checked by the tests).
+EXAMPLE_CONFIG_SECTIONS = [
+    (
+        "Server",
+        [
+            ("port", "HTTP(S) port for /metrics, /healthz and the API"),
+            ("bind_address", "Address to listen on (127.0.0.1 for local access only)"),
+            ("tls_cert", "TLS certificate for the metrics endpoint (enables HTTPS)"),
+            ("tls_key", "TLS private key for the metrics endpoint"),
+            ("tls_key_password", "Passphrase of an encrypted tls_key (or tls_key_password_file)"),
+            ("monitor_tls_cert", "Scan tls_cert too, alerting on the monitor's own certificate"),
+        ],
+    ),
+    (
+        "Certificate monitoring",
+        [
+            ("certificate_directories", "Directories scanned recursively (paths or objects)"),
+            ("allow_missing_directories", "Skip missing directories instead of reporting errors"),
+            ("groups", "Named groups of directories: {name: {description, labels}}"),
+            ("exclude_directories", "Directories skipped while scanning"),
+            ("exclude_file_patterns", "Regular expressions of file names to skip"),
+            ("exclude_files", "Globs of files to skip (full path if the glob contains '/')"),
+            ("p12_passwords", "Passwords tried for P12/PFX files (or p12_passwords_file)"),
+        ],
+    ),
+    (
+        "Scanning",
+        [
+            ("scan_interval", "Time between scans (s, m, h or d suffix)"),
+            ("workers", "Concurrent certificate parsing workers (1-32)"),
+        ],
+    ),
+    (
+        "Logging",
+        [
+            ("log_level", "DEBUG, INFO, WARNING, ERROR or CRITICAL"),
+            ("log_file", "Log file path (null logs to the console only)"),
+            ("log_file_permissions", "Mode, directory_mode, owner, group of log_file (POSIX)"),
+            ("log_journald", "Log to journald, not the console (null: under systemd)"),
+            ("log_sampling", "Sample repetitive DEBUG/INFO records (initial, then every Nth)"),
+            ("syslog", "Also log to syslog: the local socket or a udp/tcp/tls server"),
+            ("audit_log", "Audit log of API operations (JSON lines file and/or syslog)"),
+            ("event_log", "Windows only: report warnings and errors to the Event Log"),
+            ("tracing", "OpenTelemetry tracing of scans and HTTP requests over OTLP"),
+        ],
+    ),
+    (
+        "Operation modes",
+        [
+            ("dry_run", "Validate the configuration and simulate a scan without serving"),
+            ("hot_reload", "Reload the configuration when the file changes"),
+            ("watch_files", "Re-scan when certificate files change (false: periodic scans only)"),
+            (
+                "watch_poll_interval",
+                "Poll directories over the inotify watch limit this often (0s: scans only)",
+            ),
+        ],
+    ),
+    (
+        "Cache",
+        [
+            ("cache_enabled", "false disables caching of parsed certificates entirely"),
+            ("cache_type", "memory, file or both"),
+            ("cache_backend", "File cache storage: json (cache.json) or sqlite (cache.db)"),
+            ("cache_compression", "Compression of persisted entries: none or gzip"),
+            ("cache_dir", "Directory of the file cache (empty keeps the cache in memory)"),
+            ("cache_ttl", "Lifetime of cache entries"),
+            ("cache_ttls", "Lifetimes by entry kind, overriding cache_ttl (cert: parsed files)"),
+            ("cache_max_size", "Maximum cache size in bytes"),
+            ("cache_max_entries", "Maximum number of cache entries (0: unlimited)"),
+            ("cache_max_entry_size", "Values larger than this many bytes are not cached"),
+            (
+                "cache_memory_limit",
+                "Evict cache entries above this process memory in bytes (0: off, auto: cgroup)",
+            ),
+            ("cache_save_interval", "Interval of file cache saves (0s: on demand and shutdown)"),
+            (
+                "cache_compact_threshold",
+                "Compact the file cache when a load prunes more than this % of entries (0: off)",
+            ),
+            ("history", "Record every observed certificate in an SQLite database (see README)"),
+            ("scan_state", "Keep the last scan across restarts, served as stale until a scan"),
+        ],
+    ),
+    (
+        "Security",
+        [
+            ("allowed_ips", "IP addresses and CIDR networks allowed to access the API"),
+            ("enable_ip_whitelist", "Enforce allowed_ips"),
+        ],
+    ),
+    (
+        "Expiry evaluation",
+        [
+            ("expiry_thresholds", "Time left before NotAfter for warning/critical severities"),
+            ("not_yet_valid_grace", "Clock skew tolerated before flagging not-yet-valid certs"),
+            ("expiry_grace", "Safety margin subtracted from NotAfter when evaluating expiry"),
+        ],
+    ),
+    (
+        "Health",
+        [
+            ("health_thresholds", "Disk usage, cache hit rate and parse error ratio limits"),
+            ("health_check_timeout", "Time each /healthz check may take (checks run concurrently)"),
+            ("initial_scan_wait", "Serve only after the first scan, waiting at most this long"),
+        ],
+    ),
+    (
+        "Silences, notifications and alerts (see README for the entry formats)",
+        [
+            ("silences", "Maintenance windows: {matchers, starts_at, ends_at, comment}"),
+            ("notifiers", "Notification targets: {name, type, ...}"),
+            ("digest", "Scheduled digest report sent to the named notifiers"),
+            ("alerts", "Built-in alert rules evaluated after every scan"),
+            ("zabbix", "Push certificate expiry items to a Zabbix trapper after every scan"),
+            ("snmp", "Read-only SNMPv1/v2c agent exposing summary objects of the last scan"),
+            ("changes", "Changes between consecutive scans (/api/v1/changes, /api/v1/events)"),
+        ],
+    ),
+]
+
+
+def generate_example_config() -> str:
+    """
+    Generate a commented example configuration with every setting at its default.
+
+    Returns:
+        YAML document that loads to the default configuration
+    """
+    defaults = Config().model_dump(mode="json", by_alias=True)
+    lines = [
+        "# TLS Certificate Monitor configuration",
+        "# All settings are shown with their default values. Most settings can also be set",
+        "# with TLS_MONITOR_* environment variables or command line flags (see --help).",
+    ]
+    for title, settings in EXAMPLE_CONFIG_SECTIONS:
+        lines.extend(["", f"# {title}"])
+        for key, description in settings:
+            lines.append(f"# {description}")
+            value = yaml.safe_dump(
+                {key: defaults[key]}, default_flow_style=False, sort_keys=False
+            )
+            lines.extend(value.rstrip("\n").split("\n"))
+    return "\n".join(lines) + "\n"
+
+
 def create_example_config(output_path: str = "config.example.yaml") -> None:
     """Create an example configuration file."""
-    example_config = {
-        "port": 3200,
-        "bind_address": "0.0.0.0",  # nosec B104  # Intentional for production - allows external access
-        "certificate_directories": ["/etc/ssl/certs", "/etc/pki/tls/certs"],
-        "exclude_directories": ["/etc/ssl/certs/private", "/etc/ssl/certs/backup"],
-        "exclude_file_patterns": ["dhparam.pem", ".*\\.key$", ".*backup.*"],
-        "p12_passwords": ["", "changeit", "password", "123456"],
-        "scan_interval": "5m",
-        "workers": 4,
-        "log_level": "INFO",
-        "dry_run": False,
-        "hot_reload": True,
-        "cache_type": "memory",
-        "cache_dir": "./cache",
-        "cache_ttl": "1h",
-        "cache_max_size": 10485760,  # 10MB for memory, 30MB (31457280) for file
-        "allowed_ips": ["127.0.0.1", "::1", "192.168.1.0/24"],  # localhost + local network
-        "enable_ip_whitelist": True,
-    }
-
     with open(output_path, "w", encoding="utf-8") as f:
-        yaml.dump(example_config, f, default_flow_style=False, sort_keys=False)
+        f.write(generate_example_config())
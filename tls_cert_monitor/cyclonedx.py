@@ -0,0 +1,135 @@
+"""
+CycloneDX inventory export for TLS Certificate Monitor.
+
+The certificates of scan results as a CycloneDX 1.6 BOM: one cryptographic-asset component per
+certificate (by SHA-256 fingerprint) with its certificate properties, the files it was found in
+as occurrences, and the monitor's evaluation (severity, key, findings, labels) as properties in
+the tls-cert-monitor namespace. Asset-management and compliance tools that ingest CycloneDX
+(CBOM) can then track the certificate estate like any other inventory.
+"""
+
+import socket
+import time
+import uuid
+from datetime import datetime, timezone
+from pathlib import PurePath
+from typing import Any, Dict, List, Optional
+
+from tls_cert_monitor import __version__
+from tls_cert_monitor.check import certificate_findings
+from tls_cert_monitor.history import certificate_fingerprint
+
+SPEC_VERSION = "1.6"
+
+# Media type of the BOM, as served by /api/v1/inventory/cyclonedx
+BOM_MEDIA_TYPE = f"application/vnd.cyclonedx+json; version={SPEC_VERSION}"
+
+# Namespace of the monitor's properties
+PROPERTY_PREFIX = "tls-cert-monitor"
+
+# Certificate fields reported as properties
+PROPERTY_FIELDS = (
+    "severity",
+    "days_until_expiry",
+    "serial",
+    "key_algorithm",
+    "key_size",
+    "signature_algorithm",
+)
+
+
+def _property(name: str, value: Any) -> Dict[str, str]:
+    """Build a property in the monitor's namespace."""
+    return {"name": f"{PROPERTY_PREFIX}:{name}", "value": str(value)}
+
+
+def _component(cert: Dict[str, Any], paths: List[str]) -> Dict[str, Any]:
+    """Build the cryptographic-asset component of a certificate found in the files."""
+    fingerprint = certificate_fingerprint(cert)
+    certificate_properties = {
+        "subjectName": cert.get("subject", ""),
+        "issuerName": cert.get("issuer", ""),
+        "notValidBefore": cert.get("not_before", ""),
+        "notValidAfter": cert.get("not_after", ""),
+        "certificateFormat": "X.509",
+    }
+    extension = PurePath(paths[0]).suffix.lstrip(".")
+    if extension:
+        certificate_properties["certificateExtension"] = extension
+
+    properties = [
+        _property(field, cert[field]) for field in PROPERTY_FIELDS if cert.get(field) is not None
+    ]
+    properties.extend(_property("san", san) for san in cert.get("san_list", []))
+    properties.extend(
+        _property("finding", f"{severity}: {description}")
+        for severity, description in certificate_findings(cert)
+    )
+    if cert.get("silenced"):
+        properties.append(_property("silenced", "true"))
+    properties.extend(
+        _property(f"label:{name}", value) for name, value in cert.get("labels", {}).items()
+    )
+
+    component: Dict[str, Any] = {
+        "type": "cryptographic-asset",
+        "bom-ref": f"certificate:{fingerprint}",
+        "name": cert.get("common_name") or fingerprint,
+        "cryptoProperties": {
+            "assetType": "certificate",
+            "certificateProperties": certificate_properties,
+        },
+        "evidence": {"occurrences": [{"location": path} for path in paths]},
+        "properties": properties,
+    }
+    if cert.get("fingerprint_sha256"):
+        component["hashes"] = [{"alg": "SHA-256", "content": cert["fingerprint_sha256"]}]
+    return component
+
+
+def build_bom(
+    certificates: List[Dict[str, Any]], timestamp: Optional[float] = None
+) -> Dict[str, Any]:
+    """
+    Build a CycloneDX BOM of certificates.
+
+    Args:
+        certificates: Certificate data as in scan results; a certificate found in several files
+            is one component with an occurrence per file
+        timestamp: Time of the inventory (default: now)
+
+    Returns:
+        CycloneDX JSON document
+    """
+    by_fingerprint: Dict[str, Dict[str, Any]] = {}
+    paths: Dict[str, List[str]] = {}
+    for cert in certificates:
+        fingerprint = certificate_fingerprint(cert)
+        by_fingerprint.setdefault(fingerprint, cert)
+        paths.setdefault(fingerprint, []).append(cert.get("path", ""))
+
+    moment = datetime.fromtimestamp(time.time() if timestamp is None else timestamp, timezone.utc)
+    return {
+        "bomFormat": "CycloneDX",
+        "specVersion": SPEC_VERSION,
+        "serialNumber": uuid.uuid4().urn,
+        "version": 1,
+        "metadata": {
+            "timestamp": moment.replace(microsecond=0).isoformat().replace("+00:00", "Z"),
+            "tools": {
+                "components": [
+                    {"type": "application", "name": "tls-cert-monitor", "version": __version__}
+                ]
+            },
+            # The host the certificates were found on
+            "component": {
+                "type": "device",
+                "bom-ref": "host",
+                "name": socket.gethostname(),
+            },
+        },
+        "components": [
+            _component(cert, sorted(paths[fingerprint]))
+            for fingerprint, cert in by_fingerprint.items()
+        ],
+    }
@@ -0,0 +1,251 @@
+"""
+Scheduled digest reports for TLS Certificate Monitor.
+"""
+
+import asyncio
+import time
+from datetime import datetime
+from typing import Any, Dict, List, Optional, Tuple
+
+from tls_cert_monitor.config import Config
+from tls_cert_monitor.logger import get_logger
+from tls_cert_monitor.notifiers import Notification, Notifier, dispatch
+from tls_cert_monitor.scanner import CertificateScanner
+from tls_cert_monitor.schedule import CronSchedule
+
+InventoryKey = Tuple[str, str]  # (path, serial)
+
+
+def _inventory(scan_results: Dict[str, Any]) -> Dict[InventoryKey, Dict[str, Any]]:
+    """Index certificates from scan results by path and serial."""
+    inventory = {}
+    for directory_result in scan_results.get("directories", {}).values():
+        for cert in directory_result.get("certificates", []):
+            inventory[(cert.get("path", ""), cert.get("serial", ""))] = cert
+    return inventory
+
+
+def _summarize(cert: Dict[str, Any], now: float) -> Dict[str, Any]:
+    """Reduce certificate data to the fields shown in a digest."""
+    return {
+        "common_name": cert.get("common_name", "unknown"),
+        "path": cert.get("path", ""),
+        "serial": cert.get("serial", ""),
+        "issuer": cert.get("issuer", ""),
+        "not_after": cert.get("not_after", ""),
+        "days_until_expiry": int((cert.get("expiration_timestamp", now) - now) // 86400),
+        "severity": cert.get("severity", "ok"),
+    }
+
+
+class DigestReporter:
+    """
+    Periodic summary of certificate state, independent of threshold alerts.
+
+    The digest lists certificates expiring within the configured thresholds,
+    certificates added or removed since the previous digest and problems
+    found by the last scan (parse errors, weak keys, deprecated algorithms).
+    """
+
+    def __init__(
+        self,
+        config: Config,
+        scanner: CertificateScanner,
+        notifiers: Dict[str, Notifier],
+    ):
+        self.config = config
+        self.scanner = scanner
+        self.notifiers = [notifiers[name] for name in config.digest.notifiers]
+        self.schedule = CronSchedule(config.digest.schedule)
+        self.logger = get_logger("digest")
+
+        # Inventory at the time of the previous digest (or first scan)
+        self._baseline: Optional[Dict[InventoryKey, Dict[str, Any]]] = None
+        self._task: Optional[asyncio.Task] = None
+        self.last_sent: Optional[float] = None
+
+        scanner.add_scan_listener(self._on_scan_complete)
+
+    async def start(self) -> None:
+        """Start the digest schedule."""
+        if self._task:
+            return
+        self._task = asyncio.create_task(self._run_loop())
+        self.logger.info(
+            f"Digest reports scheduled - Schedule: {self.schedule.expression}, "
+            f"Notifiers: {[notifier.name for notifier in self.notifiers]}"
+        )
+
+    async def stop(self) -> None:
+        """Stop the digest schedule."""
+        if self._task:
+            self._task.cancel()
+            try:
+                await self._task
+            except asyncio.CancelledError:
+                pass
+            self._task = None
+        self.logger.info("Digest reporter stopped")
+
+    async def _on_scan_complete(self, scan_results: Dict[str, Any]) -> None:
+        """Use the first scan as the baseline for new/removed certificates."""
+        if self._baseline is None:
+            self._baseline = _inventory(scan_results)
+
+    async def _run_loop(self) -> None:
+        """Sleep until each scheduled time and send the digest."""
+        while True:
+            try:
+                now = datetime.now().astimezone()
+                next_run = self.schedule.next_after(now)
+                self.logger.debug(f"Next digest at {next_run.isoformat()}")
+                await asyncio.sleep((next_run - now).total_seconds())
+                await self.send_digest()
+            except asyncio.CancelledError:
+                break
+            except Exception as e:
+                self.logger.error(f"Error in digest loop: {e}")
+                await asyncio.sleep(60)  # Wait before retrying
+
+    def build_digest(self, now: Optional[float] = None) -> Dict[str, Any]:
+        """
+        Build the digest from the last scan results.
+
+        Args:
+            now: Current Unix timestamp (defaults to time.time())
+
+        Returns:
+            Digest dictionary
+        """
+        now = now if now is not None else time.time()
+        scan_results = self.scanner.last_scan_results or {"directories": {}}
+        current = _inventory(scan_results)
+        baseline = self._baseline if self._baseline is not None else current
+
+        expiring: List[Dict[str, Any]] = []
+        expired: List[Dict[str, Any]] = []
+        problems: List[Dict[str, Any]] = []
+        silenced = 0
+
+        for cert in current.values():
+            if cert.get("silenced"):
+                silenced += 1
+                continue
+
+            severity = cert.get("severity", "ok")
+            if severity == "expired":
+                expired.append(_summarize(cert, now))
+            elif severity in ("warning", "critical"):
+                expiring.append(_summarize(cert, now))
+
+            if cert.get("is_weak_key"):
+                problems.append({**_summarize(cert, now), "problem": "weak key"})
+            if cert.get("is_deprecated_algorithm"):
+                problems.append({**_summarize(cert, now), "problem": "deprecated algorithm"})
+
+        for directory, directory_result in scan_results.get("directories", {}).items():
+            if directory_result.get("error"):
+                problems.append({"path": directory, "problem": directory_result["error"]})
+            elif directory_result.get("parse_errors"):
+                problems.append(
+                    {
+                        "path": directory,
+                        "problem": f"{directory_result['parse_errors']} parse error(s)",
+                    }
+                )
+
+        expiring.sort(key=lambda item: item["days_until_expiry"])
+
+        return {
+            "generated_at": now,
+            "last_scan_at": scan_results.get("timestamp"),
+            "total_certificates": len(current),
+            "silenced_certificates": silenced,
+            "expiring": expiring,
+            "expired": expired,
+            "new": [_summarize(cert, now) for key, cert in current.items() if key not in baseline],
+            "removed": [
+                _summarize(cert, now) for key, cert in baseline.items() if key not in current
+            ],
+            "problems": problems,
+        }
+
+    def format_digest(self, digest: Dict[str, Any]) -> Notification:
+        """
+        Render a digest as a notification.
+
+        Args:
+            digest: Digest from build_digest()
+
+        Returns:
+            Notification with a plain text body and the digest as data
+        """
+        title = (
+            f"TLS certificate digest: {len(digest['expiring'])} expiring, "
+            f"{len(digest['expired'])} expired, {len(digest['problems'])} problem(s)"
+        )
+
+        generated = datetime.fromtimestamp(digest["generated_at"]).astimezone()
+        lines = [
+            f"Certificate digest generated {generated.strftime('%Y-%m-%d %H:%M %Z')}",
+            f"Certificates monitored: {digest['total_certificates']} "
+            f"({digest['silenced_certificates']} silenced)",
+        ]
+
+        def section(heading: str, items: List[Dict[str, Any]], describe: Any) -> None:
+            lines.append("")
+            lines.append(f"{heading} ({len(items)})")
+            if not items:
+                lines.append("  none")
+            for item in items:
+                lines.append(f"  - {describe(item)}")
+
+        section(
+            "Expiring soon",
+            digest["expiring"],
+            lambda c: f"[{c['severity']}] {c['common_name']} in {c['days_until_expiry']} days "
+            f"({c['path']})",
+        )
+        section(
+            "Expired",
+            digest["expired"],
+            lambda c: f"{c['common_name']} expired {c['not_after']} ({c['path']})",
+        )
+        section(
+            "New certificates",
+            digest["new"],
+            lambda c: f"{c['common_name']} expires {c['not_after']} ({c['path']})",
+        )
+        section(
+            "Removed certificates",
+            digest["removed"],
+            lambda c: f"{c['common_name']} serial {c['serial']} ({c['path']})",
+        )
+        section("Problems", digest["problems"], lambda p: f"{p['path']}: {p['problem']}")
+
+        severity = "info"
+        if digest["expired"] or any(c["severity"] == "critical" for c in digest["expiring"]):
+            severity = "critical"
+        elif digest["expiring"] or digest["problems"]:
+            severity = "warning"
+
+        return Notification(title=title, body="\n".join(lines), severity=severity, data=digest)
+
+    async def send_digest(self) -> int:
+        """
+        Build and send the digest, then reset the new/removed baseline.
+
+        Returns:
+            Number of notifiers that delivered the digest
+        """
+        digest = self.build_digest()
+        notification = self.format_digest(digest)
+
+        delivered = await dispatch(self.notifiers, notification, dry_run=self.config.dry_run)
+
+        if self.scanner.last_scan_results is not None:
+            self._baseline = _inventory(self.scanner.last_scan_results)
+        self.last_sent = time.time()
+
+        self.logger.info(f"Digest sent to {delivered}/{len(self.notifiers)} notifier(s)")
+        return delivered
@@ -0,0 +1,143 @@
+"""
+Encrypted configuration files (SOPS, age) for TLS Certificate Monitor.
+
+Encrypted files are detected by content and decrypted with the sops or age command line
+tools, so notifier credentials can be committed to git:
+
+- SOPS: a YAML document with a top-level ``sops`` metadata section. Keys are found the way
+  sops finds them (SOPS_AGE_KEY_FILE, PGP, cloud KMS credentials, ...).
+- age: a binary or ASCII-armored age file, decrypted with the identity file given by
+  TLS_MONITOR_AGE_KEY_FILE (or SOPS_AGE_KEY_FILE).
+"""
+
+import os
+import shutil
+import subprocess  # nosec B404 - runs the sops/age binaries with fixed arguments
+from pathlib import Path
+from typing import Any, Dict, List, Optional
+
+import yaml
+
+AGE_KEY_FILE_ENV_VAR = "TLS_MONITOR_AGE_KEY_FILE"
+SOPS_AGE_KEY_FILE_ENV_VAR = "SOPS_AGE_KEY_FILE"
+
+AGE_HEADERS = (b"age-encryption.org/v1", b"-----BEGIN AGE ENCRYPTED FILE-----")
+
+# Decryption may reach out to a KMS, but should never hang startup or a reload
+DECRYPT_TIMEOUT_SECONDS = 30
+
+
+class ConfigDecryptionError(ValueError):
+    """An encrypted configuration file could not be decrypted."""
+
+
+def is_age_encrypted(raw: bytes) -> bool:
+    """Check if file content is age-encrypted (binary or ASCII-armored)."""
+    return raw.lstrip().startswith(AGE_HEADERS)
+
+
+def is_sops_document(data: Any) -> bool:
+    """Check if a parsed YAML document is SOPS-encrypted."""
+    return (
+        isinstance(data, dict)
+        and isinstance(data.get("sops"), dict)
+        and ("mac" in data["sops"] or "version" in data["sops"])
+    )
+
+
+def _age_key_file() -> Optional[str]:
+    """Get the age identity file from the environment."""
+    return os.getenv(AGE_KEY_FILE_ENV_VAR) or os.getenv(SOPS_AGE_KEY_FILE_ENV_VAR)
+
+
+def _run(tool: str, args: List[str], path: Path, env: Optional[Dict[str, str]] = None) -> str:
+    """Run a decryption tool and return its output."""
+    binary = shutil.which(tool)
+    if binary is None:
+        raise ConfigDecryptionError(
+            f"Configuration file {path} is encrypted but '{tool}' is not installed"
+        )
+
+    try:
+        result = subprocess.run(  # nosec B603 - fixed arguments, no shell
+            [binary, *args],
+            capture_output=True,
+            check=False,
+            env=env,
+            text=True,
+            timeout=DECRYPT_TIMEOUT_SECONDS,
+        )
+    except (OSError, subprocess.TimeoutExpired) as e:
+        raise ConfigDecryptionError(f"Failed to decrypt {path} with {tool}: {e}") from e
+
+    if result.returncode != 0:
+        message = result.stderr.strip() or f"exit code {result.returncode}"
+        raise ConfigDecryptionError(f"Failed to decrypt {path} with {tool}: {message}")
+    return result.stdout
+
+
+def decrypt_age_file(path: Path) -> str:
+    """
+    Decrypt an age-encrypted file.
+
+    Args:
+        path: Encrypted file
+
+    Returns:
+        Decrypted content
+
+    Raises:
+        ConfigDecryptionError: If no identity is configured or decryption fails
+    """
+    key_file = _age_key_file()
+    if not key_file:
+        raise ConfigDecryptionError(
+            f"Configuration file {path} is age-encrypted; set {AGE_KEY_FILE_ENV_VAR} "
+            "to the age identity file"
+        )
+    return _run("age", ["--decrypt", "--identity", key_file, str(path)], path)
+
+
+def decrypt_sops_file(path: Path) -> str:
+    """
+    Decrypt a SOPS-encrypted YAML file.
+
+    Args:
+        path: Encrypted file
+
+    Returns:
+        Decrypted YAML
+
+    Raises:
+        ConfigDecryptionError: If decryption fails
+    """
+    env = dict(os.environ)
+    key_file = os.getenv(AGE_KEY_FILE_ENV_VAR)
+    if key_file:
+        env.setdefault(SOPS_AGE_KEY_FILE_ENV_VAR, key_file)
+    return _run(
+        "sops", ["--decrypt", "--input-type", "yaml", "--output-type", "yaml", str(path)], path, env
+    )
+
+
+def read_config_file(path: Path) -> Any:
+    """
+    Read a YAML configuration file, decrypting it first if it is encrypted.
+
+    Args:
+        path: Configuration file
+
+    Returns:
+        Parsed YAML document (None for an empty file)
+
+    Raises:
+        ConfigDecryptionError: If the file is encrypted and cannot be decrypted
+    """
+    raw = path.read_bytes()
+    if is_age_encrypted(raw):
+        return yaml.safe_load(decrypt_age_file(path))
+
+    data = yaml.safe_load(raw.decode("utf-8"))
+    if is_sops_document(data):
+        return yaml.safe_load(decrypt_sops_file(path))
+    return data
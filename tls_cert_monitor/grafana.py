@@ -0,0 +1,340 @@
+"""
+Grafana dashboard for TLS Certificate Monitor.
+
+Builds a dashboard wired to the exporter's metric names and labels, with the expiry thresholds
+and scan interval of the configuration, ready to import in Grafana (Dashboards > New > Import)
+or to drop into a provisioning directory. The Prometheus data source and the instances are
+dashboard variables, so it works unchanged across Grafana installations.
+"""
+
+from typing import Any, Dict, List, Optional
+
+from tls_cert_monitor import __version__
+from tls_cert_monitor.config import Config
+
+# UID of the dashboard: importing a newer dashboard replaces the previous one
+DASHBOARD_UID = "tls-cert-monitor"
+
+# Panels are laid out on Grafana's 24 column grid
+GRID_WIDTH = 24
+
+# Selector of the instance variable, added to every query
+INSTANCE = 'instance=~"$instance"'
+
+DATASOURCE = {"type": "prometheus", "uid": "${datasource}"}
+
+SEVERITY_COLORS = {"ok": "green", "warning": "orange", "critical": "red", "expired": "dark-red"}
+
+
+def _thresholds(steps: List[Any]) -> Dict[str, Any]:
+    """Build absolute thresholds from (color, value) steps, the first value being None."""
+    return {"mode": "absolute", "steps": [{"color": c, "value": v} for c, v in steps]}
+
+
+def _panel(
+    panel_type: str,
+    title: str,
+    expr: str,
+    grid: Dict[str, int],
+    description: str = "",
+    unit: str = "none",
+    thresholds: Optional[Dict[str, Any]] = None,
+    legend: str = "",
+    instant: bool = False,
+    options: Optional[Dict[str, Any]] = None,
+    **extra: Any,
+) -> Dict[str, Any]:
+    """Build a panel of one Prometheus query."""
+    defaults: Dict[str, Any] = {"unit": unit}
+    if thresholds:
+        defaults["thresholds"] = thresholds
+        defaults["color"] = {"mode": "thresholds"}
+    target: Dict[str, Any] = {"datasource": DATASOURCE, "expr": expr, "refId": "A"}
+    if legend:
+        target["legendFormat"] = legend
+    if instant:
+        target.update({"instant": True, "range": False, "format": "table"})
+    return {
+        "type": panel_type,
+        "title": title,
+        "description": description,
+        "datasource": DATASOURCE,
+        "gridPos": grid,
+        "fieldConfig": {"defaults": defaults, "overrides": []},
+        "options": options or {},
+        "targets": [target],
+        **extra,
+    }
+
+
+def _stat(
+    title: str, expr: str, x: int, y: int, description: str, thresholds: Dict[str, Any], **kw: Any
+) -> Dict[str, Any]:
+    """Build a stat panel of a single value."""
+    return _panel(
+        "stat",
+        title,
+        expr,
+        {"h": 4, "w": 3, "x": x, "y": y},
+        description,
+        thresholds=thresholds,
+        options={
+            "colorMode": "background",
+            "graphMode": "none",
+            "reduceOptions": {"calcs": ["lastNotNull"], "fields": "", "values": False},
+        },
+        **kw,
+    )
+
+
+def _row(title: str, y: int) -> Dict[str, Any]:
+    """Build a row header."""
+    return {
+        "type": "row",
+        "title": title,
+        "collapsed": False,
+        "gridPos": {"h": 1, "w": GRID_WIDTH, "x": 0, "y": y},
+        "panels": [],
+    }
+
+
+def build_dashboard(config: Config) -> Dict[str, Any]:
+    """
+    Build the Grafana dashboard of the monitor.
+
+    Args:
+        config: Configuration: the expiry thresholds color the days left, the scan interval
+            tells when the last scan is stale
+
+    Returns:
+        Dashboard JSON model
+    """
+    warning_days = config.expiry_thresholds.warning_seconds / 86400
+    critical_days = config.expiry_thresholds.critical_seconds / 86400
+    # A scan is overdue once two intervals passed without one
+    stale_seconds = 2 * config.scan_interval_seconds
+
+    days_left = f"(ssl_cert_expiration_timestamp{{{INSTANCE}}} - time()) / 86400"
+    days_thresholds = _thresholds(
+        [("dark-red", None), ("red", 0), ("orange", critical_days), ("green", warning_days)]
+    )
+    zero_is_good = _thresholds([("green", None), ("red", 1)])
+
+    panels: List[Dict[str, Any]] = [_row("Overview", 0)]
+    stats = [
+        (
+            "Certificates",
+            f"sum(ssl_certs_by_severity{{{INSTANCE}}})",
+            "Certificates found by the last scans",
+            _thresholds([("blue", None)]),
+        ),
+        *(
+            (
+                severity.capitalize(),
+                f'sum(ssl_certs_by_severity{{{INSTANCE}, severity="{severity}"}})',
+                f"Certificates with {severity} expiry severity",
+                _thresholds([("green", None), (SEVERITY_COLORS[severity], 1)]),
+            )
+            for severity in ("warning", "critical", "expired")
+        ),
+        (
+            "Parse errors",
+            f"sum(ssl_cert_parse_errors_total{{{INSTANCE}}})",
+            "Files that failed to parse in the last scans",
+            zero_is_good,
+        ),
+        (
+            "Weak keys",
+            f"sum(ssl_cert_weak_key_total{{{INSTANCE}}})",
+            "Certificates with weak keys",
+            zero_is_good,
+        ),
+        (
+            "Deprecated algorithms",
+            f"sum(ssl_cert_deprecated_sigalg_total{{{INSTANCE}}})",
+            "Certificates signed with deprecated algorithms",
+            zero_is_good,
+        ),
+    ]
+    for index, (title, expr, description, thresholds) in enumerate(stats):
+        panels.append(_stat(title, expr, 3 * index, 1, description, thresholds))
+    panels.append(
+        _stat(
+            "Last scan",
+            f"time() - min(max by (instance) (ssl_cert_last_scan_timestamp{{{INSTANCE}}}))",
+            21,
+            1,
+            f"Time since the last scan of the stalest instance (stale after {stale_seconds}s)",
+            _thresholds([("green", None), ("red", stale_seconds)]),
+            unit="s",
+        )
+    )
+
+    panels.append(_row("Expiry", 5))
+    panels.append(
+        _panel(
+            "table",
+            "Certificates by days left",
+            days_left,
+            {"h": 10, "w": 14, "x": 0, "y": 6},
+            f"Days until expiry: orange below {warning_days:g}, red below {critical_days:g}",
+            thresholds=days_thresholds,
+            instant=True,
+            options={"sortBy": [{"displayName": "Days left", "desc": False}]},
+            transformations=[
+                {
+                    "id": "organize",
+                    "options": {
+                        "excludeByName": {"Time": True, "__name__": True, "job": True},
+                        "renameByName": {"Value": "Days left"},
+                    },
+                }
+            ],
+        )
+    )
+    panels[-1]["fieldConfig"]["overrides"] = [
+        {
+            "matcher": {"id": "byName", "options": "Days left"},
+            "properties": [
+                {"id": "decimals", "value": 0},
+                {"id": "custom.cellOptions", "value": {"type": "color-background"}},
+            ],
+        }
+    ]
+    panels.append(
+        _panel(
+            "bargauge",
+            "Soonest expiring",
+            f"bottomk(10, {days_left})",
+            {"h": 10, "w": 10, "x": 14, "y": 6},
+            "The ten certificates closest to expiry, in days",
+            thresholds=days_thresholds,
+            legend="{{common_name}} ({{instance}})",
+            options={"displayMode": "basic", "orientation": "horizontal"},
+        )
+    )
+    panels.append(
+        _panel(
+            "timeseries",
+            "Certificates by severity",
+            f"sum by (severity) (ssl_certs_by_severity{{{INSTANCE}}})",
+            {"h": 8, "w": 12, "x": 0, "y": 16},
+            "Certificates in each expiry severity over time",
+            legend="{{severity}}",
+        )
+    )
+    panels[-1]["fieldConfig"]["overrides"] = [
+        {
+            "matcher": {"id": "byName", "options": severity},
+            "properties": [{"id": "color", "value": {"fixedColor": color, "mode": "fixed"}}],
+        }
+        for severity, color in SEVERITY_COLORS.items()
+    ]
+    panels.append(
+        _panel(
+            "table",
+            "Silenced certificates",
+            f"ssl_cert_silenced{{{INSTANCE}}} == 1",
+            {"h": 8, "w": 12, "x": 12, "y": 16},
+            "Certificates matched by an active silence (not alerted on)",
+            instant=True,
+            transformations=[
+                {
+                    "id": "organize",
+                    "options": {
+                        "excludeByName": {
+                            "Time": True,
+                            "Value": True,
+                            "__name__": True,
+                            "job": True,
+                        }
+                    },
+                }
+            ],
+        )
+    )
+
+    panels.append(_row("Scans", 24))
+    panels.append(
+        _panel(
+            "timeseries",
+            "Scan duration",
+            f"rate(ssl_cert_scan_duration_seconds_sum{{{INSTANCE}}}[$__rate_interval])"
+            f" / rate(ssl_cert_scan_duration_seconds_count{{{INSTANCE}}}[$__rate_interval])",
+            {"h": 8, "w": 8, "x": 0, "y": 25},
+            "Average scan duration per directory",
+            unit="s",
+            legend="{{directory}} ({{instance}})",
+        )
+    )
+    panels.append(
+        _panel(
+            "timeseries",
+            "Certificate files",
+            f"ssl_cert_files_total{{{INSTANCE}}}",
+            {"h": 8, "w": 8, "x": 8, "y": 25},
+            "Certificate files processed per directory",
+            legend="{{directory}} ({{instance}})",
+        )
+    )
+    panels.append(
+        _panel(
+            "timeseries",
+            "Health checks",
+            f"ssl_cert_monitor_health_check{{{INSTANCE}}}",
+            {"h": 8, "w": 8, "x": 16, "y": 25},
+            "Status of each /healthz check: 0 healthy, 1 degraded, 2 unhealthy",
+            thresholds=_thresholds([("green", None), ("orange", 1), ("red", 2)]),
+            legend="{{name}} ({{instance}})",
+        )
+    )
+
+    for panel_id, panel in enumerate(panels, start=1):
+        panel["id"] = panel_id
+
+    return {
+        "uid": DASHBOARD_UID,
+        "title": "TLS Certificate Monitor",
+        "description": f"Generated by TLS Certificate Monitor v{__version__}",
+        "tags": ["tls", "certificates", "tls-cert-monitor"],
+        "editable": True,
+        "graphTooltip": 1,
+        "refresh": "1m",
+        "schemaVersion": 39,
+        "time": {"from": "now-7d", "to": "now"},
+        "timezone": "browser",
+        "version": 1,
+        "annotations": {"list": []},
+        "links": [],
+        "panels": panels,
+        "templating": {
+            "list": [
+                {
+                    "name": "datasource",
+                    "label": "Data source",
+                    "type": "datasource",
+                    "query": "prometheus",
+                    "current": {},
+                    "hide": 0,
+                },
+                {
+                    "name": "instance",
+                    "label": "Instance",
+                    "type": "query",
+                    "datasource": DATASOURCE,
+                    "query": {
+                        "query": "label_values(ssl_certs_by_severity, instance)",
+                        "refId": "instance",
+                    },
+                    "definition": "label_values(ssl_certs_by_severity, instance)",
+                    "refresh": 2,
+                    "includeAll": True,
+                    "multi": True,
+                    "allValue": ".*",
+                    "current": {"text": "All", "value": "$__all"},
+                    "sort": 1,
+                    "hide": 0,
+                },
+            ]
+        },
+    }
@@ -0,0 +1,312 @@
+"""
+Health evaluation for TLS Certificate Monitor.
+
+/healthz reports "healthy", "degraded" or "unhealthy", judged against the configured
+health_thresholds; the reasons for anything but healthy are listed in health_reasons.
+"""
+
+import asyncio
+import shutil
+from concurrent.futures import ThreadPoolExecutor
+from typing import Any, Awaitable, Callable, Dict, Iterable, List, Optional, Tuple, TypeVar
+
+from tls_cert_monitor.cache import bytes_to_mib
+from tls_cert_monitor.config import HealthThresholds
+
+HEALTHY = "healthy"
+DEGRADED = "degraded"
+UNHEALTHY = "unhealthy"
+
+# Health statuses, best first
+HEALTH_STATUSES = (HEALTHY, DEGRADED, UNHEALTHY)
+
+# The first scan of an empty cache only misses: the hit rate is judged from the second scan
+MIN_SCANS_FOR_HIT_RATE = 2
+
+# A health check: coroutine function returning health data to merge into /healthz
+HealthCheck = Callable[[], Awaitable[Dict[str, Any]]]
+
+# Blocking checks run on their own threads: a check hung on a dead NFS mount keeps its thread,
+# and must not take one of the default executor's (cache loads and saves) with it
+_executor = ThreadPoolExecutor(max_workers=4, thread_name_prefix="health")
+
+T = TypeVar("T")
+
+
+class HealthCheckFailure(Exception):
+    """Raised by a health check to report a problem, degrading /healthz (or worse)."""
+
+    def __init__(
+        self, reason: str, status: str = DEGRADED, data: Optional[Dict[str, Any]] = None
+    ):
+        super().__init__(reason)
+        if status not in (DEGRADED, UNHEALTHY):
+            raise ValueError(f"Invalid health check failure status: {status}")
+        self.status = status
+        # Health data still reported along with the failure
+        self.data = data
+
+
+class HealthChecker:
+    """
+    Health checks contributed by subsystems (alerting, network scanners, cloud sources, ...).
+
+    /healthz runs the registered checks alongside its own, each within health_check_timeout.
+    A check's data is reported under its name; it reports a problem by raising
+    HealthCheckFailure (any other exception or a timeout degrades /healthz).
+    """
+
+    # Names of the built-in checks of /healthz
+    RESERVED_NAMES = ("scanner", "cache", "metrics", "system")
+
+    def __init__(self) -> None:
+        self._checks: Dict[str, HealthCheck] = {}
+
+    def register(self, name: str, check: HealthCheck) -> None:
+        """
+        Register a health check.
+
+        Args:
+            name: Unique check name, the key of its data in /healthz
+            check: Coroutine function returning the check's health data
+
+        Raises:
+            ValueError: If the name is taken
+        """
+        if name in self.RESERVED_NAMES or name.startswith("disk ") or name in self._checks:
+            raise ValueError(f"Health check already registered: {name}")
+        self._checks[name] = check
+
+    def unregister(self, name: str) -> None:
+        """Remove a registered health check (no-op if it is not registered)."""
+        self._checks.pop(name, None)
+
+    @property
+    def checks(self) -> Dict[str, HealthCheck]:
+        """Registered checks, each wrapped to report its data under its name."""
+
+        def nested(name: str, check: HealthCheck) -> HealthCheck:
+            async def run() -> Dict[str, Any]:
+                try:
+                    return {name: await check()}
+                except HealthCheckFailure as e:
+                    if e.data is not None:
+                        e.data = {name: e.data}
+                    raise
+
+            return run
+
+        return {name: nested(name, check) for name, check in self._checks.items()}
+
+
+async def run_blocking(func: Callable[..., T], *args: Any) -> T:
+    """Run a blocking (filesystem) health check function off the event loop."""
+    return await asyncio.get_running_loop().run_in_executor(_executor, func, *args)
+
+
+async def run_checks(checks: Dict[str, HealthCheck], timeout: float) -> Dict[str, Any]:
+    """
+    Run health checks concurrently, each bounded by a timeout.
+
+    A check that fails or times out does not hold up or fail the others: it is reported in
+    health_check_errors (by check name, with the status it causes) instead.
+
+    Args:
+        checks: Health checks by name
+        timeout: Time each check may take, in seconds
+
+    Returns:
+        Merged health data of the checks, with health_check_errors
+    """
+    errors: Dict[str, Dict[str, str]] = {}
+
+    async def run(name: str, check: HealthCheck) -> Dict[str, Any]:
+        try:
+            return await asyncio.wait_for(check(), timeout)
+        except asyncio.TimeoutError:
+            errors[name] = {"status": DEGRADED, "error": f"timed out after {timeout:g}s"}
+        except HealthCheckFailure as e:
+            errors[name] = {"status": e.status, "error": str(e)}
+            return e.data or {}
+        except Exception as e:
+            errors[name] = {"status": DEGRADED, "error": str(e) or type(e).__name__}
+        return {}
+
+    results = await asyncio.gather(*(run(name, check) for name, check in checks.items()))
+    health_data: Dict[str, Any] = {}
+    for result in results:
+        health_data.update(result)
+    health_data["health_check_errors"] = errors
+    return health_data
+
+
+def disk_usage_key(directory: str) -> str:
+    """Get the /healthz key of a directory's disk usage (separators and drive colons as _)."""
+    return "diskspace_" + directory.replace("/", "_").replace("\\", "_").replace(":", "_")
+
+
+def disk_usage(directory: str) -> Dict[str, Any]:
+    """
+    Get the disk usage of the filesystem holding a directory.
+
+    shutil.disk_usage is portable: statvfs on POSIX, GetDiskFreeSpaceExW on Windows, which
+    handles drive letters, mount points and UNC shares (\\\\server\\share\\certs) alike.
+
+    Raises:
+        OSError: If the directory does not exist or its filesystem cannot be queried
+    """
+    usage = shutil.disk_usage(directory)
+    return {
+        "directory": directory,
+        "total_bytes": usage.total,
+        "total_mib": round(bytes_to_mib(usage.total), 2),
+        "used_bytes": usage.used,
+        "used_mib": round(bytes_to_mib(usage.used), 2),
+        "free_bytes": usage.free,
+        "free_mib": round(bytes_to_mib(usage.free), 2),
+        # Filesystems reporting no size (some network and pseudo filesystems) count as empty
+        "percent_used": round((usage.used / usage.total) * 100, 2) if usage.total else 0.0,
+    }
+
+
+def worst_status(statuses: Iterable[str]) -> str:
+    """Get the worst of health statuses (healthy if there are none)."""
+    return max(statuses, key=HEALTH_STATUSES.index, default=HEALTHY)
+
+
+def _find_problems(
+    health_data: Dict[str, Any], thresholds: HealthThresholds
+) -> List[Tuple[str, str, str]]:
+    """Get the problems in health data as (check name, status, reason) tuples."""
+    problems: List[Tuple[str, str, str]] = []
+
+    for name, error in health_data.get("health_check_errors", {}).items():
+        problems.append((name, error["status"], f"Health check {name} failed: {error['error']}"))
+
+    for key, usage in health_data.items():
+        if not key.startswith("diskspace_") or not isinstance(usage, dict):
+            continue
+        percent_used = usage.get("percent_used")
+        if percent_used is None:
+            continue
+        directory = usage.get("directory", key)
+        if percent_used >= thresholds.disk_usage_unhealthy:
+            status = UNHEALTHY
+            limit = thresholds.disk_usage_unhealthy
+        elif percent_used >= thresholds.disk_usage_degraded:
+            status = DEGRADED
+            limit = thresholds.disk_usage_degraded
+        else:
+            continue
+        problems.append(
+            (
+                f"disk {directory}",
+                status,
+                f"Disk usage of {directory} is {percent_used}% (>= {limit}%)",
+            )
+        )
+
+    windows = health_data.get("expiry_thresholds", {})
+    for severity, count in health_data.get("expiring_certificates", {}).items():
+        status = getattr(thresholds, f"certificates_{severity}", HEALTHY)
+        if not count or status == HEALTHY:
+            continue
+        if severity == "expired":
+            reason = f"{count} certificate(s) expired"
+        else:
+            window = windows.get(severity, "the configured window")
+            reason = f"{count} certificate(s) expire within {window} ({severity})"
+        problems.append(("scanner", status, reason))
+
+    scan = health_data.get("current_scan")
+    if scan:
+        average = health_data.get("average_scan_duration") or 0
+        limit = max(thresholds.scan_stall_factor * average, thresholds.scan_stall_min_seconds)
+        if scan["running_seconds"] > limit:
+            reason = f"Scan running for {scan['running_seconds']:.0f}s (> {limit:.0f}s)"
+            stuck_on = scan.get("oldest_file") or scan.get("directory")
+            if stuck_on:
+                reason += f", stalled on {stuck_on}"
+            problems.append(("scanner", DEGRADED, reason))
+
+    hit_rate = health_data.get("cache_hit_rate")
+    if (
+        health_data.get("cache_enabled")
+        and hit_rate is not None
+        and health_data.get("scans_completed", 0) >= MIN_SCANS_FOR_HIT_RATE
+        and hit_rate < thresholds.cache_hit_rate_degraded
+    ):
+        problems.append(
+            (
+                "cache",
+                DEGRADED,
+                f"Cache hit rate is {hit_rate} (< {thresholds.cache_hit_rate_degraded})",
+            )
+        )
+
+    save_failures = health_data.get("cache_save_failures", 0)
+    if save_failures >= thresholds.cache_save_failures_degraded:
+        problems.append(
+            (
+                "cache",
+                DEGRADED,
+                f"{save_failures} cache saves failed in a row: "
+                f"{health_data.get('cache_last_save_error')}",
+            )
+        )
+    load_error = health_data.get("cache_last_load_error")
+    if load_error:
+        problems.append(("cache", DEGRADED, f"Persistent cache failed to load: {load_error}"))
+
+    error_ratio = health_data.get("parse_error_ratio")
+    if error_ratio is not None and error_ratio > thresholds.parse_error_ratio_degraded:
+        problems.append(
+            (
+                "scanner",
+                DEGRADED,
+                f"{error_ratio:.0%} of the files of the last scan failed to parse "
+                f"(> {thresholds.parse_error_ratio_degraded:.0%})",
+            )
+        )
+
+    return problems
+
+
+def evaluate_health(
+    health_data: Dict[str, Any], thresholds: HealthThresholds
+) -> Tuple[str, List[str]]:
+    """
+    Judge the health data reported by /healthz against thresholds.
+
+    Args:
+        health_data: Combined scanner, cache and system health data
+        thresholds: Configured health thresholds
+
+    Returns:
+        Tuple of (health status, reasons the status is not healthy)
+    """
+    problems = _find_problems(health_data, thresholds)
+    return (
+        worst_status(status for _, status, _ in problems),
+        [reason for _, _, reason in problems],
+    )
+
+
+def check_statuses(
+    check_names: Iterable[str], health_data: Dict[str, Any], thresholds: HealthThresholds
+) -> Dict[str, str]:
+    """
+    Judge each health check of /healthz on its own, for the health check metric.
+
+    Args:
+        check_names: Names of the checks that were run
+        health_data: Health data reported by the checks
+        thresholds: Configured health thresholds
+
+    Returns:
+        Health status by check name
+    """
+    statuses = {name: HEALTHY for name in check_names}
+    for name, status, _ in _find_problems(health_data, thresholds):
+        statuses[name] = worst_status((statuses.get(name, HEALTHY), status))
+    return statuses
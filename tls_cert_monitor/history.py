@@ -0,0 +1,214 @@
+"""
+Certificate history for TLS Certificate Monitor.
+
+Every certificate observed by a scan is recorded in an SQLite database (history.db in the cache
+directory by default), by SHA-256 fingerprint: its validity, the files it was found in, and
+when it was first and last seen, in total and in each file. Unlike the cache, the history keeps
+certificates that are gone, so it answers audit questions such as when a certificate appeared
+and when the one in a file was renewed (the next certificate first seen there).
+"""
+
+import asyncio
+import sqlite3
+import threading
+import time
+from datetime import datetime, timezone
+from pathlib import Path
+from typing import Any, Dict, List, Optional
+
+from tls_cert_monitor.config import Config
+from tls_cert_monitor.logger import get_logger
+from tls_cert_monitor.scanner import CertificateScanner
+
+# Certificate fields stored with the fingerprint
+CERTIFICATE_FIELDS = ("common_name", "subject", "issuer", "serial", "not_before", "not_after")
+
+# Most certificates returned by one query
+MAX_QUERY_LIMIT = 1000
+
+SCHEMA = (
+    "CREATE TABLE IF NOT EXISTS certificates ("
+    "fingerprint TEXT PRIMARY KEY, common_name TEXT, subject TEXT, issuer TEXT, serial TEXT, "
+    "not_before TEXT, not_after TEXT, first_seen REAL NOT NULL, last_seen REAL NOT NULL)",
+    "CREATE TABLE IF NOT EXISTS locations ("
+    "fingerprint TEXT NOT NULL, path TEXT NOT NULL, directory TEXT NOT NULL, "
+    "first_seen REAL NOT NULL, last_seen REAL NOT NULL, PRIMARY KEY (fingerprint, path))",
+    "CREATE INDEX IF NOT EXISTS locations_path ON locations (path)",
+    "CREATE TABLE IF NOT EXISTS scans (name TEXT PRIMARY KEY, value REAL)",
+)
+
+
+def _isoformat(timestamp: float) -> str:
+    """Format a Unix timestamp as ISO 8601 (UTC)."""
+    return datetime.fromtimestamp(timestamp, timezone.utc).isoformat()
+
+
+def certificate_fingerprint(cert: Dict[str, Any]) -> str:
+    """Identify a certificate by fingerprint (path and serial for older cache entries)."""
+    return cert.get("fingerprint_sha256") or f"{cert.get('path', '')}#{cert.get('serial', '')}"
+
+
+class CertificateHistory:
+    """SQLite database of every certificate observed by the scans."""
+
+    def __init__(self, config: Config, scanner: Optional[CertificateScanner] = None):
+        self.config = config.history
+        self.path = Path(config.history.path or Path(config.cache_dir) / "history.db")
+        self.logger = get_logger("history")
+        self._connection: Optional[sqlite3.Connection] = None
+        # Scans record on a worker thread while the API queries on another
+        self._lock = threading.Lock()
+
+        if scanner:
+            scanner.add_scan_listener(self._on_scan_complete)
+
+    def _connect(self) -> sqlite3.Connection:
+        if self._connection is None:
+            self.path.parent.mkdir(parents=True, exist_ok=True)
+            connection = sqlite3.connect(str(self.path), check_same_thread=False)
+            try:
+                connection.execute("PRAGMA journal_mode=WAL")
+                for statement in SCHEMA:
+                    connection.execute(statement)
+                connection.commit()
+            except sqlite3.Error:
+                connection.close()
+                raise
+            self._connection = connection
+        return self._connection
+
+    async def _on_scan_complete(self, scan_results: Dict[str, Any]) -> None:
+        """Record the certificates of the scan, logging failures."""
+        try:
+            recorded = await asyncio.to_thread(self.record, scan_results)
+        except (OSError, sqlite3.Error) as e:
+            self.logger.warning(f"Failed to record certificate history in {self.path}: {e}")
+            return
+        self.logger.debug(f"Recorded {recorded} certificates in the history")
+
+    def record(self, scan_results: Dict[str, Any], now: Optional[float] = None) -> int:
+        """
+        Record the certificates of scan results, then drop those past the retention.
+
+        Args:
+            scan_results: Results from CertificateScanner.scan_once()
+            now: Time of the scan (default: now)
+
+        Returns:
+            Number of certificate sightings recorded
+        """
+        now = time.time() if now is None else now
+        sightings = [
+            (directory, cert)
+            for directory, result in scan_results.get("directories", {}).items()
+            for cert in result.get("certificates", [])
+        ]
+        with self._lock:
+            connection = self._connect()
+            # One transaction: a scan is recorded completely or not at all
+            with connection:
+                for directory, cert in sightings:
+                    fingerprint = certificate_fingerprint(cert)
+                    values = tuple(str(cert.get(name) or "") for name in CERTIFICATE_FIELDS)
+                    connection.execute(
+                        f"INSERT INTO certificates (fingerprint, {', '.join(CERTIFICATE_FIELDS)},"
+                        " first_seen, last_seen) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+                        " ON CONFLICT (fingerprint) DO UPDATE SET last_seen = excluded.last_seen",
+                        (fingerprint,) + values + (now, now),
+                    )
+                    connection.execute(
+                        "INSERT INTO locations (fingerprint, path, directory, first_seen,"
+                        " last_seen) VALUES (?, ?, ?, ?, ?) ON CONFLICT (fingerprint, path)"
+                        " DO UPDATE SET last_seen = excluded.last_seen",
+                        (fingerprint, cert.get("path", ""), directory, now, now),
+                    )
+                connection.execute(
+                    "INSERT OR REPLACE INTO scans (name, value) VALUES ('last_scan', ?)", (now,)
+                )
+                if self.config.retention_seconds:
+                    cutoff = now - self.config.retention_seconds
+                    connection.execute("DELETE FROM locations WHERE last_seen < ?", (cutoff,))
+                    connection.execute("DELETE FROM certificates WHERE last_seen < ?", (cutoff,))
+        return len(sightings)
+
+    def query(
+        self,
+        fingerprint: Optional[str] = None,
+        common_name: Optional[str] = None,
+        path: Optional[str] = None,
+        limit: int = 100,
+    ) -> List[Dict[str, Any]]:
+        """
+        Find recorded certificates, most recently first seen first.
+
+        Args:
+            fingerprint: SHA-256 fingerprint
+            common_name: Glob pattern of the common name (e.g. "*.example.com")
+            path: Glob pattern of a file the certificate was found in
+            limit: Most certificates returned (at most MAX_QUERY_LIMIT)
+
+        Returns:
+            Certificates with their validity, first and last seen times, whether the last scan
+            found them (present), and the files they were found in with the same times
+        """
+        conditions, parameters = [], []
+        if fingerprint:
+            conditions.append("fingerprint = ?")
+            parameters.append(fingerprint)
+        if common_name:
+            conditions.append("common_name GLOB ?")
+            parameters.append(common_name)
+        if path:
+            conditions.append(
+                "fingerprint IN (SELECT fingerprint FROM locations WHERE path GLOB ?)"
+            )
+            parameters.append(path)
+        where = f"WHERE {' AND '.join(conditions)}" if conditions else ""
+        parameters.append(max(1, min(limit, MAX_QUERY_LIMIT)))
+
+        with self._lock:
+            connection = self._connect()
+            last_scan = connection.execute(
+                "SELECT value FROM scans WHERE name = 'last_scan'"
+            ).fetchone()
+            rows = connection.execute(
+                f"SELECT fingerprint, {', '.join(CERTIFICATE_FIELDS)}, first_seen, last_seen"
+                f" FROM certificates {where} ORDER BY first_seen DESC, fingerprint LIMIT ?",
+                parameters,
+            ).fetchall()
+            locations: Dict[str, List[Dict[str, Any]]] = {row[0]: [] for row in rows}
+            for row in connection.execute(
+                "SELECT fingerprint, path, directory, first_seen, last_seen FROM locations"
+                f" WHERE fingerprint IN ({', '.join('?' * len(rows))}) ORDER BY first_seen, path",
+                list(locations),
+            ):
+                locations[row[0]].append(
+                    {
+                        "path": row[1],
+                        "directory": row[2],
+                        "first_seen": _isoformat(row[3]),
+                        "last_seen": _isoformat(row[4]),
+                        "present": bool(last_scan) and row[4] >= last_scan[0],
+                    }
+                )
+
+        certificates = []
+        for row in rows:
+            certificate = {"fingerprint": row[0], **dict(zip(CERTIFICATE_FIELDS, row[1:7]))}
+            certificate.update(
+                {
+                    "first_seen": _isoformat(row[7]),
+                    "last_seen": _isoformat(row[8]),
+                    "present": bool(last_scan) and row[8] >= last_scan[0],
+                    "locations": locations[row[0]],
+                }
+            )
+            certificates.append(certificate)
+        return certificates
+
+    def close(self) -> None:
+        """Close the database."""
+        with self._lock:
+            if self._connection is not None:
+                self._connection.close()
+                self._connection = None
@@ -3,16 +3,28 @@ Hot reload functionality for TLS Certificate Monitor.
 """
 
 import asyncio
+import errno
+import threading
+from collections import deque
+from datetime import datetime, timezone
 from pathlib import Path
-from typing import Any, Coroutine, Optional, Set
+from typing import Any, Coroutine, Deque, Dict, List, Optional, Set
 
 from watchdog.events import FileSystemEvent, FileSystemEventHandler
 from watchdog.observers import Observer
-
-from tls_cert_monitor.config import Config, load_config
-from tls_cert_monitor.logger import get_logger, log_hot_reload
+from watchdog.observers.api import ObservedWatch
+from watchdog.observers.polling import PollingObserver
+
+from tls_cert_monitor.cache import CacheManager
+from tls_cert_monitor.config import CONFIG_FILE_SUFFIXES, Config, diff_configs, load_config
+from tls_cert_monitor.health import HealthCheckFailure
+from tls_cert_monitor.logger import get_logger, log_config_changes, log_hot_reload, set_log_level
+from tls_cert_monitor.remote_config import create_remote_source
 from tls_cert_monitor.scanner import CertificateScanner
 
+# Errors adding a native watch because a kernel limit is reached (inotify watches, instances)
+WATCH_LIMIT_ERRNOS = (errno.ENOSPC, errno.EMFILE)
+
 
 class CertificateFileHandler(FileSystemEventHandler):
     """Handler for certificate file system events."""
@@ -32,14 +44,33 @@ class CertificateFileHandler(FileSystemEventHandler):
         if event.event_type not in meaningful_events:
             return
 
-        file_path = Path(event.src_path)
+        if event.event_type == "moved":
+            # Deployments (certbot, ansible) write a temporary file and rename it over the
+            # certificate: the source is gone and the destination changed, whichever of the
+            # two is a certificate file
+            self._handle_file_event(event.src_path, event.event_type, "deleted")
+            dest_path = getattr(event, "dest_path", "")
+            if dest_path:
+                self._handle_file_event(dest_path, event.event_type, "created")
+            return
+
+        # Map "closed" events to "created" since they indicate a new file was written;
+        # permission and ownership changes (chmod, chown) arrive as "modified"
+        actual_event_type = "created" if event.event_type == "closed" else event.event_type
+        self._handle_file_event(event.src_path, event.event_type, actual_event_type)
+
+    def _handle_file_event(self, path: str, event_type: str, actual_event_type: str) -> None:
+        """Schedule the handling of a change of a path, if it is a certificate file."""
+        file_path = Path(path)
 
         # Check if it's a certificate file
         if file_path.suffix.lower() in CertificateScanner.SUPPORTED_EXTENSIONS:
-            # Map "closed" events to "created" since they indicate a new file was written
-            actual_event_type = "created" if event.event_type == "closed" else event.event_type
+            if self.manager.config.excluded_file_glob(file_path) is not None:
+                self.logger.debug(f"Ignoring event for excluded file: {file_path}")
+                return
+
             self.logger.debug(
-                f"Certificate file event: {event.event_type} -> {actual_event_type} - {file_path}"
+                f"Certificate file event: {event_type} -> {actual_event_type} - {file_path}"
             )
             self.manager._schedule_coro(
                 self.manager._handle_certificate_change(str(file_path), actual_event_type)
@@ -66,7 +97,7 @@ class ConfigFileHandler(FileSystemEventHandler):
 
         # Check if it's the configuration file (handle cases where file might not exist)
         try:
-            if (
+            if self._is_config_directory_file(file_path) or (
                 self.manager.config_path
                 and file_path.exists()
                 and file_path.samefile(self.manager.config_path)
@@ -77,6 +108,34 @@ class ConfigFileHandler(FileSystemEventHandler):
             # File might be a temporary file that was quickly deleted
             pass
 
+    def on_created(self, event: FileSystemEvent) -> None:
+        """Handle files added to a configuration directory."""
+        self._on_directory_change(event, "added")
+
+    def on_deleted(self, event: FileSystemEvent) -> None:
+        """Handle files removed from a configuration directory."""
+        self._on_directory_change(event, "removed")
+
+    def _on_directory_change(self, event: FileSystemEvent, action: str) -> None:
+        if event.is_directory:
+            return
+
+        file_path = Path(event.src_path)
+        if self._is_config_directory_file(file_path):
+            self.logger.info(f"Configuration file {action}: {file_path}")
+            self.manager._schedule_coro(self.manager._handle_config_change())
+
+    def _is_config_directory_file(self, file_path: Path) -> bool:
+        """Check if a path is a *.yaml file in a conf.d-style configuration directory."""
+        config_path = self.manager.config_path
+        return bool(
+            config_path
+            and config_path.is_dir()
+            and file_path.parent == config_path
+            and file_path.suffix.lower() in CONFIG_FILE_SUFFIXES
+            and not file_path.name.startswith(".")
+        )
+
 
 class HotReloadManager:
     """
@@ -88,27 +147,77 @@ class HotReloadManager:
     - Certificate file additions/modifications/deletions
     """
 
+    # Number of configuration reload records kept for /config/history
+    HISTORY_SIZE = 20
+
+    # Seconds without certificate file events before the batch of changes is processed, and
+    # the longest a batch waits for such a pause (files written continuously still get picked up)
+    CERT_CHANGE_DEBOUNCE = 2.0
+    CERT_CHANGE_MAX_DELAY = 10.0
+
+    # Settings read when the cache manager is created: changing one replaces the cache
+    CACHE_SETTINGS = (
+        "cache_enabled",
+        "cache_type",
+        "cache_backend",
+        "cache_compression",
+        "cache_dir",
+        "cache_ttl",
+        "cache_max_size",
+        "cache_max_entries",
+        "cache_max_entry_size",
+        "cache_memory_limit",
+        "cache_save_interval",
+        "cache_compact_threshold",
+    )
+
     def __init__(
-        self, config: Config, scanner: CertificateScanner, config_path: Optional[str] = None
+        self,
+        config: Config,
+        scanner: CertificateScanner,
+        config_path: Optional[str] = None,
+        config_overrides: Optional[Dict[str, Any]] = None,
+        remote_config: Optional[str] = None,
+        profile: Optional[str] = None,
     ):
         self.config = config
         self.scanner = scanner
         self.config_path = Path(config_path) if config_path else None
+        # Command line flags keep precedence over the reloaded file
+        self.config_overrides = config_overrides or {}
+        self.remote_config = remote_config
+        self.profile = profile
         self.logger = get_logger("hot_reload")
 
         self._observer = Observer()
         self._watching = False
         self._watched_paths: Set[str] = set()
+        self._cert_watches: Dict[str, ObservedWatch] = {}
+        # Directories that could not be watched natively because the watch limit is reached,
+        # polled by their own observer (unless watch_poll_interval is 0s)
+        self._watch_limited: Set[str] = set()
+        self._polling_observer: Optional[PollingObserver] = None
         self._event_loop: Optional[asyncio.AbstractEventLoop] = None
 
         # Event handlers
         self._cert_handler = CertificateFileHandler(self)
         self._config_handler = ConfigFileHandler(self)
 
-        # Debouncing for rapid file changes
+        # Debouncing for rapid file changes: certificate file events are coalesced by path
+        # (latest event type) into a batch processed with one rescan
+        self._pending_cert_changes: Dict[str, str] = {}
+        self._cert_batch_started: Optional[float] = None
+        self._cert_change_timer: Optional[asyncio.Task] = None
         self._cert_change_tasks: Set[asyncio.Task] = set()
         self._config_change_task: Optional[asyncio.Task] = None
 
+        # Remote configuration watch (blocking queries run in a thread)
+        self._remote_watch_thread: Optional[threading.Thread] = None
+        self._remote_watch_stop = threading.Event()
+
+        # Audit trail of applied configuration reloads, newest last
+        self._history: Deque[Dict[str, Any]] = deque(maxlen=self.HISTORY_SIZE)
+
         self.logger.info("Hot reload manager initialized")
 
     def _schedule_coro(self, coro: Coroutine[Any, Any, Any]) -> None:
@@ -133,26 +242,40 @@ class HotReloadManager:
 
         try:
             # Watch configuration file
-            if self.config_path and self.config_path.exists():
+            if self.config_path and self.config_path.is_dir():
+                self._observer.schedule(
+                    self._config_handler, str(self.config_path), recursive=False
+                )
+                self._watched_paths.add(str(self.config_path))
+                self.logger.info(f"Watching configuration directory: {self.config_path}")
+            elif self.config_path and self.config_path.exists():
                 config_dir = self.config_path.parent
                 self._observer.schedule(self._config_handler, str(config_dir), recursive=False)
                 self._watched_paths.add(str(config_dir))
                 self.logger.info(f"Watching configuration file: {self.config_path}")
 
-            # Watch certificate directories
-            for cert_dir in self.config.certificate_directories:
-                cert_path = Path(cert_dir)
-                if cert_path.exists() and cert_path.is_dir():
-                    self._observer.schedule(self._cert_handler, str(cert_path), recursive=True)
-                    self._watched_paths.add(str(cert_path))
-                    self.logger.info(f"Watching certificate directory: {cert_path}")
-                else:
-                    self.logger.warning(f"Certificate directory does not exist: {cert_dir}")
-
-            # Start observer
+            # Start the observer before watching certificate directories: native watches are
+            # then added as directories are scheduled, so one over the watch limit fails alone
             self._observer.start()
             self._watching = True
 
+            # Watch certificate directories
+            if self.config.watch_files:
+                self._watch_certificate_directories(self.config.certificate_directories)
+            else:
+                self.logger.info(
+                    "Certificate file watching disabled (watch_files: false) - "
+                    "relying on periodic scans"
+                )
+
+            if self.remote_config:
+                self._remote_watch_stop.clear()
+                self._remote_watch_thread = threading.Thread(
+                    target=self._watch_remote_config, name="remote-config-watch", daemon=True
+                )
+                self._remote_watch_thread.start()
+                self.logger.info("Watching remote configuration")
+
             self.logger.info(f"Hot reload started - Watching {len(self._watched_paths)} paths")
 
         except Exception as e:
@@ -167,8 +290,20 @@ class HotReloadManager:
         try:
             self._observer.stop()
             self._observer.join(timeout=5.0)
+            # Observer threads can't be restarted: start() needs a fresh one
+            self._observer = Observer()
+            if self._polling_observer:
+                self._polling_observer.stop()
+                self._polling_observer.join(timeout=5.0)
+                self._polling_observer = None
+            self._watch_limited.clear()
+
+            # The watch thread is a daemon blocked on a long poll; don't wait for it
+            self._remote_watch_stop.set()
 
             # Cancel pending tasks
+            if self._cert_change_timer:
+                self._cert_change_timer.cancel()
             for task in self._cert_change_tasks:
                 task.cancel()
 
@@ -177,90 +312,226 @@ class HotReloadManager:
 
             self._watching = False
             self._watched_paths.clear()
+            self._cert_watches.clear()
 
             self.logger.info("Hot reload stopped")
 
         except Exception as e:
             self.logger.error(f"Error stopping hot reload: {e}")
 
+    def _watch_certificate_directories(self, directories: List[str]) -> None:
+        """Start watching certificate directories for file changes."""
+        for cert_dir in directories:
+            cert_path = Path(cert_dir)
+            if cert_path.exists() and cert_path.is_dir():
+                if self._watch_certificate_directory(cert_path):
+                    self.logger.info(f"Watching certificate directory: {cert_path}")
+            else:
+                self.logger.warning(f"Certificate directory does not exist: {cert_dir}")
+        self._update_watch_metrics()
+
+    def _watch_certificate_directory(self, cert_path: Path) -> bool:
+        """
+        Watch a certificate directory natively, or by polling once the watch limit is reached.
+
+        Returns:
+            True if the directory is watched, False if it is left to periodic scans
+        """
+        try:
+            watch = self._observer.schedule(self._cert_handler, str(cert_path), recursive=True)
+        except OSError as e:
+            if e.errno not in WATCH_LIMIT_ERRNOS:
+                raise
+            self._watch_limited.add(str(cert_path))
+            if not self.config.watch_poll_interval_seconds:
+                self.logger.warning(
+                    f"Cannot watch certificate directory {cert_path}: {e} - relying on periodic "
+                    "scans (raise fs.inotify.max_user_watches, or set watch_poll_interval)"
+                )
+                return False
+
+            if self._polling_observer is None:
+                self._polling_observer = PollingObserver(
+                    timeout=self.config.watch_poll_interval_seconds
+                )
+                self._polling_observer.start()
+            watch = self._polling_observer.schedule(
+                self._cert_handler, str(cert_path), recursive=True
+            )
+            self.logger.warning(
+                f"Cannot watch certificate directory {cert_path}: {e} - polling it every "
+                f"{self.config.watch_poll_interval} (raise fs.inotify.max_user_watches to "
+                "watch it natively)"
+            )
+
+        self._cert_watches[str(cert_path)] = watch
+        self._watched_paths.add(str(cert_path))
+        return True
+
+    def _unwatch_certificate_directories(self) -> None:
+        """Stop watching all certificate directories."""
+        for path, watch in self._cert_watches.items():
+            if path in self._watch_limited and self._polling_observer:
+                self._polling_observer.unschedule(watch)
+            else:
+                self._observer.unschedule(watch)
+            self._watched_paths.discard(path)
+        self._cert_watches.clear()
+        self._watch_limited.clear()
+        self._update_watch_metrics()
+
+    def _update_watch_metrics(self) -> None:
+        """Export the number of certificate directories watched natively and by polling."""
+        polled = sum(1 for path in self._cert_watches if path in self._watch_limited)
+        if hasattr(self.scanner, "metrics"):
+            self.scanner.metrics.update_watch_metrics(len(self._cert_watches) - polled, polled)
+
+    def _watch_remote_config(self) -> None:
+        """Wait for remote configuration changes and schedule reloads (watch thread)."""
+        assert self.remote_config is not None
+        try:
+            source = create_remote_source(self.remote_config)
+            _, version = source.fetch()
+        except Exception as e:
+            self.logger.error(f"Remote configuration watch not started: {e}")
+            return
+
+        while not self._remote_watch_stop.is_set():
+            try:
+                new_version = source.wait_for_change(version)
+            except Exception as e:
+                self.logger.error(f"Remote configuration watch failed: {e}")
+                self._remote_watch_stop.wait(30)  # Wait before retrying
+                continue
+
+            if new_version != version and not self._remote_watch_stop.is_set():
+                version = new_version
+                self.logger.info("Remote configuration changed")
+                self._schedule_coro(self._handle_config_change())
+
     async def _handle_certificate_change(self, file_path: str, event_type: str) -> None:
         """
         Handle certificate file changes with debouncing.
 
+        The change joins the pending batch, which is processed once no event arrived for
+        CERT_CHANGE_DEBOUNCE seconds (at most CERT_CHANGE_MAX_DELAY after its first event).
+
         Args:
             file_path: Path to changed certificate file
             event_type: Type of file system event
         """
         try:
-            # Cancel any existing task for this file
-            tasks_to_remove = set()
-            for task in self._cert_change_tasks:
-                if not task.done() and hasattr(task, "_file_path") and task._file_path == file_path:
-                    task.cancel()
-                    tasks_to_remove.add(task)
+            now = asyncio.get_running_loop().time()
+            self._pending_cert_changes[file_path] = event_type
+            if self._cert_batch_started is None:
+                self._cert_batch_started = now
+
+            # Restart the debounce timer (a batch being processed is not affected)
+            if self._cert_change_timer and not self._cert_change_timer.done():
+                self._cert_change_timer.cancel()
+            delay = min(
+                self.CERT_CHANGE_DEBOUNCE,
+                max(0.0, self._cert_batch_started + self.CERT_CHANGE_MAX_DELAY - now),
+            )
+            self._cert_change_timer = asyncio.create_task(self._debounced_cert_changes(delay))
 
-            self._cert_change_tasks -= tasks_to_remove
+        except Exception as e:
+            self.logger.error(f"Error handling certificate change for {file_path}: {e}")
 
-            # Create new debounced task
-            task = asyncio.create_task(self._debounced_cert_change(file_path, event_type))
-            task._file_path = file_path  # type: ignore[attr-defined] # Store file path for cancellation
-            self._cert_change_tasks.add(task)
+    async def _debounced_cert_changes(self, delay: float) -> None:
+        """
+        Wait for the debounce period, then process the pending batch of changes.
 
-            # Clean up completed tasks
-            self._cert_change_tasks = {t for t in self._cert_change_tasks if not t.done()}
+        Args:
+            delay: Seconds to wait
+        """
+        try:
+            await asyncio.sleep(delay)
+        except asyncio.CancelledError:
+            return  # Superseded by a later event
 
-        except Exception as e:
-            self.logger.error(f"Error handling certificate change for {file_path}: {e}")
+        changes, self._pending_cert_changes = self._pending_cert_changes, {}
+        self._cert_batch_started = None
+        task = asyncio.create_task(self._process_certificate_changes(changes))
+        self._cert_change_tasks.add(task)
+        task.add_done_callback(self._cert_change_tasks.discard)
 
-    async def _debounced_cert_change(self, file_path: str, event_type: str) -> None:
+    async def _process_certificate_changes(self, changes: Dict[str, str]) -> None:
         """
-        Debounced certificate change handler.
+        Process a batch of certificate file changes with one rescan of their directories.
 
         Args:
-            file_path: Path to changed certificate file
-            event_type: Type of file system event
+            changes: Latest file system event type by changed certificate file
         """
         try:
-            # Increased debounce period to catch rapid successive writes
-            await asyncio.sleep(2.0)
+            for file_path, event_type in list(changes.items()):
+                # Re-stat the path, the event may be outdated by now: a temporary file renamed
+                # away is gone, a certificate renamed back over a deleted one is there
+                if not Path(file_path).exists():
+                    event_type = "deleted"
+                elif event_type == "deleted":
+                    event_type = "created"
+                changes[file_path] = event_type
+                log_hot_reload(self.logger, file_path, event_type)
+
+            # Verify the files have stabilized (for non-delete events)
+            written = [Path(path) for path, event in changes.items() if event != "deleted"]
+            initial_mtimes = {path: path.stat().st_mtime for path in written if path.exists()}
+            if initial_mtimes:
+                await asyncio.sleep(0.5)  # Wait to ensure the files are stable
+                if any(
+                    path.exists() and path.stat().st_mtime != mtime
+                    for path, mtime in initial_mtimes.items()
+                ):
+                    # Files are still being written, wait a bit more
+                    await asyncio.sleep(1.0)
+
+            directories = self._changed_directories(changes)
+            description = (
+                f"certificate {next(iter(changes.values()))}: {next(iter(changes))}"
+                if len(changes) == 1
+                else f"{len(changes)} certificate file changes"
+            )
 
-            # Verify file still exists and has stabilized (for non-delete events)
-            file_path_obj = Path(file_path)
-            if file_path_obj.exists() and event_type != "deleted":
-                initial_mtime = file_path_obj.stat().st_mtime
-                await asyncio.sleep(0.5)  # Wait to ensure file is stable
-                if file_path_obj.exists():
-                    current_mtime = file_path_obj.stat().st_mtime
-                    if initial_mtime != current_mtime:
-                        # File is still being written, wait a bit more
-                        await asyncio.sleep(1.0)
-
-            log_hot_reload(self.logger, file_path, event_type)
-
-            # Clear entire cache for any file change to ensure consistency
-            # Individual cache invalidation is unreliable due to mtime changes
-            if hasattr(self.scanner, "cache"):
-                await self.scanner.cache.clear()
-                self.logger.info(f"Cache cleared due to certificate {event_type}: {file_path}")
-
-            # Clear all certificate metrics and trigger immediate re-scan for ALL changes
+            # The cache is kept: cache keys cover the file's mtime, ctime, size and inode, so
+            # changed files are reparsed and the other directories keep their entries
+            # Rebuild all certificate metrics: the rescan exports them again, those of the
+            # directories not rescanned from their previous results; the current ones are
+            # served until it completes
             if hasattr(self.scanner, "metrics"):
-                self.scanner.metrics.clear_all_certificate_metrics()
                 self.scanner.metrics.reset_scan_metrics()
+                self.scanner.metrics.clear_all_certificate_metrics()
                 self.scanner.metrics.reset_parse_error_metrics()
-                self.logger.info(f"Metrics cleared due to certificate {event_type}: {file_path}")
+                self.logger.info(f"Metrics cleared due to {description}")
 
-            # Trigger immediate re-scan to update all metrics
+            # Trigger immediate re-scan of the changed directories to update all metrics
             try:
-                self.logger.info(f"Triggering re-scan due to certificate {event_type}: {file_path}")
-                await self.scanner.scan_once()
+                self.logger.info(
+                    f"Triggering re-scan of {', '.join(sorted(directories)) or 'all directories'} "
+                    f"due to {description}"
+                )
+                await self.scanner.scan_once(directories=directories or None)
             except Exception as e:
-                self.logger.error(f"Failed to trigger re-scan after {event_type}: {e}")
+                self.logger.error(f"Failed to trigger re-scan after {description}: {e}")
 
         except asyncio.CancelledError:
-            self.logger.debug(f"Certificate change handling cancelled for: {file_path}")
+            self.logger.debug(f"Certificate change handling cancelled for: {', '.join(changes)}")
         except Exception as e:
-            self.logger.error(f"Error in debounced certificate change for {file_path}: {e}")
+            self.logger.error(f"Error processing certificate changes: {e}")
+
+    def _changed_directories(self, changes: Dict[str, str]) -> Set[str]:
+        """Get the configured certificate directories holding changed files."""
+        directories = set()
+        for file_path in changes:
+            path = Path(file_path)
+            # Nested directories both hold the file
+            containing = [
+                directory
+                for directory in self.config.certificate_directories
+                if path.is_relative_to(directory)
+            ]
+            directories.update(containing)
+        return directories
 
     async def _handle_config_change(self) -> None:
         """Handle configuration file changes with debouncing."""
@@ -282,9 +553,71 @@ class HotReloadManager:
             await asyncio.sleep(2.0)
 
             self.logger.info("Reloading configuration due to file change")
+            await self.reload_config()
+
+        except asyncio.CancelledError:
+            self.logger.debug("Configuration change handling cancelled")
+
+    async def reload_and_rescan(self, trigger: str = "SIGHUP", raise_errors: bool = False) -> None:
+        """
+        Force a configuration reload followed by an immediate re-scan.
+
+        Used for SIGHUP, which config-management tools send after replacing files
+        atomically via rename - a change the file watcher can miss - and POST /config/reload.
+
+        Args:
+            trigger: What requested the reload, for the log
+            raise_errors: Raise the error of a failed reload instead of re-scanning with the
+                current configuration
+        """
+        self.logger.info(f"Reloading configuration and re-scanning ({trigger})")
+
+        # A forced reload supersedes any pending debounced reload
+        if self._config_change_task and not self._config_change_task.done():
+            self._config_change_task.cancel()
+
+        try:
+            rescanned = await self.reload_config(raise_errors=True)
+        except Exception:
+            if raise_errors:
+                raise
+            rescanned = False
+        if not rescanned:
+            try:
+                await self.scanner.scan_once()
+            except Exception as e:
+                self.logger.error(f"Failed to re-scan after forced reload: {e}")
+
+    async def _replace_cache(self, config: Config) -> None:
+        """Replace the scanner's cache with one created from the new configuration."""
+        old_cache = self.scanner.cache
+        new_cache = CacheManager(config)
+        # Save the old cache first, so a new cache in the same cache_dir loads its entries
+        await old_cache.close()
+        await new_cache.initialize()
+        self.scanner.cache = new_cache
+        self.logger.info("Cache recreated due to cache setting changes")
+
+    async def reload_config(self, raise_errors: bool = False) -> bool:
+        """
+        Reload configuration and apply changes to the scanner.
 
+        Args:
+            raise_errors: Raise the error of a failed reload (invalid configuration, remote
+                store unreachable) after logging it
+
+        Returns:
+            True if applying the changes already triggered a re-scan
+        """
+        rescanned = False
+        try:
             # Load new configuration
-            new_config = load_config(str(self.config_path) if self.config_path else None)
+            new_config = load_config(
+                str(self.config_path) if self.config_path else None,
+                overrides=self.config_overrides,
+                remote_config=self.remote_config,
+                profile=self.profile,
+            )
 
             # Check if certificate directories changed
             old_dirs = set(self.config.certificate_directories)
@@ -307,12 +640,35 @@ class HotReloadManager:
             new_exclude_patterns = set(new_config.exclude_file_patterns or [])
             exclude_patterns_changed = old_exclude_patterns != new_exclude_patterns
 
-            exclude_changed = exclude_dirs_changed or exclude_patterns_changed
+            old_exclude_files = set(self.config.exclude_files)
+            new_exclude_files = set(new_config.exclude_files)
+            exclude_files_changed = old_exclude_files != new_exclude_files
+
+            # Per-directory excludes and labels (also of their groups) change which results
+            # are reported
+            directory_settings_changed = (
+                self.config.directory_settings != new_config.directory_settings
+                or self.config.groups != new_config.groups
+            )
+
+            exclude_changed = (
+                exclude_dirs_changed
+                or exclude_patterns_changed
+                or exclude_files_changed
+                or directory_settings_changed
+            )
 
             # Update configuration
             old_config = self.config
             self.config = new_config
             self.scanner.config = new_config
+            self.scanner.silences.load_config_silences(new_config.silences)
+
+            if hasattr(self.scanner, "cache") and any(
+                getattr(old_config, name) != getattr(new_config, name)
+                for name in self.CACHE_SETTINGS
+            ):
+                await self._replace_cache(new_config)
 
             # Update watched directories if needed
             if dirs_added or dirs_removed:
@@ -321,10 +677,10 @@ class HotReloadManager:
                     await self.scanner.cache.clear()
                     self.logger.info("Cache cleared due to directory changes")
 
-                # Reset all metrics when directories change
+                # Reset all metrics when directories change (served until the rescan)
                 if hasattr(self.scanner, "metrics"):
-                    self.scanner.metrics.clear_all_certificate_metrics()
                     self.scanner.metrics.reset_scan_metrics()
+                    self.scanner.metrics.clear_all_certificate_metrics()
                     self.logger.info("Metrics cleared and reset due to directory changes")
 
                 await self._update_watched_directories(dirs_added, dirs_removed)
@@ -333,6 +689,7 @@ class HotReloadManager:
                 try:
                     self.logger.info("Triggering certificate re-scan due to directory changes")
                     await self.scanner.scan_once()
+                    rescanned = True
                 except Exception as e:
                     self.logger.error(f"Failed to trigger re-scan after directory change: {e}")
 
@@ -357,6 +714,7 @@ class HotReloadManager:
                 try:
                     self.logger.info("Triggering certificate re-scan due to password changes")
                     await self.scanner.scan_once()
+                    rescanned = True
                 except Exception as e:
                     self.logger.error(f"Failed to trigger re-scan after password change: {e}")
 
@@ -368,8 +726,8 @@ class HotReloadManager:
 
                 # Clear all certificate metrics to remove excluded certificates from metrics
                 if hasattr(self.scanner, "metrics"):
-                    self.scanner.metrics.clear_all_certificate_metrics()
                     self.scanner.metrics.reset_scan_metrics()
+                    self.scanner.metrics.clear_all_certificate_metrics()
                     self.logger.info(
                         "Certificate metrics cleared and scan metrics reset due to exclude pattern changes"
                     )
@@ -380,17 +738,54 @@ class HotReloadManager:
                         "Triggering certificate re-scan due to exclude pattern changes"
                     )
                     await self.scanner.scan_once()
+                    rescanned = True
                 except Exception as e:
                     self.logger.error(
                         f"Failed to trigger re-scan after exclude pattern change: {e}"
                     )
 
+            # Re-evaluate severities (and the alerts and metrics derived from them)
+            # when the expiry thresholds changed
+            thresholds_changed = old_config.expiry_thresholds != new_config.expiry_thresholds
+            if thresholds_changed and not rescanned:
+                try:
+                    self.logger.info(
+                        "Triggering certificate re-scan due to expiry threshold changes"
+                    )
+                    await self.scanner.scan_once()
+                    rescanned = True
+                except Exception as e:
+                    self.logger.error(f"Failed to trigger re-scan after threshold change: {e}")
+
+            # Apply log level changes without a restart
+            log_level_changed = old_config.log_level != new_config.log_level
+            if log_level_changed:
+                set_log_level(new_config.log_level)
+
+            # Turn certificate file watching on or off
+            watch_files_changed = old_config.watch_files != new_config.watch_files
+            if watch_files_changed and self._watching:
+                if new_config.watch_files:
+                    self._watch_certificate_directories(new_config.certificate_directories)
+                else:
+                    self._unwatch_certificate_directories()
+                    self.logger.info(
+                        "Certificate file watching disabled - relying on periodic scans"
+                    )
+
             # Log configuration changes
             changes = []
             if dirs_added:
                 changes.append(f"Added directories: {dirs_added}")
             if dirs_removed:
                 changes.append(f"Removed directories: {dirs_removed}")
+            if thresholds_changed:
+                old_thresholds = old_config.expiry_thresholds
+                new_thresholds = new_config.expiry_thresholds
+                changes.append(
+                    f"Expiry thresholds: {old_thresholds.warning}/{old_thresholds.critical} -> "
+                    f"{new_thresholds.warning}/{new_thresholds.critical}"
+                )
             if old_config.scan_interval != new_config.scan_interval:
                 changes.append(
                     f"Scan interval: {old_config.scan_interval} -> {new_config.scan_interval}"
@@ -418,18 +813,61 @@ class HotReloadManager:
                     changes.append(f"Added exclude patterns: {exclude_patterns_added}")
                 if exclude_patterns_removed:
                     changes.append(f"Removed exclude patterns: {exclude_patterns_removed}")
+            if exclude_files_changed:
+                exclude_files_added = new_exclude_files - old_exclude_files
+                exclude_files_removed = old_exclude_files - new_exclude_files
+                if exclude_files_added:
+                    changes.append(f"Added exclude files: {exclude_files_added}")
+                if exclude_files_removed:
+                    changes.append(f"Removed exclude files: {exclude_files_removed}")
+
+            if directory_settings_changed:
+                changes.append("Per-directory settings or groups changed")
+            if log_level_changed:
+                changes.append(f"Log level: {old_config.log_level} -> {new_config.log_level}")
+            if watch_files_changed:
+                changes.append(f"Watch files: {old_config.watch_files} -> {new_config.watch_files}")
 
             if changes:
                 self.logger.info(f"Configuration updated: {'; '.join(changes)}")
             else:
                 self.logger.info("Configuration reloaded (no significant changes detected)")
 
+            self._record_reload(old_config, new_config)
+
             log_hot_reload(self.logger, str(self.config_path), "config_reloaded")
 
-        except asyncio.CancelledError:
-            self.logger.debug("Configuration change handling cancelled")
         except Exception as e:
             self.logger.error(f"Error reloading configuration: {e}")
+            if raise_errors:
+                raise
+
+        return rescanned
+
+    def _config_source(self) -> str:
+        """Describe where the configuration is loaded from."""
+        sources = [str(self.config_path) if self.config_path else "defaults"]
+        if self.remote_config:
+            # The query string may carry an ACL token
+            sources.append(self.remote_config.split("?", 1)[0])
+        return ", ".join(sources)
+
+    def _record_reload(self, old_config: Config, new_config: Config) -> None:
+        """Log the redacted settings diff of a reload and add it to the history."""
+        setting_changes = diff_configs(old_config, new_config)
+        source = self._config_source()
+        log_config_changes(self.logger, source, setting_changes)
+        self._history.append(
+            {
+                "timestamp": datetime.now(timezone.utc).isoformat(),
+                "source": source,
+                "changes": setting_changes,
+            }
+        )
+
+    def get_history(self) -> List[Dict[str, Any]]:
+        """Get the recorded configuration reloads, newest first."""
+        return list(reversed(self._history))
 
     async def _update_watched_directories(
         self, dirs_added: Set[str], dirs_removed: Set[str]
@@ -441,6 +879,9 @@ class HotReloadManager:
             dirs_added: Set of directory paths to start watching
             dirs_removed: Set of directory paths to stop watching
         """
+        if not self.config.watch_files:
+            return
+
         try:
             # Note: watchdog doesn't support removing individual watches easily,
             # so we restart the entire observer if directories changed
@@ -453,23 +894,76 @@ class HotReloadManager:
                 for cert_dir in dirs_added:
                     cert_path = Path(cert_dir)
                     if cert_path.exists() and cert_path.is_dir():
-                        self._observer.schedule(self._cert_handler, str(cert_path), recursive=True)
-                        self._watched_paths.add(str(cert_path))
-                        self.logger.info(f"Started watching new directory: {cert_path}")
+                        if self._watch_certificate_directory(cert_path):
+                            self.logger.info(f"Started watching new directory: {cert_path}")
                     else:
                         self.logger.warning(f"New certificate directory does not exist: {cert_dir}")
+                self._update_watch_metrics()
 
         except Exception as e:
             self.logger.error(f"Error updating watched directories: {e}")
 
+    async def get_health_status(self) -> dict:
+        """
+        Get hot reload health status (the hot_reload check of /healthz).
+
+        Raises:
+            HealthCheckFailure: If the file watcher died or misses configured directories,
+                so certificate changes would silently go unnoticed until the next scan
+        """
+        status = self.get_status()
+        problems = self._watcher_problems()
+        if problems:
+            raise HealthCheckFailure("; ".join(problems), data=status)
+        return status
+
+    def _watcher_problems(self) -> List[str]:
+        """Check the file watcher is alive and watches the configured directories."""
+        if not self.config.hot_reload:
+            return []
+        if not self._watching:
+            return ["File watcher is not running"]
+        if not self._observer.is_alive():
+            return ["File watcher thread died"]
+        if not self.config.watch_files:
+            return []
+        problems = []
+        if self._watch_limited:
+            polling = (
+                f", polled every {self.config.watch_poll_interval}"
+                if self._polling_observer
+                else ""
+            )
+            problems.append(
+                f"File watch limit reached (fs.inotify.max_user_watches) for: "
+                f"{', '.join(sorted(self._watch_limited))}{polling}"
+            )
+            if self._polling_observer and not self._polling_observer.is_alive():
+                problems.append("Polling file watcher thread died")
+        # Missing directories are reported by the scanner
+        unwatched = [
+            directory
+            for directory in self.config.certificate_directories
+            if str(Path(directory)) not in self._cert_watches and Path(directory).is_dir()
+        ]
+        if unwatched:
+            problems.append(f"Certificate directories not watched: {', '.join(unwatched)}")
+        return problems
+
     def get_status(self) -> dict:
         """Get hot reload status information."""
         return {
             "enabled": self.config.hot_reload,
+            "watch_files": self.config.watch_files,
             "watching": self._watching,
             "watched_paths": list(self._watched_paths),
+            "watch_limit_reached": sorted(self._watch_limited),
+            "polled_paths": sorted(
+                path for path in self._cert_watches if path in self._watch_limited
+            ),
             "config_path": str(self.config_path) if self.config_path else None,
             "active_cert_tasks": len(self._cert_change_tasks),
+            "pending_cert_changes": len(self._pending_cert_changes),
             "active_config_task": (
                 self._config_change_task is not None and not self._config_change_task.done()
             ),
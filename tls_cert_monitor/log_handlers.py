@@ -0,0 +1,515 @@
+"""
+External log sinks for TLS Certificate Monitor.
+
+- Log file: rotated, with a configurable mode and owner applied to each file it creates.
+- Syslog: the local syslog socket (/dev/log, /var/run/syslog on macOS) or a remote server over
+  UDP, TCP or TLS. Messages are formatted per RFC 5424; over TCP and TLS they are framed by
+  octet counting (RFC 6587), which rsyslog, syslog-ng and most log collectors accept.
+- journald: the native journal protocol, with the structured fields of records (cert_path,
+  scan_duration, ...) as journal fields, usable with journalctl CERT_PATH=...
+- Windows Event Log: the Application log, through advapi32 (no pywin32 needed).
+"""
+
+import ctypes
+import json
+import logging
+import logging.handlers
+import os
+import re
+import socket
+import ssl
+import struct
+import sys
+import time
+from datetime import datetime, timezone
+from typing import Any, Dict, Optional, Tuple, Union
+
+from tls_cert_monitor.config import EventLogConfig, LogFilePermissionsConfig, SyslogConfig
+
+# Local syslog sockets, tried in order
+LOCAL_SYSLOG_SOCKETS = ("/dev/log", "/var/run/syslog")
+
+# RFC 5424 NILVALUE, for fields without a value
+NILVALUE = "-"
+
+# A remote server that cannot be reached is retried after this long; records logged meanwhile
+# are dropped rather than blocking the event loop on every log call
+RECONNECT_DELAY_SECONDS = 30
+CONNECT_TIMEOUT_SECONDS = 2
+
+JOURNAL_SOCKET = "/run/systemd/journal/socket"
+
+# Attributes every LogRecord has: the others were passed as extra and become journal fields
+STANDARD_RECORD_ATTRIBUTES = frozenset(vars(logging.LogRecord("", 0, "", 0, "", None, None)))
+
+# Journal field names: uppercase letters, digits and underscores, not starting with _
+INVALID_FIELD_CHARACTERS = re.compile(r"[^A-Z0-9_]")
+
+# Event Log source registration; EventCreate.exe's message table shows the message as is
+# for event IDs 1-1000
+EVENT_LOG_SOURCE_KEY = r"SYSTEM\CurrentControlSet\Services\EventLog\Application"
+EVENT_LOG_MESSAGE_FILE = r"%SystemRoot%\System32\EventCreate.exe"
+# Event Log strings are limited to 31839 characters
+EVENT_LOG_MAX_MESSAGE = 31839
+EVENTLOG_ERROR_TYPE = 0x0001
+EVENTLOG_WARNING_TYPE = 0x0002
+EVENTLOG_INFORMATION_TYPE = 0x0004
+
+
+def resolve_account(owner: Optional[str], group: Optional[str]) -> Tuple[int, int]:
+    """
+    Get the user and group IDs of an owner and group (names or IDs), -1 for unset ones.
+
+    Raises:
+        OSError: If a user or group does not exist, or on Windows
+    """
+    if owner is None and group is None:
+        return -1, -1
+    if sys.platform == "win32":
+        raise OSError("Log file owners are not supported on Windows")
+    import grp
+    import pwd
+
+    uid = gid = -1
+    try:
+        if owner is not None:
+            uid = int(owner) if owner.isdigit() else pwd.getpwnam(owner).pw_uid
+        if group is not None:
+            gid = int(group) if group.isdigit() else grp.getgrnam(group).gr_gid
+    except KeyError as e:
+        raise OSError(f"Unknown user or group: {e.args[0]}") from e
+    return uid, gid
+
+
+def apply_permissions(path: str, mode: Optional[int], uid: int, gid: int) -> None:
+    """
+    Set the mode and owner of a file or directory (unset ones are left alone).
+
+    Raises:
+        OSError: If they cannot be changed (changing the owner needs root)
+    """
+    if uid != -1 or gid != -1:
+        os.chown(path, uid, gid)
+    if mode is not None and sys.platform != "win32":
+        os.chmod(path, mode)
+
+
+class PermissionedFileHandler(logging.handlers.RotatingFileHandler):
+    """
+    Rotating log file handler applying a mode and owner to each file it opens.
+
+    Failing to apply them does not stop logging: the first error is kept in permission_error.
+    """
+
+    def __init__(
+        self, filename: str, mode: Optional[int], uid: int, gid: int, **kwargs: Any
+    ) -> None:
+        self.file_mode = mode
+        self.uid = uid
+        self.gid = gid
+        self.permission_error: Optional[OSError] = None
+        super().__init__(filename, **kwargs)
+
+    def _open(self) -> Any:
+        stream = super()._open()
+        try:
+            apply_permissions(self.baseFilename, self.file_mode, self.uid, self.gid)
+        except OSError as e:
+            # Logging the error from here would recurse into this handler
+            self.permission_error = self.permission_error or e
+        return stream
+
+
+def create_log_file_handler(
+    path: str, permissions: LogFilePermissionsConfig
+) -> PermissionedFileHandler:
+    """
+    Create the log file handler (10MB files, 5 backups), creating its directory if needed.
+
+    Args:
+        path: Log file path
+        permissions: Mode and owner of the file and its directory
+
+    Returns:
+        Log handler; permission_error reports mode or owner settings that could not be applied
+
+    Raises:
+        OSError: If the directory or file cannot be created
+    """
+    directory = os.path.dirname(os.path.abspath(path))
+    os.makedirs(directory, exist_ok=True)
+
+    permission_error = None
+    try:
+        uid, gid = resolve_account(permissions.owner, permissions.group)
+    except OSError as e:
+        uid = gid = -1
+        permission_error = e
+    try:
+        apply_permissions(directory, permissions.directory_mode, uid, gid)
+    except OSError as e:
+        permission_error = permission_error or e
+
+    handler = PermissionedFileHandler(
+        path,
+        permissions.mode,
+        uid,
+        gid,
+        maxBytes=10 * 1024 * 1024,
+        backupCount=5,
+        encoding="utf-8",
+    )
+    handler.permission_error = permission_error or handler.permission_error
+    return handler
+
+
+class RFC5424Formatter(logging.Formatter):
+    """Format records as RFC 5424 syslog messages, without the <PRI> the handler prepends."""
+
+    def __init__(self, app_name: str) -> None:
+        super().__init__()
+        self.app_name = app_name
+        self.hostname = socket.gethostname() or NILVALUE
+
+    def format(self, record: logging.LogRecord) -> str:
+        """Format a record as VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG."""
+        timestamp = (
+            datetime.fromtimestamp(record.created, timezone.utc)
+            .isoformat(timespec="milliseconds")
+            .replace("+00:00", "Z")
+        )
+        message = record.getMessage()
+        request_id = getattr(record, "request_id", None)
+        if request_id:
+            message = f"[{request_id}] {message}"
+        if record.exc_info:
+            message += "\n" + self.formatException(record.exc_info)
+        # MSGID is the logger name, at most 32 characters
+        msgid = record.name[:32] or NILVALUE
+        procid = record.process or NILVALUE
+        return (
+            f"1 {timestamp} {self.hostname} {self.app_name} {procid} {msgid} {NILVALUE} "
+            f"{message}"
+        )
+
+
+def syslog_priority(facility: int, levelname: str) -> int:
+    """Get the syslog PRI value of a record level."""
+    handler = logging.handlers.SysLogHandler
+    severity = handler.priority_names[handler.priority_map.get(levelname, "warning")]
+    return (facility << 3) | severity
+
+
+class StreamSyslogHandler(logging.Handler):
+    """Send syslog messages to a remote server over TCP or TLS, reconnecting when needed."""
+
+    def __init__(
+        self,
+        address: Tuple[str, int],
+        facility: int,
+        ssl_context: Optional[ssl.SSLContext] = None,
+    ) -> None:
+        super().__init__()
+        self.address = address
+        self.facility = facility
+        self.ssl_context = ssl_context
+        self._socket: Optional[socket.socket] = None
+        self._retry_at = 0.0
+
+    def _connect(self) -> socket.socket:
+        """Connect to the server, wrapping the connection in TLS if configured."""
+        sock = socket.create_connection(self.address, timeout=CONNECT_TIMEOUT_SECONDS)
+        if self.ssl_context:
+            sock = self.ssl_context.wrap_socket(sock, server_hostname=self.address[0])
+        return sock
+
+    def _close_socket(self) -> None:
+        if self._socket is not None:
+            try:
+                self._socket.close()
+            except OSError:
+                pass
+            self._socket = None
+
+    def emit(self, record: logging.LogRecord) -> None:
+        """Send a record, octet-counting framed (handle() holds the handler lock)."""
+        if self._socket is None and time.monotonic() < self._retry_at:
+            return
+        try:
+            message = f"<{syslog_priority(self.facility, record.levelname)}>{self.format(record)}"
+            encoded = message.encode("utf-8")
+            frame = f"{len(encoded)} ".encode("ascii") + encoded
+            # A connection the server closed only fails on the next send: retry once
+            for attempt in range(2):
+                if self._socket is None:
+                    self._socket = self._connect()
+                try:
+                    self._socket.sendall(frame)
+                    return
+                except OSError:
+                    self._close_socket()
+                    if attempt:
+                        raise
+        except Exception:
+            self._close_socket()
+            self._retry_at = time.monotonic() + RECONNECT_DELAY_SECONDS
+            self.handleError(record)
+
+    def close(self) -> None:
+        self.acquire()
+        try:
+            self._close_socket()
+        finally:
+            self.release()
+        super().close()
+
+
+def local_syslog_address() -> Union[str, Tuple[str, int]]:
+    """Get the local syslog socket, or the local UDP port if there is none (e.g. Windows)."""
+    for path in LOCAL_SYSLOG_SOCKETS:
+        if os.path.exists(path):
+            return path
+    return ("localhost", logging.handlers.SYSLOG_UDP_PORT)
+
+
+def create_syslog_handler(config: SyslogConfig) -> logging.Handler:
+    """
+    Create the syslog handler for the configured destination.
+
+    Args:
+        config: Syslog settings
+
+    Returns:
+        Log handler formatting records per RFC 5424
+
+    Raises:
+        OSError: If the local syslog socket cannot be opened
+        ssl.SSLError: If the CA bundle cannot be loaded
+    """
+    facility = logging.handlers.SysLogHandler.facility_names[config.facility]
+    handler: logging.Handler
+    if config.address and config.protocol in ("tcp", "tls"):
+        ssl_context = None
+        if config.protocol == "tls":
+            ssl_context = ssl.create_default_context(cafile=config.ca_file)
+        handler = StreamSyslogHandler(config.server_address(), facility, ssl_context)
+    else:
+        address = config.server_address() if config.address else local_syslog_address()
+        syslog_handler = logging.handlers.SysLogHandler(address=address, facility=facility)
+        # RFC 5424 messages are not NUL-terminated
+        syslog_handler.append_nul = False
+        handler = syslog_handler
+    handler.setFormatter(RFC5424Formatter(config.app_name))
+    return handler
+
+
+def journal_field_name(name: str) -> str:
+    """Get the journal field name of a record attribute (cert_path -> CERT_PATH)."""
+    field = INVALID_FIELD_CHARACTERS.sub("_", name.upper()).lstrip("_")
+    return field[:64] or "FIELD"
+
+
+def _journal_value(value: Any) -> str:
+    """Serialize a field value (mappings and lists as JSON)."""
+    if isinstance(value, (dict, list, tuple)):
+        return json.dumps(value, default=str, ensure_ascii=False)
+    return str(value)
+
+
+class JournaldHandler(logging.Handler):
+    """Send records to systemd-journald with the native protocol."""
+
+    def __init__(self, identifier: str, socket_path: str = JOURNAL_SOCKET) -> None:
+        super().__init__()
+        self.identifier = identifier
+        self.socket_path = socket_path
+        self._socket = socket.socket(socket.AF_UNIX, socket.SOCK_DGRAM)
+
+    def record_fields(self, record: logging.LogRecord) -> Dict[str, str]:
+        """Get the journal fields of a record."""
+        message = record.getMessage()
+        if record.exc_info:
+            formatter = self.formatter or logging.Formatter()
+            message += "\n" + formatter.formatException(record.exc_info)
+        fields = {
+            "MESSAGE": message,
+            "PRIORITY": str(syslog_priority(0, record.levelname)),
+            "SYSLOG_IDENTIFIER": self.identifier,
+            "LOGGER": record.name,
+            "CODE_FILE": record.pathname,
+            "CODE_LINE": str(record.lineno),
+            "CODE_FUNC": record.funcName or "",
+            "THREAD_NAME": record.threadName or "",
+        }
+        for name, value in vars(record).items():
+            if name not in STANDARD_RECORD_ATTRIBUTES and value is not None:
+                fields.setdefault(journal_field_name(name), _journal_value(value))
+        return fields
+
+    def emit(self, record: logging.LogRecord) -> None:
+        """Send a record as one datagram of journal fields."""
+        try:
+            datagram = b""
+            for name, value in self.record_fields(record).items():
+                encoded = value.encode("utf-8")
+                if b"\n" in encoded:
+                    # Multi-line values are sent length-prefixed
+                    datagram += name.encode() + b"\n" + struct.pack("<Q", len(encoded))
+                    datagram += encoded + b"\n"
+                else:
+                    datagram += name.encode() + b"=" + encoded + b"\n"
+            self._socket.sendto(datagram, self.socket_path)
+        except Exception:
+            self.handleError(record)
+
+    def close(self) -> None:
+        self._socket.close()
+        super().close()
+
+
+def journald_available() -> bool:
+    """Check if the journal socket exists (systemd is running)."""
+    return os.path.exists(JOURNAL_SOCKET)
+
+
+def create_journald_handler(identifier: str = "tls-cert-monitor") -> JournaldHandler:
+    """
+    Create the journald handler.
+
+    Args:
+        identifier: SYSLOG_IDENTIFIER of the records (journalctl -t)
+
+    Returns:
+        Log handler sending records to the journal
+
+    Raises:
+        OSError: If there is no journal socket
+    """
+    if not journald_available():
+        raise OSError(f"Journal socket not found: {JOURNAL_SOCKET}")
+    return JournaldHandler(identifier)
+
+
+def stderr_is_journal() -> bool:
+    """
+    Check if stdout or stderr is connected to the journal, i.e. the monitor runs as a systemd
+    service with StandardOutput=journal (systemd sets JOURNAL_STREAM to its device:inode).
+    """
+    journal_stream = os.getenv("JOURNAL_STREAM")
+    if not journal_stream:
+        return False
+    for stream in (sys.stderr, sys.stdout):
+        try:
+            stat = os.fstat(stream.fileno())
+        except (AttributeError, OSError, ValueError):
+            continue
+        if journal_stream == f"{stat.st_dev}:{stat.st_ino}":
+            return True
+    return False
+
+
+def event_log_type(levelno: int) -> Tuple[int, int]:
+    """Get the (event type, event ID) of a record level."""
+    if levelno >= logging.ERROR:
+        return EVENTLOG_ERROR_TYPE, 3
+    if levelno >= logging.WARNING:
+        return EVENTLOG_WARNING_TYPE, 2
+    return EVENTLOG_INFORMATION_TYPE, 1
+
+
+def register_event_source(source: str) -> None:
+    """
+    Register the event source with EventCreate.exe as message file, so Event Viewer shows the
+    messages. Needs administrator rights (the service runs as LocalSystem); without them,
+    messages are logged but shown with a "description cannot be found" note.
+    """
+    if sys.platform != "win32":
+        return
+    import winreg
+
+    try:
+        with winreg.CreateKeyEx(
+            winreg.HKEY_LOCAL_MACHINE, f"{EVENT_LOG_SOURCE_KEY}\\{source}", 0, winreg.KEY_WRITE
+        ) as key:
+            winreg.SetValueEx(
+                key, "EventMessageFile", 0, winreg.REG_EXPAND_SZ, EVENT_LOG_MESSAGE_FILE
+            )
+            winreg.SetValueEx(
+                key,
+                "TypesSupported",
+                0,
+                winreg.REG_DWORD,
+                EVENTLOG_ERROR_TYPE | EVENTLOG_WARNING_TYPE | EVENTLOG_INFORMATION_TYPE,
+            )
+    except OSError:
+        pass
+
+
+class EventLogHandler(logging.Handler):
+    """Report records to the Windows Application event log."""
+
+    def __init__(self, source: str, min_level: int = logging.WARNING) -> None:
+        super().__init__()
+        self.source = source
+        # Kept apart from the handler level, which set_log_level changes
+        self.min_level = min_level
+        self._advapi32 = ctypes.WinDLL("advapi32", use_last_error=True)  # type: ignore
+        self._advapi32.RegisterEventSourceW.restype = ctypes.c_void_p
+        self._advapi32.RegisterEventSourceW.argtypes = [ctypes.c_wchar_p, ctypes.c_wchar_p]
+        self._advapi32.ReportEventW.argtypes = [
+            ctypes.c_void_p,
+            ctypes.c_ushort,
+            ctypes.c_ushort,
+            ctypes.c_ulong,
+            ctypes.c_void_p,
+            ctypes.c_ushort,
+            ctypes.c_ulong,
+            ctypes.POINTER(ctypes.c_wchar_p),
+            ctypes.c_void_p,
+        ]
+        self._advapi32.DeregisterEventSource.argtypes = [ctypes.c_void_p]
+        self._handle = self._advapi32.RegisterEventSourceW(None, source)
+        if not self._handle:
+            raise ctypes.WinError(ctypes.get_last_error())  # type: ignore
+
+    def emit(self, record: logging.LogRecord) -> None:
+        """Report a record as an event of its level's type."""
+        if record.levelno < self.min_level:
+            return
+        try:
+            event_type, event_id = event_log_type(record.levelno)
+            message = self.format(record)[:EVENT_LOG_MAX_MESSAGE]
+            strings = (ctypes.c_wchar_p * 1)(message)
+            if not self._advapi32.ReportEventW(
+                self._handle, event_type, 0, event_id, None, 1, 0, strings, None
+            ):
+                raise ctypes.WinError(ctypes.get_last_error())  # type: ignore
+        except Exception:
+            self.handleError(record)
+
+    def close(self) -> None:
+        if self._handle:
+            self._advapi32.DeregisterEventSource(self._handle)
+            self._handle = None
+        super().close()
+
+
+def create_event_log_handler(config: EventLogConfig) -> EventLogHandler:
+    """
+    Create the Windows Event Log handler.
+
+    Args:
+        config: Event Log settings
+
+    Returns:
+        Log handler reporting records of config.level and above
+
+    Raises:
+        OSError: If not running on Windows, or the event source cannot be opened
+    """
+    if sys.platform != "win32":
+        raise OSError("The Windows Event Log is only available on Windows")
+    register_event_source(config.source)
+    handler = EventLogHandler(config.source, getattr(logging, config.level))
+    handler.setFormatter(logging.Formatter("%(name)s: %(message)s"))
+    return handler
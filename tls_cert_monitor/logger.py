@@ -2,13 +2,27 @@
 Standardized logging configuration for TLS Certificate Monitor.
 """
 
+import json
 import logging
-import logging.handlers
+import ssl
 import sys
-from pathlib import Path
-from typing import Optional
+import threading
+import time
+from contextvars import ContextVar
+from typing import Dict, Optional, TextIO, Tuple
 
-from tls_cert_monitor.config import Config
+from tls_cert_monitor.config import Config, LogSamplingConfig
+from tls_cert_monitor.log_handlers import (
+    create_event_log_handler,
+    create_journald_handler,
+    create_log_file_handler,
+    create_syslog_handler,
+    journald_available,
+    stderr_is_journal,
+)
+
+# ID of the API request being handled, set by the API's request ID middleware
+request_id_var: ContextVar[Optional[str]] = ContextVar("request_id", default=None)
 
 
 class CustomFormatter(logging.Formatter):
@@ -43,6 +57,9 @@ class CustomFormatter(logging.Formatter):
 
         # Format message
         message = record.getMessage()
+        request_id = getattr(record, "request_id", None)
+        if request_id:
+            message = f"[{request_id}] {message}"
 
         # Add exception info if present
         if record.exc_info:
@@ -81,6 +98,13 @@ class StructuredFormatter(logging.Formatter):
             log_data["scan_duration"] = record.scan_duration
         if hasattr(record, "error_type"):
             log_data["error_type"] = record.error_type
+        if hasattr(record, "config_changes"):
+            log_data["config_changes"] = record.config_changes
+        if hasattr(record, "scan_summary"):
+            log_data["event"] = record.event
+            log_data["scan_summary"] = record.scan_summary
+        if hasattr(record, "request_id"):
+            log_data["request_id"] = record.request_id
 
         # Add exception info
         if record.exc_info:
@@ -89,13 +113,77 @@ class StructuredFormatter(logging.Formatter):
         return json.dumps(log_data, ensure_ascii=False)
 
 
-def setup_logging(config: Config) -> None:
+class RequestIdFilter(logging.Filter):
+    """Add the ID of the API request being handled, if any, to records (request_id)."""
+
+    def filter(self, record: logging.LogRecord) -> bool:
+        """Set the request_id of a record logged while handling a request."""
+        if not hasattr(record, "request_id"):
+            request_id = request_id_var.get()
+            if request_id is not None:
+                record.request_id = request_id
+        return True
+
+
+class SamplingFilter(logging.Filter):
+    """
+    Sample repetitive DEBUG and INFO records, per log statement and second.
+
+    Messages are mostly f-strings (one per file), so records are counted by the statement
+    logging them: each second, the first `initial` records of a statement are kept, then every
+    `thereafter`th. WARNING and above are always kept.
+    """
+
+    TICK_SECONDS = 1.0
+
+    def __init__(self, config: LogSamplingConfig) -> None:
+        super().__init__()
+        self.initial = config.initial
+        self.thereafter = config.thereafter
+        self._counts: Dict[Tuple[str, str, int, int], int] = {}
+        self._tick = 0.0
+        self._lock = threading.Lock()
+        # The filter is shared by all handlers: a record is counted once
+        self._last_record: Optional[logging.LogRecord] = None
+        self._last_decision = True
+
+    def filter(self, record: logging.LogRecord) -> bool:
+        if record.levelno >= logging.WARNING:
+            return True
+        with self._lock:
+            if record is self._last_record:
+                return self._last_decision
+            now = time.monotonic()
+            if now - self._tick >= self.TICK_SECONDS:
+                self._tick = now
+                self._counts.clear()
+            key = (record.name, record.pathname, record.lineno, record.levelno)
+            count = self._counts.get(key, 0) + 1
+            self._counts[key] = count
+            keep = count <= self.initial or (
+                self.thereafter > 0 and (count - self.initial) % self.thereafter == 0
+            )
+            self._last_record = record
+            self._last_decision = keep
+            return keep
+
+
+def setup_logging(config: Config, stream: Optional[TextIO] = None) -> None:
     """
     Setup logging configuration.
 
     Args:
         config: Configuration object
+        stream: Console log stream (stdout by default)
     """
+    console_stream = stream or sys.stdout
+
+    # Under systemd, journald replaces the console: with structured fields, and no
+    # console lines stored a second time by journald
+    use_journald = config.log_journald
+    if use_journald is None:
+        use_journald = stream is None and stderr_is_journal() and journald_available()
+
     # Get root logger
     root_logger = logging.getLogger()
     root_logger.setLevel(getattr(logging, config.log_level))
@@ -103,25 +191,34 @@ def setup_logging(config: Config) -> None:
     # Clear existing handlers
     root_logger.handlers.clear()
 
+    # Journald handler if enabled, falling back to the console
+    journald_error = None
+    journald_handler: Optional[logging.Handler] = None
+    if use_journald:
+        try:
+            journald_handler = create_journald_handler()
+            journald_handler.setLevel(getattr(logging, config.log_level))
+            root_logger.addHandler(journald_handler)
+        except OSError as e:
+            journald_error = e
+
     # Console handler
-    console_handler = logging.StreamHandler(sys.stdout)
-    console_handler.setLevel(getattr(logging, config.log_level))
+    if journald_handler is None:
+        console_handler = logging.StreamHandler(console_stream)
+        console_handler.setLevel(getattr(logging, config.log_level))
 
-    # Use colored formatter for console if output is a TTY
-    use_color = hasattr(sys.stdout, "isatty") and sys.stdout.isatty()
-    console_formatter = CustomFormatter(use_color=use_color)
-    console_handler.setFormatter(console_formatter)
-    root_logger.addHandler(console_handler)
+        # Use colored formatter for console if output is a TTY
+        use_color = hasattr(console_stream, "isatty") and console_stream.isatty()
+        console_formatter = CustomFormatter(use_color=use_color)
+        console_handler.setFormatter(console_formatter)
+        root_logger.addHandler(console_handler)
 
     # File handler if log file is specified
+    permission_error = None
     if config.log_file:
-        log_path = Path(config.log_file)
-        log_path.parent.mkdir(parents=True, exist_ok=True)
-
-        # Rotating file handler (10MB max, 5 backups)
-        file_handler = logging.handlers.RotatingFileHandler(
-            config.log_file, maxBytes=10 * 1024 * 1024, backupCount=5, encoding="utf-8"  # 10MB
-        )
+        # Rotating file handler (10MB max, 5 backups), with the configured mode and owner
+        file_handler = create_log_file_handler(config.log_file, config.log_file_permissions)
+        permission_error = file_handler.permission_error
         file_handler.setLevel(getattr(logging, config.log_level))
 
         # Use structured formatter for file logging
@@ -129,6 +226,35 @@ def setup_logging(config: Config) -> None:
         file_handler.setFormatter(file_formatter)
         root_logger.addHandler(file_handler)
 
+    # Syslog handler if enabled; the monitor keeps running without it
+    syslog_error = None
+    if config.syslog.enabled:
+        try:
+            syslog_handler = create_syslog_handler(config.syslog)
+            syslog_handler.setLevel(getattr(logging, config.log_level))
+            root_logger.addHandler(syslog_handler)
+        except (OSError, ssl.SSLError) as e:
+            syslog_error = e
+
+    # Windows Event Log handler if enabled, with its own minimum level
+    event_log_error = None
+    if config.event_log.enabled:
+        try:
+            root_logger.addHandler(create_event_log_handler(config.event_log))
+        except OSError as e:
+            event_log_error = e
+
+    # Tag records with the API request being handled, on every handler
+    request_id_filter = RequestIdFilter()
+    for handler in root_logger.handlers:
+        handler.addFilter(request_id_filter)
+
+    # Sample repetitive records on every handler
+    if config.log_sampling.enabled:
+        sampling_filter = SamplingFilter(config.log_sampling)
+        for handler in root_logger.handlers:
+            handler.addFilter(sampling_filter)
+
     # Set specific logger levels
     logging.getLogger("uvicorn").setLevel(logging.WARNING)
     logging.getLogger("watchdog").setLevel(logging.WARNING)
@@ -139,6 +265,62 @@ def setup_logging(config: Config) -> None:
 
     if config.log_file:
         app_logger.info(f"Log file: {config.log_file}")
+    if permission_error:
+        app_logger.error(f"Failed to set log file permissions: {permission_error}")
+    if config.log_sampling.enabled:
+        sampling = config.log_sampling
+        thereafter = f"every {sampling.thereafter}th" if sampling.thereafter else "none"
+        app_logger.info(
+            f"Log sampling: first {sampling.initial} DEBUG/INFO records per statement "
+            f"and second, then {thereafter}"
+        )
+    if journald_error:
+        app_logger.error(f"Failed to set up journald logging: {journald_error}")
+    elif journald_handler is not None:
+        app_logger.info("Logging to journald")
+    if event_log_error:
+        app_logger.error(f"Failed to set up Windows Event Log logging: {event_log_error}")
+    elif config.event_log.enabled:
+        event_log = config.event_log
+        app_logger.info(
+            f"Windows Event Log: source {event_log.source}, {event_log.level} and above"
+        )
+    if syslog_error:
+        app_logger.error(f"Failed to set up syslog logging: {syslog_error}")
+    elif config.syslog.enabled:
+        syslog = config.syslog
+        destination = f"{syslog.address} ({syslog.protocol})" if syslog.address else "local socket"
+        app_logger.info(f"Syslog: {destination}")
+
+
+def get_log_level() -> str:
+    """Get the current log level name."""
+    return logging.getLevelName(logging.getLogger().level)
+
+
+def set_log_level(level: str) -> str:
+    """
+    Change the log level at runtime.
+
+    Args:
+        level: New level name (DEBUG, INFO, WARNING, ERROR, CRITICAL)
+
+    Returns:
+        Previous level name
+    """
+    root_logger = logging.getLogger()
+    previous = logging.getLevelName(root_logger.level)
+    new_level = getattr(logging, level.upper())
+
+    root_logger.setLevel(new_level)
+    for handler in root_logger.handlers:
+        handler.setLevel(new_level)
+
+    # Log at a level that is still shown after lowering verbosity
+    logging.getLogger("tls_cert_monitor").log(
+        max(new_level, logging.INFO), f"Log level changed: {previous} -> {level.upper()}"
+    )
+    return previous
 
 
 def get_logger(name: str) -> logging.Logger:
@@ -201,6 +383,14 @@ def log_cert_scan_complete(
     )
 
 
+def log_scan_summary(logger: logging.Logger, summary: dict) -> None:
+    """Log the summary record of a scan: one per scan, as JSON in the message and the field."""
+    logger.info(
+        f"Scan summary: {json.dumps(summary, sort_keys=True)}",
+        extra={"event": "scan_summary", "scan_summary": summary},
+    )
+
+
 def log_cache_operation(
     logger: logging.Logger, operation: str, key: str, hit: Optional[bool] = None
 ) -> None:
@@ -227,6 +417,19 @@ def log_hot_reload(logger: logging.Logger, file_path: str, event_type: str) -> N
     )
 
 
+def log_config_changes(logger: logging.Logger, source: str, changes: list) -> None:
+    """Log the settings changed by a configuration reload."""
+    for change in changes:
+        logger.info(
+            f"Config change: {change['setting']}: {change['old']} -> {change['new']}",
+            extra={"config_source": source, "config_setting": change["setting"]},
+        )
+    logger.debug(
+        f"Configuration reload changed {len(changes)} setting(s)",
+        extra={"config_source": source, "config_changes": changes},
+    )
+
+
 def log_metrics_collection(
     logger: logging.Logger, metric_name: str, value: float, labels: Optional[dict] = None
 ) -> None:
@@ -2,10 +2,11 @@
 Prometheus metrics collection for TLS Certificate Monitor.
 """
 
+import re
 import socket
 import time
 from collections import defaultdict
-from typing import Any, Dict, List, Type, Union
+from typing import Any, Dict, Iterator, List, Optional, Tuple, Type, Union
 
 import psutil
 from prometheus_client import (
@@ -17,8 +18,30 @@ from prometheus_client import (
     generate_latest,
 )
 
+from tls_cert_monitor.config import ExpiryThresholds
+from tls_cert_monitor.health import HEALTH_STATUSES
 from tls_cert_monitor.logger import get_logger, log_metrics_collection
 
+# Expiry severities, ordered from least to most severe
+SEVERITY_LEVELS = ("ok", "warning", "critical", "expired")
+
+
+def certificate_label_name(name: str) -> str:
+    """Get the ssl_cert_labels label of a directory label (label_ and a valid name)."""
+    return "label_" + re.sub(r"[^a-zA-Z0-9_]", "_", name)
+
+
+class _PublishedMetrics:
+    """Registry collector serving the certificate metrics of the last completed scan."""
+
+    def __init__(self, metrics: Dict[str, Union[Gauge, Info]]) -> None:
+        self.metrics = metrics
+
+    def collect(self) -> Iterator[Any]:
+        # Replaced as a whole by publish_scan_metrics(), never while iterated
+        for metric in list(self.metrics.values()):
+            yield from metric.collect()
+
 
 class MetricsCollector:
     """Prometheus metrics collector for TLS certificates and application metrics."""
@@ -27,104 +50,109 @@ class MetricsCollector:
         self.logger = get_logger("metrics")
         self.registry = CollectorRegistry()
 
-        # Certificate metrics
-        self.ssl_cert_expiration_timestamp = Gauge(
-            "ssl_cert_expiration_timestamp",
-            "Certificate expiration time (Unix timestamp)",
-            ["common_name", "issuer", "path", "serial"],
+        # Certificate metrics, built by each scan off to the side and swapped in when it
+        # completes (reset_scan_metrics, publish_scan_metrics), so that /metrics scraped during
+        # a scan serves the previous scan instead of empty or partial certificate data
+        self._certificate_label_names: List[str] = []
+        self._cert_severities: Dict[Tuple[str, str], str] = {}  # (common_name, path) -> severity
+        self._scan_metrics: Dict[str, Union[Gauge, Info]] = {}
+        self._published = _PublishedMetrics(self._scan_metrics)
+        self.registry.register(self._published)
+        self._create_scan_metrics()
+
+        self.ssl_cert_expiry_threshold_seconds = Gauge(
+            "ssl_cert_expiry_threshold_seconds",
+            "Configured expiry threshold (time remaining before a severity applies)",
+            ["severity"],
             registry=self.registry,
         )
 
-        self.ssl_cert_san_count = Gauge(
-            "ssl_cert_san_count",
-            "Number of Subject Alternative Names",
-            ["common_name", "path"],
+        # Operational metrics
+        self.ssl_cert_files_total = Gauge(
+            "ssl_cert_files_total",
+            "Total certificate files processed",
+            ["directory"],
             registry=self.registry,
         )
 
-        self.ssl_cert_info = Info(
-            "ssl_cert_info",
-            "Certificate information with labels",
-            ["path", "common_name", "issuer", "serial", "subject"],
+        self.ssl_cert_scan_duration_seconds = Histogram(
+            "ssl_cert_scan_duration_seconds",
+            "Directory scan duration",
+            ["directory"],
             registry=self.registry,
         )
 
-        self.ssl_cert_duplicate_count = Gauge(
-            "ssl_cert_duplicate_count", "Number of duplicate certificates", registry=self.registry
-        )
-
-        # Fixed: Simplified duplicate names metric to avoid label conflicts
-        self.ssl_cert_duplicate_names = Info(
-            "ssl_cert_duplicate_names",
-            "Names of certificates that are duplicates",
-            ["serial_number"],  # Changed from 'serial' and removed 'paths'
+        self.ssl_cert_last_scan_timestamp = Gauge(
+            "ssl_cert_last_scan_timestamp",
+            "Last successful scan time",
+            ["directory"],
             registry=self.registry,
         )
 
-        self.ssl_cert_issuer_code = Gauge(
-            "ssl_cert_issuer_code",
-            "Numeric issuer classification",
-            ["common_name", "issuer", "path"],
+        self.ssl_cert_watched_directories = Gauge(
+            "ssl_cert_watched_directories",
+            "Certificate directories watched for file changes (native, or polling once the "
+            "watch limit is reached)",
+            ["mode"],
             registry=self.registry,
         )
 
-        # Cryptographic security metrics
-        self.ssl_cert_weak_key_total = Gauge(
-            "ssl_cert_weak_key_total",
-            "Current count of certificates with weak cryptographic keys",
+        self.ssl_cert_scan_state_stale = Gauge(
+            "ssl_cert_scan_state_stale",
+            "Whether the metrics are the last scan before a restart, until a scan completes "
+            "(1=stale)",
             registry=self.registry,
         )
 
-        self.ssl_cert_deprecated_sigalg_total = Gauge(
-            "ssl_cert_deprecated_sigalg_total",
-            "Current count of certificates using deprecated signature algorithms",
+        # Application metrics
+        self.app_memory_bytes = Gauge(
+            "app_memory_bytes",
+            "Application memory usage in bytes",
+            ["type"],  # rss, vms, shared, etc.
             registry=self.registry,
         )
 
-        # Operational metrics
-        self.ssl_cert_files_total = Gauge(
-            "ssl_cert_files_total",
-            "Total certificate files processed",
-            ["directory"],
-            registry=self.registry,
+        self.app_cache_entries = Gauge(
+            "app_cache_entries", "Number of entries in the cache", registry=self.registry
         )
 
-        self.ssl_certs_parsed_total = Gauge(
-            "ssl_certs_parsed_total", "Successfully parsed certificates", registry=self.registry
+        self.app_cache_size_bytes = Gauge(
+            "app_cache_size_bytes", "Approximate memory used by the cache", registry=self.registry
         )
 
-        self.ssl_cert_parse_errors_total = Gauge(
-            "ssl_cert_parse_errors_total",
-            "Current count of certificate parsing errors",
+        self.app_cache_source_entries = Gauge(
+            "app_cache_source_entries",
+            "Number of cache entries by source type",
+            ["source"],
             registry=self.registry,
         )
 
-        self.ssl_cert_parse_error_names = Info(
-            "ssl_cert_parse_error_names",
-            "Names of certificates that have parsing errors",
-            ["filename", "error_type", "error_message"],
+        self.app_cache_source_size_bytes = Gauge(
+            "app_cache_source_size_bytes",
+            "Approximate memory used by the cache entries of a source type",
+            ["source"],
             registry=self.registry,
         )
 
-        self.ssl_cert_scan_duration_seconds = Histogram(
-            "ssl_cert_scan_duration_seconds",
-            "Directory scan duration",
-            ["directory"],
+        self.ssl_cert_monitor_health_check = Gauge(
+            "ssl_cert_monitor_health_check",
+            "Status of a /healthz check (0=healthy, 1=degraded, 2=unhealthy)",
+            ["name"],
             registry=self.registry,
         )
 
-        self.ssl_cert_last_scan_timestamp = Gauge(
-            "ssl_cert_last_scan_timestamp",
-            "Last successful scan time",
-            ["directory"],
+        self.app_cache_evictions_total = Gauge(
+            "app_cache_evictions_total",
+            "Cache entries evicted since startup",
+            # size: over cache_max_size, count: over cache_max_entries,
+            # memory: process over cache_memory_limit
+            ["reason"],
             registry=self.registry,
         )
 
-        # Application metrics
-        self.app_memory_bytes = Gauge(
-            "app_memory_bytes",
-            "Application memory usage in bytes",
-            ["type"],  # rss, vms, shared, etc.
+        self.app_cache_oversized_skips_total = Gauge(
+            "app_cache_oversized_skips_total",
+            "Values not cached because they exceed cache_max_entry_size",
             registry=self.registry,
         )
 
@@ -195,6 +223,34 @@ class MetricsCollector:
                 issuer_code
             )
 
+            # Expiry severity (set by the scanner from the configured thresholds)
+            if "severity" in cert_data:
+                self._update_severity(common_name, path, cert_data["severity"])
+
+            # Validity start (set by the scanner with the clock skew grace applied)
+            if "not_yet_valid" in cert_data:
+                self.ssl_cert_not_yet_valid.labels(common_name=common_name, path=path).set(
+                    1 if cert_data["not_yet_valid"] else 0
+                )
+
+            # Silence state (set by the scanner from active silences)
+            if "silenced" in cert_data:
+                self.ssl_cert_silenced.labels(common_name=common_name, path=path).set(
+                    1 if cert_data["silenced"] else 0
+                )
+
+            # Directory and group labels
+            labels = cert_data.get("labels") or {}
+            if labels and self._certificate_label_names:
+                self.ssl_cert_labels.labels(
+                    common_name=common_name,
+                    path=path,
+                    **{
+                        certificate_label_name(name): labels.get(name, "")
+                        for name in self._certificate_label_names
+                    },
+                ).set(1)
+
             # Track duplicates by serial number
             if serial != "unknown":
                 self._duplicate_certificates[serial].append(path)
@@ -217,6 +273,21 @@ class MetricsCollector:
         except Exception as e:
             self.logger.error(f"Failed to update certificate metrics: {e}")
 
+    def _update_severity(self, common_name: str, path: str, severity: str) -> None:
+        """Set the severity series for a certificate, dropping its previous severity."""
+        key = (common_name, path)
+        previous = self._cert_severities.get(key)
+        if previous and previous != severity:
+            try:
+                self.ssl_cert_expiry_severity.remove(common_name, path, previous)
+            except KeyError:
+                pass
+
+        self.ssl_cert_expiry_severity.labels(
+            common_name=common_name, path=path, severity=severity
+        ).set(1)
+        self._cert_severities[key] = severity
+
     def update_scan_metrics(
         self,
         directory: str,
@@ -261,6 +332,50 @@ class MetricsCollector:
         except Exception as e:
             self.logger.error(f"Failed to update scan metrics: {e}")
 
+    def update_restored_scan_metrics(
+        self, directory_results: Dict[str, Dict[str, Any]], timestamp: float
+    ) -> None:
+        """
+        Set the scan metrics from the last scan before a restart, flagged stale.
+
+        The certificates must have been exported with update_certificate_metrics() first.
+
+        Args:
+            directory_results: Results by directory of the restored scan
+            timestamp: Time of the restored scan (for directories without their own)
+        """
+        for directory, result in directory_results.items():
+            self.ssl_cert_files_total.labels(directory=directory).set(
+                int(result.get("files_processed", 0))
+            )
+            self.ssl_cert_last_scan_timestamp.labels(directory=directory).set(
+                int(result.get("scanned_at", timestamp))
+            )
+        self.ssl_certs_parsed_total.set(
+            sum(result.get("certificates_parsed", 0) for result in directory_results.values())
+        )
+        self.ssl_cert_parse_errors_total.set(
+            sum(result.get("parse_errors", 0) for result in directory_results.values())
+        )
+        self.ssl_cert_weak_key_total.set(self._current_scan_weak_keys)
+        self.ssl_cert_deprecated_sigalg_total.set(self._current_scan_deprecated_sigalgs)
+        self.ssl_cert_scan_state_stale.set(1)
+
+    def update_watch_metrics(self, native: int, polling: int) -> None:
+        """
+        Update the number of watched certificate directories.
+
+        Args:
+            native: Directories watched natively (inotify, FSEvents, ...)
+            polling: Directories polled because the native watch limit is reached
+        """
+        self.ssl_cert_watched_directories.labels(mode="native").set(native)
+        self.ssl_cert_watched_directories.labels(mode="polling").set(polling)
+
+    def clear_scan_state_stale(self) -> None:
+        """Flag the metrics as those of a scan since startup."""
+        self.ssl_cert_scan_state_stale.set(0)
+
     def record_parse_error(self, filename: str, error_type: str, error_message: str) -> None:
         """
         Record a certificate parsing error.
@@ -367,21 +482,170 @@ class MetricsCollector:
         except Exception as e:
             self.logger.error(f"Failed to update system metrics: {e}")
 
+    def update_cache_metrics(self, cache_stats: Dict[str, Any]) -> None:
+        """
+        Update cache metrics.
+
+        Args:
+            cache_stats: Cache statistics (see CacheManager.get_stats)
+        """
+        self.app_cache_entries.set(cache_stats["entries_total"])
+        self.app_cache_size_bytes.set(cache_stats["current_size_bytes"])
+        self.app_cache_oversized_skips_total.set(cache_stats["oversized_skips"])
+        # Drop sources that no longer have entries
+        self.app_cache_source_entries.clear()
+        self.app_cache_source_size_bytes.clear()
+        for source, source_stats in cache_stats.get("sources", {}).items():
+            self.app_cache_source_entries.labels(source=source).set(source_stats["entries"])
+            self.app_cache_source_size_bytes.labels(source=source).set(
+                source_stats["size_bytes"]
+            )
+        for reason in ("size", "count", "memory"):
+            self.app_cache_evictions_total.labels(reason=reason).set(
+                cache_stats[f"evictions_{reason}"]
+            )
+
+    def update_health_metrics(self, check_statuses: Dict[str, str]) -> None:
+        """
+        Update the health check metrics.
+
+        Args:
+            check_statuses: Health status by check name (see health.check_statuses)
+        """
+        # Drop checks that are no longer run (directories removed, checks unregistered)
+        self.ssl_cert_monitor_health_check.clear()
+        for name, status in check_statuses.items():
+            self.ssl_cert_monitor_health_check.labels(name=name).set(
+                HEALTH_STATUSES.index(status)
+            )
+
+    def update_expiry_metrics(
+        self, severity_counts: Dict[str, int], thresholds: ExpiryThresholds
+    ) -> None:
+        """
+        Update expiry bucket counts and the thresholds that define them.
+
+        Args:
+            severity_counts: Number of certificates per severity
+            thresholds: Configured warning/critical thresholds
+        """
+        for severity in SEVERITY_LEVELS:
+            self.ssl_certs_by_severity.labels(severity=severity).set(
+                severity_counts.get(severity, 0)
+            )
+        self.ssl_cert_expiry_threshold_seconds.labels(severity="warning").set(
+            thresholds.warning_seconds
+        )
+        self.ssl_cert_expiry_threshold_seconds.labels(severity="critical").set(
+            thresholds.critical_seconds
+        )
+
+    def set_certificate_label_names(self, names: List[str]) -> None:
+        """
+        Set the directory labels exported by ssl_cert_labels, recreating it if they changed.
+
+        Args:
+            names: Names of the labels of the certificate directories
+        """
+        if names == self._certificate_label_names:
+            return
+        self._certificate_label_names = list(names)
+        self._recreate_certificate_labels()
+
+    def _recreate_certificate_labels(self) -> None:
+        """Recreate ssl_cert_labels with the current label names."""
+        self._recreate_metric(
+            "ssl_cert_labels",
+            Gauge,
+            "ssl_cert_labels",
+            "Labels of the certificate's directory and group (always 1)",
+            ["common_name", "path"]
+            + sorted({certificate_label_name(name) for name in self._certificate_label_names}),
+        )
+
     def reset_scan_metrics(self) -> None:
-        """Reset scan-specific metrics for a new scan. Resets current counts but preserves historical data."""
+        """
+        Start building the certificate metrics of a new scan, empty.
+
+        The metrics of the previous scan are served until publish_scan_metrics(); scan
+        durations, per-directory and application metrics are not reset.
+        """
         self._duplicate_certificates.clear()
         self._current_scan_parse_errors = 0
         self._current_scan_weak_keys = 0
         self._current_scan_deprecated_sigalgs = 0
 
-        # Immediately reset the gauge metrics to zero for instant feedback
-        self.ssl_certs_parsed_total.set(0)
-        self.ssl_cert_parse_errors_total.set(0)
-        self.ssl_cert_weak_key_total.set(0)
-        self.ssl_cert_deprecated_sigalg_total.set(0)
-        self.ssl_cert_duplicate_count.set(0)
+        self._scan_metrics = {}
+        self._create_scan_metrics()
+
+        self.logger.debug("Scan metrics reset (built off to the side until published)")
+
+    def publish_scan_metrics(self) -> None:
+        """Serve the certificate metrics built since reset_scan_metrics(), all at once."""
+        self.update_duplicate_metrics()
+        self._published.metrics = self._scan_metrics
+        self.logger.debug("Scan metrics published")
+
+    def _create_scan_metrics(self) -> None:
+        """Create the metrics built by a scan, in the set being built."""
+        self.clear_all_certificate_metrics()
+
+        self._recreate_metric(
+            "ssl_cert_duplicate_count",
+            Gauge,
+            "ssl_cert_duplicate_count",
+            "Number of duplicate certificates",
+            [],
+        )
+
+        self._recreate_metric(
+            "ssl_certs_by_severity",
+            Gauge,
+            "ssl_certs_by_severity",
+            "Number of certificates in each expiry severity bucket",
+            ["severity"],
+        )
+
+        # Cryptographic security metrics
+        self._recreate_metric(
+            "ssl_cert_weak_key_total",
+            Gauge,
+            "ssl_cert_weak_key_total",
+            "Current count of certificates with weak cryptographic keys",
+            [],
+        )
 
-        self.logger.debug("Scan metrics reset (current counts cleared and gauges zeroed)")
+        self._recreate_metric(
+            "ssl_cert_deprecated_sigalg_total",
+            Gauge,
+            "ssl_cert_deprecated_sigalg_total",
+            "Current count of certificates using deprecated signature algorithms",
+            [],
+        )
+
+        self._recreate_metric(
+            "ssl_certs_parsed_total",
+            Gauge,
+            "ssl_certs_parsed_total",
+            "Successfully parsed certificates",
+            [],
+        )
+
+        self._recreate_metric(
+            "ssl_cert_parse_errors_total",
+            Gauge,
+            "ssl_cert_parse_errors_total",
+            "Current count of certificate parsing errors",
+            [],
+        )
+
+        self._recreate_metric(
+            "ssl_cert_parse_error_names",
+            Info,
+            "ssl_cert_parse_error_names",
+            "Names of certificates that have parsing errors",
+            ["filename", "error_type", "error_message"],
+        )
 
     def _recreate_metric(
         self,
@@ -392,7 +656,10 @@ class MetricsCollector:
         labels: List[str],
     ) -> None:
         """
-        Helper method to recreate a single metric.
+        Helper method to recreate a single metric built by scans.
+
+        The metric is replaced in the set being built: between scans that is the set served,
+        during a scan the one published when it completes.
 
         Args:
             metric_attr: Attribute name to store the metric
@@ -402,23 +669,21 @@ class MetricsCollector:
             labels: List of label names
         """
         try:
-            # Unregister existing metric
-            current_metric = getattr(self, metric_attr, None)
-            if current_metric:
-                try:
-                    self.registry.unregister(current_metric)
-                except KeyError:
-                    pass
-
-            # Create new metric
-            new_metric = metric_class(name, description, labels, registry=self.registry)
+            # Served through _PublishedMetrics, not registered on its own
+            new_metric = metric_class(name, description, labels, registry=None)
+            self._scan_metrics[metric_attr] = new_metric
             setattr(self, metric_attr, new_metric)
 
         except Exception as e:
             self.logger.error(f"Failed to recreate metric {metric_attr}: {e}")
 
     def clear_all_certificate_metrics(self) -> None:
-        """Clear all labeled certificate metrics. Used when exclude patterns change."""
+        """
+        Clear all labeled certificate metrics. Used when exclude patterns change.
+
+        During a scan (after reset_scan_metrics()) the metrics served are only replaced when
+        it publishes its own.
+        """
         try:
             # Recreate all labeled certificate metrics using helper method
             self._recreate_metric(
@@ -461,6 +726,33 @@ class MetricsCollector:
                 ["common_name", "issuer", "path"],
             )
 
+            self._recreate_metric(
+                "ssl_cert_expiry_severity",
+                Gauge,
+                "ssl_cert_expiry_severity",
+                "Current expiry severity of the certificate (ok, warning, critical, expired)",
+                ["common_name", "path", "severity"],
+            )
+            self._cert_severities.clear()
+
+            self._recreate_metric(
+                "ssl_cert_not_yet_valid",
+                Gauge,
+                "ssl_cert_not_yet_valid",
+                "Whether the certificate is not yet valid beyond the clock skew grace",
+                ["common_name", "path"],
+            )
+
+            self._recreate_metric(
+                "ssl_cert_silenced",
+                Gauge,
+                "ssl_cert_silenced",
+                "Whether an active silence matches the certificate (1=silenced)",
+                ["common_name", "path"],
+            )
+
+            self._recreate_certificate_labels()
+
             self.logger.debug("All labeled certificate metrics cleared and recreated")
 
         except Exception as e:
@@ -541,7 +833,17 @@ class MetricsCollector:
                         "ssl_cert_duplicate_count",
                         "app_memory_bytes",
                         "app_thread_count",
+                        "app_cache_",
                         "ssl_cert_issuer_code",
+                        "ssl_cert_silenced",
+                        "ssl_cert_labels",
+                        "ssl_cert_not_yet_valid",
+                        "ssl_cert_expiry_severity",
+                        "ssl_certs_by_severity",
+                        "ssl_cert_expiry_threshold_seconds",
+                        "ssl_cert_monitor_health_check",
+                        "ssl_cert_scan_state_stale",
+                        "ssl_cert_watched_directories",
                     ]
                 ):
                     try:
@@ -651,6 +953,59 @@ def is_weak_key(key_size: int, algorithm: str) -> bool:
     return key_size < 2048
 
 
+def get_expiry_severity(
+    expiration_timestamp: float,
+    thresholds: ExpiryThresholds,
+    now: Optional[float] = None,
+    margin: int = 0,
+) -> str:
+    """
+    Classify a certificate by time remaining until expiration.
+
+    Args:
+        expiration_timestamp: Certificate NotAfter as Unix timestamp
+        thresholds: Configured warning/critical thresholds
+        now: Current Unix timestamp (defaults to time.time())
+        margin: Safety margin in seconds, subtracted from the time remaining
+
+    Returns:
+        One of SEVERITY_LEVELS
+    """
+    remaining = expiration_timestamp - (now if now is not None else time.time()) - margin
+
+    if remaining <= 0:
+        return "expired"
+    if remaining <= thresholds.critical_seconds:
+        return "critical"
+    if remaining <= thresholds.warning_seconds:
+        return "warning"
+    return "ok"
+
+
+def count_certificates_by_severity(
+    directory_results: Dict[str, Any], include_silenced: bool = True
+) -> Dict[str, int]:
+    """
+    Count scanned certificates in each expiry severity bucket.
+
+    Args:
+        directory_results: Per-directory scan results
+        include_silenced: Whether to count certificates matched by an active silence
+
+    Returns:
+        Mapping of every severity in SEVERITY_LEVELS to a certificate count
+    """
+    counts = {severity: 0 for severity in SEVERITY_LEVELS}
+    for result in directory_results.values():
+        for cert in result.get("certificates", []):
+            if not include_silenced and cert.get("silenced"):
+                continue
+            severity = cert.get("severity")
+            if severity in counts:
+                counts[severity] += 1
+    return counts
+
+
 def is_deprecated_signature_algorithm(algorithm: str) -> bool:
     """
     Check if a signature algorithm is deprecated.
@@ -0,0 +1,589 @@
+"""
+Notification delivery for TLS Certificate Monitor.
+"""
+
+import asyncio
+import base64
+import hashlib
+import json
+import re
+import smtplib
+import socket
+import time
+import urllib.parse
+import urllib.request
+from dataclasses import dataclass, field, replace
+from datetime import datetime, timezone
+from email.message import EmailMessage
+from pathlib import Path
+from string import Template
+from typing import Any, Dict, List, Optional, Type
+
+from tls_cert_monitor.config import NotificationTemplateConfig, NotifierConfig
+from tls_cert_monitor.logger import get_logger
+
+
+@dataclass
+class Notification:
+    """A message to deliver through one or more notifiers."""
+
+    title: str
+    body: str
+    severity: str = "info"
+    # "firing" when a condition appears, "resolved" when it clears
+    status: str = "firing"
+    # Identifying labels (rule, path, common_name, ...) for routing and grouping
+    labels: Dict[str, str] = field(default_factory=dict)
+    # Structured payload for machine-readable targets (webhooks)
+    data: Dict[str, Any] = field(default_factory=dict)
+    # When the condition was first raised, and (firing alerts re-sent on every evaluation)
+    # when it should be considered cleared unless sent again
+    starts_at: float = field(default_factory=time.time)
+    ends_at: Optional[float] = None
+
+
+def _truncate(text: str, limit: int) -> str:
+    """Truncate text to a message size limit."""
+    return text if len(text) <= limit else text[: limit - 3] + "..."
+
+
+def _flatten(data: Dict[str, Any], prefix: str = "") -> Dict[str, str]:
+    """Flatten nested data into template placeholders (old.serial -> old_serial)."""
+    flat: Dict[str, str] = {}
+    for key, value in data.items():
+        name = f"{prefix}{key}"
+        if isinstance(value, dict):
+            flat.update(_flatten(value, f"{name}_"))
+        elif isinstance(value, (list, tuple)):
+            if all(not isinstance(item, (dict, list)) for item in value):
+                flat[name] = ", ".join(str(item) for item in value)
+        elif value is not None:
+            flat[name] = str(value)
+    return flat
+
+
+def template_context(notification: Notification, notifier_name: str = "") -> Dict[str, str]:
+    """
+    Build the placeholders available to notification templates.
+
+    Certificate fields and labels come first so the notification fields
+    (title, body, severity, status) and host always keep their meaning.
+
+    Args:
+        notification: Notification being rendered
+        notifier_name: Name of the notifier rendering it
+
+    Returns:
+        Mapping of placeholder name to string value
+    """
+    context = _flatten(notification.data)
+    context.update({key: str(value) for key, value in notification.labels.items()})
+    context.update(
+        {
+            "title": notification.title,
+            "body": notification.body,
+            "severity": notification.severity,
+            "status": notification.status,
+            "rule": notification.labels.get("rule", ""),
+            "host": socket.gethostname(),
+            "notifier": notifier_name,
+        }
+    )
+    return context
+
+
+class NotificationTemplate:
+    """Title/body overrides rendered with string.Template ($name or ${name})."""
+
+    def __init__(self, config: NotificationTemplateConfig):
+        self.title = self._load(config.title, config.title_file)
+        self.body = self._load(config.body, config.body_file)
+
+    @staticmethod
+    def _load(inline: Optional[str], path: Optional[str]) -> Optional[Template]:
+        if path:
+            return Template(Path(path).read_text(encoding="utf-8"))
+        return Template(inline) if inline is not None else None
+
+    def render(self, notification: Notification, notifier_name: str = "") -> Notification:
+        """
+        Render the templates for a notification.
+
+        Unknown placeholders are left as-is rather than failing delivery.
+
+        Returns:
+            Copy of the notification with title/body replaced
+        """
+        context = template_context(notification, notifier_name)
+        title = self.title.safe_substitute(context).strip() if self.title else notification.title
+        body = self.body.safe_substitute(context) if self.body else notification.body
+        return replace(notification, title=title, body=body)
+
+
+class Notifier:
+    """Base class for notification targets."""
+
+    def __init__(self, config: NotifierConfig):
+        self.config = config
+        self.name = config.name
+        self.logger = get_logger(f"notifiers.{config.name}")
+        self.template = NotificationTemplate(config.template) if config.template else None
+
+    async def send(self, notification: Notification) -> None:
+        """
+        Deliver a notification.
+
+        Templates are applied first; blocking network I/O runs in the default executor.
+
+        Raises:
+            Exception: If delivery fails
+        """
+        if self.template:
+            notification = self.template.render(notification, self.name)
+
+        loop = asyncio.get_running_loop()
+        await loop.run_in_executor(None, self._send_sync, notification)
+
+    def _send_sync(self, notification: Notification) -> None:
+        raise NotImplementedError
+
+    def _request_json(
+        self,
+        method: str,
+        url: str,
+        payload: Any = None,
+        headers: Optional[Dict[str, str]] = None,
+    ) -> Any:
+        """
+        Send an HTTP request with an optional JSON body.
+
+        Returns:
+            Decoded JSON response, or None for an empty response
+
+        Raises:
+            urllib.error.URLError: On connection errors and HTTP error statuses
+        """
+        request_headers = {
+            "Content-Type": "application/json",
+            "Accept": "application/json",
+            **self.config.headers,
+            **(headers or {}),
+        }
+        data = None
+        if payload is not None:
+            data = json.dumps(payload, ensure_ascii=False).encode("utf-8")
+
+        request = urllib.request.Request(url, data=data, headers=request_headers, method=method)
+
+        # URL comes from operator configuration
+        with urllib.request.urlopen(request, timeout=self.config.timeout) as response:  # nosec B310
+            body = response.read()
+        return json.loads(body) if body.strip() else None
+
+    def _post_json(
+        self, url: str, payload: Any, headers: Optional[Dict[str, str]] = None
+    ) -> None:
+        """POST a JSON payload, raising on connection errors and HTTP error statuses."""
+        self._request_json("POST", url, payload, headers)
+
+    def _basic_auth_header(self) -> Dict[str, str]:
+        """Authorization header from username/password, if configured."""
+        if not self.config.username:
+            return {}
+        credentials = f"{self.config.username}:{self.config.password or ''}".encode("utf-8")
+        return {"Authorization": f"Basic {base64.b64encode(credentials).decode('ascii')}"}
+
+
+class EmailNotifier(Notifier):
+    """Send notifications as plain text email over SMTP."""
+
+    def _send_sync(self, notification: Notification) -> None:
+        message = EmailMessage()
+        message["Subject"] = notification.title
+        message["From"] = self.config.from_address
+        message["To"] = ", ".join(self.config.to_addresses)
+        message.set_content(notification.body)
+
+        with smtplib.SMTP(
+            self.config.smtp_host or "", self.config.smtp_port, timeout=self.config.timeout
+        ) as smtp:
+            if self.config.smtp_starttls:
+                smtp.starttls()
+            if self.config.smtp_username:
+                smtp.login(self.config.smtp_username, self.config.smtp_password or "")
+            smtp.send_message(message)
+
+
+class WebhookNotifier(Notifier):
+    """POST notifications as JSON to a webhook URL."""
+
+    def _send_sync(self, notification: Notification) -> None:
+        payload = {
+            "title": notification.title,
+            "text": notification.body,
+            "severity": notification.severity,
+            "status": notification.status,
+            "labels": notification.labels,
+            "data": notification.data,
+        }
+        self._post_json(self.config.url or "", payload)
+
+
+def _isoformat(timestamp: float) -> str:
+    return datetime.fromtimestamp(timestamp, timezone.utc).isoformat()
+
+
+class AlertmanagerNotifier(Notifier):
+    """
+    Push notifications as alerts to the Alertmanager v2 API.
+
+    Alertmanager resolves an alert once its endsAt passes, so the alert engine pushes
+    firing alerts again after every evaluation to keep them open.
+    """
+
+    def _send_sync(self, notification: Notification) -> None:
+        rule = notification.labels.get("rule", "")
+        alertname = "TLSCert" + "".join(part.capitalize() for part in rule.split("_"))
+
+        labels = {
+            "alertname": alertname if rule else "TLSCertMonitor",
+            "severity": notification.severity,
+            **{key: str(value) for key, value in notification.labels.items() if value},
+        }
+        alert = {
+            "labels": labels,
+            "annotations": {"summary": notification.title, "description": notification.body},
+            "startsAt": _isoformat(notification.starts_at),
+        }
+        if notification.status == "resolved":
+            alert["endsAt"] = _isoformat(time.time())
+        elif notification.ends_at is not None:
+            alert["endsAt"] = _isoformat(notification.ends_at)
+        if self.config.generator_url:
+            alert["generatorURL"] = self.config.generator_url
+
+        self._post_json(f"{(self.config.url or '').rstrip('/')}/api/v2/alerts", [alert])
+
+
+class SNSNotifier(Notifier):
+    """Publish notifications to an AWS SNS topic (requires boto3)."""
+
+    # SNS subject limit
+    MAX_SUBJECT_LENGTH = 100
+
+    def __init__(self, config: NotifierConfig):
+        super().__init__(config)
+        self._client: Any = None
+
+    def _get_client(self) -> Any:
+        """Create the SNS client on first use, assuming role_arn if configured."""
+        if self._client is not None:
+            return self._client
+
+        try:
+            import boto3
+        except ImportError as e:
+            raise RuntimeError("SNS notifier requires boto3 (pip install boto3)") from e
+
+        session = boto3.Session(
+            profile_name=self.config.aws_profile, region_name=self.config.region
+        )
+
+        if self.config.role_arn:
+            credentials = session.client("sts").assume_role(
+                RoleArn=self.config.role_arn, RoleSessionName="tls-cert-monitor"
+            )["Credentials"]
+            session = boto3.Session(
+                aws_access_key_id=credentials["AccessKeyId"],
+                aws_secret_access_key=credentials["SecretAccessKey"],
+                aws_session_token=credentials["SessionToken"],
+                region_name=self.config.region,
+            )
+
+        self._client = session.client("sns")
+        return self._client
+
+    def _send_sync(self, notification: Notification) -> None:
+        subject = _truncate(notification.title, self.MAX_SUBJECT_LENGTH)
+
+        attributes = {
+            "severity": {"DataType": "String", "StringValue": notification.severity},
+        }
+        if notification.labels.get("rule"):
+            attributes["rule"] = {"DataType": "String", "StringValue": notification.labels["rule"]}
+
+        self._get_client().publish(
+            TopicArn=self.config.topic_arn,
+            Subject=subject,
+            Message=notification.body,
+            MessageAttributes=attributes,
+        )
+
+
+class TelegramNotifier(Notifier):
+    """Send notifications to a Telegram chat through a bot."""
+
+    DEFAULT_API_URL = "https://api.telegram.org"
+    MAX_MESSAGE_LENGTH = 4096
+
+    def _send_sync(self, notification: Notification) -> None:
+        api_url = (self.config.url or self.DEFAULT_API_URL).rstrip("/")
+        self._post_json(
+            f"{api_url}/bot{self.config.bot_token}/sendMessage",
+            {
+                "chat_id": self.config.chat_id,
+                "text": _truncate(
+                    f"{notification.title}\n\n{notification.body}", self.MAX_MESSAGE_LENGTH
+                ),
+                "disable_web_page_preview": True,
+            },
+        )
+
+
+class DiscordNotifier(Notifier):
+    """Send notifications to a Discord channel through a bot or a channel webhook."""
+
+    DEFAULT_API_URL = "https://discord.com/api/v10"
+    MAX_MESSAGE_LENGTH = 2000
+
+    def _send_sync(self, notification: Notification) -> None:
+        payload = {
+            "content": _truncate(
+                f"**{notification.title}**\n{notification.body}", self.MAX_MESSAGE_LENGTH
+            ),
+            # Never ping @everyone or roles from certificate data
+            "allowed_mentions": {"parse": []},
+        }
+
+        if self.config.bot_token and self.config.channel_id:
+            api_url = (self.config.url or self.DEFAULT_API_URL).rstrip("/")
+            self._post_json(
+                f"{api_url}/channels/{self.config.channel_id}/messages",
+                payload,
+                headers={"Authorization": f"Bot {self.config.bot_token}"},
+            )
+        else:
+            self._post_json(self.config.url or "", payload)
+
+
+class TicketNotifier(Notifier):
+    """
+    Base class for notifiers that keep one ticket per certificate.
+
+    A ticket is opened when a certificate enters the critical expiry window,
+    deduplicated by certificate fingerprint, and commented and closed when
+    the alert resolves (usually after renewal). Other alerts, and expiry
+    alerts of the warning window, are ignored.
+    """
+
+    TICKET_RULES = {"expiry"}
+    TICKET_SEVERITIES = {"critical"}
+    MAX_SUMMARY_LENGTH = 255
+
+    def _send_sync(self, notification: Notification) -> None:
+        fingerprint = notification.labels.get("fingerprint")
+        if notification.labels.get("rule") not in self.TICKET_RULES or not fingerprint:
+            self.logger.debug(f"Skipping notification without ticket rule: {notification.title}")
+            return
+        firing = notification.status != "resolved"
+        if firing and notification.severity not in self.TICKET_SEVERITIES:
+            self.logger.debug(f"Skipping {notification.severity} alert: {notification.title}")
+            return
+
+        dedup_key = self._dedup_key(fingerprint)
+        ticket = self._find_open_ticket(dedup_key)
+
+        if notification.status == "resolved":
+            if ticket is None:
+                self.logger.debug(f"No open ticket to close for {dedup_key}")
+                return
+            self._close_ticket(ticket, notification)
+            self.logger.info(f"Closed ticket {ticket} for {dedup_key}")
+        elif ticket is None:
+            ticket = self._create_ticket(dedup_key, notification)
+            self.logger.info(f"Opened ticket {ticket} for {dedup_key}")
+        else:
+            self.logger.debug(f"Ticket {ticket} already open for {dedup_key}")
+
+    @staticmethod
+    def _dedup_key(fingerprint: str) -> str:
+        """Ticket deduplication key (safe for Jira labels and ServiceNow correlation IDs)."""
+        if not re.fullmatch(r"[0-9a-f]{64}", fingerprint):
+            fingerprint = hashlib.sha256(fingerprint.encode("utf-8")).hexdigest()
+        return f"tls-cert-{fingerprint[:32]}"
+
+    def _find_open_ticket(self, dedup_key: str) -> Optional[str]:
+        raise NotImplementedError
+
+    def _create_ticket(self, dedup_key: str, notification: Notification) -> str:
+        raise NotImplementedError
+
+    def _close_ticket(self, ticket: str, notification: Notification) -> None:
+        raise NotImplementedError
+
+
+class JiraNotifier(TicketNotifier):
+    """Open and close Jira issues through the REST API v2."""
+
+    def _api(self, path: str) -> str:
+        return f"{(self.config.url or '').rstrip('/')}/rest/api/2/{path}"
+
+    def _find_open_ticket(self, dedup_key: str) -> Optional[str]:
+        jql = (
+            f'project = "{self.config.project}" AND labels = "{dedup_key}" '
+            "AND statusCategory != Done"
+        )
+        query = urllib.parse.urlencode({"jql": jql, "fields": "key", "maxResults": 1})
+        result = self._request_json(
+            "GET", self._api(f"search?{query}"), headers=self._basic_auth_header()
+        )
+        issues = (result or {}).get("issues", [])
+        return issues[0]["key"] if issues else None
+
+    def _create_ticket(self, dedup_key: str, notification: Notification) -> str:
+        result = self._request_json(
+            "POST",
+            self._api("issue"),
+            {
+                "fields": {
+                    "project": {"key": self.config.project},
+                    "issuetype": {"name": self.config.issue_type},
+                    "summary": _truncate(notification.title, self.MAX_SUMMARY_LENGTH),
+                    "description": notification.body,
+                    "labels": ["tls-cert-monitor", dedup_key],
+                }
+            },
+            headers=self._basic_auth_header(),
+        )
+        return str((result or {}).get("key", "unknown"))
+
+    def _close_ticket(self, ticket: str, notification: Notification) -> None:
+        headers = self._basic_auth_header()
+        self._request_json(
+            "POST", self._api(f"issue/{ticket}/comment"), {"body": notification.body}, headers
+        )
+
+        transitions = self._request_json(
+            "GET", self._api(f"issue/{ticket}/transitions"), headers=headers
+        )
+        for transition in (transitions or {}).get("transitions", []):
+            if transition.get("name", "").lower() == self.config.close_transition.lower():
+                self._request_json(
+                    "POST",
+                    self._api(f"issue/{ticket}/transitions"),
+                    {"transition": {"id": transition["id"]}},
+                    headers,
+                )
+                return
+
+        self.logger.warning(
+            f"Jira issue {ticket} has no '{self.config.close_transition}' transition; "
+            "left open with a comment"
+        )
+
+
+class ServiceNowNotifier(TicketNotifier):
+    """Open and resolve ServiceNow records through the Table API."""
+
+    def _api(self, suffix: str = "") -> str:
+        return f"{(self.config.url or '').rstrip('/')}/api/now/table/{self.config.table}{suffix}"
+
+    def _find_open_ticket(self, dedup_key: str) -> Optional[str]:
+        query = urllib.parse.urlencode(
+            {
+                "sysparm_query": f"correlation_id={dedup_key}^active=true",
+                "sysparm_fields": "sys_id",
+                "sysparm_limit": 1,
+            }
+        )
+        result = self._request_json(
+            "GET", self._api(f"?{query}"), headers=self._basic_auth_header()
+        )
+        records = (result or {}).get("result", [])
+        return records[0]["sys_id"] if records else None
+
+    def _create_ticket(self, dedup_key: str, notification: Notification) -> str:
+        record = {
+            "short_description": _truncate(notification.title, self.MAX_SUMMARY_LENGTH),
+            "description": notification.body,
+            "correlation_id": dedup_key,
+            "correlation_display": "tls-cert-monitor",
+        }
+        if self.config.queue:
+            record["assignment_group"] = self.config.queue
+
+        result = self._request_json("POST", self._api(), record, self._basic_auth_header())
+        return str((result or {}).get("result", {}).get("number", "unknown"))
+
+    def _close_ticket(self, ticket: str, notification: Notification) -> None:
+        self._request_json(
+            "PATCH",
+            self._api(f"/{ticket}"),
+            {
+                "work_notes": notification.body,
+                "state": self.config.close_state,
+                "close_code": "Solved (Permanently)",
+                "close_notes": notification.body,
+            },
+            self._basic_auth_header(),
+        )
+
+
+NOTIFIER_TYPES: Dict[str, Type[Notifier]] = {
+    "email": EmailNotifier,
+    "webhook": WebhookNotifier,
+    "alertmanager": AlertmanagerNotifier,
+    "sns": SNSNotifier,
+    "telegram": TelegramNotifier,
+    "discord": DiscordNotifier,
+    "jira": JiraNotifier,
+    "servicenow": ServiceNowNotifier,
+}
+
+
+def create_notifiers(configs: List[NotifierConfig]) -> Dict[str, Notifier]:
+    """
+    Create notifiers from configuration.
+
+    Args:
+        configs: Notifier configurations
+
+    Returns:
+        Mapping of notifier name to notifier
+    """
+    return {config.name: NOTIFIER_TYPES[config.type](config) for config in configs}
+
+
+async def dispatch(
+    notifiers: List[Notifier], notification: Notification, dry_run: bool = False
+) -> int:
+    """
+    Send a notification to several notifiers, logging failures.
+
+    Args:
+        notifiers: Target notifiers
+        notification: Notification to send
+        dry_run: Log the notification instead of sending it
+
+    Returns:
+        Number of notifiers that delivered the notification
+    """
+    logger = get_logger("notifiers")
+    delivered = 0
+
+    for notifier in notifiers:
+        if dry_run:
+            logger.info(
+                f"Notification to '{notifier.name}' not sent - dry run mode enabled: "
+                f"{notification.title}"
+            )
+            continue
+
+        try:
+            await notifier.send(notification)
+            delivered += 1
+            logger.info(f"Notification sent to '{notifier.name}': {notification.title}")
+        except Exception as e:
+            logger.error(f"Failed to send notification to '{notifier.name}': {e}")
+
+    return delivered
@@ -0,0 +1,138 @@
+"""
+Machine-readable output of the scan and check commands for TLS Certificate Monitor.
+
+JSON output is the scan results as returned by the API's /scan endpoint. Table and CSV output
+flatten the certificates of the results into one inventory row each; CycloneDX output is the
+certificates as a CycloneDX BOM (see cyclonedx.py).
+"""
+
+import csv
+import io
+import json
+from typing import Any, Dict, List
+
+from tls_cert_monitor.check import certificate_findings
+from tls_cert_monitor.cyclonedx import build_bom
+from tls_cert_monitor.metrics import SEVERITY_LEVELS
+
+# Output formats of the scan and check commands (text: the human-readable summary)
+OUTPUT_FORMATS = ("text", "json", "table", "csv", "cyclonedx")
+
+# Columns of an inventory row, as written to CSV
+INVENTORY_COLUMNS = (
+    "path",
+    "common_name",
+    "severity",
+    "days_until_expiry",
+    "not_before",
+    "not_after",
+    "issuer",
+    "subject",
+    "serial",
+    "fingerprint_sha256",
+    "key_algorithm",
+    "key_size",
+    "signature_algorithm",
+    "san_list",
+    "silenced",
+    "findings",
+)
+
+# Columns of the table, narrow enough for a terminal or a ticket
+TABLE_COLUMNS = ("severity", "days_until_expiry", "not_after", "common_name", "path", "findings")
+
+# Table headers of columns whose name is too long
+TABLE_HEADERS = {"days_until_expiry": "DAYS"}
+
+
+def certificates_of(results: Dict[str, Any]) -> List[Dict[str, Any]]:
+    """Get the certificates of scan results, worst severity first, then by path."""
+    certificates = [
+        cert
+        for result in results.get("directories", {}).values()
+        for cert in result.get("certificates", [])
+    ]
+    return sorted(
+        certificates,
+        key=lambda cert: (-SEVERITY_LEVELS.index(cert.get("severity", "ok")), cert.get("path", "")),
+    )
+
+
+def inventory_row(cert: Dict[str, Any]) -> Dict[str, str]:
+    """
+    Flatten certificate data into an inventory row.
+
+    Args:
+        cert: Certificate data as in scan results
+
+    Returns:
+        Dict of INVENTORY_COLUMNS to text values
+    """
+    row = {column: cert.get(column) for column in INVENTORY_COLUMNS}
+    row["severity"] = cert.get("severity", "ok")
+    row["san_list"] = " ".join(cert.get("san_list", []))
+    row["silenced"] = "yes" if cert.get("silenced") else "no"
+    row["findings"] = "; ".join(description for _, description in certificate_findings(cert))
+    return {column: "" if value is None else str(value) for column, value in row.items()}
+
+
+def format_json(data: Dict[str, Any]) -> str:
+    """Format scan results or certificate data as indented JSON."""
+    return json.dumps(data, indent=2, default=str)
+
+
+def format_table(certificates: List[Dict[str, Any]]) -> str:
+    """
+    Format certificates as an aligned table of TABLE_COLUMNS.
+
+    Args:
+        certificates: Certificate data as in scan results
+
+    Returns:
+        Table with a header line
+    """
+    headers = [TABLE_HEADERS.get(column, column.upper()) for column in TABLE_COLUMNS]
+    rows = [[inventory_row(cert)[column] for column in TABLE_COLUMNS] for cert in certificates]
+    widths = [max(len(cell) for cell in cells) for cells in zip(headers, *rows)]
+    lines = [
+        "  ".join(cell.ljust(width) for cell, width in zip(cells, widths)).rstrip()
+        for cells in [headers, *rows]
+    ]
+    return "\n".join(lines)
+
+
+def format_csv(certificates: List[Dict[str, Any]]) -> str:
+    """
+    Format certificates as CSV with a header row of INVENTORY_COLUMNS.
+
+    Args:
+        certificates: Certificate data as in scan results
+
+    Returns:
+        CSV text
+    """
+    buffer = io.StringIO()
+    writer = csv.DictWriter(buffer, fieldnames=INVENTORY_COLUMNS, lineterminator="\n")
+    writer.writeheader()
+    writer.writerows(inventory_row(cert) for cert in certificates)
+    return buffer.getvalue().rstrip("\n")
+
+
+def format_results(results: Dict[str, Any], output: str) -> str:
+    """
+    Format scan results as json, table, csv or cyclonedx.
+
+    Args:
+        results: Results from CertificateScanner.scan_once() or simulate_scan()
+        output: Output format (one of OUTPUT_FORMATS except text)
+
+    Returns:
+        Formatted results
+    """
+    if output == "json":
+        return format_json(results)
+    if output == "table":
+        return format_table(certificates_of(results))
+    if output == "cyclonedx":
+        return format_json(build_bom(certificates_of(results)))
+    return format_csv(certificates_of(results))
@@ -0,0 +1,130 @@
+"""
+Prometheus alerting rules for TLS Certificate Monitor.
+
+Renders recommended alerting rules on the exporter's metrics, with the expiry thresholds and
+scan interval of the configuration, to load into Prometheus (rule_files) or a PrometheusRule.
+Generating them from the monitor keeps their metric names and labels in sync with the exporter.
+"""
+
+from typing import Any, Dict, List, Optional
+
+import yaml
+
+from tls_cert_monitor.config import Config
+
+# Name of the rule group
+GROUP_NAME = "tls-cert-monitor"
+
+# Certificates matched by an active silence are not alerted on
+UNLESS_SILENCED = "unless on (instance, path) ssl_cert_silenced == 1"
+
+
+def _days(seconds: int) -> int:
+    """Round seconds to whole days."""
+    return round(seconds / 86400)
+
+
+def _rule(
+    alert: str, expr: str, duration: str, severity: str, summary: str, description: str
+) -> Dict[str, Any]:
+    """Build an alerting rule."""
+    return {
+        "alert": alert,
+        "expr": expr,
+        "for": duration,
+        "labels": {"severity": severity},
+        "annotations": {"summary": summary, "description": description},
+    }
+
+
+def build_rules(config: Config, expiry_days: Optional[List[int]] = None) -> Dict[str, Any]:
+    """
+    Build the alerting rules of the monitor.
+
+    Args:
+        config: Configuration: the expiry thresholds are the default expiry windows, the scan
+            interval tells when scans stalled
+        expiry_days: Days before expiry to alert at instead of the warning and critical
+            thresholds; thresholds at or below the critical one alert as critical
+
+    Returns:
+        Prometheus rule file content (groups)
+    """
+    critical_days = _days(config.expiry_thresholds.critical_seconds)
+    days = sorted(
+        set(expiry_days or [_days(config.expiry_thresholds.warning_seconds), critical_days]),
+        reverse=True,
+    )
+    remaining = "(ssl_cert_expiration_timestamp - time())"
+
+    rules = []
+    # One window per threshold, so a certificate only fires the tightest one it falls in
+    for index, threshold in enumerate(days):
+        lower = days[index + 1] * 86400 if index + 1 < len(days) else 0
+        rules.append(
+            _rule(
+                f"TLSCertExpiringIn{threshold}Days",
+                f"{remaining} <= {threshold * 86400} and {remaining} > {lower} {UNLESS_SILENCED}",
+                "15m",
+                "critical" if threshold <= critical_days else "warning",
+                f"Certificate {{{{ $labels.common_name }}}} expires in less than {threshold} days",
+                "{{ $labels.path }} on {{ $labels.instance }} expires in "
+                "{{ $value | humanizeDuration }}.",
+            )
+        )
+    rules.append(
+        _rule(
+            "TLSCertExpired",
+            f"{remaining} <= 0 {UNLESS_SILENCED}",
+            "5m",
+            "critical",
+            "Certificate {{ $labels.common_name }} has expired",
+            "{{ $labels.path }} on {{ $labels.instance }} has expired.",
+        )
+    )
+    rules.append(
+        _rule(
+            "TLSCertWeakKey",
+            "ssl_cert_weak_key_total > 0",
+            "15m",
+            "warning",
+            "Certificates with weak keys on {{ $labels.instance }}",
+            "{{ $value }} certificates have weak keys (see ssl_cert_info).",
+        )
+    )
+    rules.append(
+        _rule(
+            "TLSCertDeprecatedSignatureAlgorithm",
+            "ssl_cert_deprecated_sigalg_total > 0",
+            "15m",
+            "warning",
+            "Certificates with deprecated signature algorithms on {{ $labels.instance }}",
+            "{{ $value }} certificates are signed with deprecated algorithms.",
+        )
+    )
+
+    # A scan is overdue once two intervals (of the slowest directory) passed without one
+    interval = max(
+        [config.scan_interval_seconds]
+        + [
+            config.directory_scan_interval_seconds(directory)
+            for directory in config.certificate_directories
+        ]
+    )
+    rules.append(
+        _rule(
+            "TLSCertScanStalled",
+            f"time() - ssl_cert_last_scan_timestamp > {2 * interval}",
+            "5m",
+            "warning",
+            "Certificate scans of {{ $labels.directory }} stalled on {{ $labels.instance }}",
+            "The last scan of {{ $labels.directory }} was {{ $value | humanizeDuration }} ago.",
+        )
+    )
+
+    return {"groups": [{"name": GROUP_NAME, "rules": rules}]}
+
+
+def format_rules(rules: Dict[str, Any]) -> str:
+    """Format rules as a Prometheus rule file (YAML)."""
+    return yaml.safe_dump(rules, default_flow_style=False, sort_keys=False, width=1000)
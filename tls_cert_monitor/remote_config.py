@@ -0,0 +1,267 @@
+"""
+Remote configuration backends (Consul KV, etcd) for TLS Certificate Monitor.
+
+The configuration is a YAML document stored under a single key and addressed by URL:
+
+- consul://consul.example.com:8500/tls-cert-monitor/config.yaml
+- etcd://etcd.example.com:2379/tls-cert-monitor/config.yaml
+
+Use consul+https:// or etcd+https:// for TLS. The ACL/auth token is read from
+TLS_MONITOR_REMOTE_CONFIG_TOKEN (or a ?token= query parameter).
+"""
+
+import base64
+import json
+import os
+import socket
+import urllib.error
+import urllib.parse
+import urllib.request
+from typing import Any, Dict, Optional, Tuple
+
+import yaml
+
+TOKEN_ENV_VAR = "TLS_MONITOR_REMOTE_CONFIG_TOKEN"
+
+
+class RemoteConfigError(Exception):
+    """Remote configuration could not be loaded."""
+
+
+def _parse_document(raw: bytes, key: str) -> Dict[str, Any]:
+    """Parse the YAML configuration document stored under a key."""
+    try:
+        data = yaml.safe_load(raw.decode("utf-8")) or {}
+    except (UnicodeDecodeError, yaml.YAMLError) as e:
+        raise RemoteConfigError(f"Invalid YAML in remote key {key}: {e}") from e
+    if not isinstance(data, dict):
+        raise RemoteConfigError(f"Remote key {key} must contain a mapping")
+    return data
+
+
+class RemoteConfigSource:
+    """A YAML configuration document stored under a key in a remote KV store."""
+
+    # How long a single watch request blocks before it is renewed
+    WATCH_WAIT_SECONDS = 300
+
+    def __init__(self, base_url: str, key: str, token: Optional[str] = None, timeout: int = 10):
+        self.base_url = base_url.rstrip("/")
+        self.key = key
+        self.token = token
+        self.timeout = timeout
+
+    def fetch(self) -> Tuple[Dict[str, Any], Any]:
+        """
+        Fetch the configuration document.
+
+        Returns:
+            Tuple of (configuration data, version of the key)
+
+        Raises:
+            RemoteConfigError: If the key is missing, unreadable or invalid
+        """
+        raise NotImplementedError
+
+    def wait_for_change(self, version: Any) -> Any:
+        """
+        Block until the key changes or the watch times out.
+
+        Args:
+            version: Version returned by fetch() or a previous call
+
+        Returns:
+            Current version (equal to version if nothing changed)
+        """
+        raise NotImplementedError
+
+    def _request(
+        self,
+        method: str,
+        url: str,
+        payload: Any = None,
+        headers: Optional[Dict[str, str]] = None,
+        timeout: Optional[float] = None,
+    ) -> Any:
+        """Send a request, returning the open response."""
+        data = json.dumps(payload).encode("utf-8") if payload is not None else None
+        request = urllib.request.Request(url, data=data, headers=headers or {}, method=method)
+        # URL comes from operator configuration
+        return urllib.request.urlopen(request, timeout=timeout or self.timeout)  # nosec B310
+
+
+class ConsulSource(RemoteConfigSource):
+    """Consul KV source, watched with blocking queries."""
+
+    def _headers(self) -> Dict[str, str]:
+        return {"X-Consul-Token": self.token} if self.token else {}
+
+    def _key_url(self, **params: Any) -> str:
+        url = f"{self.base_url}/v1/kv/{urllib.parse.quote(self.key)}"
+        return f"{url}?{urllib.parse.urlencode(params)}" if params else url
+
+    def fetch(self) -> Tuple[Dict[str, Any], Any]:
+        try:
+            with self._request("GET", self._key_url(), headers=self._headers()) as response:
+                entries = json.loads(response.read())
+                index = response.headers.get("X-Consul-Index")
+        except urllib.error.HTTPError as e:
+            if e.code == 404:
+                raise RemoteConfigError(f"Consul key not found: {self.key}") from e
+            raise RemoteConfigError(f"Consul request failed: {e}") from e
+        except (urllib.error.URLError, OSError, ValueError) as e:
+            raise RemoteConfigError(f"Consul request failed: {e}") from e
+
+        raw = base64.b64decode(entries[0].get("Value") or "")
+        return _parse_document(raw, self.key), index
+
+    def wait_for_change(self, version: Any) -> Any:
+        url = self._key_url(index=version, wait=f"{self.WATCH_WAIT_SECONDS}s")
+        try:
+            with self._request(
+                "GET", url, headers=self._headers(), timeout=self.WATCH_WAIT_SECONDS + 30
+            ) as response:
+                response.read()
+                return response.headers.get("X-Consul-Index", version)
+        except urllib.error.HTTPError as e:
+            if e.code == 404:
+                # Key deleted: keep the current configuration
+                return version
+            raise RemoteConfigError(f"Consul watch failed: {e}") from e
+        except (urllib.error.URLError, OSError) as e:
+            raise RemoteConfigError(f"Consul watch failed: {e}") from e
+
+
+class EtcdSource(RemoteConfigSource):
+    """etcd v3 source using the JSON gRPC gateway, watched with the watch API."""
+
+    def _headers(self) -> Dict[str, str]:
+        headers = {"Content-Type": "application/json"}
+        if self.token:
+            headers["Authorization"] = self.token
+        return headers
+
+    def _encoded_key(self) -> str:
+        return base64.b64encode(self.key.encode("utf-8")).decode("ascii")
+
+    def fetch(self) -> Tuple[Dict[str, Any], Any]:
+        try:
+            with self._request(
+                "POST",
+                f"{self.base_url}/v3/kv/range",
+                {"key": self._encoded_key()},
+                headers=self._headers(),
+            ) as response:
+                result = json.loads(response.read())
+        except (urllib.error.URLError, OSError, ValueError) as e:
+            raise RemoteConfigError(f"etcd request failed: {e}") from e
+
+        kvs = result.get("kvs") or []
+        if not kvs:
+            raise RemoteConfigError(f"etcd key not found: {self.key}")
+
+        raw = base64.b64decode(kvs[0].get("value") or "")
+        return _parse_document(raw, self.key), int(kvs[0].get("mod_revision", 0))
+
+    def wait_for_change(self, version: Any) -> Any:
+        request = {
+            "create_request": {"key": self._encoded_key(), "start_revision": int(version) + 1}
+        }
+        try:
+            with self._request(
+                "POST",
+                f"{self.base_url}/v3/watch",
+                request,
+                headers=self._headers(),
+                timeout=self.WATCH_WAIT_SECONDS,
+            ) as response:
+                # The gateway streams one JSON message per line: a "created"
+                # acknowledgement, then results carrying events
+                for line in response:
+                    if not line.strip():
+                        continue
+                    events = json.loads(line).get("result", {}).get("events") or []
+                    for event in events:
+                        kv = event.get("kv", {})
+                        if event.get("type") != "DELETE":
+                            return int(kv.get("mod_revision", version))
+        except socket.timeout:
+            return version
+        except (urllib.error.URLError, OSError, ValueError) as e:
+            raise RemoteConfigError(f"etcd watch failed: {e}") from e
+        return version
+
+
+SOURCE_TYPES = {"consul": ConsulSource, "etcd": EtcdSource}
+
+
+def create_remote_source(url: str) -> RemoteConfigSource:
+    """
+    Create a remote configuration source from a URL.
+
+    Args:
+        url: consul://, consul+https://, etcd:// or etcd+https:// URL with the key as path
+
+    Returns:
+        Remote configuration source
+
+    Raises:
+        ValueError: If the URL is invalid
+    """
+    parsed = urllib.parse.urlparse(url)
+    backend, _, transport = parsed.scheme.partition("+")
+    if backend not in SOURCE_TYPES:
+        raise ValueError(
+            f"Unsupported remote config scheme '{parsed.scheme}' "
+            f"(expected one of {sorted(SOURCE_TYPES)}, optionally with +https)"
+        )
+    if transport not in ("", "http", "https"):
+        raise ValueError(f"Unsupported remote config transport '{transport}'")
+
+    key = parsed.path.lstrip("/")
+    if not parsed.netloc or not key:
+        raise ValueError(f"Remote config URL must include a host and a key: {url}")
+
+    query = urllib.parse.parse_qs(parsed.query)
+    token = query.get("token", [None])[0] or os.getenv(TOKEN_ENV_VAR)
+
+    base_url = f"{transport or 'http'}://{parsed.netloc}"
+    return SOURCE_TYPES[backend](base_url, key, token=token)
+
+
+def load_remote_config(url: str) -> Dict[str, Any]:
+    """
+    Load a configuration document from a remote KV store.
+
+    Args:
+        url: Remote configuration URL
+
+    Returns:
+        Configuration data
+    """
+    data, _ = create_remote_source(url).fetch()
+    return data
+
+
+def check_remote_config(url: str, timeout: Optional[float] = None) -> Dict[str, Any]:
+    """
+    Check the remote KV store is reachable and the key readable with the configured token.
+
+    Run as the remote_config /healthz check, so an unreachable store or a revoked token shows
+    up before the next reload falls back to the current configuration.
+
+    Args:
+        url: Remote configuration URL
+        timeout: Request timeout in seconds (default: the source's)
+
+    Returns:
+        Health data: URL (without query parameters, which may hold the token), key and version
+
+    Raises:
+        RemoteConfigError: If the key cannot be fetched
+    """
+    source = create_remote_source(url)
+    if timeout:
+        source.timeout = timeout
+    _, version = source.fetch()
+    return {"url": url.split("?", 1)[0], "key": source.key, "version": version}
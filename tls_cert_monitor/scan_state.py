@@ -0,0 +1,100 @@
+"""
+Scan state persistence for TLS Certificate Monitor.
+
+The results of every scan are saved to a JSON file (scan_state.json in cache_dir by default).
+At startup they are restored before the first scan, so that on hosts where scanning takes
+minutes /metrics serves the previous known state, flagged by ssl_cert_scan_state_stale, instead
+of empty gauges; the parse cache only makes that first scan shorter.
+"""
+
+import asyncio
+import json
+import os
+import time
+from typing import Any, Dict, Optional
+
+from tls_cert_monitor.config import Config
+from tls_cert_monitor.logger import get_logger
+from tls_cert_monitor.scanner import CertificateScanner
+
+# Format of the state file, bumped on incompatible changes (other versions are not restored)
+STATE_VERSION = 1
+
+
+class ScanStateStore:
+    """The results of the last scan, saved after every scan and loaded at startup."""
+
+    def __init__(self, config: Config, scanner: Optional[CertificateScanner] = None):
+        path = config.scan_state_path()
+        if path is None:
+            raise ValueError("The scan state is not kept: it is disabled or has no path")
+        self.config = config.scan_state
+        self.path = path
+        self.logger = get_logger("scan_state")
+
+        if scanner:
+            scanner.add_scan_listener(self._on_scan_complete)
+
+    async def _on_scan_complete(self, scan_results: Dict[str, Any]) -> None:
+        """Save the results of the scan, off the event loop."""
+        try:
+            await asyncio.to_thread(self.save, scan_results)
+        except (OSError, TypeError, ValueError) as e:
+            self.logger.warning(f"Failed to save the scan state to {self.path}: {e}")
+
+    def save(self, scan_results: Dict[str, Any]) -> None:
+        """
+        Save scan results, replacing the state file atomically.
+
+        Args:
+            scan_results: Results from CertificateScanner.scan_once()
+        """
+        self.path.parent.mkdir(parents=True, exist_ok=True)
+        # Written next to the state file, so the rename does not cross file systems and a crash
+        # never leaves a truncated state behind
+        temp_path = self.path.with_name(self.path.name + ".tmp")
+        try:
+            with open(temp_path, "w", encoding="utf-8") as f:
+                json.dump({"version": STATE_VERSION, "results": scan_results}, f)
+            os.replace(temp_path, self.path)
+        finally:
+            if temp_path.exists():
+                temp_path.unlink()
+
+    def load(self, now: Optional[float] = None) -> Optional[Dict[str, Any]]:
+        """
+        Load the results of the last scan saved.
+
+        Args:
+            now: Current time, to judge the age of the state (default: now)
+
+        Returns:
+            The scan results, or None if there is no state to restore: missing, unreadable,
+            of another version, or older than max_age
+        """
+        if not self.path.exists():
+            return None
+        try:
+            with open(self.path, encoding="utf-8") as f:
+                state = json.load(f)
+        except (OSError, ValueError) as e:
+            self.logger.warning(f"Ignoring unreadable scan state {self.path}: {e}")
+            return None
+
+        if (
+            not isinstance(state, dict)
+            or state.get("version") != STATE_VERSION
+            or not isinstance(state.get("results"), dict)
+        ):
+            self.logger.warning(f"Ignoring scan state {self.path} of an unsupported version")
+            return None
+
+        results: Dict[str, Any] = state["results"]
+        age = (time.time() if now is None else now) - results.get("timestamp", 0)
+        if self.config.max_age_seconds and age > self.config.max_age_seconds:
+            self.logger.info(
+                f"Ignoring scan state {self.path} from {age / 3600:.1f}h ago "
+                f"(max_age: {self.config.max_age})"
+            )
+            return None
+        return results
@@ -7,12 +7,26 @@ import os
 import re
 import shutil
 import time
-from concurrent.futures import ThreadPoolExecutor
+from collections import deque
+from concurrent.futures import FIRST_COMPLETED, ThreadPoolExecutor, wait
 from datetime import datetime, timezone
 from pathlib import Path
-from typing import Any, Dict, List, Optional
+from typing import (
+    Any,
+    Awaitable,
+    Callable,
+    Collection,
+    Deque,
+    Dict,
+    List,
+    Optional,
+    Set,
+    Tuple,
+    Union,
+)
 
 from cryptography import x509
+from cryptography.hazmat.primitives import hashes
 from cryptography.hazmat.primitives.serialization import pkcs12
 
 from tls_cert_monitor.cache import CacheManager
@@ -23,12 +37,109 @@ from tls_cert_monitor.logger import (
     log_cert_parsed,
     log_cert_scan_complete,
     log_cert_scan_start,
+    log_scan_summary,
 )
 from tls_cert_monitor.metrics import (
     MetricsCollector,
+    count_certificates_by_severity,
+    get_expiry_severity,
     is_deprecated_signature_algorithm,
     is_weak_key,
 )
+from tls_cert_monitor.silences import SilenceManager
+from tls_cert_monitor.tracing import start_async_span, start_file_span, start_span
+
+ScanListener = Callable[[Dict[str, Any]], Awaitable[None]]
+
+# Number of recent scan durations a running scan's duration is judged against
+SCAN_DURATION_HISTORY = 10
+
+# Version of the scan summary record schema, raised on incompatible changes
+SCAN_SUMMARY_SCHEMA_VERSION = 1
+# New and removed certificate paths listed in a scan summary (all are counted)
+SCAN_SUMMARY_MAX_PATHS = 100
+
+# PEM certificate blocks, wherever they are in the file: tools write banners and text around
+# them (certbot, openssl x509 -text, the Bag Attributes of openssl pkcs12)
+PEM_CERTIFICATE_PATTERN = re.compile(
+    rb"-----BEGIN (?:X509 )?CERTIFICATE-----.*?-----END (?:X509 )?CERTIFICATE-----", re.DOTALL
+)
+
+
+class FairScheduler:
+    """
+    Parser slots shared by the directories of a scan, handed out to them in turn.
+
+    A freed slot goes to the next directory with files waiting, round-robin, rather than to the
+    file that waited the longest: a huge directory cannot hold every worker for the whole cycle
+    while small directories queue behind it, so those finish early in the scan.
+    """
+
+    def __init__(self, workers: int):
+        self._free = workers
+        # Files waiting for a slot by directory, and the directories with files waiting in turn
+        self._waiting: Dict[str, Deque["asyncio.Future[None]"]] = {}
+        self._rotation: Deque[str] = deque()
+
+    def directory(self, directory: str, workers: int) -> "DirectorySlots":
+        """Get the slots of a directory, at most workers of them at once."""
+        return DirectorySlots(self, directory, workers)
+
+    async def acquire(self, directory: str) -> None:
+        """Wait for a slot, in the directory's turn."""
+        if self._free and not self._rotation:
+            self._free -= 1
+            return
+
+        future: "asyncio.Future[None]" = asyncio.get_running_loop().create_future()
+        queue = self._waiting.setdefault(directory, deque())
+        if not queue:
+            self._rotation.append(directory)
+        queue.append(future)
+        try:
+            await future
+        except asyncio.CancelledError:
+            # Cancelled after the slot was handed over: pass it on
+            if future.done() and not future.cancelled():
+                self.release()
+            raise
+
+    def release(self) -> None:
+        """Hand a slot to the next directory waiting, or free it."""
+        while self._rotation:
+            directory = self._rotation.popleft()
+            queue = self._waiting[directory]
+            future = queue.popleft()
+            if queue:
+                self._rotation.append(directory)
+            else:
+                del self._waiting[directory]
+            # Skip files cancelled while waiting
+            if not future.done():
+                future.set_result(None)
+                return
+        self._free += 1
+
+
+class DirectorySlots:
+    """Slots of a directory: its own workers limit, then a slot of the scan in its turn."""
+
+    def __init__(self, scheduler: FairScheduler, directory: str, workers: int):
+        self._scheduler = scheduler
+        self._directory = directory
+        self._semaphore = asyncio.Semaphore(workers)
+
+    async def __aenter__(self) -> None:
+        await self._semaphore.acquire()
+        try:
+            await self._scheduler.acquire(self._directory)
+        except BaseException:
+            self._semaphore.release()
+            raise
+
+    async def __aexit__(self, *exc_info: Any) -> None:
+        self._scheduler.release()
+        self._semaphore.release()
 
 
 class CertificateScanner:
@@ -43,19 +154,59 @@ class CertificateScanner:
 
     SUPPORTED_EXTENSIONS = {".pem", ".crt", ".cer", ".cert", ".der", ".p12", ".pfx"}
 
-    def __init__(self, config: Config, cache: CacheManager, metrics: MetricsCollector):
+    def __init__(
+        self,
+        config: Config,
+        cache: CacheManager,
+        metrics: MetricsCollector,
+        silences: Optional[SilenceManager] = None,
+    ):
         self.config = config
         self.cache = cache
         self.metrics = metrics
+        self.silences = silences or SilenceManager(config)
         self.logger = get_logger("scanner")
 
         self._scanning = False
         self._scan_task: Optional[asyncio.Task] = None
-        self._executor = ThreadPoolExecutor(max_workers=config.workers)
+        self._pool_workers = self._max_workers(config)
+        self._executor = ThreadPoolExecutor(max_workers=self._pool_workers)
         self._scan_lock: Optional[asyncio.Lock] = None  # Initialize lock lazily in async context
+        self._scan_listeners: List[ScanListener] = []
+        self.last_scan_results: Optional[Dict[str, Any]] = None
+        self.scans_completed = 0
+        # When the first and the latest scan completed (the monitor is ready after the first)
+        self.first_scan_completed_at: Optional[float] = None
+        self.last_scan_completed_at: Optional[float] = None
+        # Time of the scan before a restart served until the first scan (restore_scan_results)
+        self.restored_scan_at: Optional[float] = None
+        self._first_scan_done: Optional[asyncio.Event] = None  # Created lazily like the lock
+        # Progress of the running scan, and durations of recent scans to judge it against
+        self._current_scan: Dict[str, Any] = {}
+        # Parser slots the directories of the running scan share
+        self._scan_scheduler: Optional[FairScheduler] = None
+        self._scan_durations: Deque[float] = deque(maxlen=SCAN_DURATION_HISTORY)
+        # Files being parsed, with the time parsing started
+        self._files_in_progress: Dict[str, float] = {}
+        # Latest result per directory, reused until the directory's interval elapses
+        self._directory_results: Dict[str, Dict[str, Any]] = {}
+        # Missing directories skipped because of allow_missing_directories
+        self._missing_directories: Set[str] = set()
+        # Current cache key per file, to drop entries of replaced file versions
+        self._file_cache_keys: Dict[str, str] = {}
+        # Certificates of the previous scan by key (path and fingerprint), for the scan summary
+        self._previous_certificates: Optional[Dict[str, str]] = None
 
         self.logger.info(f"Certificate scanner initialized - Workers: {config.workers}")
 
+    @staticmethod
+    def _max_workers(config: Config) -> int:
+        """Size the parser pool for the largest per-directory worker setting."""
+        directory_workers = [
+            settings.workers for settings in config.directory_settings.values() if settings.workers
+        ]
+        return max([config.workers] + directory_workers)
+
     async def start_scanning(self) -> None:
         """Start the periodic certificate scanning."""
         if self._scanning:
@@ -80,10 +231,36 @@ class CertificateScanner:
         self._executor.shutdown(wait=True)
         self.logger.info("Certificate scanner stopped")
 
-    async def scan_once(self) -> Dict[str, Any]:
+    def add_scan_listener(self, listener: ScanListener) -> None:
+        """
+        Register a coroutine called with the results of every completed scan.
+
+        Args:
+            listener: Async callable receiving the scan results
+        """
+        self._scan_listeners.append(listener)
+
+    async def _notify_scan_listeners(self, scan_results: Dict[str, Any]) -> None:
+        """Call scan listeners, isolating failures."""
+        for listener in self._scan_listeners:
+            try:
+                await listener(scan_results)
+            except Exception as e:
+                self.logger.error(f"Scan listener failed: {e}")
+
+    async def scan_once(
+        self, due_only: bool = False, directories: Optional[Collection[str]] = None
+    ) -> Dict[str, Any]:
         """
         Perform a single scan of all configured directories.
 
+        Args:
+            due_only: Only rescan directories whose scan interval has elapsed and
+                      reuse the previous results of the others
+            directories: Only rescan these directories (e.g. those with changed files) and
+                      reuse the previous results of the others; directories without previous
+                      results are scanned regardless
+
         Returns:
             Scan results summary
         """
@@ -92,14 +269,21 @@ class CertificateScanner:
             self._scan_lock = asyncio.Lock()
 
         # Prevent concurrent scans
-        async with self._scan_lock:
+        async with self._scan_lock, start_async_span("scan", {"scan.due_only": due_only}) as span:
             start_time = time.time()
+            self._current_scan = {
+                "started_at": start_time,
+                "files_processed": 0,
+                "directory": None,
+                "directories": [],
+            }
             total_files = 0
             total_parsed = 0
             total_errors = 0
 
-            # Reset metrics for new scan
+            # Build the metrics of the scan, published when it completes
             self.metrics.reset_scan_metrics()
+            self.metrics.set_certificate_label_names(self.config.directory_label_names())
 
             scan_results: Dict[str, Any] = {
                 "directories": {},
@@ -107,18 +291,39 @@ class CertificateScanner:
                 "timestamp": start_time,
             }
 
-            for directory in self.config.certificate_directories:
-                dir_start_time = time.time()
-
-                try:
-                    result = await self._scan_directory(directory)
+            configured = self.config.certificate_directories
+            due = [
+                d
+                for d in configured
+                if (not due_only or self._is_directory_due(d, start_time))
+                and (directories is None or d in directories or d not in self._directory_results)
+            ]
+            results: Dict[str, Dict[str, Any]] = {}
+
+            # Re-evaluate reused directories first so the totals set by the scanned
+            # directories' update_scan_metrics() include their certificates
+            for directory in configured:
+                if directory not in due:
+                    results[directory] = self._reuse_directory_result(directory)
+                    total_parsed += results[directory]["certificates_parsed"]
+
+            # Due directories are scanned together, their files parsed in turn (FairScheduler)
+            self._scan_scheduler = FairScheduler(self._pool_workers)
+            try:
+                scanned = await asyncio.gather(
+                    *(self._scan_due_directory(directory) for directory in due)
+                )
+            finally:
+                self._scan_scheduler = None
 
-                    dir_duration = time.time() - dir_start_time
-                    total_files += result["files_processed"]
-                    total_parsed += result["certificates_parsed"]
-                    total_errors += result["parse_errors"]
+            for directory, (result, dir_duration) in zip(due, scanned):
+                results[directory] = result
+                total_files += result["files_processed"]
+                total_parsed += result["certificates_parsed"]
+                total_errors += result["parse_errors"]
 
-                    # Update metrics
+                # Update metrics, in the configured order
+                if dir_duration is not None:
                     self.metrics.update_scan_metrics(
                         directory=directory,
                         duration=dir_duration,
@@ -127,26 +332,21 @@ class CertificateScanner:
                         errors_total=result["parse_errors"],
                     )
 
-                    scan_results["directories"][directory] = result
-
-                    log_cert_scan_complete(
-                        self.logger,
-                        directory,
-                        dir_duration,
-                        result["certificates_parsed"],
-                        result["parse_errors"],
-                    )
-
-                except Exception as e:
-                    self.logger.error(f"Failed to scan directory {directory}: {e}")
-                    scan_results["directories"][directory] = {
-                        "error": str(e),
-                        "files_processed": 0,
-                        "certificates_parsed": 0,
-                        "parse_errors": 1,
-                    }
-                    total_errors += 1
-
+            scan_results["directories"] = {d: results[d] for d in configured}
+
+            tls_cert = self.config.monitored_tls_cert()
+            if tls_cert:
+                self._current_scan["directory"] = tls_cert
+                result = await self._scan_tls_cert(tls_cert)
+                scan_results["directories"][tls_cert] = result
+                total_files += result["files_processed"]
+                total_parsed += result["certificates_parsed"]
+                total_errors += result["parse_errors"]
+
+            severity_counts = count_certificates_by_severity(scan_results["directories"])
+            self.metrics.update_expiry_metrics(severity_counts, self.config.expiry_thresholds)
+            self.metrics.publish_scan_metrics()
+            self.metrics.clear_scan_state_stale()
             total_duration = time.time() - start_time
 
             scan_results["summary"] = {
@@ -154,22 +354,249 @@ class CertificateScanner:
                 "total_files": total_files,
                 "total_parsed": total_parsed,
                 "total_errors": total_errors,
-                "directories_scanned": len(self.config.certificate_directories),
+                "directories_scanned": len(due),
+                "directories_reused": len(configured) - len(due),
+                "directories_skipped": sum(1 for r in results.values() if "skipped" in r),
             }
 
             self.logger.info(
                 f"Scan completed - Duration: {total_duration:.2f}s, "
                 f"Files: {total_files}, Parsed: {total_parsed}, Errors: {total_errors}"
             )
+            log_scan_summary(
+                self.logger, self._scan_summary(scan_results, start_time, severity_counts)
+            )
+            if span is not None:
+                span.set_attribute("scan.files", total_files)
+                span.set_attribute("scan.certificates_parsed", total_parsed)
+                span.set_attribute("scan.errors", total_errors)
+                span.set_attribute("scan.directories_scanned", len(due))
+
+            self.last_scan_results = scan_results
+            self.scans_completed += 1
+            self._scan_durations.append(total_duration)
+            self.last_scan_completed_at = time.time()
+            if self.first_scan_completed_at is None:
+                self.first_scan_completed_at = self.last_scan_completed_at
+            self._first_scan_event().set()
+
+        # Listeners run outside the scan lock so slow notifiers never block other scans
+        await self._notify_scan_listeners(scan_results)
+
+        return scan_results
+
+    def _scan_summary(
+        self, scan_results: Dict[str, Any], start_time: float, severity_counts: Dict[str, int]
+    ) -> Dict[str, Any]:
+        """
+        Build the scan summary record, for log-based dashboards (Splunk, ELK, ...).
+
+        New and removed certificates are relative to the previous scan; the first scan after
+        startup is the baseline and reports none.
+
+        Returns:
+            Scan summary (schema_version SCAN_SUMMARY_SCHEMA_VERSION)
+        """
+        summary = scan_results["summary"]
+        certificates: Dict[str, str] = {}
+        for result in scan_results["directories"].values():
+            for cert in result.get("certificates", []):
+                key = f"{cert.get('path', '')}#{cert.get('fingerprint_sha256', cert.get('serial'))}"
+                certificates[key] = cert.get("path", "")
+
+        previous = self._previous_certificates
+        new = [] if previous is None else [k for k in certificates if k not in previous]
+        removed = [] if previous is None else [k for k in previous if k not in certificates]
+        self._previous_certificates = certificates
+
+        def paths(keys: List[str], known: Dict[str, str]) -> List[str]:
+            return sorted(known[key] for key in keys)[:SCAN_SUMMARY_MAX_PATHS]
+
+        return {
+            "schema_version": SCAN_SUMMARY_SCHEMA_VERSION,
+            "scan_number": self.scans_completed + 1,
+            "started_at": datetime.fromtimestamp(start_time, timezone.utc).isoformat(),
+            "duration_seconds": round(summary["total_duration"], 3),
+            "baseline": previous is None,
+            "directories": {
+                "scanned": summary["directories_scanned"],
+                "reused": summary["directories_reused"],
+                "skipped": summary["directories_skipped"],
+            },
+            "files": summary["total_files"],
+            "certificates": len(certificates),
+            "errors": summary["total_errors"],
+            "certificates_by_severity": severity_counts,
+            "new_certificates": len(new),
+            "removed_certificates": len(removed),
+            "new_paths": paths(new, certificates),
+            "removed_paths": paths(removed, previous or {}),
+        }
+
+    async def _scan_due_directory(self, directory: str) -> Tuple[Dict[str, Any], Optional[float]]:
+        """
+        Scan a due directory of a scan, recording its result.
+
+        Returns:
+            The result of the directory (skipped or failed ones included), and how long the
+            scan took (None if it was skipped or failed)
+        """
+        if self._is_skipped_missing_directory(directory):
+            skipped = {
+                "skipped": f"Directory does not exist: {directory}",
+                "files_processed": 0,
+                "certificates_parsed": 0,
+                "parse_errors": 0,
+            }
+            return skipped, None
+
+        dir_start_time = time.time()
+        scanning: List[str] = self._current_scan["directories"]
+        scanning.append(directory)
+        self._current_scan["directory"] = scanning[0]
+
+        try:
+            with start_span("scan.directory", {"scan.directory": directory}) as dir_span:
+                result = await self._scan_directory(directory)
+                if dir_span is not None:
+                    dir_span.set_attribute("scan.files", result["files_processed"])
+                    dir_span.set_attribute("scan.errors", result["parse_errors"])
+            result["scanned_at"] = dir_start_time
+            self._directory_results[directory] = result
+
+            dir_duration = time.time() - dir_start_time
+            log_cert_scan_complete(
+                self.logger,
+                directory,
+                dir_duration,
+                result["certificates_parsed"],
+                result["parse_errors"],
+            )
+            return result, dir_duration
+
+        except Exception as e:
+            self.logger.error(f"Failed to scan directory {directory}: {e}")
+            self._directory_results.pop(directory, None)
+            failed = {
+                "error": str(e),
+                "files_processed": 0,
+                "certificates_parsed": 0,
+                "parse_errors": 1,
+                # Parse error alerts of the directory still route to its owners
+                "labels": self.config.directory_labels(directory),
+            }
+            return failed, None
+
+        finally:
+            scanning.remove(directory)
+            # The directory scanned the longest is reported while others run
+            self._current_scan["directory"] = scanning[0] if scanning else None
 
-            return scan_results
+    def _is_skipped_missing_directory(self, directory: str) -> bool:
+        """
+        Check if a directory is missing and should be skipped rather than fail the scan.
+
+        A warning is logged once when a directory goes missing and a message when it reappears.
+        """
+        if not self.config.allow_missing_directories:
+            return False
+
+        if Path(directory).exists():
+            if directory in self._missing_directories:
+                self._missing_directories.discard(directory)
+                self.logger.info(f"Certificate directory is now available: {directory}")
+            return False
+
+        if directory not in self._missing_directories:
+            self._missing_directories.add(directory)
+            self.logger.warning(f"Skipping missing certificate directory: {directory}")
+        self._directory_results.pop(directory, None)
+        return True
+
+    def _is_directory_due(self, directory: str, now: float) -> bool:
+        """Check if a directory's scan interval has elapsed since it was last scanned."""
+        previous = self._directory_results.get(directory)
+        if previous is None:
+            return True
+        # Allow for loop jitter so directories on the loop interval are always due
+        interval = self.config.directory_scan_interval_seconds(directory)
+        return now - previous["scanned_at"] >= interval - 1
+
+    def _reevaluate_certificates(self, certificates: List[Dict[str, Any]]) -> List[Dict[str, Any]]:
+        """Re-evaluate and export the certificates of a previous scan."""
+        results = []
+        for cert in certificates:
+            # Severity and silences depend on the current time
+            cert_result = dict(cert)
+            self._annotate_severity(cert_result)
+            self._annotate_silence(cert_result)
+            results.append(cert_result)
+            self.metrics.update_certificate_metrics(cert_result)
+        return results
+
+    def _reuse_directory_result(self, directory: str) -> Dict[str, Any]:
+        """Re-evaluate the previous result of a directory that is not due for a scan."""
+        previous = self._directory_results[directory]
+        return {
+            **previous,
+            "certificates": self._reevaluate_certificates(previous.get("certificates", [])),
+        }
+
+    def restore_scan_results(self, scan_results: Dict[str, Any]) -> Dict[str, Any]:
+        """
+        Serve the results of the last scan before a restart until the first scan completes.
+
+        The certificates are re-evaluated and exported as after a scan, with
+        ssl_cert_scan_state_stale set; directories no longer configured are dropped. Scans do
+        not reuse the restored results: the first one scans every directory.
+
+        Args:
+            scan_results: Results of the scan, as saved by ScanStateStore
+
+        Returns:
+            The restored scan results, flagged stale
+        """
+        configured = set(self.config.certificate_directories)
+        tls_cert = self.config.monitored_tls_cert()
+        if tls_cert:
+            configured.add(tls_cert)
+
+        self.metrics.reset_scan_metrics()
+        self.metrics.set_certificate_label_names(self.config.directory_label_names())
+        directories = {
+            directory: {
+                **result,
+                "certificates": self._reevaluate_certificates(result.get("certificates", [])),
+            }
+            for directory, result in scan_results.get("directories", {}).items()
+            if directory in configured
+        }
+        timestamp = scan_results.get("timestamp", 0)
+        self.metrics.update_restored_scan_metrics(directories, timestamp)
+        self.metrics.update_expiry_metrics(
+            count_certificates_by_severity(directories), self.config.expiry_thresholds
+        )
+        self.metrics.publish_scan_metrics()
+
+        restored = {**scan_results, "directories": directories, "stale": True}
+        self.last_scan_results = restored
+        self.restored_scan_at = timestamp
+        return restored
+
+    def _loop_interval_seconds(self) -> int:
+        """Get the scan loop interval: the shortest global or per-directory interval."""
+        intervals = [self.config.scan_interval_seconds] + [
+            self.config.directory_scan_interval_seconds(directory)
+            for directory in self.config.certificate_directories
+        ]
+        return min(intervals)
 
     async def _scan_loop(self) -> None:
         """Main scanning loop."""
         while self._scanning:
             try:
-                await self.scan_once()
-                await asyncio.sleep(self.config.scan_interval_seconds)
+                await self.scan_once(due_only=True)
+                await asyncio.sleep(self._loop_interval_seconds())
             except asyncio.CancelledError:
                 break
             except Exception as e:
@@ -194,8 +621,16 @@ class CertificateScanner:
         if not directory_path.is_dir():
             raise NotADirectoryError(f"Path is not a directory: {directory}")
 
-        # Find certificate files
-        cert_files = self._find_certificate_files(directory_path)
+        settings = self.config.get_directory_config(directory)
+        labels = self.config.directory_labels(directory)
+
+        # Find certificate files (off the event loop: walking large trees takes a while)
+        cert_files = await asyncio.to_thread(
+            self._find_certificate_files,
+            directory_path,
+            settings.excludes,
+            workers=settings.workers or self.config.workers,
+        )
 
         log_cert_scan_start(self.logger, directory, len(cert_files))
 
@@ -204,12 +639,14 @@ class CertificateScanner:
         parse_errors = 0
         certificates = []
 
-        # Process files in parallel
-        semaphore = asyncio.Semaphore(self.config.workers)
+        # Process files in parallel, up to the directory's workers, in its turn during scans
+        workers = settings.workers or self.config.workers
+        slots = (self._scan_scheduler or FairScheduler(workers)).directory(directory, workers)
         tasks = []
 
         for cert_file in cert_files:
-            task = asyncio.create_task(self._process_certificate_file(cert_file, semaphore))
+            task = asyncio.create_task(self._process_certificate_file(cert_file, slots))
+            task.add_done_callback(self._count_file_processed)
             tasks.append(task)
 
         # Wait for all tasks to complete
@@ -225,7 +662,12 @@ class CertificateScanner:
                 parse_errors += 1
             else:
                 certificates_parsed += 1
-                cert_result: Dict[str, Any] = result  # type: ignore[assignment]
+                # Copy so silence annotations never leak into cached entries
+                cert_result: Dict[str, Any] = dict(result)  # type: ignore[arg-type]
+                if labels:
+                    cert_result["labels"] = dict(labels)
+                self._annotate_severity(cert_result)
+                self._annotate_silence(cert_result)
                 certificates.append(cert_result)
 
                 # Update certificate metrics
@@ -237,100 +679,376 @@ class CertificateScanner:
             "certificates_parsed": certificates_parsed,
             "parse_errors": parse_errors,
             "certificates": certificates,
+            "labels": labels,
             "disk_usage": self._get_disk_usage(directory_path),
         }
 
-    def _find_certificate_files(self, directory: Path) -> List[Path]:
+    async def _scan_tls_cert(self, tls_cert: str) -> Dict[str, Any]:
+        """
+        Scan the server's own certificate (tls_cert), reported like a directory of one file.
+
+        Args:
+            tls_cert: Resolved path of the server certificate
+
+        Returns:
+            Scan results for the certificate, flagged as tls_cert
+        """
+        result: Dict[str, Any] = {
+            "directory": tls_cert,
+            "tls_cert": True,
+            "files_processed": 1,
+            "certificates_parsed": 0,
+            "parse_errors": 0,
+            "certificates": [],
+            "labels": {},
+        }
+        try:
+            cert_data = await self._process_certificate_file(Path(tls_cert), asyncio.Semaphore(1))
+        except Exception as e:
+            self.logger.error(f"Failed to scan server certificate {tls_cert}: {e}")
+            cert_data = None
+
+        if cert_data is None:
+            result["parse_errors"] = 1
+            return result
+
+        cert_result: Dict[str, Any] = dict(cert_data)
+        self._annotate_severity(cert_result)
+        self._annotate_silence(cert_result)
+        self.metrics.update_certificate_metrics(cert_result)
+        result["certificates_parsed"] = 1
+        result["certificates"] = [cert_result]
+        return result
+
+    def check_file(self, file_path: Path) -> Dict[str, Any]:
+        """
+        Analyze one certificate file as a scan does, without caching or metrics.
+
+        Args:
+            file_path: Certificate file (PEM, DER or PKCS#12)
+
+        Returns:
+            Certificate data with its severity and silence
+
+        Raises:
+            RuntimeError: If the file cannot be parsed
+        """
+        cert_data = self._parse_certificate_file(file_path)
+        if cert_data is None:
+            raise RuntimeError(f"No certificate found in {file_path}")
+        self._annotate_severity(cert_data)
+        self._annotate_silence(cert_data)
+        return cert_data
+
+    def check_certificate(self, cert: x509.Certificate, path: str) -> Dict[str, Any]:
+        """
+        Analyze a certificate obtained elsewhere (e.g. from a TLS server) as a scan does.
+
+        Args:
+            cert: Certificate
+            path: Where it came from, reported as its path (e.g. host:port)
+
+        Returns:
+            Certificate data with its severity and silence
+        """
+        cert_data = self._extract_certificate_info(cert)
+        cert_data["path"] = path
+        self._annotate_severity(cert_data)
+        self._annotate_silence(cert_data)
+        return cert_data
+
+    def _count_file_processed(self, _task: "asyncio.Task[Any]") -> None:
+        """Record scan progress as file tasks finish."""
+        if self._current_scan:
+            self._current_scan["files_processed"] += 1
+
+    def _annotate_severity(self, cert_data: Dict[str, Any]) -> None:
+        """Classify the certificate against the expiry thresholds and clock skew grace."""
+        now = time.time()
+        if "expiration_timestamp" in cert_data:
+            cert_data["severity"] = get_expiry_severity(
+                float(cert_data["expiration_timestamp"]),
+                self.config.expiry_thresholds,
+                now,
+                margin=self.config.expiry_grace_seconds,
+            )
+        if "not_before_timestamp" in cert_data:
+            cert_data["not_yet_valid"] = (
+                float(cert_data["not_before_timestamp"]) - now
+                > self.config.not_yet_valid_grace_seconds
+            )
+
+    def _annotate_silence(self, cert_data: Dict[str, Any]) -> None:
+        """Mark a certificate as silenced if an active silence matches it."""
+        silence = self.silences.find_silence(cert_data)
+        cert_data["silenced"] = silence is not None
+        cert_data["silence_id"] = silence.id if silence else None
+
+    def _find_certificate_files(
+        self,
+        directory: Path,
+        exclude_patterns: Optional[List[str]] = None,
+        excluded: Optional[List[Dict[str, str]]] = None,
+        workers: int = 1,
+    ) -> List[Path]:
         """
         Find all certificate files in a directory.
 
+        Subdirectories are listed concurrently by up to workers threads, so discovery in trees
+        of many directories is not bound to the file system round trips of a single thread.
+
         Args:
             directory: Directory to search
+            exclude_patterns: File name patterns excluded in addition to exclude_file_patterns
+            excluded: If given, receives {path, reason} for each excluded certificate file
+            workers: Directories listed at once
 
         Returns:
-            List of certificate file paths
+            List of certificate file paths, sorted
         """
-        cert_files = []
+        cert_files: List[Path] = []
         exclude_paths = {
             Path(exclude_dir).resolve() for exclude_dir in self.config.exclude_directories
         }
+        patterns = self.config.exclude_file_patterns + (exclude_patterns or [])
 
         try:
-            for root, _, files in os.walk(directory):
-                root_path = Path(root).resolve()
-
-                # Skip excluded directories
-                if any(
-                    root_path == exclude_path or root_path.is_relative_to(exclude_path)
-                    for exclude_path in exclude_paths
-                ):
-                    continue
-
-                for file in files:
-                    file_path = Path(root) / file
-
-                    # Check file extension
-                    if file_path.suffix.lower() in self.SUPPORTED_EXTENSIONS:
-                        # Check if file matches any exclude patterns
-                        exclude_file = False
-                        for pattern in self.config.exclude_file_patterns:
-                            try:
-                                if re.search(pattern, file_path.name, re.IGNORECASE):
-                                    self.logger.debug(
-                                        f"Excluding file {file_path.name} (matches pattern: {pattern})"
-                                    )
-                                    exclude_file = True
-                                    break
-                            except re.error as e:
-                                self.logger.warning(f"Invalid regex pattern '{pattern}': {e}")
-
-                        if not exclude_file:
-                            cert_files.append(file_path)
+            with ThreadPoolExecutor(max_workers=workers, thread_name_prefix="walk") as pool:
+                pending = {pool.submit(self._list_directory, directory, exclude_paths, patterns)}
+                while pending:
+                    done, pending = wait(pending, return_when=FIRST_COMPLETED)
+                    for future in done:
+                        subdirectories, files, excluded_files = future.result()
+                        cert_files.extend(files)
+                        if excluded is not None:
+                            excluded.extend(excluded_files)
+                        pending.update(
+                            pool.submit(self._list_directory, subdirectory, exclude_paths, patterns)
+                            for subdirectory in subdirectories
+                        )
 
         except Exception as e:
             self.logger.error(f"Error walking directory {directory}: {e}")
 
-        return cert_files
+        return sorted(cert_files)
+
+    def _list_directory(
+        self, root: Path, exclude_paths: Set[Path], patterns: List[str]
+    ) -> Tuple[List[Path], List[Path], List[Dict[str, str]]]:
+        """
+        List the subdirectories and certificate files of one directory of a walk.
+
+        Returns:
+            Subdirectories to walk (not symlinks, as os.walk, and none in excluded directories,
+            whose subtrees are pruned), certificate files, and the excluded certificate files
+            with the reason
+        """
+        subdirectories = []
+        names = []
+        try:
+            with os.scandir(root) as entries:
+                for entry in entries:
+                    try:
+                        is_dir = entry.is_dir()
+                    except OSError:
+                        is_dir = False
+                    if not is_dir:
+                        names.append(entry.name)
+                    elif not entry.is_symlink():
+                        subdirectories.append(Path(entry.path))
+        except OSError as e:
+            # Unreadable directories are skipped, as by os.walk
+            self.logger.debug(f"Cannot list directory {root}: {e}")
+            return [], [], []
+
+        cert_files = []
+        excluded: List[Dict[str, str]] = []
+        root_path = root.resolve()
+
+        # Skip excluded directories
+        excluded_by = next(
+            (
+                exclude_path
+                for exclude_path in exclude_paths
+                if root_path == exclude_path or root_path.is_relative_to(exclude_path)
+            ),
+            None,
+        )
+        if excluded_by is not None:
+            excluded.extend(
+                {"path": str(root / name), "reason": f"directory {excluded_by}"}
+                for name in names
+                if Path(name).suffix.lower() in self.SUPPORTED_EXTENSIONS
+            )
+            return [], cert_files, excluded
+
+        for name in names:
+            file_path = root / name
+
+            # Check file extension
+            if file_path.suffix.lower() in self.SUPPORTED_EXTENSIONS:
+                excluded_glob = self.config.excluded_file_glob(file_path)
+                if excluded_glob is not None:
+                    self.logger.debug(f"Excluding file {file_path} (matches glob: {excluded_glob})")
+                    excluded.append({"path": str(file_path), "reason": f"glob {excluded_glob}"})
+                    continue
+
+                # Check if file matches any exclude patterns
+                exclude_file = False
+                for pattern in patterns:
+                    try:
+                        if re.search(pattern, file_path.name, re.IGNORECASE):
+                            self.logger.debug(
+                                f"Excluding file {file_path.name} (matches pattern: {pattern})"
+                            )
+                            exclude_file = True
+                            excluded.append(
+                                {"path": str(file_path), "reason": f"pattern {pattern}"}
+                            )
+                            break
+                    except re.error as e:
+                        self.logger.warning(f"Invalid regex pattern '{pattern}': {e}")
+
+                if not exclude_file:
+                    cert_files.append(file_path)
+
+        return subdirectories, cert_files, excluded
+
+    async def simulate_scan(self) -> Dict[str, Any]:
+        """
+        Walk the configured directories read-only and parse the certificates found.
+
+        Nothing is cached and no metrics or scan listeners are updated, so this can be
+        used to test a configuration (dry-run mode).
+
+        Returns:
+            Per-directory matched files, excluded files, certificates and parse errors
+        """
+        loop = asyncio.get_running_loop()
+        directories: Dict[str, Any] = {}
+
+        for directory in self.config.certificate_directories:
+            directory_path = Path(directory)
+            if self.config.allow_missing_directories and not directory_path.exists():
+                directories[directory] = {"skipped": f"Directory does not exist: {directory}"}
+                continue
+            if not directory_path.is_dir():
+                reason = "does not exist" if not directory_path.exists() else "is not a directory"
+                directories[directory] = {"error": f"Directory {reason}: {directory}"}
+                continue
+
+            settings = self.config.get_directory_config(directory)
+            excluded: List[Dict[str, str]] = []
+            matched = await asyncio.to_thread(
+                self._find_certificate_files,
+                directory_path,
+                settings.excludes,
+                excluded,
+                settings.workers or self.config.workers,
+            )
+
+            certificates = []
+            errors = []
+            for file_path in matched:
+                try:
+                    cert_data = await loop.run_in_executor(
+                        self._executor, self._parse_certificate_file, file_path
+                    )
+                except Exception as e:
+                    errors.append({"path": str(file_path), "error": str(e)})
+                    continue
+
+                if cert_data is None:
+                    errors.append({"path": str(file_path), "error": "No certificate found"})
+                    continue
+
+                cert_result = dict(cert_data)
+                self._annotate_severity(cert_result)
+                self._annotate_silence(cert_result)
+                certificates.append(cert_result)
+
+            directories[directory] = {
+                "matched": [str(file_path) for file_path in matched],
+                "excluded": excluded,
+                "certificates": certificates,
+                "errors": errors,
+            }
+
+        return {"directories": directories, "timestamp": time.time()}
 
     async def _process_certificate_file(
-        self, file_path: Path, semaphore: asyncio.Semaphore
+        self, file_path: Path, semaphore: Union[asyncio.Semaphore, DirectorySlots]
     ) -> Optional[Dict[str, Any]]:
         """
         Process a single certificate file.
 
         Args:
             file_path: Path to certificate file
-            semaphore: Semaphore for concurrency control
+            semaphore: Semaphore for concurrency control (or the slots of the directory)
 
         Returns:
             Certificate data or None if failed
         """
         async with semaphore:
-            # Check cache first
-            cache_key = self.cache.make_key("cert", str(file_path), file_path.stat().st_mtime)
-            cached_result = await self.cache.get(cache_key)
-
-            if cached_result is not None:
-                return cached_result  # type: ignore[no-any-return]
+            with start_file_span(str(file_path)) as span:
+                # Check cache first
+                cache_key = self._file_cache_key(file_path)
+                previous_key = self._file_cache_keys.get(str(file_path))
+                if previous_key is not None and previous_key != cache_key:
+                    # The file changed: its old entry can never be hit again
+                    await self.cache.delete(previous_key)
+                self._file_cache_keys[str(file_path)] = cache_key
+                cached_result = await self.cache.get(cache_key)
+
+                if span is not None:
+                    span.set_attribute("cache.hit", cached_result is not None)
+                if cached_result is not None:
+                    return cached_result  # type: ignore[no-any-return]
+
+                # Process in thread pool
+                try:
+                    loop = asyncio.get_event_loop()
+                    self._files_in_progress[str(file_path)] = time.time()
+                    result = await loop.run_in_executor(
+                        self._executor, self._parse_certificate_file, file_path
+                    )
 
-            # Process in thread pool
-            try:
-                loop = asyncio.get_event_loop()
-                result = await loop.run_in_executor(
-                    self._executor, self._parse_certificate_file, file_path
-                )
+                    if result:
+                        # Cache successful result
+                        await self.cache.set(
+                            cache_key, result, ttl=self.config.cache_ttl_seconds_for("cert")
+                        )
 
-                if result:
-                    # Cache successful result
-                    await self.cache.set(cache_key, result)
+                    return result
 
-                return result
+                except Exception as e:
+                    error_type = type(e).__name__
+                    if span is not None:
+                        span.set_attribute("error.type", error_type)
+                    self.metrics.record_parse_error(file_path.name, error_type, str(e))
+                    log_cert_error(self.logger, str(file_path), e, error_type)
+                    return None
+                finally:
+                    self._files_in_progress.pop(str(file_path), None)
+
+    def _file_cache_key(self, file_path: Path) -> str:
+        """
+        Build the cache key of a certificate file version.
 
-            except Exception as e:
-                error_type = type(e).__name__
-                self.metrics.record_parse_error(file_path.name, error_type, str(e))
-                log_cert_error(self.logger, str(file_path), e, error_type)
-                return None
+        Besides the path, the key covers modification time, change time, size and inode,
+        so a file replaced in place (even with its mtime preserved, e.g. cp -p or rsync -t)
+        is always reparsed instead of being served from the cache.
+        """
+        stat = file_path.stat()
+        return self.cache.make_key(
+            "cert",
+            str(file_path),
+            stat.st_mtime_ns,
+            stat.st_ctime_ns,
+            stat.st_size,
+            stat.st_ino,
+            source="file",
+        )
 
     def _parse_certificate_file(self, file_path: Path) -> Optional[Dict[str, Any]]:
         """
@@ -376,22 +1094,66 @@ class CertificateScanner:
             raise RuntimeError(f"Failed to parse {file_path}: {e}") from e
 
     def _parse_pem_der_file(self, file_path: Path) -> Optional[Dict[str, Any]]:
-        """Parse PEM or DER certificate file."""
+        """
+        Parse PEM or DER certificate file.
+
+        Like chains, files of several certificates (PEM blocks or concatenated DER) report
+        their first certificate; text around PEM blocks and blocks that are not certificates
+        (keys) are ignored.
+        """
         with open(file_path, "rb") as f:
             cert_data = f.read()
 
         # Try PEM first
-        try:
-            cert = x509.load_pem_x509_certificate(cert_data)
-        except ValueError:
-            # Try DER
+        blocks = PEM_CERTIFICATE_PATTERN.findall(cert_data)
+        if blocks:
             try:
-                cert = x509.load_der_x509_certificate(cert_data)
+                cert = x509.load_pem_x509_certificate(blocks[0])
             except ValueError as e:
-                raise ValueError(f"Could not parse as PEM or DER: {e}") from e
+                raise ValueError(f"Could not parse PEM certificate: {e}") from e
+            return self._extract_certificate_info(cert)
+
+        # Try DER, one certificate or several concatenated
+        certificates = self._split_der_certificates(cert_data)
+        try:
+            cert = x509.load_der_x509_certificate(certificates[0] if certificates else cert_data)
+        except ValueError as e:
+            raise ValueError(f"Could not parse as PEM or DER: {e}") from e
+        if len(certificates) > 1:
+            self.logger.debug(f"{file_path} holds {len(certificates)} DER certificates")
 
         return self._extract_certificate_info(cert)
 
+    @staticmethod
+    def _split_der_certificates(data: bytes) -> List[bytes]:
+        """
+        Split concatenated DER certificates by their ASN.1 SEQUENCE headers.
+
+        Returns:
+            The certificates, up to trailing data that is not one (a final newline); none if
+            the data does not start with a DER SEQUENCE
+        """
+        certificates = []
+        offset = 0
+        while offset < len(data):
+            if data[offset] != 0x30 or offset + 2 > len(data):
+                break
+            length = data[offset + 1]
+            header = 2
+            if length & 0x80:
+                # Long form: the next 1-4 bytes are the length (0x80 is BER's indefinite form)
+                size = length & 0x7F
+                if not 1 <= size <= 4 or offset + 2 + size > len(data):
+                    break
+                length = int.from_bytes(data[offset + 2 : offset + 2 + size], "big")
+                header += size
+            end = offset + header + length
+            if end > len(data):
+                break
+            certificates.append(data[offset:end])
+            offset = end
+        return certificates
+
     def _parse_pkcs12_file(self, file_path: Path) -> Optional[Dict[str, Any]]:
         """Parse PKCS#12/PFX certificate file."""
         with open(file_path, "rb") as f:
@@ -443,6 +1205,7 @@ class CertificateScanner:
         issuer = self._get_issuer_name(cert)
         subject = cert.subject.rfc4514_string()
         serial = str(cert.serial_number)
+        fingerprint = cert.fingerprint(hashes.SHA256()).hex()
 
         # Dates
         not_before = cert.not_valid_before_utc
@@ -478,8 +1241,10 @@ class CertificateScanner:
             "issuer": issuer,
             "subject": subject,
             "serial": serial,
+            "fingerprint_sha256": fingerprint,
             "not_before": not_before.isoformat(),
             "not_after": not_after.isoformat(),
+            "not_before_timestamp": not_before.timestamp(),
             "expiration_timestamp": expiration_timestamp,
             "days_until_expiry": days_until_expiry,
             "key_size": key_size,
@@ -540,10 +1305,112 @@ class CertificateScanner:
             self.logger.warning(f"Could not get disk usage for {directory}: {e}")
             return {"total": 0, "used": 0, "free": 0}
 
+    def _parse_error_ratio(self) -> Optional[float]:
+        """
+        Share of the files of the last scan that failed to parse, in the directories whose
+        parse errors degrade health (None before a scan or if no directory's do).
+        """
+        if self.last_scan_results is None:
+            return None
+        counted = [
+            result
+            for directory, result in self.last_scan_results["directories"].items()
+            if self.config.parse_errors_degrade_health(directory)
+        ]
+        if not counted:
+            return None
+        total_files = sum(result["files_processed"] for result in counted)
+        total_errors = sum(result["parse_errors"] for result in counted)
+        if not total_files:
+            # Only directory errors: nothing was parsed
+            return 1.0 if total_errors else 0.0
+        return round(min(total_errors / total_files, 1.0), 3)
+
+    def _scan_progress(self) -> Optional[Dict[str, Any]]:
+        """Progress of the running scan (None if no scan is running)."""
+        if self._scan_lock is None or not self._scan_lock.locked() or not self._current_scan:
+            return None
+        now = time.time()
+        progress = {
+            **self._current_scan,
+            "running_seconds": round(now - self._current_scan["started_at"], 1),
+        }
+        if self._files_in_progress:
+            # The file parsed the longest is the likeliest to be stuck
+            path, started = min(self._files_in_progress.items(), key=lambda item: item[1])
+            progress["oldest_file"] = path
+            progress["oldest_file_seconds"] = round(now - started, 1)
+        return progress
+
+    def _first_scan_event(self) -> asyncio.Event:
+        """Get the event set once the first scan completed."""
+        if self._first_scan_done is None:
+            self._first_scan_done = asyncio.Event()
+            if self.first_scan_completed_at is not None:
+                self._first_scan_done.set()
+        return self._first_scan_done
+
+    async def wait_for_first_scan(self, timeout: float) -> bool:
+        """
+        Wait until the first scan completed.
+
+        Args:
+            timeout: Time to wait at most, in seconds
+
+        Returns:
+            True if the first scan completed, False on timeout
+        """
+        try:
+            await asyncio.wait_for(self._first_scan_event().wait(), timeout)
+            return True
+        except asyncio.TimeoutError:
+            return False
+
+    def get_readiness(self) -> Dict[str, Any]:
+        """
+        Get readiness: ready once metrics hold the results of a scan.
+
+        Until the first scan completes, those of the scan before a restart (restored, stale)
+        count.
+        """
+        last_scan_age = (
+            round(time.time() - self.last_scan_completed_at, 1)
+            if self.last_scan_completed_at is not None
+            else None
+        )
+        stale = self.first_scan_completed_at is None and self.restored_scan_at is not None
+        return {
+            "ready": self.first_scan_completed_at is not None or stale,
+            "stale": stale,
+            "first_scan_completed_at": self.first_scan_completed_at,
+            "last_scan_age_seconds": last_scan_age,
+            "scan_running": bool(self._scan_lock and self._scan_lock.locked()),
+        }
+
     async def get_health_status(self) -> Dict[str, Any]:
         """Get scanner health status."""
+        directory_results = (self.last_scan_results or {}).get("directories", {})
+        unsilenced = count_certificates_by_severity(directory_results, include_silenced=False)
         return {
             "cert_scan_status": "running" if self._scanning else "stopped",
+            "scans_completed": self.scans_completed,
+            "last_scan_age_seconds": self.get_readiness()["last_scan_age_seconds"],
+            "current_scan": self._scan_progress(),
+            "average_scan_duration": (
+                round(sum(self._scan_durations) / len(self._scan_durations), 1)
+                if self._scan_durations
+                else None
+            ),
+            "parse_error_ratio": self._parse_error_ratio(),
             "certificate_directories": self.config.certificate_directories,
             "worker_pool_size": self.config.workers,
+            "expiry_thresholds": {
+                "warning": self.config.expiry_thresholds.warning,
+                "critical": self.config.expiry_thresholds.critical,
+            },
+            "certificates_by_severity": count_certificates_by_severity(directory_results),
+            # Certificates that need attention, excluding silenced ones
+            "expiring_certificates": {
+                severity: unsilenced[severity] for severity in ("expired", "critical", "warning")
+            },
         }
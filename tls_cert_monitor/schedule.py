@@ -0,0 +1,126 @@
+"""
+Cron-like schedules for TLS Certificate Monitor.
+"""
+
+from datetime import datetime, timedelta
+from typing import List, Set, Tuple
+
+# Field name, minimum, maximum
+_FIELDS: List[Tuple[str, int, int]] = [
+    ("minute", 0, 59),
+    ("hour", 0, 23),
+    ("day", 1, 31),
+    ("month", 1, 12),
+    ("weekday", 0, 7),  # 0 and 7 are both Sunday
+]
+
+_ALIASES = {
+    "@hourly": "0 * * * *",
+    "@daily": "0 0 * * *",
+    "@midnight": "0 0 * * *",
+    "@weekly": "0 0 * * 0",
+    "@monthly": "0 0 1 * *",
+}
+
+
+def _parse_field(expression: str, name: str, minimum: int, maximum: int) -> Set[int]:
+    """Parse a single cron field (e.g. '*', '*/15', '1-5', '0,30')."""
+    values: Set[int] = set()
+
+    for part in expression.split(","):
+        step = 1
+        if "/" in part:
+            part, step_str = part.split("/", 1)
+            if not step_str.isdigit() or int(step_str) == 0:
+                raise ValueError(f"Invalid step in cron {name} field: '{expression}'")
+            step = int(step_str)
+
+        if part == "*":
+            start, end = minimum, maximum
+        elif "-" in part:
+            start_str, end_str = part.split("-", 1)
+            if not start_str.isdigit() or not end_str.isdigit():
+                raise ValueError(f"Invalid range in cron {name} field: '{expression}'")
+            start, end = int(start_str), int(end_str)
+        elif part.isdigit():
+            start = int(part)
+            end = maximum if step > 1 else start
+        else:
+            raise ValueError(f"Invalid cron {name} field: '{expression}'")
+
+        if start < minimum or end > maximum or start > end:
+            raise ValueError(
+                f"Cron {name} field out of range ({minimum}-{maximum}): '{expression}'"
+            )
+
+        values.update(range(start, end + 1, step))
+
+    return values
+
+
+class CronSchedule:
+    """
+    Standard five-field cron schedule (minute hour day-of-month month day-of-week).
+
+    As in cron, when both day-of-month and day-of-week are restricted a time
+    matches if either of them matches.
+    """
+
+    def __init__(self, expression: str):
+        self.expression = expression.strip()
+        fields = _ALIASES.get(self.expression.lower(), self.expression).split()
+
+        if len(fields) != len(_FIELDS):
+            raise ValueError(
+                f"Cron schedule must have 5 fields or be one of {sorted(_ALIASES)}, "
+                f"got '{expression}'"
+            )
+
+        parsed = [
+            _parse_field(field, name, minimum, maximum)
+            for field, (name, minimum, maximum) in zip(fields, _FIELDS)
+        ]
+        self.minutes, self.hours, self.days, self.months, weekdays = parsed
+        # Normalize Sunday (7 -> 0) to match datetime.isoweekday() % 7
+        self.weekdays = {day % 7 for day in weekdays}
+        self._day_restricted = fields[2] != "*"
+        self._weekday_restricted = fields[4] != "*"
+
+    def _matches_day(self, moment: datetime) -> bool:
+        day_match = moment.day in self.days
+        weekday_match = moment.isoweekday() % 7 in self.weekdays
+
+        if self._day_restricted and self._weekday_restricted:
+            return day_match or weekday_match
+        return day_match and weekday_match
+
+    def next_after(self, moment: datetime) -> datetime:
+        """
+        Get the next time strictly after the given moment that matches the schedule.
+
+        Args:
+            moment: Reference time (naive or timezone-aware)
+
+        Returns:
+            Next matching time, truncated to the minute
+        """
+        candidate = moment.replace(second=0, microsecond=0) + timedelta(minutes=1)
+        # Five years covers every valid expression, including Feb 29
+        limit = candidate + timedelta(days=366 * 5)
+
+        while candidate < limit:
+            if candidate.month not in self.months or not self._matches_day(candidate):
+                candidate = candidate.replace(hour=0, minute=0) + timedelta(days=1)
+                continue
+            if candidate.hour not in self.hours:
+                candidate = candidate.replace(minute=0) + timedelta(hours=1)
+                continue
+            if candidate.minute not in self.minutes:
+                candidate += timedelta(minutes=1)
+                continue
+            return candidate
+
+        raise ValueError(f"Cron schedule '{self.expression}' never matches")
+
+    def __repr__(self) -> str:
+        return f"CronSchedule({self.expression!r})"
@@ -0,0 +1,168 @@
+"""
+Silences and maintenance windows for TLS Certificate Monitor.
+"""
+
+import fnmatch
+import hashlib
+import uuid
+from datetime import datetime, timezone
+from typing import Any, Dict, List, Optional
+
+from tls_cert_monitor.config import SILENCE_LABEL_PREFIX, Config, SilenceConfig
+from tls_cert_monitor.logger import get_logger
+
+
+def _as_utc(value: Optional[datetime]) -> Optional[datetime]:
+    """Treat naive datetimes from config as UTC."""
+    if value is None:
+        return None
+    if value.tzinfo is None:
+        return value.replace(tzinfo=timezone.utc)
+    return value
+
+
+class Silence:
+    """A single silence with a time window and certificate field matchers."""
+
+    def __init__(self, silence_config: SilenceConfig, source: str = "config"):
+        self.id = silence_config.id or self._derive_id(silence_config)
+        self.matchers = dict(silence_config.matchers)
+        self.starts_at = _as_utc(silence_config.starts_at)
+        self.ends_at = _as_utc(silence_config.ends_at)
+        self.comment = silence_config.comment
+        self.created_by = silence_config.created_by
+        self.source = source
+
+    @staticmethod
+    def _derive_id(silence_config: SilenceConfig) -> str:
+        """Derive a stable ID for config silences so they survive reloads unchanged."""
+        key_data = silence_config.model_dump_json(exclude={"id"}).encode("utf-8")
+        return f"cfg-{hashlib.sha256(key_data).hexdigest()[:12]}"
+
+    def is_active(self, now: Optional[datetime] = None) -> bool:
+        """Check if the silence window covers the given time."""
+        now = now or datetime.now(timezone.utc)
+        if self.starts_at and now < self.starts_at:
+            return False
+        if self.ends_at and now >= self.ends_at:
+            return False
+        return True
+
+    def is_expired(self, now: Optional[datetime] = None) -> bool:
+        """Check if the silence window has already ended."""
+        now = now or datetime.now(timezone.utc)
+        return self.ends_at is not None and now >= self.ends_at
+
+    def matches(self, cert_data: Dict[str, Any]) -> bool:
+        """
+        Check if all matchers match the certificate (case-insensitive globs).
+
+        labels.<name> matchers match the directory and group labels of the certificate.
+        """
+        for field, pattern in self.matchers.items():
+            if field.startswith(SILENCE_LABEL_PREFIX):
+                value = (cert_data.get("labels") or {}).get(field[len(SILENCE_LABEL_PREFIX) :])
+            else:
+                value = cert_data.get(field)
+            if value is None:
+                return False
+            if not fnmatch.fnmatchcase(str(value).lower(), pattern.lower()):
+                return False
+        return True
+
+    def to_dict(self) -> Dict[str, Any]:
+        """Serialize silence for API responses."""
+        return {
+            "id": self.id,
+            "matchers": self.matchers,
+            "starts_at": self.starts_at.isoformat() if self.starts_at else None,
+            "ends_at": self.ends_at.isoformat() if self.ends_at else None,
+            "comment": self.comment,
+            "created_by": self.created_by,
+            "source": self.source,
+            "active": self.is_active(),
+        }
+
+
+class SilenceManager:
+    """
+    Manager for silences defined in configuration or created through the API.
+
+    Config silences are replaced on every config reload; API silences are kept
+    in memory until they are deleted or their window ends.
+    """
+
+    def __init__(self, config: Config):
+        self.logger = get_logger("silences")
+        self._config_silences: Dict[str, Silence] = {}
+        self._api_silences: Dict[str, Silence] = {}
+        self.load_config_silences(config.silences)
+
+    def load_config_silences(self, silence_configs: List[SilenceConfig]) -> None:
+        """Replace silences defined in configuration."""
+        self._config_silences = {}
+        for silence_config in silence_configs:
+            silence = Silence(silence_config, source="config")
+            self._config_silences[silence.id] = silence
+
+        self.logger.info(f"Loaded {len(self._config_silences)} silence(s) from configuration")
+
+    def add_silence(self, silence_config: SilenceConfig) -> Silence:
+        """Add a silence created at runtime."""
+        if not silence_config.id:
+            silence_config = silence_config.model_copy(update={"id": uuid.uuid4().hex})
+
+        silence = Silence(silence_config, source="api")
+        self._api_silences[silence.id] = silence
+        self.logger.info(
+            f"Silence {silence.id} created by {silence.created_by or 'unknown'}: {silence.matchers}"
+        )
+        return silence
+
+    def remove_silence(self, silence_id: str) -> bool:
+        """
+        Remove a runtime silence.
+
+        Config silences can only be removed by editing the configuration file.
+
+        Returns:
+            True if the silence was removed, False if not found
+        """
+        if silence_id in self._api_silences:
+            del self._api_silences[silence_id]
+            self.logger.info(f"Silence {silence_id} removed")
+            return True
+        return False
+
+    def get_silence(self, silence_id: str) -> Optional[Silence]:
+        """Get a silence by ID."""
+        return self._api_silences.get(silence_id) or self._config_silences.get(silence_id)
+
+    def list_silences(self) -> List[Silence]:
+        """List all silences, pruning expired runtime silences first."""
+        self._prune_expired()
+        return list(self._config_silences.values()) + list(self._api_silences.values())
+
+    def find_silence(self, cert_data: Dict[str, Any]) -> Optional[Silence]:
+        """
+        Find the first active silence matching a certificate.
+
+        Args:
+            cert_data: Certificate data dictionary
+
+        Returns:
+            Matching silence or None
+        """
+        now = datetime.now(timezone.utc)
+        for silence in self.list_silences():
+            if silence.is_active(now) and silence.matches(cert_data):
+                return silence
+        return None
+
+    def _prune_expired(self) -> None:
+        """Drop runtime silences whose window has ended."""
+        now = datetime.now(timezone.utc)
+        expired = [sid for sid, s in self._api_silences.items() if s.is_expired(now)]
+        for silence_id in expired:
+            del self._api_silences[silence_id]
+            self.logger.info(f"Silence {silence_id} expired")
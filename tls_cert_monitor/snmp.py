@@ -0,0 +1,325 @@
+"""
+Read-only SNMP agent for TLS Certificate Monitor.
+
+Answers SNMPv1 and SNMPv2c Get, GetNext and GetBulk requests for summary objects of the last
+scan, so network management systems that cannot scrape Prometheus can poll the monitor. The
+objects live below a configurable base OID:
+
+    <base>.1.0  certificates      Gauge32    Certificates found (silenced ones not counted)
+    <base>.2.0  ok                Gauge32    ... by severity
+    <base>.3.0  warning           Gauge32
+    <base>.4.0  critical          Gauge32
+    <base>.5.0  expired           Gauge32
+    <base>.6.0  parseErrors       Gauge32    Files that failed to parse in the last scan
+    <base>.7.0  minDaysLeft       INTEGER    Days until the soonest expiry (0 before a scan)
+    <base>.8.0  lastScan          Gauge32    Unix time of the last completed scan (0: none yet)
+    <base>.9.0  scansCompleted    Counter32  Scans completed since startup
+"""
+
+import asyncio
+from typing import Any, Callable, Dict, List, Optional, Tuple
+
+from tls_cert_monitor.config import Config
+from tls_cert_monitor.logger import get_logger
+from tls_cert_monitor.metrics import count_certificates_by_severity
+from tls_cert_monitor.scanner import CertificateScanner
+
+Oid = Tuple[int, ...]
+
+# BER tags
+INTEGER = 0x02
+OCTET_STRING = 0x04
+NULL = 0x05
+OBJECT_IDENTIFIER = 0x06
+SEQUENCE = 0x30
+COUNTER32 = 0x41
+GAUGE32 = 0x42
+
+# PDU tags
+GET_REQUEST = 0xA0
+GET_NEXT_REQUEST = 0xA1
+GET_RESPONSE = 0xA2
+GET_BULK_REQUEST = 0xA5
+
+# SNMPv2c exceptions in variable bindings
+NO_SUCH_OBJECT = 0x80
+END_OF_MIB_VIEW = 0x82
+
+# Message versions
+VERSION_1 = 0
+VERSION_2C = 1
+
+# SNMPv1 error-status of a missing object
+NO_SUCH_NAME = 2
+
+# Most variable bindings returned for a GetBulk request
+MAX_BULK_BINDINGS = 64
+
+# Objects below the base OID: (sub-identifier, name, type)
+OBJECTS = [
+    (1, "certificates", GAUGE32),
+    (2, "ok", GAUGE32),
+    (3, "warning", GAUGE32),
+    (4, "critical", GAUGE32),
+    (5, "expired", GAUGE32),
+    (6, "parseErrors", GAUGE32),
+    (7, "minDaysLeft", INTEGER),
+    (8, "lastScan", GAUGE32),
+    (9, "scansCompleted", COUNTER32),
+]
+
+
+def parse_oid(text: str) -> Oid:
+    """Parse a dotted OID (e.g. 1.3.6.1.4.1.8072.9999.9999)."""
+    return tuple(int(arc) for arc in text.strip(".").split("."))
+
+
+def encode_length(length: int) -> bytes:
+    """Encode a BER length."""
+    if length < 0x80:
+        return bytes([length])
+    encoded = length.to_bytes((length.bit_length() + 7) // 8, "big")
+    return bytes([0x80 | len(encoded)]) + encoded
+
+
+def encode_tlv(tag: int, value: bytes) -> bytes:
+    """Encode a BER tag, length and value."""
+    return bytes([tag]) + encode_length(len(value)) + value
+
+
+def encode_integer(value: int, tag: int = INTEGER) -> bytes:
+    """Encode a signed INTEGER, or an unsigned application type (Counter32, Gauge32)."""
+    size = max(1, (value.bit_length() + 8) // 8)
+    return encode_tlv(tag, value.to_bytes(size, "big", signed=value < 0 or tag == INTEGER))
+
+
+def encode_oid(oid: Oid) -> bytes:
+    """Encode an OBJECT IDENTIFIER."""
+    encoded = bytearray([40 * oid[0] + oid[1]])
+    for arc in oid[2:]:
+        chunk = [arc & 0x7F]
+        arc >>= 7
+        while arc:
+            chunk.append(0x80 | (arc & 0x7F))
+            arc >>= 7
+        encoded.extend(reversed(chunk))
+    return encode_tlv(OBJECT_IDENTIFIER, bytes(encoded))
+
+
+def decode_tlv(data: bytes, offset: int = 0) -> Tuple[int, bytes, int]:
+    """
+    Decode a BER tag, length and value.
+
+    Returns:
+        Tuple of (tag, value, offset after the value)
+
+    Raises:
+        ValueError: If the data is truncated or uses an indefinite length
+    """
+    if offset + 2 > len(data):
+        raise ValueError("Truncated BER data")
+    tag = data[offset]
+    length = data[offset + 1]
+    offset += 2
+    if length & 0x80:
+        size = length & 0x7F
+        if not 0 < size <= 4 or offset + size > len(data):
+            raise ValueError("Invalid BER length")
+        length = int.from_bytes(data[offset : offset + size], "big")
+        offset += size
+    if offset + length > len(data):
+        raise ValueError("Truncated BER data")
+    return tag, data[offset : offset + length], offset + length
+
+
+def decode_sequence(data: bytes) -> List[Tuple[int, bytes]]:
+    """Decode the (tag, value) elements of a constructed value."""
+    elements = []
+    offset = 0
+    while offset < len(data):
+        tag, value, offset = decode_tlv(data, offset)
+        elements.append((tag, value))
+    return elements
+
+
+def decode_oid(value: bytes) -> Oid:
+    """Decode the value of an OBJECT IDENTIFIER."""
+    if not value:
+        raise ValueError("Empty OID")
+    arcs = list(divmod(value[0], 40)) if value[0] < 80 else [2, value[0] - 80]
+    arc = 0
+    for byte in value[1:]:
+        arc = (arc << 7) | (byte & 0x7F)
+        if not byte & 0x80:
+            arcs.append(arc)
+            arc = 0
+    return tuple(arcs)
+
+
+class SnmpAgent:
+    """Answer SNMP requests for summary objects of the last scan."""
+
+    def __init__(self, config: Config, scanner: CertificateScanner):
+        self.config = config.snmp
+        self.scanner = scanner
+        self.base_oid = parse_oid(config.snmp.base_oid)
+        self.logger = get_logger("snmp")
+        self._transport: Optional[asyncio.DatagramTransport] = None
+
+        # Sorted for GetNext: (OID, type, value getter)
+        self.objects: List[Tuple[Oid, int, Callable[[Dict[str, int]], int]]] = [
+            (self.base_oid + (sub_id, 0), tag, lambda values, name=name: values[name])
+            for sub_id, name, tag in OBJECTS
+        ]
+
+    async def start(self) -> None:
+        """Start listening for requests."""
+        if self._transport:
+            return
+        loop = asyncio.get_running_loop()
+        try:
+            self._transport, _ = await loop.create_datagram_endpoint(
+                lambda: _AgentProtocol(self),
+                local_addr=(self.config.bind_address, self.config.port),
+            )
+        except OSError as e:
+            # Metrics are still served; the agent is an optional extra
+            self.logger.error(
+                f"Failed to start SNMP agent on {self.config.bind_address}:{self.config.port}: {e}"
+            )
+            return
+        self.logger.info(
+            f"SNMP agent listening on {self.config.bind_address}:{self.config.port}/udp "
+            f"(base OID {self.config.base_oid})"
+        )
+
+    async def stop(self) -> None:
+        """Stop listening for requests."""
+        if self._transport:
+            self._transport.close()
+            self._transport = None
+            self.logger.info("SNMP agent stopped")
+
+    def values(self) -> Dict[str, int]:
+        """Get the values of the objects from the last scan."""
+        results = self.scanner.last_scan_results or {"directories": {}, "summary": {}}
+        directories = results.get("directories", {})
+        counts = count_certificates_by_severity(directories, include_silenced=False)
+        days = [
+            cert.get("days_until_expiry", 0)
+            for result in directories.values()
+            for cert in result.get("certificates", [])
+            if not cert.get("silenced")
+        ]
+        return {
+            "certificates": sum(counts.values()),
+            **counts,
+            "parseErrors": results.get("summary", {}).get("total_errors", 0),
+            "minDaysLeft": min(days, default=0),
+            "lastScan": int(self.scanner.last_scan_completed_at or 0),
+            "scansCompleted": self.scanner.scans_completed & 0xFFFFFFFF,
+        }
+
+    def handle(self, data: bytes) -> Optional[bytes]:
+        """
+        Answer a request message.
+
+        Args:
+            data: Received SNMP message
+
+        Returns:
+            Response message, or None for messages that are dropped (malformed, wrong
+            community, unsupported version or PDU)
+        """
+        try:
+            tag, message, _ = decode_tlv(data)
+            if tag != SEQUENCE:
+                return None
+            (_, version), (_, community), (pdu_tag, pdu) = decode_sequence(message)[:3]
+            version_number = int.from_bytes(version, "big")
+            if version_number not in (VERSION_1, VERSION_2C):
+                return None
+            if community.decode("utf-8", "replace") != self.config.community:
+                self.logger.debug("Dropped SNMP request with a wrong community")
+                return None
+            if pdu_tag == GET_BULK_REQUEST and version_number == VERSION_1:
+                return None
+            if pdu_tag not in (GET_REQUEST, GET_NEXT_REQUEST, GET_BULK_REQUEST):
+                return None
+            (_, request_id), (_, field1), (_, field2), (_, bindings) = decode_sequence(pdu)[:4]
+            oids = [decode_oid(decode_sequence(b)[0][1]) for _, b in decode_sequence(bindings)]
+        except (ValueError, IndexError):
+            self.logger.debug("Dropped malformed SNMP request")
+            return None
+
+        values = self.values()
+        if pdu_tag == GET_BULK_REQUEST:
+            responses = self._get_bulk(
+                oids, int.from_bytes(field1, "big"), int.from_bytes(field2, "big"), values
+            )
+        else:
+            responses = [self._lookup(oid, pdu_tag == GET_NEXT_REQUEST, values) for oid in oids]
+
+        error_status = error_index = 0
+        if version_number == VERSION_1:
+            # SNMPv1 has no exceptions: report the first missing object, echoing the request
+            missing = [i for i, (_, value) in enumerate(responses) if value[0] >= NO_SUCH_OBJECT]
+            if missing:
+                error_status, error_index = NO_SUCH_NAME, missing[0] + 1
+                responses = [(oid, encode_tlv(NULL, b"")) for oid in oids]
+
+        encoded_bindings = b"".join(
+            encode_tlv(SEQUENCE, encode_oid(oid) + value) for oid, value in responses
+        )
+        response_pdu = encode_tlv(
+            GET_RESPONSE,
+            encode_tlv(INTEGER, request_id)
+            + encode_integer(error_status)
+            + encode_integer(error_index)
+            + encode_tlv(SEQUENCE, encoded_bindings),
+        )
+        return encode_tlv(
+            SEQUENCE,
+            encode_integer(version_number)
+            + encode_tlv(OCTET_STRING, community)
+            + response_pdu,
+        )
+
+    def _lookup(self, oid: Oid, next_object: bool, values: Dict[str, int]) -> Tuple[Oid, bytes]:
+        """Get an object (or the one after it for GetNext) as (OID, encoded value)."""
+        for object_oid, tag, getter in self.objects:
+            if (object_oid > oid) if next_object else (object_oid == oid):
+                return object_oid, encode_integer(getter(values), tag)
+        return oid, encode_tlv(END_OF_MIB_VIEW if next_object else NO_SUCH_OBJECT, b"")
+
+    def _get_bulk(
+        self, oids: List[Oid], non_repeaters: int, max_repetitions: int, values: Dict[str, int]
+    ) -> List[Tuple[Oid, bytes]]:
+        """Answer a GetBulk request: GetNext of the non-repeaters, then repeated GetNext."""
+        responses = [self._lookup(oid, True, values) for oid in oids[:non_repeaters]]
+        repeaters = oids[non_repeaters:]
+        for _ in range(max_repetitions):
+            if not repeaters or len(responses) + len(repeaters) > MAX_BULK_BINDINGS:
+                break
+            step = [self._lookup(oid, True, values) for oid in repeaters]
+            responses.extend(step)
+            if all(value[0] == END_OF_MIB_VIEW for _, value in step):
+                break
+            repeaters = [oid for oid, _ in step]
+        return responses
+
+
+class _AgentProtocol(asyncio.DatagramProtocol):
+    """Datagram protocol passing requests to the agent."""
+
+    def __init__(self, agent: SnmpAgent):
+        self.agent = agent
+        self.transport: Optional[asyncio.DatagramTransport] = None
+
+    def connection_made(self, transport: Any) -> None:
+        self.transport = transport
+
+    def datagram_received(self, data: bytes, addr: Tuple[str, int]) -> None:
+        response = self.agent.handle(data)
+        if response and self.transport:
+            self.transport.sendto(response, addr)
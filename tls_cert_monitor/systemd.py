@@ -0,0 +1,123 @@
+"""
+systemd service notifications for TLS Certificate Monitor.
+
+Under a Type=notify unit, READY=1 is sent once the first scan completed and the server listens,
+so units ordered after the monitor start when its metrics are real. With WatchdogSec= set,
+WATCHDOG=1 is sent from the event loop at half the watchdog interval: a hung monitor stops
+pinging and is restarted by systemd. Outside systemd (no NOTIFY_SOCKET) nothing is sent.
+"""
+
+import asyncio
+import os
+import socket
+from typing import Callable, List, Optional
+
+from tls_cert_monitor.logger import get_logger
+from tls_cert_monitor.scanner import CertificateScanner
+
+# Seconds between checks whether the server listens, once the first scan completed
+READY_POLL_SECONDS = 0.1
+
+# Seconds to wait for the first scan between STATUS= updates
+FIRST_SCAN_WAIT_SECONDS = 60
+
+
+def notify(state: str) -> bool:
+    """
+    Send a state to the service manager (sd_notify), e.g. READY=1.
+
+    Args:
+        state: Newline-separated assignments
+
+    Returns:
+        True if the state was sent, False outside systemd or if sending failed
+    """
+    address = os.environ.get("NOTIFY_SOCKET")
+    if not address or not hasattr(socket, "AF_UNIX"):
+        return False
+    if address.startswith("@"):
+        # Abstract namespace socket
+        address = "\0" + address[1:]
+    try:
+        with socket.socket(socket.AF_UNIX, socket.SOCK_DGRAM) as sock:
+            # Never block the event loop on a service manager that is not reading
+            sock.setblocking(False)
+            sock.sendto(state.encode("utf-8"), address)
+        return True
+    except OSError as e:
+        get_logger("systemd").debug(f"Failed to notify systemd ({state!r}): {e}")
+        return False
+
+
+def watchdog_interval() -> Optional[float]:
+    """
+    Get the seconds between watchdog pings: half the unit's WatchdogSec=.
+
+    Returns:
+        Seconds between pings, or None if the watchdog is off or meant for another process
+    """
+    usec = os.environ.get("WATCHDOG_USEC")
+    pid = os.environ.get("WATCHDOG_PID")
+    # The onefile binary runs the monitor in a child of the process systemd started
+    if not usec or (pid and pid not in (str(os.getpid()), str(os.getppid()))):
+        return None
+    try:
+        seconds = int(usec) / 1_000_000
+    except ValueError:
+        return None
+    return seconds / 2 if seconds > 0 else None
+
+
+class SystemdNotifier:
+    """Notify systemd of readiness and ping its watchdog."""
+
+    def __init__(self, scanner: CertificateScanner, server_started: Callable[[], bool]):
+        self.scanner = scanner
+        self.server_started = server_started
+        self.logger = get_logger("systemd")
+        self._tasks: List[asyncio.Task] = []
+
+    @property
+    def enabled(self) -> bool:
+        """Whether the monitor runs under a service manager listening for notifications."""
+        return bool(os.environ.get("NOTIFY_SOCKET"))
+
+    async def start(self) -> None:
+        """Start waiting for readiness and pinging the watchdog."""
+        if not self.enabled:
+            return
+        self._tasks.append(asyncio.create_task(self._notify_ready()))
+        interval = watchdog_interval()
+        if interval:
+            self.logger.info(f"Pinging the systemd watchdog every {interval:g}s")
+            self._tasks.append(asyncio.create_task(self._watchdog_loop(interval)))
+
+    async def stop(self) -> None:
+        """Notify systemd of the shutdown and stop pinging the watchdog."""
+        for task in self._tasks:
+            task.cancel()
+        for task in self._tasks:
+            try:
+                await task
+            except asyncio.CancelledError:
+                pass
+        self._tasks = []
+        if self.enabled:
+            notify("STOPPING=1")
+
+    async def _notify_ready(self) -> None:
+        """Send READY=1 once the first scan completed and the server listens."""
+        waited = 0
+        while not await self.scanner.wait_for_first_scan(FIRST_SCAN_WAIT_SECONDS):
+            waited += FIRST_SCAN_WAIT_SECONDS
+            notify(f"STATUS=Waiting for the first scan ({waited}s)")
+        while not self.server_started():
+            await asyncio.sleep(READY_POLL_SECONDS)
+        notify("READY=1\nSTATUS=Serving metrics")
+        self.logger.info("Notified systemd of readiness")
+
+    async def _watchdog_loop(self, interval: float) -> None:
+        """Ping the watchdog while the event loop runs."""
+        while True:
+            notify("WATCHDOG=1")
+            await asyncio.sleep(interval)
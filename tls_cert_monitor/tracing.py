@@ -0,0 +1,143 @@
+"""
+OpenTelemetry tracing for TLS Certificate Monitor.
+
+Scans (with a span per directory and, optionally, per file) and HTTP requests are traced and
+exported over OTLP, e.g. to Jaeger or Tempo. Tracing needs the OpenTelemetry SDK and exporter
+(pip install .[tracing]); while it is disabled, spans are no-ops and nothing is imported.
+"""
+
+from contextlib import asynccontextmanager, contextmanager, nullcontext
+from typing import Any, AsyncIterator, ContextManager, Dict, Iterator, Mapping, Optional
+
+from tls_cert_monitor import __version__
+from tls_cert_monitor.config import TracingConfig
+
+# Set by setup_tracing; None while tracing is disabled
+_tracer: Any = None
+_provider: Any = None
+_file_spans = False
+
+
+def setup_tracing(config: TracingConfig) -> None:
+    """
+    Set up span export over OTLP.
+
+    Args:
+        config: Tracing settings
+
+    Raises:
+        RuntimeError: If the OpenTelemetry packages are not installed
+    """
+    global _tracer, _provider, _file_spans
+    shutdown_tracing()
+    if not config.enabled:
+        return
+
+    try:
+        from opentelemetry import trace
+        from opentelemetry.sdk.resources import Resource
+        from opentelemetry.sdk.trace import TracerProvider
+        from opentelemetry.sdk.trace.export import BatchSpanProcessor
+        from opentelemetry.sdk.trace.sampling import ParentBased, TraceIdRatioBased
+
+        if config.protocol == "grpc":
+            from opentelemetry.exporter.otlp.proto.grpc.trace_exporter import OTLPSpanExporter
+        else:
+            from opentelemetry.exporter.otlp.proto.http.trace_exporter import (  # type: ignore
+                OTLPSpanExporter,
+            )
+    except ImportError as e:
+        raise RuntimeError(
+            "Tracing requires the OpenTelemetry SDK and OTLP exporter (pip install .[tracing])"
+        ) from e
+
+    resource = Resource.create(
+        {"service.name": config.service_name, "service.version": __version__}
+    )
+    provider = TracerProvider(
+        resource=resource, sampler=ParentBased(TraceIdRatioBased(config.sample_ratio))
+    )
+    # Without an endpoint, the exporter reads OTEL_EXPORTER_OTLP_* (localhost by default)
+    exporter_args: Dict[str, Any] = {"headers": dict(config.headers) or None}
+    if config.endpoint:
+        exporter_args["endpoint"] = config.endpoint
+    provider.add_span_processor(BatchSpanProcessor(OTLPSpanExporter(**exporter_args)))
+
+    _provider = provider
+    _tracer = trace.get_tracer("tls_cert_monitor", __version__, tracer_provider=provider)
+    _file_spans = config.file_spans
+
+
+def shutdown_tracing() -> None:
+    """Export pending spans and stop tracing."""
+    global _tracer, _provider
+    if _provider is not None:
+        _provider.shutdown()
+    _tracer = None
+    _provider = None
+
+
+def tracing_enabled() -> bool:
+    """Check if spans are recorded."""
+    return _tracer is not None
+
+
+def start_span(name: str, attributes: Optional[Dict[str, Any]] = None) -> ContextManager[Any]:
+    """
+    Start a span as the current span (a no-op yielding None while tracing is disabled).
+
+    Args:
+        name: Span name
+        attributes: Span attributes
+
+    Returns:
+        Context manager yielding the span
+    """
+    if _tracer is None:
+        return nullcontext(None)
+    return _tracer.start_as_current_span(name, attributes=attributes)  # type: ignore
+
+
+@asynccontextmanager
+async def start_async_span(
+    name: str, attributes: Optional[Dict[str, Any]] = None
+) -> AsyncIterator[Any]:
+    """Start a span as the current span, for async with statements (see start_span)."""
+    with start_span(name, attributes) as span:
+        yield span
+
+
+def start_file_span(path: str) -> ContextManager[Any]:
+    """Start the span of a certificate file, unless file spans are turned off."""
+    if not _file_spans:
+        return nullcontext(None)
+    return start_span("scan.file", {"file.path": path})
+
+
+@contextmanager
+def start_request_span(method: str, path: str, headers: Mapping[str, str]) -> Iterator[Any]:
+    """
+    Start the server span of an HTTP request, continuing the caller's trace (traceparent).
+
+    Args:
+        method: HTTP method
+        path: Request path
+        headers: Request headers
+
+    Yields:
+        The span (None while tracing is disabled)
+    """
+    if _tracer is None:
+        yield None
+        return
+
+    from opentelemetry import propagate
+    from opentelemetry.trace import SpanKind
+
+    with _tracer.start_as_current_span(
+        f"{method} {path}",
+        context=propagate.extract(dict(headers)),
+        kind=SpanKind.SERVER,
+        attributes={"http.request.method": method, "url.path": path},
+    ) as span:
+        yield span
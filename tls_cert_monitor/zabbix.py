@@ -0,0 +1,170 @@
+"""
+Zabbix sender for TLS Certificate Monitor.
+
+After every scan, certificate expiry items are pushed to a Zabbix server or proxy trapper with
+the sender protocol, like zabbix_sender does. The items of each certificate are created by
+low-level discovery from the <prefix>.discovery item, sent along with them.
+"""
+
+import asyncio
+import json
+import socket
+import struct
+import time
+from typing import Any, Dict, List, Optional, Tuple
+
+from tls_cert_monitor.config import Config
+from tls_cert_monitor.logger import get_logger
+from tls_cert_monitor.metrics import SEVERITY_LEVELS, count_certificates_by_severity
+from tls_cert_monitor.scanner import CertificateScanner
+
+# Header of sender protocol packets: signature and protocol flags
+HEADER = b"ZBXD\x01"
+
+# Largest response accepted from the trapper
+MAX_RESPONSE_BYTES = 1024 * 1024
+
+
+def item_key(prefix: str, name: str, parameter: str) -> str:
+    """Build the item key <prefix>.<name>["<parameter>"], quoting the parameter."""
+    quoted = parameter.replace('"', '\\"')
+    return f'{prefix}.{name}["{quoted}"]'
+
+
+def build_items(
+    scan_results: Dict[str, Any], host: str, prefix: str, clock: int
+) -> List[Dict[str, Any]]:
+    """
+    Build the items of scan results.
+
+    Args:
+        scan_results: Results from CertificateScanner.scan_once()
+        host: Zabbix host the items belong to
+        prefix: Prefix of the item keys
+        clock: Unix timestamp of the values
+
+    Returns:
+        Sender data: the discovery item, per-certificate items (days, expiry, severity)
+        and summary items (certificates by severity, parse errors)
+    """
+    certificates: Dict[str, Tuple[str, Dict[str, Any]]] = {}
+    for directory, result in scan_results.get("directories", {}).items():
+        for cert in result.get("certificates", []):
+            certificates.setdefault(cert.get("path", ""), (directory, cert))
+
+    def item(key: str, value: Any) -> Dict[str, Any]:
+        return {"host": host, "key": key, "value": str(value), "clock": clock}
+
+    discovery = [
+        {"{#PATH}": path, "{#CN}": cert.get("common_name", "unknown"), "{#DIRECTORY}": directory}
+        for path, (directory, cert) in certificates.items()
+    ]
+    items = [item(f"{prefix}.discovery", json.dumps(discovery))]
+    for path, (_, cert) in certificates.items():
+        severity = cert.get("severity", "ok")
+        items.extend(
+            [
+                item(item_key(prefix, "days", path), cert.get("days_until_expiry", 0)),
+                item(item_key(prefix, "expiry", path), int(cert.get("expiration_timestamp", 0))),
+                item(item_key(prefix, "severity", path), SEVERITY_LEVELS.index(severity)),
+            ]
+        )
+
+    # Silenced certificates are acknowledged: not counted, as in alerts
+    counts = count_certificates_by_severity(
+        scan_results.get("directories", {}), include_silenced=False
+    )
+    items.extend(item(item_key(prefix, "certificates", s), counts[s]) for s in SEVERITY_LEVELS)
+    errors = scan_results.get("summary", {}).get("total_errors", 0)
+    items.append(item(f"{prefix}.parse_errors", errors))
+    return items
+
+
+def encode_packet(data: Dict[str, Any]) -> bytes:
+    """Encode a sender protocol packet: header, data length, reserved length, JSON."""
+    payload = json.dumps(data).encode("utf-8")
+    return HEADER + struct.pack("<II", len(payload), 0) + payload
+
+
+async def send_items(
+    server: Tuple[str, int], items: List[Dict[str, Any]], timeout: float
+) -> Dict[str, Any]:
+    """
+    Send items to a Zabbix trapper.
+
+    Args:
+        server: Trapper (host, port)
+        items: Sender data from build_items
+        timeout: Seconds to connect and get the response
+
+    Returns:
+        Response of the trapper: response (success), info (processed, failed, total)
+
+    Raises:
+        OSError: If the trapper cannot be reached
+        asyncio.TimeoutError: If it does not respond in time
+        ValueError: If the response is invalid or not a success
+    """
+
+    async def exchange() -> bytes:
+        reader, writer = await asyncio.open_connection(*server)
+        try:
+            writer.write(encode_packet({"request": "sender data", "data": items}))
+            await writer.drain()
+            header = await reader.readexactly(len(HEADER) + 8)
+            if not header.startswith(HEADER):
+                raise ValueError("Not a Zabbix sender protocol response")
+            length = struct.unpack("<I", header[len(HEADER) : len(HEADER) + 4])[0]
+            if length > MAX_RESPONSE_BYTES:
+                raise ValueError(f"Response too large ({length} bytes)")
+            return await reader.readexactly(length)
+        finally:
+            writer.close()
+
+    try:
+        payload = await asyncio.wait_for(exchange(), timeout)
+    except asyncio.IncompleteReadError as e:
+        raise ValueError("Connection closed before the response was complete") from e
+    response = json.loads(payload)
+    if response.get("response") != "success":
+        raise ValueError(f"Zabbix rejected the data: {response.get('info', response)}")
+    return response
+
+
+class ZabbixSender:
+    """Push certificate expiry items to a Zabbix trapper after every scan."""
+
+    def __init__(self, config: Config, scanner: CertificateScanner):
+        self.config = config.zabbix
+        self.host = config.zabbix.host or socket.gethostname()
+        self.logger = get_logger("zabbix")
+        self.last_sent: Optional[float] = None
+
+        scanner.add_scan_listener(self._on_scan_complete)
+
+    async def _on_scan_complete(self, scan_results: Dict[str, Any]) -> None:
+        """Send the items of the scan."""
+        await self.send(scan_results)
+
+    async def send(self, scan_results: Dict[str, Any]) -> bool:
+        """
+        Send the items of scan results, logging failures.
+
+        Returns:
+            True if the trapper accepted the data
+        """
+        items = build_items(scan_results, self.host, self.config.key_prefix, int(time.time()))
+        try:
+            response = await send_items(
+                self.config.server_address(), items, self.config.timeout_seconds
+            )
+        except (OSError, asyncio.TimeoutError, ValueError) as e:
+            self.logger.warning(f"Failed to send items to Zabbix server {self.config.server}: {e}")
+            return False
+
+        self.last_sent = time.time()
+        self.logger.info(
+            f"Sent {len(items)} items to Zabbix server {self.config.server} "
+            f"as host {self.host}: {response.get('info', '')}"
+        )
+        return True